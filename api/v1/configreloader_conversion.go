@@ -0,0 +1,424 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"fmt"
+
+	"sigs.k8s.io/controller-runtime/pkg/conversion"
+
+	configv2 "github.com/shehbazk/config-reloader-operator/api/v2"
+)
+
+// ConvertTo converts this ConfigReloader (v1) to the Hub version (v2).
+func (src *ConfigReloader) ConvertTo(dstRaw conversion.Hub) error {
+	dst, ok := dstRaw.(*configv2.ConfigReloader)
+	if !ok {
+		return fmt.Errorf("expected a v2 ConfigReloader but got %T", dstRaw)
+	}
+
+	dst.ObjectMeta = src.ObjectMeta
+
+	dst.Spec.Trigger.ConfigMaps = convertRefsToV2(src.Spec.ConfigMaps)
+	dst.Spec.Trigger.Secrets = convertRefsToV2(src.Spec.Secrets)
+	dst.Spec.Trigger.IgnoredFieldManagers = src.Spec.IgnoredFieldManagers
+	dst.Spec.Targets.Selector = src.Spec.Selector
+	dst.Spec.Targets.OwnerReferences = configv2.OwnerReferencePolicyInclude
+	if src.Spec.IgnoreOwnerReferences {
+		dst.Spec.Targets.OwnerReferences = configv2.OwnerReferencePolicyIgnore
+	}
+	dst.Spec.Strategy.Type = convertPolicyToV2(src.Spec.RestartPolicy)
+	dst.Spec.DryRun = src.Spec.DryRun
+	dst.Spec.Suspend = src.Spec.Suspend
+	dst.Spec.ApplyPendingOnResume = src.Spec.ApplyPendingOnResume
+	dst.Spec.History.Limit = src.Spec.HistoryLimit
+	dst.Spec.History.TTL = src.Spec.HistoryTTL
+	dst.Spec.History.Revisions = src.Spec.RevisionHistoryLimit
+	dst.Spec.DeletionPolicy = configv2.DeletionPolicy(src.Spec.DeletionPolicy)
+	dst.Spec.Priority = src.Spec.Priority
+	dst.Spec.Approval = (*configv2.ApprovalPolicy)(src.Spec.Approval)
+	dst.Spec.Strategy.Canary = convertCanaryToV2(src.Spec.Canary)
+
+	dst.Status.ObservedGeneration = src.Status.ObservedGeneration
+	dst.Status.Conditions = src.Status.Conditions
+	dst.Status.LastReloadTime = src.Status.LastReloadTime
+	dst.Status.PendingReload = src.Status.PendingReload
+	dst.Status.LastReloadRequest = src.Status.LastReloadRequest
+	if src.Status.PendingApproval != nil {
+		dst.Status.PendingApproval = &configv2.PendingApproval{
+			ID:             src.Status.PendingApproval.ID,
+			Cause:          src.Status.PendingApproval.Cause,
+			RequestTime:    src.Status.PendingApproval.RequestTime,
+			ExpirationTime: src.Status.PendingApproval.ExpirationTime,
+			Resources:      convertChangedResourcesToV2(src.Status.PendingApproval.Resources),
+		}
+	}
+	dst.Status.LastApproval = (*configv2.ApprovalRecord)(src.Status.LastApproval)
+	dst.Status.Canary = convertCanaryStatusToV2(src.Status.Canary)
+	if src.Status.WatchedResources != nil {
+		dst.Status.WatchedResources = make([]configv2.WatchedResource, len(src.Status.WatchedResources))
+		for i, watched := range src.Status.WatchedResources {
+			dst.Status.WatchedResources[i] = configv2.WatchedResource{
+				Kind:            watched.Kind,
+				Name:            watched.Name,
+				Namespace:       watched.Namespace,
+				ResourceVersion: watched.ResourceVersion,
+				LastUpdateTime:  watched.LastUpdateTime,
+				LastChangedBy:   (*configv2.ChangeAttribution)(watched.LastChangedBy),
+				KeysHash:        watched.KeysHash,
+			}
+		}
+	}
+	if src.Status.PodsRestarted != nil {
+		dst.Status.PodsRestarted = make([]configv2.PodRestart, len(src.Status.PodsRestarted))
+		for i, restart := range src.Status.PodsRestarted {
+			dst.Status.PodsRestarted[i] = configv2.PodRestart(restart)
+		}
+	}
+	if src.Status.DryRunPlan != nil {
+		dst.Status.DryRunPlan = make([]configv2.PlannedRestart, len(src.Status.DryRunPlan))
+		for i, planned := range src.Status.DryRunPlan {
+			dst.Status.DryRunPlan[i] = configv2.PlannedRestart{
+				PodName:      planned.PodName,
+				Namespace:    planned.Namespace,
+				WorkloadKind: planned.WorkloadKind,
+				WorkloadName: planned.WorkloadName,
+				Strategy:     convertPolicyToV2(planned.Policy),
+			}
+		}
+	}
+	if src.Status.FailedRestarts != nil {
+		dst.Status.FailedRestarts = make([]configv2.FailedRestart, len(src.Status.FailedRestarts))
+		for i, failed := range src.Status.FailedRestarts {
+			dst.Status.FailedRestarts[i] = configv2.FailedRestart(failed)
+		}
+	}
+	if src.Status.Targets != nil {
+		dst.Status.Targets = make([]configv2.TargetStatus, len(src.Status.Targets))
+		for i, target := range src.Status.Targets {
+			dst.Status.Targets[i] = configv2.TargetStatus{
+				Kind:                target.Kind,
+				Name:                target.Name,
+				Namespace:           target.Namespace,
+				LastConfigHash:      target.LastConfigHash,
+				LastRestartTime:     target.LastRestartTime,
+				RolloutState:        configv2.RolloutState(target.RolloutState),
+				ConsecutiveFailures: target.ConsecutiveFailures,
+			}
+		}
+	}
+	dst.Status.Summary = configv2.TargetSummary(src.Status.Summary)
+	if src.Status.Cleanup != nil {
+		dst.Status.Cleanup = &configv2.CleanupStatus{
+			Cleaned:        src.Status.Cleanup.Cleaned,
+			HistoryDeleted: src.Status.Cleanup.HistoryDeleted,
+			LastError:      src.Status.Cleanup.LastError,
+		}
+		if src.Status.Cleanup.Pending != nil {
+			dst.Status.Cleanup.Pending = make([]configv2.CleanupTarget, len(src.Status.Cleanup.Pending))
+			for i, target := range src.Status.Cleanup.Pending {
+				dst.Status.Cleanup.Pending[i] = configv2.CleanupTarget(target)
+			}
+		}
+	}
+
+	return nil
+}
+
+// ConvertFrom converts the Hub version (v2) to this version (v1).
+func (dst *ConfigReloader) ConvertFrom(srcRaw conversion.Hub) error {
+	src, ok := srcRaw.(*configv2.ConfigReloader)
+	if !ok {
+		return fmt.Errorf("expected a v2 ConfigReloader but got %T", srcRaw)
+	}
+
+	dst.ObjectMeta = src.ObjectMeta
+
+	dst.Spec.ConfigMaps = convertRefsFromV2(src.Spec.Trigger.ConfigMaps)
+	dst.Spec.Secrets = convertRefsFromV2(src.Spec.Trigger.Secrets)
+	dst.Spec.IgnoredFieldManagers = src.Spec.Trigger.IgnoredFieldManagers
+	dst.Spec.Selector = src.Spec.Targets.Selector
+	dst.Spec.IgnoreOwnerReferences = src.Spec.Targets.OwnerReferences == configv2.OwnerReferencePolicyIgnore
+	dst.Spec.RestartPolicy = convertPolicyFromV2(src.Spec.Strategy.Type)
+	dst.Spec.DryRun = src.Spec.DryRun
+	dst.Spec.Suspend = src.Spec.Suspend
+	dst.Spec.ApplyPendingOnResume = src.Spec.ApplyPendingOnResume
+	dst.Spec.HistoryLimit = src.Spec.History.Limit
+	dst.Spec.HistoryTTL = src.Spec.History.TTL
+	dst.Spec.RevisionHistoryLimit = src.Spec.History.Revisions
+	dst.Spec.DeletionPolicy = DeletionPolicy(src.Spec.DeletionPolicy)
+	dst.Spec.Priority = src.Spec.Priority
+	dst.Spec.Approval = (*ApprovalPolicy)(src.Spec.Approval)
+	dst.Spec.Canary = convertCanaryFromV2(src.Spec.Strategy.Canary)
+
+	dst.Status.ObservedGeneration = src.Status.ObservedGeneration
+	dst.Status.Conditions = src.Status.Conditions
+	dst.Status.LastReloadTime = src.Status.LastReloadTime
+	dst.Status.PendingReload = src.Status.PendingReload
+	dst.Status.LastReloadRequest = src.Status.LastReloadRequest
+	if src.Status.PendingApproval != nil {
+		dst.Status.PendingApproval = &PendingApproval{
+			ID:             src.Status.PendingApproval.ID,
+			Cause:          src.Status.PendingApproval.Cause,
+			RequestTime:    src.Status.PendingApproval.RequestTime,
+			ExpirationTime: src.Status.PendingApproval.ExpirationTime,
+			Resources:      convertChangedResourcesFromV2(src.Status.PendingApproval.Resources),
+		}
+	}
+	dst.Status.LastApproval = (*ApprovalRecord)(src.Status.LastApproval)
+	dst.Status.Canary = convertCanaryStatusFromV2(src.Status.Canary)
+	if src.Status.WatchedResources != nil {
+		dst.Status.WatchedResources = make([]WatchedResource, len(src.Status.WatchedResources))
+		for i, watched := range src.Status.WatchedResources {
+			dst.Status.WatchedResources[i] = WatchedResource{
+				Kind:            watched.Kind,
+				Name:            watched.Name,
+				Namespace:       watched.Namespace,
+				ResourceVersion: watched.ResourceVersion,
+				LastUpdateTime:  watched.LastUpdateTime,
+				LastChangedBy:   (*ChangeAttribution)(watched.LastChangedBy),
+				KeysHash:        watched.KeysHash,
+			}
+		}
+	}
+	if src.Status.PodsRestarted != nil {
+		dst.Status.PodsRestarted = make([]PodRestart, len(src.Status.PodsRestarted))
+		for i, restart := range src.Status.PodsRestarted {
+			dst.Status.PodsRestarted[i] = PodRestart(restart)
+		}
+	}
+	if src.Status.DryRunPlan != nil {
+		dst.Status.DryRunPlan = make([]PlannedRestart, len(src.Status.DryRunPlan))
+		for i, planned := range src.Status.DryRunPlan {
+			dst.Status.DryRunPlan[i] = PlannedRestart{
+				PodName:      planned.PodName,
+				Namespace:    planned.Namespace,
+				WorkloadKind: planned.WorkloadKind,
+				WorkloadName: planned.WorkloadName,
+				Policy:       convertPolicyFromV2(planned.Strategy),
+			}
+		}
+	}
+	if src.Status.FailedRestarts != nil {
+		dst.Status.FailedRestarts = make([]FailedRestart, len(src.Status.FailedRestarts))
+		for i, failed := range src.Status.FailedRestarts {
+			dst.Status.FailedRestarts[i] = FailedRestart(failed)
+		}
+	}
+	if src.Status.Targets != nil {
+		dst.Status.Targets = make([]TargetStatus, len(src.Status.Targets))
+		for i, target := range src.Status.Targets {
+			dst.Status.Targets[i] = TargetStatus{
+				Kind:                target.Kind,
+				Name:                target.Name,
+				Namespace:           target.Namespace,
+				LastConfigHash:      target.LastConfigHash,
+				LastRestartTime:     target.LastRestartTime,
+				RolloutState:        RolloutState(target.RolloutState),
+				ConsecutiveFailures: target.ConsecutiveFailures,
+			}
+		}
+	}
+	dst.Status.Summary = TargetSummary(src.Status.Summary)
+	if src.Status.Cleanup != nil {
+		dst.Status.Cleanup = &CleanupStatus{
+			Cleaned:        src.Status.Cleanup.Cleaned,
+			HistoryDeleted: src.Status.Cleanup.HistoryDeleted,
+			LastError:      src.Status.Cleanup.LastError,
+		}
+		if src.Status.Cleanup.Pending != nil {
+			dst.Status.Cleanup.Pending = make([]CleanupTarget, len(src.Status.Cleanup.Pending))
+			for i, target := range src.Status.Cleanup.Pending {
+				dst.Status.Cleanup.Pending[i] = CleanupTarget(target)
+			}
+		}
+	}
+
+	return nil
+}
+
+func convertRefsToV2(refs []ResourceRef) []configv2.ObjectReference {
+	if refs == nil {
+		return nil
+	}
+	out := make([]configv2.ObjectReference, len(refs))
+	for i, ref := range refs {
+		out[i] = configv2.ObjectReference(ref)
+	}
+	return out
+}
+
+func convertRefsFromV2(refs []configv2.ObjectReference) []ResourceRef {
+	if refs == nil {
+		return nil
+	}
+	out := make([]ResourceRef, len(refs))
+	for i, ref := range refs {
+		out[i] = ResourceRef(ref)
+	}
+	return out
+}
+
+// convertPolicyToV2 maps a restart policy to its strategy type. Unknown values
+// are passed through so they round-trip and fail validation of the target version.
+func convertPolicyToV2(policy RestartPolicy) configv2.StrategyType {
+	switch policy {
+	case RestartPolicyAnnotation:
+		return configv2.StrategyRolloutRestart
+	case RestartPolicyDelete:
+		return configv2.StrategyDeletePods
+	default:
+		return configv2.StrategyType(policy)
+	}
+}
+
+func convertPolicyFromV2(strategy configv2.StrategyType) RestartPolicy {
+	switch strategy {
+	case configv2.StrategyRolloutRestart:
+		return RestartPolicyAnnotation
+	case configv2.StrategyDeletePods:
+		return RestartPolicyDelete
+	default:
+		return RestartPolicy(strategy)
+	}
+}
+
+func convertChangedResourcesToV2(resources []ChangedResource) []configv2.ChangedResource {
+	if resources == nil {
+		return nil
+	}
+	out := make([]configv2.ChangedResource, len(resources))
+	for i, resource := range resources {
+		out[i] = configv2.ChangedResource{
+			Kind:            resource.Kind,
+			Name:            resource.Name,
+			Namespace:       resource.Namespace,
+			PreviousVersion: resource.PreviousVersion,
+			Version:         resource.Version,
+			ChangedBy:       (*configv2.ChangeAttribution)(resource.ChangedBy),
+			Keys:            convertKeyChangesToV2(resource.Keys),
+		}
+	}
+	return out
+}
+
+func convertKeyChangesToV2(keys []KeyChange) []configv2.KeyChange {
+	if keys == nil {
+		return nil
+	}
+	out := make([]configv2.KeyChange, len(keys))
+	for i, key := range keys {
+		out[i] = configv2.KeyChange{Key: key.Key, Type: configv2.KeyChangeType(key.Type)}
+	}
+	return out
+}
+
+func convertKeyChangesFromV2(keys []configv2.KeyChange) []KeyChange {
+	if keys == nil {
+		return nil
+	}
+	out := make([]KeyChange, len(keys))
+	for i, key := range keys {
+		out[i] = KeyChange{Key: key.Key, Type: KeyChangeType(key.Type)}
+	}
+	return out
+}
+
+func convertChangedResourcesFromV2(resources []configv2.ChangedResource) []ChangedResource {
+	if resources == nil {
+		return nil
+	}
+	out := make([]ChangedResource, len(resources))
+	for i, resource := range resources {
+		out[i] = ChangedResource{
+			Kind:            resource.Kind,
+			Name:            resource.Name,
+			Namespace:       resource.Namespace,
+			PreviousVersion: resource.PreviousVersion,
+			Version:         resource.Version,
+			ChangedBy:       (*ChangeAttribution)(resource.ChangedBy),
+			Keys:            convertKeyChangesFromV2(resource.Keys),
+		}
+	}
+	return out
+}
+
+func convertCanaryToV2(canary *CanaryStrategy) *configv2.CanaryStrategy {
+	if canary == nil {
+		return nil
+	}
+	return &configv2.CanaryStrategy{
+		Percent:              canary.Percent,
+		BakeTime:             canary.BakeTime,
+		MaxContainerRestarts: canary.MaxContainerRestarts,
+		Analysis:             (*configv2.CanaryAnalysis)(canary.Analysis),
+	}
+}
+
+func convertCanaryFromV2(canary *configv2.CanaryStrategy) *CanaryStrategy {
+	if canary == nil {
+		return nil
+	}
+	return &CanaryStrategy{
+		Percent:              canary.Percent,
+		BakeTime:             canary.BakeTime,
+		MaxContainerRestarts: canary.MaxContainerRestarts,
+		Analysis:             (*CanaryAnalysis)(canary.Analysis),
+	}
+}
+
+func convertCanaryStatusToV2(canary *CanaryStatus) *configv2.CanaryStatus {
+	if canary == nil {
+		return nil
+	}
+	out := &configv2.CanaryStatus{
+		Phase:          configv2.CanaryPhase(canary.Phase),
+		Cause:          canary.Cause,
+		Resources:      convertChangedResourcesToV2(canary.Resources),
+		StartTime:      canary.StartTime,
+		CompletionTime: canary.CompletionTime,
+		Message:        canary.Message,
+	}
+	if canary.Targets != nil {
+		out.Targets = make([]configv2.CanaryTarget, len(canary.Targets))
+		for i, target := range canary.Targets {
+			out.Targets[i] = configv2.CanaryTarget(target)
+		}
+	}
+	return out
+}
+
+func convertCanaryStatusFromV2(canary *configv2.CanaryStatus) *CanaryStatus {
+	if canary == nil {
+		return nil
+	}
+	out := &CanaryStatus{
+		Phase:          CanaryPhase(canary.Phase),
+		Cause:          canary.Cause,
+		Resources:      convertChangedResourcesFromV2(canary.Resources),
+		StartTime:      canary.StartTime,
+		CompletionTime: canary.CompletionTime,
+		Message:        canary.Message,
+	}
+	if canary.Targets != nil {
+		out.Targets = make([]CanaryTarget, len(canary.Targets))
+		for i, target := range canary.Targets {
+			out.Targets[i] = CanaryTarget(target)
+		}
+	}
+	return out
+}
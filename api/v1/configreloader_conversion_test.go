@@ -0,0 +1,155 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"math/rand"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/api/apitesting/fuzzer"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	metafuzzer "k8s.io/apimachinery/pkg/apis/meta/fuzzer"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	runtimeserializer "k8s.io/apimachinery/pkg/runtime/serializer"
+	"k8s.io/apimachinery/pkg/util/diff"
+	"sigs.k8s.io/randfill"
+
+	configv2 "github.com/shehbazk/config-reloader-operator/api/v2"
+)
+
+const fuzzIterations = 1000
+
+// conversionFuzzerFuncs restricts enums to the values admitted by the CRD
+// schemas, which are the values the conversion maps between versions
+func conversionFuzzerFuncs(_ runtimeserializer.CodecFactory) []interface{} {
+	policies := []RestartPolicy{RestartPolicyAnnotation, RestartPolicyDelete}
+	strategies := []configv2.StrategyType{configv2.StrategyRolloutRestart, configv2.StrategyDeletePods}
+	ownerPolicies := []configv2.OwnerReferencePolicy{
+		configv2.OwnerReferencePolicyInclude,
+		configv2.OwnerReferencePolicyIgnore,
+	}
+
+	return []interface{}{
+		func(in *ConfigReloader, c randfill.Continue) {
+			c.FillNoCustom(in)
+			in.TypeMeta = metav1.TypeMeta{}
+		},
+		func(in *configv2.ConfigReloader, c randfill.Continue) {
+			c.FillNoCustom(in)
+			in.TypeMeta = metav1.TypeMeta{}
+		},
+		func(in *RestartPolicy, c randfill.Continue) {
+			*in = policies[c.Intn(len(policies))]
+		},
+		func(in *configv2.StrategyType, c randfill.Continue) {
+			*in = strategies[c.Intn(len(strategies))]
+		},
+		func(in *configv2.OwnerReferencePolicy, c randfill.Continue) {
+			*in = ownerPolicies[c.Intn(len(ownerPolicies))]
+		},
+	}
+}
+
+// newConversionFiller returns a filler for both versions seeded from src, or
+// from data when fuzzing
+func newConversionFiller(t testing.TB, src rand.Source, data []byte) *randfill.Filler {
+	scheme := runtime.NewScheme()
+	if err := AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := configv2.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	funcs := fuzzer.MergeFuzzerFuncs(metafuzzer.Funcs, conversionFuzzerFuncs)
+	codecs := runtimeserializer.NewCodecFactory(scheme)
+	if data != nil {
+		return randfill.NewFromGoFuzz(data).NilChance(.5).NumElements(0, 1).Funcs(funcs(codecs)...)
+	}
+	return fuzzer.FuzzerFor(funcs, src, codecs)
+}
+
+// roundTripSpoke converts a v1 ConfigReloader to v2 and back
+func roundTripSpoke(t testing.TB, in *ConfigReloader) {
+	t.Helper()
+
+	hub := &configv2.ConfigReloader{}
+	if err := in.DeepCopy().ConvertTo(hub); err != nil {
+		t.Fatalf("failed to convert v1 to v2: %v", err)
+	}
+	out := &ConfigReloader{}
+	if err := out.ConvertFrom(hub); err != nil {
+		t.Fatalf("failed to convert v2 to v1: %v", err)
+	}
+	if !apiequality.Semantic.DeepEqual(in, out) {
+		t.Fatalf("v1 -> v2 -> v1 round trip is lossy:\n%s", diff.ObjectReflectDiff(in, out))
+	}
+}
+
+// roundTripHub converts a v2 ConfigReloader to v1 and back
+func roundTripHub(t testing.TB, in *configv2.ConfigReloader) {
+	t.Helper()
+
+	spoke := &ConfigReloader{}
+	if err := spoke.ConvertFrom(in.DeepCopy()); err != nil {
+		t.Fatalf("failed to convert v2 to v1: %v", err)
+	}
+	out := &configv2.ConfigReloader{}
+	if err := spoke.ConvertTo(out); err != nil {
+		t.Fatalf("failed to convert v1 to v2: %v", err)
+	}
+	if !apiequality.Semantic.DeepEqual(in, out) {
+		t.Fatalf("v2 -> v1 -> v2 round trip is lossy:\n%s", diff.ObjectReflectDiff(in, out))
+	}
+}
+
+func TestConversionRoundTrip(t *testing.T) {
+	filler := newConversionFiller(t, rand.NewSource(rand.Int63()), nil)
+
+	t.Run("spoke-hub-spoke", func(t *testing.T) {
+		for range fuzzIterations {
+			in := &ConfigReloader{}
+			filler.Fill(in)
+			roundTripSpoke(t, in)
+		}
+	})
+
+	t.Run("hub-spoke-hub", func(t *testing.T) {
+		for range fuzzIterations {
+			in := &configv2.ConfigReloader{}
+			filler.Fill(in)
+			roundTripHub(t, in)
+		}
+	})
+}
+
+func FuzzConversionRoundTrip(f *testing.F) {
+	f.Add([]byte("config-reloader"))
+	f.Add([]byte{0, 1, 2, 3, 4, 5, 6, 7, 8, 9})
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		filler := newConversionFiller(t, nil, data)
+
+		spoke := &ConfigReloader{}
+		filler.Fill(spoke)
+		roundTripSpoke(t, spoke)
+
+		hub := &configv2.ConfigReloader{}
+		filler.Fill(hub)
+		roundTripHub(t, hub)
+	})
+}
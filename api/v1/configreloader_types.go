@@ -4,6 +4,15 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
+const (
+	// ApproveAnnotation approves the reload held by a ConfigReloader whose
+	// pending approval ID it is set to
+	ApproveAnnotation = "config.dev/approve"
+	// ApprovedByAnnotation is the user who set the approve annotation,
+	// recorded by the admission webhook
+	ApprovedByAnnotation = "config.dev/approved-by"
+)
+
 // ConfigReloaderSpec defines the desired state of ConfigReloader
 type ConfigReloaderSpec struct {
 	// ConfigMaps to watch for changes
@@ -14,6 +23,12 @@ type ConfigReloaderSpec struct {
 	// +optional
 	Secrets []ResourceRef `json:"secrets,omitempty"`
 
+	// IgnoredFieldManagers lists field managers whose changes to the watched
+	// resources do not trigger a reload, for example the manager of a
+	// controller that rewrites the data without changing it
+	// +optional
+	IgnoredFieldManagers []string `json:"ignoredFieldManagers,omitempty"`
+
 	// Selector for pods to restart when config changes
 	Selector *metav1.LabelSelector `json:"selector,omitempty"`
 
@@ -25,6 +40,121 @@ type ConfigReloaderSpec struct {
 	// IgnoreOwnerReferences ignores pods that are owned by controllers
 	// +kubebuilder:default=false
 	IgnoreOwnerReferences bool `json:"ignoreOwnerReferences,omitempty"`
+
+	// DryRun computes the restart plan and reports it in status and Events
+	// without restarting any pods
+	// +kubebuilder:default=false
+	// +optional
+	DryRun bool `json:"dryRun,omitempty"`
+
+	// Suspend pauses restarts. Changes are still tracked while suspended.
+	// +kubebuilder:default=false
+	// +optional
+	Suspend bool `json:"suspend,omitempty"`
+
+	// ApplyPendingOnResume restarts pods once on resume if changes were
+	// detected while suspended
+	// +kubebuilder:default=false
+	// +optional
+	ApplyPendingOnResume bool `json:"applyPendingOnResume,omitempty"`
+
+	// HistoryLimit is the number of ReloadEvents kept for this ConfigReloader.
+	// Zero disables recording.
+	// +kubebuilder:default=10
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	HistoryLimit *int32 `json:"historyLimit,omitempty"`
+
+	// HistoryTTL deletes ReloadEvents older than this duration
+	// +optional
+	HistoryTTL *metav1.Duration `json:"historyTTL,omitempty"`
+
+	// RevisionHistoryLimit enables ConfigRevision snapshots of the watched
+	// resources and sets how many are kept per resource. Secrets only record
+	// key names and hashes.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	RevisionHistoryLimit *int32 `json:"revisionHistoryLimit,omitempty"`
+
+	// DeletionPolicy defines what happens to the workloads restarted by this
+	// ConfigReloader when it is deleted. Retain, the default, leaves them in
+	// place. Cleanup removes the restart annotations added by the operator,
+	// which rolls the workloads that carried them once more. Reload restarts
+	// the affected pods a last time while cleaning up.
+	// +kubebuilder:default=Retain
+	// +optional
+	DeletionPolicy DeletionPolicy `json:"deletionPolicy,omitempty"`
+
+	// Priority decides which ConfigReloader restarts a workload that is
+	// selected by several ConfigReloaders reacting to the same change. The
+	// one with the highest priority restarts it using its own strategy and
+	// the others skip it. Ties are broken by name.
+	// +optional
+	Priority int32 `json:"priority,omitempty"`
+
+	// Approval holds reloads until they are approved, for namespaces where
+	// config changes must be reviewed before pods are restarted. The held
+	// changes are listed in status.pendingApproval and applied once a user
+	// allowed to approve the ConfigReloader sets the config.dev/approve
+	// annotation to their ID.
+	// +optional
+	Approval *ApprovalPolicy `json:"approval,omitempty"`
+
+	// Canary restarts a fraction of the targets first and restarts the rest
+	// only once they stayed healthy for the bake time. The reload is aborted
+	// and the rest left untouched when a canary fails.
+	// +optional
+	Canary *CanaryStrategy `json:"canary,omitempty"`
+}
+
+// ApprovalPolicy configures the approval of reloads
+type ApprovalPolicy struct {
+	// Timeout discards the held changes when they are not approved in time
+	// +kubebuilder:default="24h"
+	// +optional
+	Timeout *metav1.Duration `json:"timeout,omitempty"`
+}
+
+// CanaryStrategy restarts a fraction of the targets before the rest
+type CanaryStrategy struct {
+	// Percent of the targets restarted first, rounded up to at least one.
+	// Targets are the owning workloads when they are rolled and the pods
+	// when they are deleted. The pods of a single rolled workload are the
+	// targets too, and its canaries are deleted.
+	// +kubebuilder:default=10
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=100
+	// +optional
+	Percent int32 `json:"percent,omitempty"`
+
+	// BakeTime the canaries must stay healthy before the rest is restarted
+	// +kubebuilder:default="5m"
+	// +optional
+	BakeTime *metav1.Duration `json:"bakeTime,omitempty"`
+
+	// MaxContainerRestarts is the number of container restarts tolerated in
+	// a canary pod during the bake time
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MaxContainerRestarts int32 `json:"maxContainerRestarts,omitempty"`
+
+	// Analysis checks a Prometheus-compatible query endpoint during the bake time
+	// +optional
+	Analysis *CanaryAnalysis `json:"analysis,omitempty"`
+}
+
+// CanaryAnalysis is an instant query run against a Prometheus-compatible HTTP API
+type CanaryAnalysis struct {
+	// Address of the API, for example http://prometheus.monitoring:9090. It
+	// must be one of the addresses the operator allows with
+	// --canary-analysis-addresses, or the canary is aborted.
+	// +kubebuilder:validation:MinLength=1
+	Address string `json:"address"`
+
+	// Query returns the unhealthy series. The canary is aborted when it
+	// returns a sample with a non-zero value or fails.
+	// +kubebuilder:validation:MinLength=1
+	Query string `json:"query"`
 }
 
 // ResourceRef references a ConfigMap or Secret
@@ -35,6 +165,13 @@ type ResourceRef struct {
 	// Namespace of the resource (defaults to same namespace as ConfigReloader)
 	// +optional
 	Namespace string `json:"namespace,omitempty"`
+
+	// Keys restricts the reloads to changes of these data keys. Changes
+	// that leave them untouched are recorded without restarting anything.
+	// All keys trigger a reload when empty.
+	// +optional
+	// +listType=set
+	Keys []string `json:"keys,omitempty"`
 }
 
 // RestartPolicy defines restart strategies
@@ -46,8 +183,25 @@ const (
 	RestartPolicyDelete     RestartPolicy = "delete"
 )
 
+// DeletionPolicy defines the cleanup performed when a ConfigReloader is deleted
+// +kubebuilder:validation:Enum=Cleanup;Retain;Reload
+type DeletionPolicy string
+
+const (
+	// DeletionPolicyCleanup removes the annotations added by the operator
+	DeletionPolicyCleanup DeletionPolicy = "Cleanup"
+	// DeletionPolicyRetain leaves the workloads and pods untouched
+	DeletionPolicyRetain DeletionPolicy = "Retain"
+	// DeletionPolicyReload restarts the affected pods a last time
+	DeletionPolicyReload DeletionPolicy = "Reload"
+)
+
 // ConfigReloaderStatus defines the observed state of ConfigReloader
 type ConfigReloaderStatus struct {
+	// ObservedGeneration is the generation of the spec last reconciled
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
 	// Conditions represent the latest available observations
 	Conditions []metav1.Condition `json:"conditions,omitempty"`
 
@@ -59,9 +213,231 @@ type ConfigReloaderStatus struct {
 	// +optional
 	WatchedResources []WatchedResource `json:"watchedResources,omitempty"`
 
-	// PodsRestarted tracks recently restarted pods
+	// PodsRestarted tracks the last 10 restarted pods. The full history is
+	// recorded in ReloadEvents.
 	// +optional
 	PodsRestarted []PodRestart `json:"podsRestarted,omitempty"`
+
+	// DryRunPlan lists the restarts that would have been performed by the
+	// last change detected while running in dry-run mode
+	// +optional
+	DryRunPlan []PlannedRestart `json:"dryRunPlan,omitempty"`
+
+	// PendingReload is set when changes were detected while suspended
+	// +optional
+	PendingReload bool `json:"pendingReload,omitempty"`
+
+	// LastReloadRequest is the last handled value of the
+	// config.dev/reload-requested-at annotation
+	// +optional
+	LastReloadRequest string `json:"lastReloadRequest,omitempty"`
+
+	// FailedRestarts lists the targets whose last restart attempt failed.
+	// They are retried with exponential backoff.
+	// +optional
+	FailedRestarts []FailedRestart `json:"failedRestarts,omitempty"`
+
+	// Targets reports the state of every workload restarted by this
+	// ConfigReloader. Standalone pods are reported with the Pod kind.
+	// +optional
+	Targets []TargetStatus `json:"targets,omitempty"`
+
+	// Summary counts the targets by rollout state
+	// +optional
+	Summary TargetSummary `json:"summary,omitempty"`
+
+	// Cleanup reports the progress of the cleanup once the ConfigReloader is
+	// deleted
+	// +optional
+	Cleanup *CleanupStatus `json:"cleanup,omitempty"`
+
+	// PendingApproval is the reload held until it is approved
+	// +optional
+	PendingApproval *PendingApproval `json:"pendingApproval,omitempty"`
+
+	// LastApproval is the last approved reload
+	// +optional
+	LastApproval *ApprovalRecord `json:"lastApproval,omitempty"`
+
+	// Canary is the progress of the last canary reload
+	// +optional
+	Canary *CanaryStatus `json:"canary,omitempty"`
+}
+
+// PendingApproval is a reload waiting for approval
+type PendingApproval struct {
+	// ID approves the reload when set on the config.dev/approve annotation.
+	// It changes when more changes are held before the approval.
+	ID string `json:"id"`
+	// Cause summarizes the held changes
+	Cause string `json:"cause"`
+	// Resources lists the changed resources with their versions before and
+	// after the held changes
+	// +optional
+	Resources []ChangedResource `json:"resources,omitempty"`
+	// RequestTime is when the first change was held
+	RequestTime metav1.Time `json:"requestTime"`
+	// ExpirationTime is when the held changes are discarded unless approved
+	ExpirationTime metav1.Time `json:"expirationTime"`
+}
+
+// ChangedResource is a change of a watched resource
+type ChangedResource struct {
+	// Kind of resource (ConfigMap or Secret)
+	Kind string `json:"kind"`
+	// Name of the resource
+	Name string `json:"name"`
+	// Namespace of the resource
+	Namespace string `json:"namespace"`
+	// PreviousVersion is the resourceVersion seen before the change
+	// +optional
+	PreviousVersion string `json:"previousVersion,omitempty"`
+	// Version is the resourceVersion after the change
+	Version string `json:"version"`
+	// ChangedBy is the field manager that last wrote the data
+	// +optional
+	ChangedBy *ChangeAttribution `json:"changedBy,omitempty"`
+	// Keys lists the data keys added, removed or modified by the change. It
+	// is only known with revisionHistoryLimit set, from the second revision
+	// of the resource on.
+	// +listType=map
+	// +listMapKey=key
+	// +optional
+	Keys []KeyChange `json:"keys,omitempty"`
+}
+
+// CanaryStatus is the progress of a canary reload
+type CanaryStatus struct {
+	// Phase of the canary
+	Phase CanaryPhase `json:"phase"`
+	// Cause of the reload
+	Cause string `json:"cause"`
+	// Resources lists the changes rolled out by the reload
+	// +optional
+	Resources []ChangedResource `json:"resources,omitempty"`
+	// Targets lists the canaries
+	Targets []CanaryTarget `json:"targets"`
+	// StartTime is when the canaries were restarted
+	StartTime metav1.Time `json:"startTime"`
+	// CompletionTime is when the canary was promoted or aborted
+	// +optional
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+	// Message explains the phase
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// CanaryPhase is the phase of a canary reload
+// +kubebuilder:validation:Enum=Baking;Promoted;Aborted
+type CanaryPhase string
+
+const (
+	// CanaryPhaseBaking waits for the bake time while checking the canaries
+	CanaryPhaseBaking CanaryPhase = "Baking"
+	// CanaryPhasePromoted restarted the rest of the targets
+	CanaryPhasePromoted CanaryPhase = "Promoted"
+	// CanaryPhaseAborted left the rest of the targets untouched
+	CanaryPhaseAborted CanaryPhase = "Aborted"
+)
+
+// CanaryTarget is a workload or pod restarted as a canary
+type CanaryTarget struct {
+	// Kind of the target
+	Kind string `json:"kind"`
+	// Name of the target
+	Name string `json:"name"`
+}
+
+// ApprovalRecord is an approved reload
+type ApprovalRecord struct {
+	// ID of the approved reload
+	ID string `json:"id"`
+	// Approver is the user who set the approval
+	Approver string `json:"approver"`
+	// Time when the approved reload was applied
+	Time metav1.Time `json:"time"`
+}
+
+// CleanupStatus is the progress of the cleanup of a deleted ConfigReloader.
+// The finalizer is removed once it completes or times out.
+type CleanupStatus struct {
+	// Pending lists the targets left to clean up
+	// +optional
+	Pending []CleanupTarget `json:"pending,omitempty"`
+
+	// Cleaned counts the targets cleaned up so far
+	Cleaned int32 `json:"cleaned"`
+
+	// HistoryDeleted is set once the ReloadEvents and ConfigRevisions of the
+	// ConfigReloader are deleted
+	// +optional
+	HistoryDeleted bool `json:"historyDeleted,omitempty"`
+
+	// LastError of the last cleanup attempt
+	// +optional
+	LastError string `json:"lastError,omitempty"`
+}
+
+// CleanupTarget is a workload or pod to clean up
+type CleanupTarget struct {
+	// Kind of the workload, or Pod
+	Kind string `json:"kind"`
+	// Name of the target
+	Name string `json:"name"`
+	// Namespace of the target
+	Namespace string `json:"namespace"`
+	// Reload restarts the target a last time
+	// +optional
+	Reload bool `json:"reload,omitempty"`
+}
+
+// TargetStatus is the state of a workload restarted by a ConfigReloader
+type TargetStatus struct {
+	// Kind of the workload, or Pod for standalone pods
+	Kind string `json:"kind"`
+	// Name of the workload
+	Name string `json:"name"`
+	// Namespace of the workload
+	Namespace string `json:"namespace"`
+	// LastConfigHash is the hash of the watched resources that triggered the
+	// last restart
+	// +optional
+	LastConfigHash string `json:"lastConfigHash,omitempty"`
+	// LastRestartTime when the workload was last restarted
+	// +optional
+	LastRestartTime *metav1.Time `json:"lastRestartTime,omitempty"`
+	// RolloutState of the last restart
+	// +optional
+	RolloutState RolloutState `json:"rolloutState,omitempty"`
+	// ConsecutiveFailures counts the restart attempts that failed since the
+	// last successful one
+	// +optional
+	ConsecutiveFailures int32 `json:"consecutiveFailures,omitempty"`
+}
+
+// RolloutState describes the progress of a restart
+// +kubebuilder:validation:Enum=Progressing;Complete;Failed
+type RolloutState string
+
+const (
+	// RolloutProgressing is set while the restarted pods are being replaced
+	RolloutProgressing RolloutState = "Progressing"
+	// RolloutComplete is set once all pods of the workload are updated and available
+	RolloutComplete RolloutState = "Complete"
+	// RolloutFailed is set when the restart failed or the rollout stalled
+	RolloutFailed RolloutState = "Failed"
+)
+
+// TargetSummary counts the targets of a ConfigReloader by rollout state
+type TargetSummary struct {
+	// Total number of targets
+	Total int32 `json:"total"`
+	// Progressing targets
+	Progressing int32 `json:"progressing"`
+	// Complete targets
+	Complete int32 `json:"complete"`
+	// Failed targets
+	Failed int32 `json:"failed"`
 }
 
 // WatchedResource represents a resource being watched
@@ -74,8 +450,29 @@ type WatchedResource struct {
 	Namespace string `json:"namespace"`
 	// ResourceVersion of the last seen version
 	ResourceVersion string `json:"resourceVersion,omitempty"`
-	// LastUpdateTime when this resource was last updated
+	// LastUpdateTime when this resource was last modified
 	LastUpdateTime *metav1.Time `json:"lastUpdateTime,omitempty"`
+	// LastChangedBy is the field manager that last wrote the data
+	// +optional
+	LastChangedBy *ChangeAttribution `json:"lastChangedBy,omitempty"`
+	// KeysHash is the hash of the selected keys of the last seen version,
+	// set when the reference selects keys
+	// +optional
+	KeysHash string `json:"keysHash,omitempty"`
+}
+
+// ChangeAttribution identifies who changed a watched resource, taken from
+// its managed fields
+type ChangeAttribution struct {
+	// Manager is the field manager that wrote the change, for example
+	// kubectl-edit, helm or argocd-controller
+	Manager string `json:"manager"`
+	// Operation is the type of the write, Apply or Update
+	// +optional
+	Operation string `json:"operation,omitempty"`
+	// Time of the write
+	// +optional
+	Time *metav1.Time `json:"time,omitempty"`
 }
 
 // PodRestart tracks a pod restart event
@@ -90,10 +487,49 @@ type PodRestart struct {
 	Reason string `json:"reason"`
 }
 
+// FailedRestart tracks a restart target whose last attempt failed
+type FailedRestart struct {
+	// Kind of the target, the owning workload kind or Pod
+	Kind string `json:"kind"`
+	// Name of the target
+	Name string `json:"name"`
+	// Namespace of the target
+	Namespace string `json:"namespace"`
+	// Attempts made so far
+	Attempts int32 `json:"attempts"`
+	// LastError returned by the last attempt
+	// +optional
+	LastError string `json:"lastError,omitempty"`
+	// LastAttemptTime when the target was last tried
+	// +optional
+	LastAttemptTime *metav1.Time `json:"lastAttemptTime,omitempty"`
+}
+
+// PlannedRestart describes a restart computed for a dry run
+type PlannedRestart struct {
+	// PodName that would be restarted
+	PodName string `json:"podName"`
+	// Namespace of the pod
+	Namespace string `json:"namespace"`
+	// WorkloadKind of the controller that owns the pod, empty for standalone pods
+	// +optional
+	WorkloadKind string `json:"workloadKind,omitempty"`
+	// WorkloadName of the controller that owns the pod
+	// +optional
+	WorkloadName string `json:"workloadName,omitempty"`
+	// Policy that would be applied
+	Policy RestartPolicy `json:"policy"`
+}
+
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
 // +kubebuilder:resource:shortName=cr
 // +kubebuilder:printcolumn:name="Status",type="string",JSONPath=".status.conditions[?(@.type=='Ready')].status"
+// +kubebuilder:printcolumn:name="Suspended",type="boolean",JSONPath=".spec.suspend"
+// +kubebuilder:printcolumn:name="Targets",type="integer",JSONPath=".status.summary.total"
+// +kubebuilder:printcolumn:name="Complete",type="integer",JSONPath=".status.summary.complete"
+// +kubebuilder:printcolumn:name="Failed",type="integer",JSONPath=".status.summary.failed"
+// +kubebuilder:printcolumn:name="Observed Generation",type="integer",JSONPath=".status.observedGeneration",priority=1
 // +kubebuilder:printcolumn:name="Last Reload",type="string",JSONPath=".status.lastReloadTime"
 // +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
 
@@ -0,0 +1,107 @@
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ConfigRevisionSpec is a snapshot of a watched ConfigMap or Secret
+type ConfigRevisionSpec struct {
+	// ConfigReloader that recorded the revision
+	ConfigReloader string `json:"configReloader"`
+
+	// Resource the snapshot was taken from
+	Resource RevisionResource `json:"resource"`
+
+	// Revision number, increasing for each recorded change of the resource
+	Revision int64 `json:"revision"`
+
+	// Hash of the data of the resource
+	Hash string `json:"hash"`
+
+	// ObservedTime when the revision was recorded
+	ObservedTime metav1.Time `json:"observedTime"`
+
+	// Author is the field manager that last wrote the data, taken from the
+	// managed fields of the resource
+	// +optional
+	Author string `json:"author,omitempty"`
+
+	// Data of a ConfigMap. Never set for Secrets.
+	// +optional
+	Data map[string]string `json:"data,omitempty"`
+
+	// BinaryData of a ConfigMap. Never set for Secrets.
+	// +optional
+	BinaryData map[string][]byte `json:"binaryData,omitempty"`
+
+	// KeyHashes holds a hash of the value of every key. Secret values are
+	// hashed with a key derived from the Secret UID so that they can be
+	// compared across revisions without being recorded.
+	// +optional
+	KeyHashes map[string]string `json:"keyHashes,omitempty"`
+
+	// Changes lists the keys that changed since the previous revision
+	// +optional
+	Changes []KeyChange `json:"changes,omitempty"`
+}
+
+// RevisionResource identifies the resource a revision was taken from
+type RevisionResource struct {
+	// Kind of resource (ConfigMap or Secret)
+	Kind string `json:"kind"`
+	// Name of the resource
+	Name string `json:"name"`
+	// Namespace of the resource
+	Namespace string `json:"namespace"`
+	// ResourceVersion of the snapshot
+	ResourceVersion string `json:"resourceVersion"`
+}
+
+// KeyChangeType describes how a key changed between two revisions
+// +kubebuilder:validation:Enum=Added;Removed;Modified
+type KeyChangeType string
+
+const (
+	KeyAdded    KeyChangeType = "Added"
+	KeyRemoved  KeyChangeType = "Removed"
+	KeyModified KeyChangeType = "Modified"
+)
+
+// KeyChange is a key that changed between two revisions
+type KeyChange struct {
+	// Key that changed
+	Key string `json:"key"`
+	// Type of the change
+	Type KeyChangeType `json:"type"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:shortName=crev
+// +kubebuilder:printcolumn:name="Kind",type="string",JSONPath=".spec.resource.kind"
+// +kubebuilder:printcolumn:name="Resource",type="string",JSONPath=".spec.resource.name"
+// +kubebuilder:printcolumn:name="Revision",type="integer",JSONPath=".spec.revision"
+// +kubebuilder:printcolumn:name="Author",type="string",JSONPath=".spec.author"
+// +kubebuilder:printcolumn:name="ConfigReloader",type="string",JSONPath=".spec.configReloader",priority=1
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+
+// ConfigRevision is a snapshot of a ConfigMap or Secret watched by a
+// ConfigReloader with revision history enabled
+type ConfigRevision struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec ConfigRevisionSpec `json:"spec,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// ConfigRevisionList contains a list of ConfigRevision
+type ConfigRevisionList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ConfigRevision `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ConfigRevision{}, &ConfigRevisionList{})
+}
@@ -0,0 +1,132 @@
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ReloadEventSpec describes what triggered a reload
+type ReloadEventSpec struct {
+	// ConfigReloader that performed the reload
+	ConfigReloader string `json:"configReloader"`
+
+	// Cause of the reload
+	Cause string `json:"cause"`
+
+	// Resources lists the watched resources whose changes triggered the reload.
+	// It is empty for manual reloads and retries.
+	// +optional
+	Resources []ReloadEventResource `json:"resources,omitempty"`
+
+	// Policy used to restart the targets
+	Policy RestartPolicy `json:"policy"`
+}
+
+// ReloadEventResource is a changed ConfigMap or Secret
+type ReloadEventResource struct {
+	// Kind of resource (ConfigMap or Secret)
+	Kind string `json:"kind"`
+	// Name of the resource
+	Name string `json:"name"`
+	// Namespace of the resource
+	Namespace string `json:"namespace"`
+	// PreviousVersion is the resourceVersion seen before the change, empty
+	// when the resource was seen for the first time
+	// +optional
+	PreviousVersion string `json:"previousVersion,omitempty"`
+	// Version is the resourceVersion that triggered the reload
+	Version string `json:"version"`
+	// ChangedBy is the field manager that wrote the change
+	// +optional
+	ChangedBy *ChangeAttribution `json:"changedBy,omitempty"`
+}
+
+// ReloadOutcome is the result of a reload
+// +kubebuilder:validation:Enum=Succeeded;PartiallyFailed;Failed
+type ReloadOutcome string
+
+const (
+	// ReloadSucceeded is set when every target was restarted
+	ReloadSucceeded ReloadOutcome = "Succeeded"
+	// ReloadPartiallyFailed is set when some targets failed to restart
+	ReloadPartiallyFailed ReloadOutcome = "PartiallyFailed"
+	// ReloadFailed is set when no target could be restarted
+	ReloadFailed ReloadOutcome = "Failed"
+)
+
+// ReloadEventStatus records the outcome of a reload
+type ReloadEventStatus struct {
+	// Outcome of the reload
+	Outcome ReloadOutcome `json:"outcome"`
+
+	// Error that prevented the reload from being planned
+	// +optional
+	Error string `json:"error,omitempty"`
+
+	// Targets restarted or failed by the reload
+	// +optional
+	Targets []ReloadEventTarget `json:"targets,omitempty"`
+
+	// RestartedPods is the number of pods restarted
+	RestartedPods int32 `json:"restartedPods"`
+
+	// FailedTargets is the number of targets that failed to restart
+	FailedTargets int32 `json:"failedTargets"`
+
+	// StartTime when the reconcile that performed the reload started
+	StartTime metav1.Time `json:"startTime"`
+
+	// CompletionTime when all restarts were issued
+	CompletionTime metav1.Time `json:"completionTime"`
+
+	// Duration between StartTime and CompletionTime
+	Duration metav1.Duration `json:"duration"`
+}
+
+// ReloadEventTarget is a workload, or standalone pod, affected by a reload
+type ReloadEventTarget struct {
+	// Kind of the workload, or Pod for standalone pods
+	Kind string `json:"kind"`
+	// Name of the workload
+	Name string `json:"name"`
+	// Namespace of the workload
+	Namespace string `json:"namespace"`
+	// Pods of the workload that were restarted
+	Pods int32 `json:"pods"`
+	// Error returned when restarting the target
+	// +optional
+	Error string `json:"error,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:shortName=re
+// +kubebuilder:printcolumn:name="ConfigReloader",type="string",JSONPath=".spec.configReloader"
+// +kubebuilder:printcolumn:name="Outcome",type="string",JSONPath=".status.outcome"
+// +kubebuilder:printcolumn:name="Restarted",type="integer",JSONPath=".status.restartedPods"
+// +kubebuilder:printcolumn:name="Failed",type="integer",JSONPath=".status.failedTargets"
+// +kubebuilder:printcolumn:name="Duration",type="string",JSONPath=".status.duration"
+// +kubebuilder:printcolumn:name="Cause",type="string",JSONPath=".spec.cause",priority=1
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+
+// ReloadEvent records a reload performed by a ConfigReloader. ReloadEvents
+// are written once and garbage collected according to the history settings
+// of their ConfigReloader.
+type ReloadEvent struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ReloadEventSpec   `json:"spec,omitempty"`
+	Status ReloadEventStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// ReloadEventList contains a list of ReloadEvent
+type ReloadEventList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ReloadEvent `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ReloadEvent{}, &ReloadEventList{})
+}
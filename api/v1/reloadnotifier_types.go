@@ -0,0 +1,167 @@
+package v1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// NotificationEvent is a step of a reload that can be notified
+// +kubebuilder:validation:Enum=ChangeDetected;ReloadStarted;ReloadCompleted;ReloadFailed
+type NotificationEvent string
+
+const (
+	// NotificationChangeDetected is sent when a watched resource changed
+	NotificationChangeDetected NotificationEvent = "ChangeDetected"
+	// NotificationReloadStarted is sent before the targets are restarted
+	NotificationReloadStarted NotificationEvent = "ReloadStarted"
+	// NotificationReloadCompleted is sent once every target was restarted
+	NotificationReloadCompleted NotificationEvent = "ReloadCompleted"
+	// NotificationReloadFailed is sent when some or all targets failed to
+	// restart
+	NotificationReloadFailed NotificationEvent = "ReloadFailed"
+)
+
+// NotifierType is the format of the notifications
+// +kubebuilder:validation:Enum=Webhook;Slack;Teams;CloudEvents
+type NotifierType string
+
+const (
+	// NotifierWebhook posts the notification as JSON, or the rendered template
+	NotifierWebhook NotifierType = "Webhook"
+	// NotifierSlack posts a Slack incoming webhook message
+	NotifierSlack NotifierType = "Slack"
+	// NotifierTeams posts a Microsoft Teams incoming webhook message card
+	NotifierTeams NotifierType = "Teams"
+	// NotifierCloudEvents posts a CloudEvent in binary content mode
+	NotifierCloudEvents NotifierType = "CloudEvents"
+)
+
+// ReloadNotifierSpec defines where and when notifications are sent
+// +kubebuilder:validation:XValidation:rule="has(self.url) != has(self.urlFrom)",message="exactly one of url and urlFrom must be set"
+type ReloadNotifierSpec struct {
+	// Type is the format of the notifications
+	// +kubebuilder:default=Webhook
+	Type NotifierType `json:"type,omitempty"`
+
+	// URL the notifications are posted to
+	// +optional
+	URL string `json:"url,omitempty"`
+
+	// URLFrom reads the URL from a key of a Secret in the namespace of the
+	// ReloadNotifier, for URLs embedding a token like Slack webhooks
+	// +optional
+	URLFrom *corev1.SecretKeySelector `json:"urlFrom,omitempty"`
+
+	// Selector selects the ConfigReloaders in the namespace whose reloads are
+	// notified. All of them are notified when empty.
+	// +optional
+	Selector *metav1.LabelSelector `json:"selector,omitempty"`
+
+	// Events notified. All of them are notified when empty.
+	// +optional
+	Events []NotificationEvent `json:"events,omitempty"`
+
+	// Template is a Go template rendered with the notification. It is the
+	// body of Webhook notifications, the message text of Slack and Teams
+	// notifications and the data of CloudEvents.
+	// +optional
+	Template string `json:"template,omitempty"`
+
+	// Headers added to the requests
+	// +optional
+	Headers map[string]string `json:"headers,omitempty"`
+
+	// Auth sets the Authorization header of the requests
+	// +optional
+	Auth *NotifierAuth `json:"auth,omitempty"`
+
+	// Retry configures the retries of failed deliveries
+	// +optional
+	Retry NotifierRetry `json:"retry,omitempty"`
+
+	// DedupeWindow is how long an identical notification is not sent again
+	// +kubebuilder:default="5m"
+	// +optional
+	DedupeWindow *metav1.Duration `json:"dedupeWindow,omitempty"`
+}
+
+// NotifierAuth reads the credentials of the requests from a Secret
+type NotifierAuth struct {
+	// Scheme of the Authorization header
+	// +kubebuilder:default=Bearer
+	Scheme string `json:"scheme,omitempty"`
+
+	// TokenFrom reads the token from a key of a Secret in the namespace of
+	// the ReloadNotifier
+	TokenFrom corev1.SecretKeySelector `json:"tokenFrom"`
+}
+
+// NotifierRetry configures the retries of failed deliveries. Network errors,
+// 429 and 5xx responses are retried with exponential backoff.
+type NotifierRetry struct {
+	// MaxAttempts is the number of attempts per notification
+	// +kubebuilder:default=3
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	MaxAttempts int32 `json:"maxAttempts,omitempty"`
+
+	// Backoff is the delay before the first retry, doubled for every retry
+	// +kubebuilder:default="1s"
+	// +optional
+	Backoff *metav1.Duration `json:"backoff,omitempty"`
+}
+
+// ReloadNotifierStatus records the deliveries of the ReloadNotifier
+type ReloadNotifierStatus struct {
+	// Delivered is the number of notifications delivered
+	// +optional
+	Delivered int64 `json:"delivered,omitempty"`
+
+	// Failed is the number of notifications that could not be delivered
+	// +optional
+	Failed int64 `json:"failed,omitempty"`
+
+	// LastDeliveryTime is when a notification was last delivered
+	// +optional
+	LastDeliveryTime *metav1.Time `json:"lastDeliveryTime,omitempty"`
+
+	// LastFailureTime is when a notification last failed to be delivered
+	// +optional
+	LastFailureTime *metav1.Time `json:"lastFailureTime,omitempty"`
+
+	// LastError is the error of the last failed delivery
+	// +optional
+	LastError string `json:"lastError,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:shortName=rn
+// +kubebuilder:printcolumn:name="Type",type="string",JSONPath=".spec.type"
+// +kubebuilder:printcolumn:name="Delivered",type="integer",JSONPath=".status.delivered"
+// +kubebuilder:printcolumn:name="Failed",type="integer",JSONPath=".status.failed"
+// +kubebuilder:printcolumn:name="Last Error",type="string",JSONPath=".status.lastError",priority=1
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+
+// ReloadNotifier sends notifications about the reloads of the
+// ConfigReloaders in its namespace
+type ReloadNotifier struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ReloadNotifierSpec   `json:"spec,omitempty"`
+	Status ReloadNotifierStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// ReloadNotifierList contains a list of ReloadNotifier
+type ReloadNotifierList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ReloadNotifier `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ReloadNotifier{}, &ReloadNotifierList{})
+}
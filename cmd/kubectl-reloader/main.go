@@ -0,0 +1,32 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// kubectl-reloader is a kubectl plugin to inspect and drive the reloads of
+// ConfigReloaders. Install it on the PATH to run it as kubectl reloader.
+package main
+
+import (
+	"context"
+	"os"
+
+	"github.com/shehbazk/config-reloader-operator/internal/plugin"
+)
+
+func main() {
+	if err := plugin.NewCommand().ExecuteContext(context.Background()); err != nil {
+		os.Exit(1)
+	}
+}
@@ -17,20 +17,26 @@ limitations under the License.
 package main
 
 import (
+	"context"
 	"crypto/tls"
 	"flag"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
 	"sigs.k8s.io/controller-runtime/pkg/certwatcher"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 	"sigs.k8s.io/controller-runtime/pkg/metrics/filters"
@@ -38,7 +44,13 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
 
 	configv1 "github.com/shehbazk/config-reloader-operator/api/v1"
+	configv2 "github.com/shehbazk/config-reloader-operator/api/v2"
 	"github.com/shehbazk/config-reloader-operator/internal/controller"
+	"github.com/shehbazk/config-reloader-operator/internal/notify"
+	"github.com/shehbazk/config-reloader-operator/internal/sharding"
+	"github.com/shehbazk/config-reloader-operator/internal/tracing"
+	webhookv1 "github.com/shehbazk/config-reloader-operator/internal/webhook/v1"
+	webhookv2 "github.com/shehbazk/config-reloader-operator/internal/webhook/v2"
 	// +kubebuilder:scaffold:imports
 )
 
@@ -51,6 +63,7 @@ func init() {
 	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
 
 	utilruntime.Must(configv1.AddToScheme(scheme))
+	utilruntime.Must(configv2.AddToScheme(scheme))
 	// +kubebuilder:scaffold:scheme
 }
 
@@ -63,6 +76,15 @@ func main() {
 	var probeAddr string
 	var secureMetrics bool
 	var enableHTTP2 bool
+	var dryRun bool
+	var watchNamespaces string
+	var analysisAddresses string
+	var notificationURLs string
+	var cacheLabelSelector, cacheNamespaces string
+	var cacheMetadataOnly bool
+	var shards int
+	var shardIdentity, shardNamespace string
+	var tracingOpts tracing.Options
 	var tlsOpts []func(*tls.Config)
 	flag.StringVar(&metricsAddr, "metrics-bind-address", "0", "The address the metrics endpoint binds to. "+
 		"Use :8443 for HTTPS or :8080 for HTTP, or leave as 0 to disable the metrics service.")
@@ -81,6 +103,40 @@ func main() {
 	flag.StringVar(&metricsCertKey, "metrics-cert-key", "tls.key", "The name of the metrics server key file.")
 	flag.BoolVar(&enableHTTP2, "enable-http2", false,
 		"If set, HTTP/2 will be enabled for the metrics and webhook servers")
+	flag.BoolVar(&dryRun, "dry-run", false,
+		"If set, restart plans are only reported in status and Events and no pods are restarted.")
+	flag.StringVar(&watchNamespaces, "watch-namespaces", os.Getenv("WATCH_NAMESPACES"),
+		"Comma-separated namespaces watched by the operator, all namespaces when empty. "+
+			"Defaults to the WATCH_NAMESPACES environment variable.")
+	flag.StringVar(&analysisAddresses, "canary-analysis-addresses", "",
+		"Comma-separated Prometheus addresses canary analyses may query. Canaries with an analysis are aborted "+
+			"when empty.")
+	flag.StringVar(&notificationURLs, "notification-urls", "",
+		"Comma-separated URL prefixes ReloadNotifiers may post to. No notification is sent when empty.")
+	flag.StringVar(&cacheLabelSelector, "cache-label-selector", "",
+		"Only cache the ConfigMaps and Secrets matching this label selector. Other watched resources are read "+
+			"from the API server and their changes are picked up by the periodic reconcile.")
+	flag.StringVar(&cacheNamespaces, "cache-namespaces", "",
+		"Comma-separated namespaces whose ConfigMaps and Secrets are cached. Other watched resources are read "+
+			"from the API server and their changes are picked up by the periodic reconcile.")
+	flag.BoolVar(&cacheMetadataOnly, "cache-metadata-only", false,
+		"If set, only the metadata of ConfigMaps and Secrets is cached and their data is read from the API "+
+			"server when a restart needs it.")
+	flag.IntVar(&shards, "shards", 0,
+		"Number of shards the ConfigReloaders are split into between the replicas, each replica reconciling "+
+			"the shards whose Lease it holds. Sharding is disabled when 0.")
+	flag.StringVar(&shardIdentity, "shard-identity", os.Getenv("POD_NAME"),
+		"Identity of the replica in the shard Leases, unique between replicas. "+
+			"Defaults to the POD_NAME environment variable or the hostname.")
+	flag.StringVar(&shardNamespace, "shard-namespace", os.Getenv("POD_NAMESPACE"),
+		"Namespace of the shard Leases. Defaults to the POD_NAMESPACE environment variable.")
+	flag.StringVar(&tracingOpts.Endpoint, "tracing-endpoint", "",
+		"The host:port of the OTLP gRPC collector receiving the traces of change propagations. "+
+			"Tracing is disabled when empty.")
+	flag.BoolVar(&tracingOpts.Insecure, "tracing-insecure", false,
+		"If set, traces are sent to the collector without TLS.")
+	flag.Float64Var(&tracingOpts.SampleRatio, "tracing-sample-ratio", 1,
+		"The fraction of change propagations traced, between 0 and 1.")
 	opts := zap.Options{
 		Development: true,
 	}
@@ -89,6 +145,35 @@ func main() {
 
 	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
 
+	cacheOptions := cache.Options{}
+	namespaces := splitList(watchNamespaces)
+	if len(namespaces) > 0 {
+		setupLog.Info("Restricting the operator to namespaces", "namespaces", namespaces)
+		cacheOptions.DefaultNamespaces = make(map[string]cache.Config, len(namespaces))
+		for _, namespace := range namespaces {
+			cacheOptions.DefaultNamespaces[namespace] = cache.Config{}
+		}
+	}
+
+	configCache := controller.ConfigCacheOptions{
+		Namespaces:   splitList(cacheNamespaces),
+		MetadataOnly: cacheMetadataOnly,
+	}
+	if len(configCache.Namespaces) == 0 {
+		// Watched resources in other namespaces are read from the API server,
+		// which fails with a clear error when access is not granted
+		configCache.Namespaces = namespaces
+	}
+	if cacheLabelSelector != "" {
+		selector, err := labels.Parse(cacheLabelSelector)
+		if err != nil {
+			setupLog.Error(err, "invalid cache label selector", "cache-label-selector", cacheLabelSelector)
+			os.Exit(1)
+		}
+		configCache.LabelSelector = selector
+	}
+	cacheOptions.ByObject = configCache.ByObject()
+
 	// if the enable-http2 flag is false (the default), http/2 should be disabled
 	// due to its vulnerabilities. More specifically, disabling http/2 will
 	// prevent from being vulnerable to the HTTP/2 Stream Cancellation and
@@ -183,6 +268,7 @@ func main() {
 		Metrics:                metricsServerOptions,
 		WebhookServer:          webhookServer,
 		HealthProbeBindAddress: probeAddr,
+		Cache:                  cacheOptions,
 		LeaderElection:         enableLeaderElection,
 		LeaderElectionID:       "a2d5c3f5.config.dev",
 		// LeaderElectionReleaseOnCancel defines if the leader should step down voluntarily
@@ -202,13 +288,101 @@ func main() {
 		os.Exit(1)
 	}
 
-	if err := (&controller.ConfigReloaderReconciler{
-		Client: mgr.GetClient(),
-		Scheme: mgr.GetScheme(),
-	}).SetupWithManager(mgr); err != nil {
+	tracingOpts.ServiceName = "config-reloader-operator"
+	tracingProvider, err := tracing.Setup(context.Background(), tracingOpts)
+	if err != nil {
+		setupLog.Error(err, "unable to set up tracing")
+		os.Exit(1)
+	}
+	if tracingProvider != nil {
+		if err := mgr.Add(tracingProvider); err != nil {
+			setupLog.Error(err, "unable to add tracing provider to manager")
+			os.Exit(1)
+		}
+		setupLog.Info("Exporting traces", "endpoint", tracingOpts.Endpoint, "sampleRatio", tracingOpts.SampleRatio)
+	}
+
+	var coordinator *sharding.Coordinator
+	if shards > 0 {
+		if shardIdentity == "" {
+			if shardIdentity, err = os.Hostname(); err != nil {
+				setupLog.Error(err, "unable to determine the shard identity")
+				os.Exit(1)
+			}
+		}
+		// Leases are read from the API server so that sharding does not
+		// depend on the namespaces cached by the manager
+		leaseClient, err := client.New(mgr.GetConfig(), client.Options{Scheme: mgr.GetScheme()})
+		if err != nil {
+			setupLog.Error(err, "unable to create shard lease client")
+			os.Exit(1)
+		}
+		coordinator, err = sharding.New(leaseClient, sharding.Options{
+			Name:          "config-reloader",
+			Namespace:     shardNamespace,
+			Identity:      shardIdentity,
+			Shards:        shards,
+			LeaseDuration: 15 * time.Second,
+			RenewDeadline: 10 * time.Second,
+			RetryPeriod:   2 * time.Second,
+		})
+		if err != nil {
+			setupLog.Error(err, "invalid sharding options")
+			os.Exit(1)
+		}
+		if err := mgr.Add(coordinator); err != nil {
+			setupLog.Error(err, "unable to add shard coordinator to manager")
+			os.Exit(1)
+		}
+		setupLog.Info("Sharding ConfigReloaders between replicas", "shards", shards, "identity", shardIdentity)
+	}
+
+	// Notifications are delivered by every replica, for the ConfigReloaders
+	// it reconciles
+	notifier := notify.NewDispatcher(mgr.GetClient(), mgr.GetAPIReader(), splitList(notificationURLs))
+	if err := mgr.Add(notifier); err != nil {
+		setupLog.Error(err, "unable to add notification dispatcher to manager")
+		os.Exit(1)
+	}
+
+	// nolint:goconst
+	enableWebhooks := os.Getenv("ENABLE_WEBHOOKS") != "false"
+	reconciler := &controller.ConfigReloaderReconciler{
+		Client:            mgr.GetClient(),
+		Scheme:            mgr.GetScheme(),
+		Recorder:          mgr.GetEventRecorderFor("configreloader-controller"),
+		DryRun:            dryRun,
+		ConfigCache:       configCache,
+		APIReader:         mgr.GetAPIReader(),
+		Shards:            coordinator,
+		Notifier:          notifier,
+		WebhooksDisabled:  !enableWebhooks,
+		AnalysisAddresses: splitList(analysisAddresses),
+	}
+	if err := reconciler.SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "ConfigReloader")
 		os.Exit(1)
 	}
+	// The dependency graph is served next to the metrics, behind the same
+	// authentication and authorization filter
+	if err := mgr.AddMetricsServerExtraHandler("/graph", reconciler.GraphHandler()); err != nil {
+		setupLog.Error(err, "unable to add dependency graph endpoint")
+		os.Exit(1)
+	}
+	if enableWebhooks {
+		if err := webhookv1.SetupConfigReloaderWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "ConfigReloader")
+			os.Exit(1)
+		}
+		if err := webhookv2.SetupConfigReloaderWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "ConfigReloader")
+			os.Exit(1)
+		}
+		if err := webhookv1.SetupReloadNotifierWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "ReloadNotifier")
+			os.Exit(1)
+		}
+	}
 	// +kubebuilder:scaffold:builder
 
 	if metricsCertWatcher != nil {
@@ -242,3 +416,14 @@ func main() {
 		os.Exit(1)
 	}
 }
+
+// splitList splits a comma-separated flag value, dropping empty entries
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
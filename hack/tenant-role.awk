@@ -0,0 +1,38 @@
+# Turns the manager ClusterRole generated from the kubebuilder:rbac markers
+# into the namespaced Role of config/namespaced/tenant-rbac. Run by
+# make manifests.
+#
+# SubjectAccessReviews are cluster scoped and cannot be granted by a Role.
+# The webhooks are allowed to create them by the metrics-auth ClusterRole.
+
+function flush() {
+	if (!drop) printf "%s", rule
+	rule = ""
+	drop = 0
+}
+
+BEGIN {
+	print "# Generated from config/rbac/role.yaml by make manifests. DO NOT EDIT."
+}
+
+/^---$/ { next }
+
+/^kind: ClusterRole$/ {
+	print "kind: Role"
+	next
+}
+
+/^- / {
+	flush()
+	inRules = 1
+}
+
+inRules {
+	rule = rule $0 "\n"
+	if ($0 ~ /subjectaccessreviews/) drop = 1
+	next
+}
+
+{ print }
+
+END { flush() }
@@ -0,0 +1,326 @@
+// Package analyzer builds the dependency graph between the ConfigMaps,
+// Secrets and workloads of rendered manifests, such as Helm or kustomize
+// output, and suggests ConfigReloaders for it without a cluster.
+package analyzer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"sigs.k8s.io/yaml"
+
+	"github.com/shehbazk/config-reloader-operator/internal/podrefs"
+)
+
+// Workload is a pod or workload of the manifests
+type Workload struct {
+	Kind      string
+	Name      string
+	Namespace string
+	// Selector selects the pods of the workload, nil for pods without labels
+	Selector *metav1.LabelSelector
+	// References lists the ConfigMaps and Secrets consumed by the pods
+	References []Reference
+}
+
+// NeedsRestart reports whether the workload consumes a ConfigMap or Secret it
+// only picks up when restarted
+func (w *Workload) NeedsRestart() bool {
+	for _, ref := range w.References {
+		if ref.NeedsRestart() {
+			return true
+		}
+	}
+	return false
+}
+
+// Reference is a ConfigMap or Secret consumed by a workload
+type Reference struct {
+	podrefs.Reference
+	// SubPath is set for volumes mounted with a subPath, which are not
+	// updated when the ConfigMap or Secret changes
+	SubPath bool
+}
+
+// NeedsRestart reports whether changes only reach the pods when they are
+// restarted. Env vars are read when the container starts, and volumes
+// mounted with a subPath are never updated, while other volumes are updated
+// in place by the kubelet.
+func (r Reference) NeedsRestart() bool {
+	return r.Source != podrefs.SourceVolume || r.SubPath
+}
+
+// Consumer is a workload consuming a ConfigMap or Secret
+type Consumer struct {
+	Workload  *Workload
+	Reference Reference
+}
+
+// Resource is a ConfigMap or Secret with the workloads consuming it
+type Resource struct {
+	Kind      string
+	Name      string
+	Namespace string
+	// Defined is set when the resource is part of the manifests
+	Defined   bool
+	Consumers []Consumer
+}
+
+// Graph is the dependency graph between the ConfigMaps, Secrets and
+// workloads of a set of manifests, ordered by namespace, kind and name
+type Graph struct {
+	Workloads []*Workload
+	Resources []*Resource
+}
+
+// Load builds the graph of the manifests in the files, and in the .yaml, .yml
+// and .json files under the directories. "-" reads the manifests from stdin.
+// Objects without a namespace are placed in namespace.
+func Load(namespace string, paths ...string) (*Graph, error) {
+	b := newBuilder(namespace)
+	for _, path := range paths {
+		if path == "-" {
+			if err := b.read(os.Stdin, "stdin"); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		err := filepath.WalkDir(path, func(file string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if entry.IsDir() {
+				return nil
+			}
+			// Files named explicitly are read whatever their extension
+			if file != path && !isManifest(file) {
+				return nil
+			}
+			f, err := os.Open(file)
+			if err != nil {
+				return err
+			}
+			defer f.Close() //nolint:errcheck
+			return b.read(f, file)
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to load manifests from %s: %w", path, err)
+		}
+	}
+	return b.graph(), nil
+}
+
+// Read builds the graph of the manifests read from r
+func Read(namespace string, r io.Reader) (*Graph, error) {
+	b := newBuilder(namespace)
+	if err := b.read(r, "input"); err != nil {
+		return nil, err
+	}
+	return b.graph(), nil
+}
+
+func isManifest(file string) bool {
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".yaml", ".yml", ".json":
+		return true
+	}
+	return false
+}
+
+// builder collects the workloads and resources of the manifests
+type builder struct {
+	namespace string
+	workloads []*Workload
+	// resources are keyed by kind/namespace/name
+	resources map[string]*Resource
+}
+
+func newBuilder(namespace string) *builder {
+	return &builder{namespace: namespace, resources: make(map[string]*Resource)}
+}
+
+// read adds every document of a YAML or JSON stream
+func (b *builder) read(r io.Reader, source string) error {
+	reader := utilyaml.NewYAMLReader(bufio.NewReader(r))
+	for i := 0; ; i++ {
+		doc, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", source, err)
+		}
+		if err := b.add(doc); err != nil {
+			return fmt.Errorf("failed to parse document %d of %s: %w", i+1, source, err)
+		}
+	}
+}
+
+// add adds a single document, expanding Lists
+func (b *builder) add(doc []byte) error {
+	if len(bytes.TrimSpace(doc)) == 0 {
+		return nil
+	}
+	var typeMeta metav1.TypeMeta
+	if err := yaml.Unmarshal(doc, &typeMeta); err != nil {
+		return err
+	}
+	gvk := typeMeta.GroupVersionKind()
+
+	switch {
+	case gvk.Group == "" && gvk.Kind == "List":
+		var list struct {
+			Items []json.RawMessage `json:"items"`
+		}
+		if err := yaml.Unmarshal(doc, &list); err != nil {
+			return err
+		}
+		for _, item := range list.Items {
+			if err := b.add(item); err != nil {
+				return err
+			}
+		}
+	case gvk.Group == "" && (gvk.Kind == podrefs.KindConfigMap || gvk.Kind == podrefs.KindSecret):
+		var obj metav1.PartialObjectMetadata
+		if err := yaml.Unmarshal(doc, &obj); err != nil {
+			return err
+		}
+		b.resource(gvk.Kind, b.namespaceOf(&obj.ObjectMeta), obj.Name).Defined = true
+	case gvk.Group == "" && gvk.Kind == "Pod":
+		var pod corev1.Pod
+		if err := yaml.Unmarshal(doc, &pod); err != nil {
+			return err
+		}
+		var selector *metav1.LabelSelector
+		if len(pod.Labels) > 0 {
+			selector = &metav1.LabelSelector{MatchLabels: pod.Labels}
+		}
+		b.workload(gvk.Kind, &pod.ObjectMeta, selector, &pod.Spec)
+	case gvk.Group == appsv1.GroupName:
+		return b.addAppsWorkload(gvk.Kind, doc)
+	}
+	// Other kinds do not consume ConfigMaps or Secrets the operator reloads
+	return nil
+}
+
+// addAppsWorkload adds a workload of the apps group, ignoring the kinds the
+// operator does not restart
+func (b *builder) addAppsWorkload(kind string, doc []byte) error {
+	var (
+		obj      metav1.ObjectMeta
+		selector *metav1.LabelSelector
+		template corev1.PodTemplateSpec
+	)
+	switch kind {
+	case "Deployment":
+		var deployment appsv1.Deployment
+		if err := yaml.Unmarshal(doc, &deployment); err != nil {
+			return err
+		}
+		obj, selector, template = deployment.ObjectMeta, deployment.Spec.Selector, deployment.Spec.Template
+	case "StatefulSet":
+		var statefulSet appsv1.StatefulSet
+		if err := yaml.Unmarshal(doc, &statefulSet); err != nil {
+			return err
+		}
+		obj, selector, template = statefulSet.ObjectMeta, statefulSet.Spec.Selector, statefulSet.Spec.Template
+	case "DaemonSet":
+		var daemonSet appsv1.DaemonSet
+		if err := yaml.Unmarshal(doc, &daemonSet); err != nil {
+			return err
+		}
+		obj, selector, template = daemonSet.ObjectMeta, daemonSet.Spec.Selector, daemonSet.Spec.Template
+	case "ReplicaSet":
+		var replicaSet appsv1.ReplicaSet
+		if err := yaml.Unmarshal(doc, &replicaSet); err != nil {
+			return err
+		}
+		obj, selector, template = replicaSet.ObjectMeta, replicaSet.Spec.Selector, replicaSet.Spec.Template
+	default:
+		return nil
+	}
+	b.workload(kind, &obj, selector, &template.Spec)
+	return nil
+}
+
+func (b *builder) namespaceOf(obj *metav1.ObjectMeta) string {
+	if obj.Namespace == "" {
+		return b.namespace
+	}
+	return obj.Namespace
+}
+
+// workload adds a workload and links it to the resources it consumes
+func (b *builder) workload(kind string, obj *metav1.ObjectMeta, selector *metav1.LabelSelector, spec *corev1.PodSpec) {
+	w := &Workload{Kind: kind, Name: obj.Name, Namespace: b.namespaceOf(obj), Selector: selector}
+
+	subPaths := make(map[string]bool)
+	for _, containers := range [][]corev1.Container{spec.InitContainers, spec.Containers} {
+		for _, container := range containers {
+			for _, mount := range container.VolumeMounts {
+				if mount.SubPath != "" || mount.SubPathExpr != "" {
+					subPaths[mount.Name] = true
+				}
+			}
+		}
+	}
+
+	podrefs.Walk(spec, func(ref podrefs.Reference) bool {
+		reference := Reference{Reference: ref, SubPath: ref.Volume != "" && subPaths[ref.Volume]}
+		w.References = append(w.References, reference)
+		resource := b.resource(ref.Kind, w.Namespace, ref.Name)
+		resource.Consumers = append(resource.Consumers, Consumer{Workload: w, Reference: reference})
+		return true
+	})
+	b.workloads = append(b.workloads, w)
+}
+
+func (b *builder) resource(kind, namespace, name string) *Resource {
+	key := kind + "/" + namespace + "/" + name
+	resource, ok := b.resources[key]
+	if !ok {
+		resource = &Resource{Kind: kind, Name: name, Namespace: namespace}
+		b.resources[key] = resource
+	}
+	return resource
+}
+
+// graph returns the collected workloads and resources in a stable order
+func (b *builder) graph() *Graph {
+	g := &Graph{Workloads: b.workloads}
+	for _, resource := range b.resources {
+		g.Resources = append(g.Resources, resource)
+	}
+	sort.SliceStable(g.Workloads, func(i, j int) bool {
+		return less(g.Workloads[i].Namespace, g.Workloads[i].Kind, g.Workloads[i].Name,
+			g.Workloads[j].Namespace, g.Workloads[j].Kind, g.Workloads[j].Name)
+	})
+	sort.Slice(g.Resources, func(i, j int) bool {
+		return less(g.Resources[i].Namespace, g.Resources[i].Kind, g.Resources[i].Name,
+			g.Resources[j].Namespace, g.Resources[j].Kind, g.Resources[j].Name)
+	})
+	return g
+}
+
+func less(namespaceA, kindA, nameA, namespaceB, kindB, nameB string) bool {
+	if namespaceA != namespaceB {
+		return namespaceA < namespaceB
+	}
+	if kindA != kindB {
+		return kindA < kindB
+	}
+	return nameA < nameB
+}
@@ -0,0 +1,206 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package analyzer
+
+import (
+	"bytes"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+
+	configv2 "github.com/shehbazk/config-reloader-operator/api/v2"
+	"github.com/shehbazk/config-reloader-operator/internal/podrefs"
+)
+
+var _ = Describe("Analyzer", func() {
+	var graph *Graph
+
+	BeforeEach(func() {
+		var err error
+		graph, err = Load("apps", "testdata/manifests")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	workload := func(kind, name string) *Workload {
+		for _, w := range graph.Workloads {
+			if w.Kind == kind && w.Name == name {
+				return w
+			}
+		}
+		return nil
+	}
+
+	resource := func(kind, namespace, name string) *Resource {
+		for _, r := range graph.Resources {
+			if r.Kind == kind && r.Namespace == namespace && r.Name == name {
+				return r
+			}
+		}
+		return nil
+	}
+
+	Describe("Load", func() {
+		It("should collect the workloads of the manifests in a stable order", func() {
+			var names []string
+			for _, w := range graph.Workloads {
+				names = append(names, w.Namespace+"/"+w.Kind+"/"+w.Name)
+			}
+			Expect(names).To(Equal([]string{
+				"apps/Deployment/web",
+				"apps/Pod/web",
+				"data/StatefulSet/db",
+			}))
+		})
+
+		It("should link the resources to the workloads consuming them", func() {
+			config := resource(podrefs.KindConfigMap, "apps", "web-config")
+			Expect(config).NotTo(BeNil())
+			Expect(config.Defined).To(BeTrue())
+			Expect(config.Consumers).To(HaveLen(2))
+			Expect(config.Consumers[0].Workload.Kind).To(Equal("Deployment"))
+			Expect(config.Consumers[0].Reference.Source).To(Equal(podrefs.SourceEnvFrom))
+			Expect(config.Consumers[1].Workload.Kind).To(Equal("Pod"))
+			Expect(config.Consumers[1].Reference.Source).To(Equal(podrefs.SourceEnv))
+
+			token := resource(podrefs.KindSecret, "apps", "web-token")
+			Expect(token).NotTo(BeNil())
+			Expect(token.Defined).To(BeFalse())
+
+			certs := resource(podrefs.KindSecret, "data", "db-certs")
+			Expect(certs).NotTo(BeNil())
+			Expect(certs.Defined).To(BeTrue())
+		})
+
+		It("should tell the references needing a restart from the volumes updated in place", func() {
+			web := workload("Deployment", "web")
+			Expect(web.Selector).To(Equal(&metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}}))
+			needsRestart := make(map[string]bool)
+			for _, ref := range web.References {
+				needsRestart[ref.Name] = ref.NeedsRestart()
+			}
+			Expect(needsRestart).To(Equal(map[string]bool{"web-static": false, "web-config": true, "web-token": true}))
+
+			db := workload("StatefulSet", "db")
+			Expect(db.NeedsRestart()).To(BeTrue())
+			for _, ref := range db.References {
+				Expect(ref.SubPath).To(Equal(ref.Name == "db-config"))
+			}
+		})
+
+		It("should report workloads only consuming volumes as not needing restarts", func() {
+			graph, err := Read("default", strings.NewReader(`
+apiVersion: apps/v1
+kind: DaemonSet
+metadata:
+  name: agent
+spec:
+  selector:
+    matchLabels:
+      app: agent
+  template:
+    spec:
+      containers:
+        - name: agent
+          image: agent
+      volumes:
+        - name: config
+          configMap:
+            name: agent-config
+`))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(graph.Workloads).To(HaveLen(1))
+			Expect(graph.Workloads[0].Namespace).To(Equal("default"))
+			Expect(graph.Workloads[0].NeedsRestart()).To(BeFalse())
+		})
+
+		It("should fail on invalid manifests", func() {
+			_, err := Read("default", strings.NewReader("apiVersion: v1\nkind: [ConfigMap\n"))
+			Expect(err).To(MatchError(ContainSubstring("document 1 of input")))
+
+			_, err = Load("default", "testdata/missing")
+			Expect(err).To(MatchError(ContainSubstring("failed to load manifests from testdata/missing")))
+		})
+	})
+
+	Describe("Suggest", func() {
+		It("should watch the resources the workloads only pick up when restarted", func() {
+			suggestions := graph.Suggest(false)
+			Expect(suggestions).To(HaveLen(3))
+
+			web := suggestions[0]
+			Expect(web.Name).To(Equal("web"))
+			Expect(web.Namespace).To(Equal("apps"))
+			Expect(web.Spec.Targets.Selector).To(Equal(workload("Deployment", "web").Selector))
+			Expect(web.Spec.Strategy.Type).To(Equal(configv2.StrategyRolloutRestart))
+			Expect(web.Spec.Trigger.ConfigMaps).To(Equal([]configv2.ObjectReference{{Name: "web-config"}}))
+			Expect(web.Spec.Trigger.Secrets).To(Equal([]configv2.ObjectReference{{Name: "web-token"}}))
+
+			pod := suggestions[1]
+			Expect(pod.Name).To(Equal("web-pod"))
+			Expect(pod.Spec.Strategy.Type).To(Equal(configv2.StrategyDeletePods))
+			Expect(pod.Spec.Trigger.ConfigMaps).To(Equal([]configv2.ObjectReference{{Name: "web-config"}}))
+
+			db := suggestions[2]
+			Expect(db.Name).To(Equal("db"))
+			Expect(db.Spec.Trigger.ConfigMaps).To(Equal([]configv2.ObjectReference{{Name: "db-config"}}))
+			Expect(db.Spec.Trigger.Secrets).To(BeEmpty())
+		})
+
+		It("should watch the volumes too when asked to", func() {
+			suggestions := graph.Suggest(true)
+			Expect(suggestions[0].Spec.Trigger.ConfigMaps).To(ConsistOf(
+				configv2.ObjectReference{Name: "web-static"},
+				configv2.ObjectReference{Name: "web-config"},
+			))
+			Expect(suggestions[2].Spec.Trigger.Secrets).To(Equal([]configv2.ObjectReference{{Name: "db-certs"}}))
+		})
+
+		It("should write the suggestions as ConfigReloader manifests", func() {
+			var out bytes.Buffer
+			Expect(WriteConfigReloaders(&out, graph.Suggest(false))).To(Succeed())
+
+			docs := strings.Split(out.String(), "\n---\n")
+			Expect(docs).To(HaveLen(3))
+			Expect(docs[0]).NotTo(ContainSubstring("status"))
+			Expect(docs[0]).NotTo(ContainSubstring("creationTimestamp"))
+			Expect(docs[0]).NotTo(ContainSubstring("history"))
+
+			var cr configv2.ConfigReloader
+			Expect(yaml.UnmarshalStrict([]byte(docs[0]), &cr)).To(Succeed())
+			Expect(cr.APIVersion).To(Equal(configv2.GroupVersion.String()))
+			Expect(cr.Kind).To(Equal("ConfigReloader"))
+			Expect(cr.Spec).To(Equal(graph.Suggest(false)[0].Spec))
+		})
+	})
+
+	Describe("WriteReport", func() {
+		It("should list the resources and workloads", func() {
+			var out bytes.Buffer
+			Expect(graph.WriteReport(&out)).To(Succeed())
+
+			report := out.String()
+			Expect(report).To(MatchRegexp(`ConfigMap/web-config\s+apps\s+yes\s+Deployment/web \(envFrom in web\), Pod/web \(env in debug\)`))
+			Expect(report).To(MatchRegexp(`ConfigMap/web-static\s+apps\s+no\s+Deployment/web \(volume static\)`))
+			Expect(report).To(MatchRegexp(`ConfigMap/db-config\s+data\s+no\s+StatefulSet/db \(subPath volume config\)`))
+			Expect(report).To(MatchRegexp(`StatefulSet/db\s+data\s+yes\s+ConfigMap/db-config \(subPath volume config\), ` +
+				`Secret/db-certs \(volume certs\)`))
+		})
+	})
+})
@@ -0,0 +1,167 @@
+package analyzer
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/yaml"
+
+	configv2 "github.com/shehbazk/config-reloader-operator/api/v2"
+	"github.com/shehbazk/config-reloader-operator/internal/podrefs"
+)
+
+// Suggest returns a ConfigReloader for every workload consuming ConfigMaps or
+// Secrets it only picks up when restarted, watching those resources. With
+// volumes, the resources consumed through volumes updated in place are
+// watched too.
+func (g *Graph) Suggest(volumes bool) []*configv2.ConfigReloader {
+	var suggestions []*configv2.ConfigReloader
+	names := make(map[string]bool)
+	for _, w := range g.Workloads {
+		cr := &configv2.ConfigReloader{
+			TypeMeta: metav1.TypeMeta{
+				APIVersion: configv2.GroupVersion.String(),
+				Kind:       "ConfigReloader",
+			},
+			ObjectMeta: metav1.ObjectMeta{Name: w.Name, Namespace: w.Namespace},
+			Spec: configv2.ConfigReloaderSpec{
+				Targets:  configv2.ReloadTargets{Selector: w.Selector},
+				Strategy: configv2.ReloadStrategy{Type: configv2.StrategyRolloutRestart},
+			},
+		}
+		if w.Kind == "Pod" {
+			// Standalone pods can only be restarted by deleting them
+			cr.Spec.Strategy.Type = configv2.StrategyDeletePods
+		}
+
+		watched := make(map[string]bool)
+		for _, ref := range w.References {
+			key := ref.Kind + "/" + ref.Name
+			if watched[key] || !ref.NeedsRestart() && !volumes {
+				continue
+			}
+			watched[key] = true
+			if ref.Kind == podrefs.KindConfigMap {
+				cr.Spec.Trigger.ConfigMaps = append(cr.Spec.Trigger.ConfigMaps, configv2.ObjectReference{Name: ref.Name})
+			} else {
+				cr.Spec.Trigger.Secrets = append(cr.Spec.Trigger.Secrets, configv2.ObjectReference{Name: ref.Name})
+			}
+		}
+		if len(watched) == 0 {
+			continue
+		}
+
+		// Workloads of different kinds may share a name
+		if key := w.Namespace + "/" + cr.Name; names[key] {
+			cr.Name = fmt.Sprintf("%s-%s", w.Name, strings.ToLower(w.Kind))
+		}
+		names[cr.Namespace+"/"+cr.Name] = true
+		suggestions = append(suggestions, cr)
+	}
+	return suggestions
+}
+
+// WriteConfigReloaders writes the ConfigReloaders as a YAML stream
+func WriteConfigReloaders(out io.Writer, configReloaders []*configv2.ConfigReloader) error {
+	for i, cr := range configReloaders {
+		obj, err := runtime.DefaultUnstructuredConverter.ToUnstructured(cr)
+		if err != nil {
+			return fmt.Errorf("failed to convert ConfigReloader %s: %w", cr.Name, err)
+		}
+		// Leave out the fields set by the API server
+		delete(obj, "status")
+		if metadata, ok := obj["metadata"].(map[string]interface{}); ok {
+			delete(metadata, "creationTimestamp")
+		}
+		if spec, ok := obj["spec"].(map[string]interface{}); ok {
+			if history, ok := spec["history"].(map[string]interface{}); ok && len(history) == 0 {
+				delete(spec, "history")
+			}
+		}
+		data, err := yaml.Marshal(obj)
+		if err != nil {
+			return fmt.Errorf("failed to marshal ConfigReloader %s: %w", cr.Name, err)
+		}
+		if i > 0 {
+			if _, err := fmt.Fprintln(out, "---"); err != nil {
+				return err
+			}
+		}
+		if _, err := out.Write(data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteReport writes the ConfigMaps and Secrets with the workloads consuming
+// them, and the workloads with whether they need to be restarted to pick up
+// changes
+func (g *Graph) WriteReport(out io.Writer) error {
+	w := tabwriter.NewWriter(out, 6, 4, 3, ' ', 0)
+
+	fmt.Fprintln(w, "RESOURCE\tNAMESPACE\tDEFINED\tCONSUMERS")
+	for _, resource := range g.Resources {
+		consumers := make([]string, 0, len(resource.Consumers))
+		for _, consumer := range resource.Consumers {
+			consumers = append(consumers, fmt.Sprintf("%s/%s (%s)",
+				consumer.Workload.Kind, consumer.Workload.Name, describe(consumer.Reference)))
+		}
+		fmt.Fprintf(w, "%s/%s\t%s\t%s\t%s\n", resource.Kind, resource.Name, namespaceOrNone(resource.Namespace),
+			yesNo(resource.Defined), joinOrNone(consumers))
+	}
+
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "WORKLOAD\tNAMESPACE\tRESTART NEEDED\tREFERENCES")
+	for _, workload := range g.Workloads {
+		refs := make([]string, 0, len(workload.References))
+		for _, ref := range workload.References {
+			refs = append(refs, fmt.Sprintf("%s/%s (%s)", ref.Kind, ref.Name, describe(ref)))
+		}
+		fmt.Fprintf(w, "%s/%s\t%s\t%s\t%s\n", workload.Kind, workload.Name, namespaceOrNone(workload.Namespace),
+			yesNo(workload.NeedsRestart()), joinOrNone(refs))
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	_, err := fmt.Fprintln(out, "\nEnv vars, envFrom and subPath mounts only pick up changes when the pods restart. "+
+		"Other volumes are updated in place.")
+	return err
+}
+
+// describe tells how a reference is consumed
+func describe(ref Reference) string {
+	switch {
+	case ref.SubPath:
+		return fmt.Sprintf("subPath volume %s", ref.Volume)
+	case ref.Container == "":
+		return fmt.Sprintf("volume %s", ref.Volume)
+	}
+	return fmt.Sprintf("%s in %s", ref.Source, ref.Container)
+}
+
+func namespaceOrNone(namespace string) string {
+	if namespace == "" {
+		return "<none>"
+	}
+	return namespace
+}
+
+func joinOrNone(values []string) string {
+	if len(values) == 0 {
+		return "<none>"
+	}
+	return strings.Join(values, ", ")
+}
+
+func yesNo(value bool) string {
+	if value {
+		return "yes"
+	}
+	return "no"
+}
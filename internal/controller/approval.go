@@ -0,0 +1,223 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilrand "k8s.io/apimachinery/pkg/util/rand"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	configv1 "github.com/shehbazk/config-reloader-operator/api/v1"
+)
+
+const (
+	defaultApprovalTimeout = 24 * time.Hour
+
+	// approvalCondition reports whether a reload is waiting for approval
+	approvalCondition = "ApprovalPending"
+)
+
+// requiresApproval reports whether reloads of the ConfigReloader are held
+// until they are approved. Dry runs restart nothing and are never held.
+func (r *ConfigReloaderReconciler) requiresApproval(cr *configv1.ConfigReloader) bool {
+	return cr.Spec.Approval != nil && !r.isDryRun(cr)
+}
+
+func approvalTimeout(cr *configv1.ConfigReloader) time.Duration {
+	if cr.Spec.Approval == nil || cr.Spec.Approval.Timeout == nil {
+		return defaultApprovalTimeout
+	}
+	return cr.Spec.Approval.Timeout.Duration
+}
+
+// gateOnApproval holds the reload triggered by this reconcile, if any, and
+// returns the held reload to perform once it is approved. Held changes that
+// are not approved before their expiration are discarded.
+func (r *ConfigReloaderReconciler) gateOnApproval(
+	ctx context.Context,
+	cr *configv1.ConfigReloader,
+	reload bool,
+	changes []resourceChange,
+	cause string,
+	now time.Time,
+) (bool, []resourceChange, string) {
+	logger := log.FromContext(ctx)
+
+	if reload {
+		r.holdForApproval(cr, changes, cause, now)
+		pending := cr.Status.PendingApproval
+		logger.Info("Holding reload until it is approved", "id", pending.ID, "cause", pending.Cause)
+		r.recordEventf(cr, corev1.EventTypeNormal, EventReasonApprovalPending,
+			"Reload held until approved, set the %s annotation to %s: %s",
+			configv1.ApproveAnnotation, pending.ID, pending.Cause)
+	}
+
+	pending := cr.Status.PendingApproval
+	if pending == nil {
+		r.updateCondition(cr, approvalCondition, metav1.ConditionFalse, "NoPendingChanges", "No reload is waiting for approval")
+		return false, nil, ""
+	}
+
+	approved := cr.Annotations[configv1.ApproveAnnotation] == pending.ID
+	switch {
+	case approved && !r.WebhooksDisabled:
+		approver := cr.Annotations[configv1.ApprovedByAnnotation]
+		logger.Info("Reload approved", "id", pending.ID, "approver", approver)
+		r.recordEventf(cr, corev1.EventTypeNormal, EventReasonReloadApproved,
+			"Reload %s approved by %s: %s", pending.ID, approver, pending.Cause)
+		cr.Status.LastApproval = &configv1.ApprovalRecord{ID: pending.ID, Approver: approver, Time: metav1.NewTime(now)}
+		cr.Status.PendingApproval = nil
+		r.updateCondition(cr, approvalCondition, metav1.ConditionFalse, "Approved",
+			fmt.Sprintf("Reload %s approved by %s", pending.ID, approver))
+		return true, changedResourceChanges(pending.Resources), fmt.Sprintf("%s, approved by %s", pending.Cause, approver)
+
+	case !now.Before(pending.ExpirationTime.Time):
+		logger.Info("Discarding reload that was not approved in time", "id", pending.ID)
+		r.recordEventf(cr, corev1.EventTypeWarning, EventReasonApprovalExpired,
+			"Reload %s was not approved before %s and is discarded: %s",
+			pending.ID, pending.ExpirationTime.UTC().Format(time.RFC3339), pending.Cause)
+		cr.Status.PendingApproval = nil
+		r.updateCondition(cr, approvalCondition, metav1.ConditionFalse, "Expired",
+			fmt.Sprintf("Reload %s was not approved in time", pending.ID))
+		return false, nil, ""
+
+	case approved:
+		// Without the webhooks anyone allowed to update the ConfigReloader
+		// could approve, and name any approver
+		condition := meta.FindStatusCondition(cr.Status.Conditions, approvalCondition)
+		if condition == nil || condition.Reason != "ApprovalUnverified" {
+			logger.Info("Ignoring approval that was not checked by the admission webhook", "id", pending.ID)
+			r.recordEventf(cr, corev1.EventTypeWarning, EventReasonApprovalUnverified,
+				"Reload %s is not applied: approvals are not checked while the admission webhooks are disabled",
+				pending.ID)
+		}
+		r.updateCondition(cr, approvalCondition, metav1.ConditionTrue, "ApprovalUnverified",
+			fmt.Sprintf("Approval of reload %s ignored as the admission webhooks are disabled, "+
+				"enable them to approve it: %s", pending.ID, pending.Cause))
+		return false, nil, ""
+	}
+
+	r.updateCondition(cr, approvalCondition, metav1.ConditionTrue, "AwaitingApproval",
+		fmt.Sprintf("Set the %s annotation to %s to approve: %s", configv1.ApproveAnnotation, pending.ID, pending.Cause))
+	return false, nil, ""
+}
+
+// holdForApproval adds a reload to the pending approval. The pending
+// approval gets a new ID and expiration, so that an approval only applies to
+// the changes that were held when it was given.
+func (r *ConfigReloaderReconciler) holdForApproval(
+	cr *configv1.ConfigReloader,
+	changes []resourceChange,
+	cause string,
+	now time.Time,
+) {
+	pending := cr.Status.PendingApproval
+	if pending == nil {
+		pending = &configv1.PendingApproval{RequestTime: metav1.NewTime(now)}
+		cr.Status.PendingApproval = pending
+	}
+
+	pending.Resources = mergeChangedResources(pending.Resources, changes)
+	if len(pending.Resources) > 0 {
+		pending.Cause = describeChanges(changedResourceChanges(pending.Resources))
+	} else {
+		pending.Cause = cause
+	}
+	pending.ID = utilrand.String(8)
+	pending.ExpirationTime = metav1.NewTime(now.Add(approvalTimeout(cr)))
+}
+
+// releaseHeldReload adds the changes held for approval to the reload once
+// approval is turned off. The admission webhook only lets approvers turn it
+// off while changes are held, so without the webhooks the held changes are
+// discarded instead.
+func (r *ConfigReloaderReconciler) releaseHeldReload(
+	ctx context.Context,
+	cr *configv1.ConfigReloader,
+	reload bool,
+	changes []resourceChange,
+	cause string,
+) (bool, []resourceChange, string) {
+	pending := cr.Status.PendingApproval
+	cr.Status.PendingApproval = nil
+	meta.RemoveStatusCondition(&cr.Status.Conditions, approvalCondition)
+	if r.WebhooksDisabled {
+		log.FromContext(ctx).Info("Discarding reload held for approval as approval was turned off", "id", pending.ID)
+		r.recordEventf(cr, corev1.EventTypeWarning, EventReasonApprovalDiscarded,
+			"Reload %s is discarded: approval was turned off while the admission webhooks are disabled: %s",
+			pending.ID, pending.Cause)
+		return reload, changes, cause
+	}
+	if !reload {
+		cause = pending.Cause
+	}
+	return true, append(changedResourceChanges(pending.Resources), changes...), cause
+}
+
+// mergeChangedResources adds changes to the changed resources. A resource
+// that changed again keeps the version seen before its first change, and its
+// changed keys are merged.
+func mergeChangedResources(resources []configv1.ChangedResource, changes []resourceChange) []configv1.ChangedResource {
+	for _, change := range changes {
+		i := 0
+		for i < len(resources) && (resources[i].Kind != change.Kind ||
+			resources[i].Namespace != change.Namespace || resources[i].Name != change.Name) {
+			i++
+		}
+		if i == len(resources) {
+			resources = append(resources, configv1.ChangedResource{
+				Kind:            change.Kind,
+				Name:            change.Name,
+				Namespace:       change.Namespace,
+				PreviousVersion: change.OldVersion,
+			})
+		}
+		resources[i].Version = change.NewVersion
+		resources[i].ChangedBy = change.ChangedBy
+		resources[i].Keys = mergeKeyChanges(resources[i].Keys, change.Keys)
+	}
+	return resources
+}
+
+// mergeKeyChanges adds the key changes of a later change to keys, so that
+// every key is reported with its change since the first change
+func mergeKeyChanges(keys, later []configv1.KeyChange) []configv1.KeyChange {
+	for _, change := range later {
+		i := slices.IndexFunc(keys, func(key configv1.KeyChange) bool { return key.Key == change.Key })
+		switch {
+		case i < 0:
+			keys = append(keys, change)
+		case keys[i].Type == configv1.KeyAdded && change.Type == configv1.KeyRemoved:
+			keys = slices.Delete(keys, i, i+1)
+		case keys[i].Type == configv1.KeyRemoved && change.Type == configv1.KeyAdded:
+			keys[i].Type = configv1.KeyModified
+		case change.Type == configv1.KeyRemoved:
+			keys[i].Type = configv1.KeyRemoved
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].Key < keys[j].Key })
+	return keys
+}
+
+// changedResourceChanges returns the changes recorded as changed resources
+func changedResourceChanges(resources []configv1.ChangedResource) []resourceChange {
+	changes := make([]resourceChange, 0, len(resources))
+	for _, resource := range resources {
+		changes = append(changes, resourceChange{
+			Kind:       resource.Kind,
+			Name:       resource.Name,
+			Namespace:  resource.Namespace,
+			OldVersion: resource.PreviousVersion,
+			NewVersion: resource.Version,
+			ChangedBy:  resource.ChangedBy,
+			Keys:       resource.Keys,
+		})
+	}
+	return changes
+}
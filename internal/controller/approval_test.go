@@ -0,0 +1,221 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	configv1 "github.com/shehbazk/config-reloader-operator/api/v1"
+)
+
+var _ = Describe("Reload approval", func() {
+	var (
+		ctx        context.Context
+		now        time.Time
+		cr         *configv1.ConfigReloader
+		reconciler *ConfigReloaderReconciler
+		change     resourceChange
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		now = time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+		cr = &configv1.ConfigReloader{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "prod"},
+			Spec: configv1.ConfigReloaderSpec{
+				Approval: &configv1.ApprovalPolicy{Timeout: &metav1.Duration{Duration: time.Hour}},
+			},
+		}
+		reconciler = &ConfigReloaderReconciler{}
+		change = resourceChange{Kind: "ConfigMap", Name: "app", Namespace: "prod", OldVersion: "1", NewVersion: "2"}
+	})
+
+	// approve sets the approval annotations as the admission webhook admits them
+	approve := func(id, approver string) {
+		cr.Annotations = map[string]string{
+			configv1.ApproveAnnotation:    id,
+			configv1.ApprovedByAnnotation: approver,
+		}
+	}
+
+	It("should only hold reloads that restart pods", func() {
+		Expect(reconciler.requiresApproval(cr)).To(BeTrue())
+		cr.Spec.DryRun = true
+		Expect(reconciler.requiresApproval(cr)).To(BeFalse())
+	})
+
+	It("should hold detected changes until they are approved", func() {
+		reload, _, _ := reconciler.gateOnApproval(ctx, cr, true, []resourceChange{change}, describeChanges([]resourceChange{change}), now)
+		Expect(reload).To(BeFalse())
+
+		pending := cr.Status.PendingApproval
+		Expect(pending).NotTo(BeNil())
+		Expect(pending.ID).NotTo(BeEmpty())
+		Expect(pending.Cause).To(Equal("ConfigMap prod/app changed"))
+		Expect(pending.Resources).To(ConsistOf(configv1.ChangedResource{
+			Kind: "ConfigMap", Name: "app", Namespace: "prod", PreviousVersion: "1", Version: "2",
+		}))
+		Expect(pending.RequestTime.Time).To(Equal(now))
+		Expect(pending.ExpirationTime.Time).To(Equal(now.Add(time.Hour)))
+		Expect(meta.IsStatusConditionTrue(cr.Status.Conditions, approvalCondition)).To(BeTrue())
+
+		// Nothing happens until the reload is approved
+		reload, _, _ = reconciler.gateOnApproval(ctx, cr, false, nil, "", now.Add(time.Minute))
+		Expect(reload).To(BeFalse())
+		Expect(cr.Status.PendingApproval).NotTo(BeNil())
+
+		approve(pending.ID, "alice")
+		reload, changes, cause := reconciler.gateOnApproval(ctx, cr, false, nil, "", now.Add(2*time.Minute))
+		Expect(reload).To(BeTrue())
+		Expect(changes).To(ConsistOf(change))
+		Expect(cause).To(Equal("ConfigMap prod/app changed, approved by alice"))
+		Expect(cr.Status.PendingApproval).To(BeNil())
+		Expect(cr.Status.LastApproval).To(Equal(&configv1.ApprovalRecord{
+			ID: pending.ID, Approver: "alice", Time: metav1.NewTime(now.Add(2 * time.Minute)),
+		}))
+		Expect(meta.IsStatusConditionFalse(cr.Status.Conditions, approvalCondition)).To(BeTrue())
+	})
+
+	It("should not apply approvals while the admission webhooks are disabled", func() {
+		reconciler.WebhooksDisabled = true
+		reconciler.gateOnApproval(ctx, cr, true, []resourceChange{change}, "", now)
+		pending := cr.Status.PendingApproval
+
+		approve(pending.ID, "mallory")
+		reload, _, _ := reconciler.gateOnApproval(ctx, cr, false, nil, "", now.Add(time.Minute))
+		Expect(reload).To(BeFalse())
+		Expect(cr.Status.PendingApproval).To(Equal(pending))
+		Expect(cr.Status.LastApproval).To(BeNil())
+		condition := meta.FindStatusCondition(cr.Status.Conditions, approvalCondition)
+		Expect(condition).NotTo(BeNil())
+		Expect(condition.Status).To(Equal(metav1.ConditionTrue))
+		Expect(condition.Reason).To(Equal("ApprovalUnverified"))
+
+		By("still discarding the held changes once they expire")
+		reload, _, _ = reconciler.gateOnApproval(ctx, cr, false, nil, "", now.Add(2*time.Hour))
+		Expect(reload).To(BeFalse())
+		Expect(cr.Status.PendingApproval).To(BeNil())
+	})
+
+	It("should require a new approval when more changes are held", func() {
+		reconciler.gateOnApproval(ctx, cr, true, []resourceChange{change}, "", now)
+		first := cr.Status.PendingApproval.ID
+		approve(first, "alice")
+
+		next := change
+		next.OldVersion, next.NewVersion = "2", "3"
+		secret := resourceChange{Kind: "Secret", Name: "tls", Namespace: "prod", OldVersion: "7", NewVersion: "8"}
+		reload, _, _ := reconciler.gateOnApproval(ctx, cr, true, []resourceChange{next, secret}, "", now.Add(30*time.Minute))
+		Expect(reload).To(BeFalse())
+
+		pending := cr.Status.PendingApproval
+		Expect(pending.ID).NotTo(Equal(first))
+		Expect(pending.RequestTime.Time).To(Equal(now))
+		Expect(pending.ExpirationTime.Time).To(Equal(now.Add(90 * time.Minute)))
+		Expect(pending.Resources).To(ConsistOf(
+			configv1.ChangedResource{Kind: "ConfigMap", Name: "app", Namespace: "prod", PreviousVersion: "1", Version: "3"},
+			configv1.ChangedResource{Kind: "Secret", Name: "tls", Namespace: "prod", PreviousVersion: "7", Version: "8"},
+		))
+		Expect(pending.Cause).To(Equal("ConfigMap prod/app changed, Secret prod/tls changed"))
+	})
+
+	It("should list the keys changed since the first held change", func() {
+		first := change
+		first.Keys = []configv1.KeyChange{
+			{Key: "added", Type: configv1.KeyAdded},
+			{Key: "dropped", Type: configv1.KeyAdded},
+			{Key: "modified", Type: configv1.KeyModified},
+			{Key: "replaced", Type: configv1.KeyRemoved},
+		}
+		reconciler.gateOnApproval(ctx, cr, true, []resourceChange{first}, "", now)
+
+		second := change
+		second.OldVersion, second.NewVersion = "2", "3"
+		second.Keys = []configv1.KeyChange{
+			{Key: "added", Type: configv1.KeyModified},
+			{Key: "dropped", Type: configv1.KeyRemoved},
+			{Key: "modified", Type: configv1.KeyRemoved},
+			{Key: "replaced", Type: configv1.KeyAdded},
+			{Key: "new", Type: configv1.KeyAdded},
+		}
+		reconciler.gateOnApproval(ctx, cr, true, []resourceChange{second}, "", now.Add(time.Minute))
+
+		resources := cr.Status.PendingApproval.Resources
+		Expect(resources).To(HaveLen(1))
+		Expect(resources[0].PreviousVersion).To(Equal("1"))
+		Expect(resources[0].Keys).To(Equal([]configv1.KeyChange{
+			{Key: "added", Type: configv1.KeyAdded},
+			{Key: "modified", Type: configv1.KeyRemoved},
+			{Key: "new", Type: configv1.KeyAdded},
+			{Key: "replaced", Type: configv1.KeyModified},
+		}))
+	})
+
+	It("should hold manual reload requests", func() {
+		reconciler.gateOnApproval(ctx, cr, true, nil, "manual reload requested at noon", now)
+		Expect(cr.Status.PendingApproval.Cause).To(Equal("manual reload requested at noon"))
+		Expect(cr.Status.PendingApproval.Resources).To(BeEmpty())
+	})
+
+	It("should discard held changes that are not approved in time", func() {
+		reconciler.gateOnApproval(ctx, cr, true, []resourceChange{change}, "", now)
+
+		reload, _, _ := reconciler.gateOnApproval(ctx, cr, false, nil, "", now.Add(time.Hour))
+		Expect(reload).To(BeFalse())
+		Expect(cr.Status.PendingApproval).To(BeNil())
+		condition := meta.FindStatusCondition(cr.Status.Conditions, approvalCondition)
+		Expect(condition).NotTo(BeNil())
+		Expect(condition.Reason).To(Equal("Expired"))
+	})
+
+	It("should apply the held changes once approval is turned off", func() {
+		reconciler.gateOnApproval(ctx, cr, true, []resourceChange{change}, "", now)
+		cr.Spec.Approval = nil
+
+		reload, changes, cause := reconciler.releaseHeldReload(ctx, cr, false, nil, "")
+		Expect(reload).To(BeTrue())
+		Expect(changes).To(ConsistOf(change))
+		Expect(cause).To(Equal("ConfigMap prod/app changed"))
+		Expect(cr.Status.PendingApproval).To(BeNil())
+		Expect(meta.FindStatusCondition(cr.Status.Conditions, approvalCondition)).To(BeNil())
+	})
+
+	It("should discard the held changes when approval is turned off without the admission webhooks", func() {
+		reconciler.WebhooksDisabled = true
+		reconciler.gateOnApproval(ctx, cr, true, []resourceChange{change}, "", now)
+		cr.Spec.Approval = nil
+
+		reload, changes, _ := reconciler.releaseHeldReload(ctx, cr, false, nil, "")
+		Expect(reload).To(BeFalse())
+		Expect(changes).To(BeEmpty())
+		Expect(cr.Status.PendingApproval).To(BeNil())
+		Expect(meta.FindStatusCondition(cr.Status.Conditions, approvalCondition)).To(BeNil())
+
+		By("still reloading the changes detected since")
+		reconciler.gateOnApproval(ctx, cr, true, []resourceChange{change}, "", now)
+		later := resourceChange{Kind: "ConfigMap", Name: "app", Namespace: "prod", OldVersion: "2", NewVersion: "3"}
+		reload, changes, _ = reconciler.releaseHeldReload(ctx, cr, true, []resourceChange{later}, "ConfigMap prod/app changed")
+		Expect(reload).To(BeTrue())
+		Expect(changes).To(ConsistOf(later))
+	})
+})
@@ -0,0 +1,420 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	configv1 "github.com/shehbazk/config-reloader-operator/api/v1"
+	"github.com/shehbazk/config-reloader-operator/internal/promquery"
+)
+
+const (
+	defaultCanaryPercent  = 10
+	defaultCanaryBakeTime = 5 * time.Minute
+	canaryQueryTimeout    = 10 * time.Second
+
+	// canaryCondition reports whether the canaries of the last canary reload
+	// are healthy
+	canaryCondition = "CanaryHealthy"
+)
+
+// MetricsQuerier runs the instant queries of canary analyses
+type MetricsQuerier interface {
+	Query(ctx context.Context, address, query string) ([]float64, error)
+}
+
+// defaultMetricsQuerier is used when the reconciler has no MetricsQuerier
+var defaultMetricsQuerier MetricsQuerier = promquery.NewClient(canaryQueryTimeout)
+
+func (r *ConfigReloaderReconciler) metricsQuerier() MetricsQuerier {
+	if r.MetricsQuerier != nil {
+		return r.MetricsQuerier
+	}
+	return defaultMetricsQuerier
+}
+
+// analysisAddressAllowed reports whether the operator may query the address
+// of a canary analysis. Only the addresses the operator was started with are
+// queried, so that ConfigReloaders cannot make it fetch arbitrary URLs.
+func (r *ConfigReloaderReconciler) analysisAddressAllowed(address string) bool {
+	address = strings.TrimSuffix(address, "/")
+	for _, allowed := range r.AnalysisAddresses {
+		if address == strings.TrimSuffix(allowed, "/") {
+			return true
+		}
+	}
+	return false
+}
+
+func canaryPercent(cr *configv1.ConfigReloader) int {
+	if cr.Spec.Canary == nil || cr.Spec.Canary.Percent == 0 {
+		return defaultCanaryPercent
+	}
+	return int(cr.Spec.Canary.Percent)
+}
+
+func canaryBakeTime(cr *configv1.ConfigReloader) time.Duration {
+	if cr.Spec.Canary == nil || cr.Spec.Canary.BakeTime == nil {
+		return defaultCanaryBakeTime
+	}
+	return cr.Spec.Canary.BakeTime.Duration
+}
+
+// canaryBaking reports whether the canaries of a reload are being checked
+// before the rest of the targets is restarted
+func canaryBaking(cr *configv1.ConfigReloader) bool {
+	return cr.Status.Canary != nil && cr.Status.Canary.Phase == configv1.CanaryPhaseBaking
+}
+
+// canaryBakeEnd is when the canaries being checked have baked long enough
+func canaryBakeEnd(cr *configv1.ConfigReloader) time.Time {
+	return cr.Status.Canary.StartTime.Add(canaryBakeTime(cr))
+}
+
+// startCanary selects the canaries of a reload and records them in status.
+// Targets are grouped like failed restarts, by owning workload when they are
+// rolled and by pod when they are deleted, and the canaries are the first
+// groups by name. When the targets are the pods of a single rolled workload,
+// a fraction of its pods is deleted instead, so that the rest of the
+// workload keeps running the previous configuration while they bake. A
+// canary still baking is superseded, its changes are rolled out with the new
+// ones.
+func (r *ConfigReloaderReconciler) startCanary(
+	ctx context.Context,
+	cr *configv1.ConfigReloader,
+	targets []restartTarget,
+	changes []resourceChange,
+	cause string,
+	now time.Time,
+) []restartTarget {
+	logger := log.FromContext(ctx)
+
+	ordered := canaryRefs(targets)
+	if len(ordered) == 0 {
+		return targets
+	}
+	if len(ordered) == 1 && ordered[0].Kind != "Pod" && canaryCount(cr, len(targets)) < len(targets) {
+		deleted := make([]restartTarget, 0, len(targets))
+		for _, target := range targets {
+			target.Policy = configv1.RestartPolicyDelete
+			deleted = append(deleted, target)
+		}
+		targets = deleted
+		ordered = canaryRefs(targets)
+	}
+
+	count := canaryCount(cr, len(ordered))
+	canaries := canarySet(ordered[:count])
+
+	var resources []configv1.ChangedResource
+	if canaryBaking(cr) {
+		logger.Info("Superseding the canary being baked", "cause", cr.Status.Canary.Cause)
+		resources = cr.Status.Canary.Resources
+	}
+	cr.Status.Canary = &configv1.CanaryStatus{
+		Phase:     configv1.CanaryPhaseBaking,
+		Cause:     cause,
+		Resources: mergeChangedResources(resources, changes),
+		Targets:   ordered[:count],
+		StartTime: metav1.NewTime(now),
+		Message: fmt.Sprintf("Restarted %d of %d targets, the rest is restarted once they stay healthy for %s",
+			count, len(ordered), canaryBakeTime(cr)),
+	}
+	logger.Info("Restarting canaries", "canaries", count, "targets", len(ordered))
+	r.recordEventf(cr, corev1.EventTypeNormal, EventReasonCanaryStarted,
+		"Restarting %s first: %s", describeCanaries(cr.Status.Canary.Targets), cause)
+	r.updateCondition(cr, canaryCondition, metav1.ConditionTrue, "Baking", cr.Status.Canary.Message)
+
+	selected := make([]restartTarget, 0, len(targets))
+	for _, target := range targets {
+		kind, name := failedTargetRef(target)
+		if canaries[configv1.CanaryTarget{Kind: kind, Name: name}] {
+			selected = append(selected, target)
+		}
+	}
+	return selected
+}
+
+// canaryRefs returns the targets grouped like failed restarts, ordered by
+// kind and name
+func canaryRefs(targets []restartTarget) []configv1.CanaryTarget {
+	refs := make(map[configv1.CanaryTarget]bool)
+	var ordered []configv1.CanaryTarget
+	for _, target := range targets {
+		kind, name := failedTargetRef(target)
+		ref := configv1.CanaryTarget{Kind: kind, Name: name}
+		if !refs[ref] {
+			refs[ref] = true
+			ordered = append(ordered, ref)
+		}
+	}
+	sort.Slice(ordered, func(i, j int) bool {
+		if ordered[i].Kind != ordered[j].Kind {
+			return ordered[i].Kind < ordered[j].Kind
+		}
+		return ordered[i].Name < ordered[j].Name
+	})
+	return ordered
+}
+
+// canaryCount returns the number of canaries among count targets
+func canaryCount(cr *configv1.ConfigReloader, count int) int {
+	return max(1, (count*canaryPercent(cr)+99)/100)
+}
+
+// progressCanary checks the canaries of the reload being baked. The rest of
+// the targets is restarted once the canaries stayed healthy for the bake
+// time, and left untouched when a canary fails.
+func (r *ConfigReloaderReconciler) progressCanary(
+	ctx context.Context,
+	cr *configv1.ConfigReloader,
+	changes []resourceChange,
+	now time.Time,
+) (*restartResult, error) {
+	logger := log.FromContext(ctx)
+	canary := cr.Status.Canary
+
+	if r.isDryRun(cr) {
+		// Nothing is restarted in dry-run mode, not even the rest
+		return nil, nil
+	}
+
+	// Removing the canary strategy promotes the canary being baked
+	if cr.Spec.Canary != nil {
+		pending, err := r.checkCanaries(ctx, cr)
+		if err == nil && now.Before(canaryBakeEnd(cr)) {
+			message := fmt.Sprintf("Baking %s until %s", describeCanaries(canary.Targets),
+				canaryBakeEnd(cr).UTC().Format(time.RFC3339))
+			if len(pending) > 0 {
+				message += ", waiting for " + strings.Join(pending, ", ")
+			}
+			r.updateCondition(cr, canaryCondition, metav1.ConditionTrue, "Baking", message)
+			return nil, nil
+		}
+		if err == nil && len(pending) > 0 {
+			err = fmt.Errorf("not ready after %s: %s", canaryBakeTime(cr), strings.Join(pending, ", "))
+		}
+		if err != nil {
+			r.abortCanary(ctx, cr, changes, err, now)
+			return nil, nil
+		}
+	}
+
+	logger.Info("Promoting canary", "cause", canary.Cause)
+	r.notifyReloadStarted(ctx, cr, changes, canary.Cause)
+	reloadCtx, span := r.startReloadSpan(ctx, cr, changes, canary.Cause, now)
+	result, err := r.restartRemaining(reloadCtx, cr, changes)
+	endReloadSpan(span, result, err)
+	if err != nil {
+		// The canary keeps baking and the promotion is tried again
+		return nil, err
+	}
+
+	completion := metav1.NewTime(now)
+	canary.Phase = configv1.CanaryPhasePromoted
+	canary.CompletionTime = &completion
+	canary.Message = fmt.Sprintf("Restarted %d remaining pods once the canaries stayed healthy for %s",
+		len(result.Restarted), canaryBakeTime(cr))
+	r.recordEventf(cr, corev1.EventTypeNormal, EventReasonCanaryPromoted,
+		"Canary promoted after %s, restarted %d remaining pods", canaryBakeTime(cr), len(result.Restarted))
+	r.updateCondition(cr, canaryCondition, metav1.ConditionTrue, "Promoted", canary.Message)
+	return result, nil
+}
+
+// restartRemaining restarts the targets of a reload left out of its canary.
+// Pods created since the canaries were restarted already run the new
+// configuration.
+func (r *ConfigReloaderReconciler) restartRemaining(
+	ctx context.Context,
+	cr *configv1.ConfigReloader,
+	changes []resourceChange,
+) (*restartResult, error) {
+	canary := cr.Status.Canary
+
+	targets, failures, err := r.planReload(ctx, cr, changes)
+	if err != nil {
+		return nil, err
+	}
+
+	canaries := canarySet(canary.Targets)
+	remaining := make([]restartTarget, 0, len(targets))
+	for _, target := range targets {
+		kind, name := failedTargetRef(target)
+		if canaries[configv1.CanaryTarget{Kind: kind, Name: name}] ||
+			!target.Pod.CreationTimestamp.Before(&canary.StartTime) {
+			continue
+		}
+		remaining = append(remaining, target)
+	}
+
+	result := r.executeRestarts(ctx, cr, remaining, canary.Cause)
+	result.Failed = append(failures, result.Failed...)
+	return result, nil
+}
+
+// checkCanaries returns an error when a canary failed, and otherwise what
+// the canaries are still waiting for. Canaries that cannot be checked are
+// waited for until the end of the bake time.
+func (r *ConfigReloaderReconciler) checkCanaries(ctx context.Context, cr *configv1.ConfigReloader) ([]string, error) {
+	logger := log.FromContext(ctx)
+	canary := cr.Status.Canary
+
+	canaries := canarySet(canary.Targets)
+	for _, failed := range cr.Status.FailedRestarts {
+		if canaries[configv1.CanaryTarget{Kind: failed.Kind, Name: failed.Name}] {
+			return nil, fmt.Errorf("restart of %s %s failed: %s", failed.Kind, failed.Name, failed.LastError)
+		}
+	}
+
+	var pending []string
+	for _, ref := range canary.Targets {
+		if ref.Kind == "Pod" {
+			// Deleted pods are checked through the pods replacing them
+			continue
+		}
+		state, err := r.workloadRolloutState(ctx, cr.Namespace, ref.Kind, ref.Name)
+		switch {
+		case apierrors.IsNotFound(err):
+			return nil, fmt.Errorf("%s %s was deleted", ref.Kind, ref.Name)
+		case err != nil:
+			logger.Error(err, "failed to check canary", "kind", ref.Kind, "name", ref.Name)
+			pending = append(pending, fmt.Sprintf("%s %s to be checked", ref.Kind, ref.Name))
+		case state == configv1.RolloutFailed:
+			return nil, fmt.Errorf("rollout of %s %s failed", ref.Kind, ref.Name)
+		case state == configv1.RolloutProgressing:
+			pending = append(pending, fmt.Sprintf("rollout of %s %s", ref.Kind, ref.Name))
+		}
+	}
+
+	pods, err := r.canaryPods(ctx, cr)
+	if err != nil {
+		logger.Error(err, "failed to list canary pods")
+		pending = append(pending, "canary pods to be checked")
+	}
+	for _, pod := range pods {
+		for _, status := range pod.Status.ContainerStatuses {
+			if status.RestartCount > cr.Spec.Canary.MaxContainerRestarts {
+				return nil, fmt.Errorf("container %s of pod %s restarted %d times",
+					status.Name, pod.Name, status.RestartCount)
+			}
+		}
+		if !podReady(pod) {
+			pending = append(pending, fmt.Sprintf("pod %s to be ready", pod.Name))
+		}
+	}
+
+	if analysis := cr.Spec.Canary.Analysis; analysis != nil {
+		if !r.analysisAddressAllowed(analysis.Address) {
+			return nil, fmt.Errorf("analysis address %s is not allowed by the operator", analysis.Address)
+		}
+		values, err := r.metricsQuerier().Query(ctx, analysis.Address, analysis.Query)
+		if err != nil {
+			logger.Error(err, "failed to run canary analysis", "address", analysis.Address)
+			pending = append(pending, "analysis query to succeed")
+		}
+		for _, value := range values {
+			if value != 0 {
+				return nil, fmt.Errorf("analysis query returned %g", value)
+			}
+		}
+	}
+
+	return pending, nil
+}
+
+// canaryPods returns the affected pods created since the canaries were
+// restarted. Pods of rolled workloads only count when the workload is a
+// canary. When the canaries are deleted pods, the pods replacing them count.
+func (r *ConfigReloaderReconciler) canaryPods(ctx context.Context, cr *configv1.ConfigReloader) ([]*corev1.Pod, error) {
+	canary := cr.Status.Canary
+
+	pods, err := r.affectedPods(ctx, cr)
+	if err != nil {
+		return nil, err
+	}
+
+	canaries := canarySet(canary.Targets)
+	rolled := false
+	for _, ref := range canary.Targets {
+		rolled = rolled || ref.Kind != "Pod"
+	}
+	var errs []error
+	selected := make([]*corev1.Pod, 0, len(pods))
+	for _, pod := range pods {
+		if pod.CreationTimestamp.Before(&canary.StartTime) {
+			continue
+		}
+		if rolled && len(pod.OwnerReferences) > 0 {
+			kind, name, err := r.resolveOwningWorkload(ctx, pod)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			if !canaries[configv1.CanaryTarget{Kind: kind, Name: name}] {
+				continue
+			}
+		}
+		selected = append(selected, pod)
+	}
+	return selected, utilerrors.NewAggregate(errs)
+}
+
+// abortCanary stops a canary reload, leaving the rest of the targets on the
+// previous configuration
+func (r *ConfigReloaderReconciler) abortCanary(
+	ctx context.Context,
+	cr *configv1.ConfigReloader,
+	changes []resourceChange,
+	reason error,
+	now time.Time,
+) {
+	logger := log.FromContext(ctx)
+	canary := cr.Status.Canary
+
+	logger.Info("Aborting canary", "reason", reason.Error())
+	completion := metav1.NewTime(now)
+	canary.Phase = configv1.CanaryPhaseAborted
+	canary.CompletionTime = &completion
+	canary.Message = fmt.Sprintf("Canary failed, the remaining targets were not restarted: %v", reason)
+	r.recordEventf(cr, corev1.EventTypeWarning, EventReasonCanaryAborted, "%s", canary.Message)
+	r.updateCondition(cr, canaryCondition, metav1.ConditionFalse, "Aborted", canary.Message)
+
+	event := newReloadEvent(cr, changes, canary.Cause, nil, fmt.Errorf("canary aborted: %w", reason), canary.StartTime.Time)
+	r.recordReloadEvent(ctx, cr, event)
+	r.notifyReloadOutcome(ctx, cr, event)
+}
+
+func canarySet(canaries []configv1.CanaryTarget) map[configv1.CanaryTarget]bool {
+	set := make(map[configv1.CanaryTarget]bool, len(canaries))
+	for _, ref := range canaries {
+		set[ref] = true
+	}
+	return set
+}
+
+// describeCanaries lists the canaries for Event and condition messages
+func describeCanaries(canaries []configv1.CanaryTarget) string {
+	names := make([]string, 0, len(canaries))
+	for _, ref := range canaries {
+		names = append(names, ref.Kind+" "+ref.Name)
+	}
+	return strings.Join(names, ", ")
+}
+
+func podReady(pod *corev1.Pod) bool {
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.PodReady {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
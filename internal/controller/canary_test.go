@@ -0,0 +1,410 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	configv1 "github.com/shehbazk/config-reloader-operator/api/v1"
+	"github.com/shehbazk/config-reloader-operator/internal/promquery"
+)
+
+var _ = Describe("Canary reloads", func() {
+	var (
+		ctx        context.Context
+		scheme     *runtime.Scheme
+		cr         *configv1.ConfigReloader
+		objects    []client.Object
+		reconciler *ConfigReloaderReconciler
+		changes    []resourceChange
+	)
+
+	// pod returns a pod of the owner using the watched ConfigMap
+	pod := func(name, ownerKind, owner string, created time.Time) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              name,
+				Namespace:         "default",
+				Labels:            map[string]string{"app": "web"},
+				CreationTimestamp: metav1.NewTime(created),
+				OwnerReferences:   []metav1.OwnerReference{{APIVersion: "apps/v1", Kind: ownerKind, Name: owner}},
+			},
+			Spec: corev1.PodSpec{
+				Containers: []corev1.Container{{Name: "app", EnvFrom: []corev1.EnvFromSource{
+					{ConfigMapRef: &corev1.ConfigMapEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: "app"}}},
+				}}},
+			},
+			Status: corev1.PodStatus{
+				Conditions:        []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}},
+				ContainerStatuses: []corev1.ContainerStatus{{Name: "app", Ready: true}},
+			},
+		}
+	}
+
+	// deployment returns a Deployment whose rollout is complete
+	deployment := func(name string, replicas int32) *appsv1.Deployment {
+		return &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", Generation: 1},
+			Spec:       appsv1.DeploymentSpec{Replicas: &replicas},
+			Status: appsv1.DeploymentStatus{
+				ObservedGeneration: 1,
+				Replicas:           replicas,
+				UpdatedReplicas:    replicas,
+				AvailableReplicas:  replicas,
+			},
+		}
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		scheme = runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+		Expect(configv1.AddToScheme(scheme)).To(Succeed())
+
+		cr = &configv1.ConfigReloader{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+			Spec: configv1.ConfigReloaderSpec{
+				ConfigMaps:    []configv1.ResourceRef{{Name: "app"}},
+				Selector:      &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+				RestartPolicy: configv1.RestartPolicyAnnotation,
+				Canary: &configv1.CanaryStrategy{
+					Percent:  10,
+					BakeTime: &metav1.Duration{Duration: 5 * time.Minute},
+				},
+			},
+		}
+		created := time.Now().Add(-time.Hour)
+		objects = []client.Object{
+			deployment("api", 1), deployment("web", 1), deployment("worker", 1),
+			pod("api-1", "Deployment", "api", created),
+			pod("web-1", "Deployment", "web", created),
+			pod("worker-1", "Deployment", "worker", created),
+		}
+		changes = []resourceChange{{Kind: "ConfigMap", Name: "app", Namespace: "default", OldVersion: "1", NewVersion: "2"}}
+	})
+
+	build := func() {
+		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objects...).Build()
+		reconciler = &ConfigReloaderReconciler{Client: c, Scheme: scheme}
+	}
+
+	// restartedWorkloads returns the workloads restarted by a result
+	restartedWorkloads := func(result *restartResult) []string {
+		names := make([]string, 0, len(result.RestartedTargets))
+		for _, target := range result.RestartedTargets {
+			names = append(names, target.WorkloadName)
+		}
+		return names
+	}
+
+	// startCanary restarts the canaries and returns when they were restarted
+	startCanary := func() time.Time {
+		result, err := reconciler.restartAffectedPods(ctx, cr, changes, "ConfigMap default/app changed")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(restartedWorkloads(result)).To(Equal([]string{"api"}))
+		return cr.Status.Canary.StartTime.Time
+	}
+
+	// replace creates the pod replacing a canary
+	replace := func(replacement *corev1.Pod) {
+		Expect(reconciler.Create(ctx, replacement)).To(Succeed())
+	}
+
+	condition := func() *metav1.Condition {
+		return meta.FindStatusCondition(cr.Status.Conditions, canaryCondition)
+	}
+
+	It("should restart the canaries first", func() {
+		build()
+		startCanary()
+
+		canary := cr.Status.Canary
+		Expect(canary.Phase).To(Equal(configv1.CanaryPhaseBaking))
+		Expect(canary.Cause).To(Equal("ConfigMap default/app changed"))
+		Expect(canary.Targets).To(Equal([]configv1.CanaryTarget{{Kind: "Deployment", Name: "api"}}))
+		Expect(canary.Resources).To(ConsistOf(configv1.ChangedResource{
+			Kind: "ConfigMap", Name: "app", Namespace: "default", PreviousVersion: "1", Version: "2",
+		}))
+		Expect(canary.Message).To(ContainSubstring("Restarted 1 of 3 targets"))
+		Expect(condition().Reason).To(Equal("Baking"))
+
+		var web appsv1.Deployment
+		Expect(reconciler.Get(ctx, client.ObjectKey{Namespace: "default", Name: "web"}, &web)).To(Succeed())
+		Expect(web.Spec.Template.Annotations).To(BeEmpty())
+	})
+
+	It("should restart the rest once the canaries stayed healthy for the bake time", func() {
+		build()
+		start := startCanary()
+		replace(pod("api-2", "Deployment", "api", start.Add(time.Second)))
+
+		result, err := reconciler.progressCanary(ctx, cr, changes, start.Add(time.Minute))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result).To(BeNil())
+		Expect(canaryBaking(cr)).To(BeTrue())
+
+		result, err = reconciler.progressCanary(ctx, cr, changes, start.Add(5*time.Minute))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(restartedWorkloads(result)).To(Equal([]string{"web", "worker"}))
+		Expect(cr.Status.Canary.Phase).To(Equal(configv1.CanaryPhasePromoted))
+		Expect(cr.Status.Canary.CompletionTime).NotTo(BeNil())
+		Expect(condition().Reason).To(Equal("Promoted"))
+	})
+
+	It("should abort when a canary pod keeps restarting", func() {
+		cr.Spec.Canary.MaxContainerRestarts = 1
+		build()
+		start := startCanary()
+		crashing := pod("api-2", "Deployment", "api", start.Add(time.Second))
+		crashing.Status.ContainerStatuses[0].RestartCount = 2
+		replace(crashing)
+
+		result, err := reconciler.progressCanary(ctx, cr, changes, start.Add(time.Minute))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result).To(BeNil())
+		Expect(cr.Status.Canary.Phase).To(Equal(configv1.CanaryPhaseAborted))
+		Expect(cr.Status.Canary.Message).To(ContainSubstring("container app of pod api-2 restarted 2 times"))
+		Expect(meta.IsStatusConditionFalse(cr.Status.Conditions, canaryCondition)).To(BeTrue())
+
+		var events configv1.ReloadEventList
+		Expect(reconciler.List(ctx, &events)).To(Succeed())
+		Expect(events.Items).To(HaveLen(1))
+		Expect(events.Items[0].Status.Outcome).To(Equal(configv1.ReloadFailed))
+
+		var web appsv1.Deployment
+		Expect(reconciler.Get(ctx, client.ObjectKey{Namespace: "default", Name: "web"}, &web)).To(Succeed())
+		Expect(web.Spec.Template.Annotations).To(BeEmpty())
+	})
+
+	It("should abort when the canaries are not ready after the bake time", func() {
+		build()
+		start := startCanary()
+		unready := pod("api-2", "Deployment", "api", start.Add(time.Second))
+		unready.Status.Conditions[0].Status = corev1.ConditionFalse
+		replace(unready)
+
+		_, err := reconciler.progressCanary(ctx, cr, changes, start.Add(time.Minute))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(canaryBaking(cr)).To(BeTrue())
+		Expect(condition().Message).To(ContainSubstring("waiting for pod api-2 to be ready"))
+
+		_, err = reconciler.progressCanary(ctx, cr, changes, start.Add(5*time.Minute))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cr.Status.Canary.Phase).To(Equal(configv1.CanaryPhaseAborted))
+		Expect(cr.Status.Canary.Message).To(ContainSubstring("not ready after 5m0s: pod api-2 to be ready"))
+	})
+
+	It("should abort when the rollout of a canary fails", func() {
+		build()
+		start := startCanary()
+
+		var api appsv1.Deployment
+		Expect(reconciler.Get(ctx, client.ObjectKey{Namespace: "default", Name: "api"}, &api)).To(Succeed())
+		api.Status.Conditions = []appsv1.DeploymentCondition{{
+			Type: appsv1.DeploymentProgressing, Status: corev1.ConditionFalse, Reason: "ProgressDeadlineExceeded",
+		}}
+		Expect(reconciler.Status().Update(ctx, &api)).To(Succeed())
+
+		_, err := reconciler.progressCanary(ctx, cr, changes, start.Add(time.Minute))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cr.Status.Canary.Phase).To(Equal(configv1.CanaryPhaseAborted))
+		Expect(cr.Status.Canary.Message).To(ContainSubstring("rollout of Deployment api failed"))
+	})
+
+	Context("with an analysis", func() {
+		var (
+			response string
+			status   int
+			queries  int
+			address  string
+		)
+
+		BeforeEach(func() {
+			status, queries = http.StatusOK, 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				queries++
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(status)
+				_, _ = w.Write([]byte(response))
+			}))
+			DeferCleanup(server.Close)
+			address = server.URL
+			cr.Spec.Canary.Analysis = &configv1.CanaryAnalysis{
+				Address: server.URL,
+				Query:   `sum(rate(http_requests_total{code=~"5.."}[1m])) > 0`,
+			}
+		})
+
+		buildWithQuerier := func() {
+			build()
+			reconciler.MetricsQuerier = promquery.NewClient(time.Second)
+			reconciler.AnalysisAddresses = []string{address + "/"}
+		}
+
+		It("should promote the canaries when the query returns no samples", func() {
+			response = `{"status":"success","data":{"resultType":"vector","result":[]}}`
+			buildWithQuerier()
+			start := startCanary()
+
+			result, err := reconciler.progressCanary(ctx, cr, changes, start.Add(5*time.Minute))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(restartedWorkloads(result)).To(Equal([]string{"web", "worker"}))
+		})
+
+		It("should abort when the query returns a non-zero sample", func() {
+			response = `{"status":"success","data":{"resultType":"vector","result":[{"metric":{},"value":[1700000000,"0.2"]}]}}`
+			buildWithQuerier()
+			start := startCanary()
+
+			result, err := reconciler.progressCanary(ctx, cr, changes, start.Add(time.Minute))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result).To(BeNil())
+			Expect(cr.Status.Canary.Phase).To(Equal(configv1.CanaryPhaseAborted))
+			Expect(cr.Status.Canary.Message).To(ContainSubstring("analysis query returned 0.2"))
+		})
+
+		It("should keep the canaries pending while the query fails", func() {
+			response, status = `{"status":"error","error":"unavailable"}`, http.StatusServiceUnavailable
+			buildWithQuerier()
+			start := startCanary()
+
+			result, err := reconciler.progressCanary(ctx, cr, changes, start.Add(time.Minute))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result).To(BeNil())
+			Expect(cr.Status.Canary.Phase).NotTo(Equal(configv1.CanaryPhaseAborted))
+			Expect(condition().Message).To(ContainSubstring("waiting for analysis query to succeed"))
+
+			By("aborting when the query still fails at the end of the bake time")
+			_, err = reconciler.progressCanary(ctx, cr, changes, start.Add(5*time.Minute))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(cr.Status.Canary.Phase).To(Equal(configv1.CanaryPhaseAborted))
+			Expect(cr.Status.Canary.Message).To(ContainSubstring("analysis query to succeed"))
+		})
+
+		It("should abort without querying an address the operator does not allow", func() {
+			response = `{"status":"success","data":{"resultType":"vector","result":[]}}`
+			buildWithQuerier()
+			reconciler.AnalysisAddresses = []string{"http://prometheus.monitoring:9090"}
+			start := startCanary()
+
+			result, err := reconciler.progressCanary(ctx, cr, changes, start.Add(time.Minute))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result).To(BeNil())
+			Expect(queries).To(BeZero())
+			Expect(cr.Status.Canary.Phase).To(Equal(configv1.CanaryPhaseAborted))
+			Expect(cr.Status.Canary.Message).To(ContainSubstring("is not allowed by the operator"))
+		})
+	})
+
+	It("should delete a fraction of the pods of a single workload with the annotation policy", func() {
+		cr.Spec.Canary.Percent = 25
+		created := time.Now().Add(-time.Hour)
+		objects = []client.Object{
+			deployment("web", 4),
+			pod("web-a", "Deployment", "web", created),
+			pod("web-b", "Deployment", "web", created),
+			pod("web-c", "Deployment", "web", created),
+			pod("web-d", "Deployment", "web", created),
+		}
+		build()
+
+		result, err := reconciler.restartAffectedPods(ctx, cr, changes, "test")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.Restarted).To(HaveLen(1))
+		Expect(cr.Status.Canary.Targets).To(Equal([]configv1.CanaryTarget{{Kind: "Pod", Name: "web-a"}}))
+		err = reconciler.Get(ctx, client.ObjectKey{Namespace: "default", Name: "web-a"}, &corev1.Pod{})
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+		var web appsv1.Deployment
+		Expect(reconciler.Get(ctx, client.ObjectKey{Namespace: "default", Name: "web"}, &web)).To(Succeed())
+		Expect(web.Spec.Template.Annotations).To(BeEmpty())
+
+		By("checking the pod replacing the canary")
+		start := cr.Status.Canary.StartTime.Time
+		unready := pod("web-e", "Deployment", "web", start.Add(time.Second))
+		unready.Status.Conditions[0].Status = corev1.ConditionFalse
+		replace(unready)
+		_, err = reconciler.progressCanary(ctx, cr, changes, start.Add(time.Minute))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(condition().Message).To(ContainSubstring("waiting for pod web-e to be ready"))
+
+		By("rolling the workload once the canary is promoted")
+		unready.Status.Conditions[0].Status = corev1.ConditionTrue
+		Expect(reconciler.Status().Update(ctx, unready)).To(Succeed())
+		result, err = reconciler.progressCanary(ctx, cr, changes, start.Add(5*time.Minute))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(restartedWorkloads(result)).To(ContainElement("web"))
+		Expect(reconciler.Get(ctx, client.ObjectKey{Namespace: "default", Name: "web"}, &web)).To(Succeed())
+		Expect(web.Spec.Template.Annotations).NotTo(BeEmpty())
+	})
+
+	It("should roll a single workload whose pods cannot be split", func() {
+		created := time.Now().Add(-time.Hour)
+		objects = []client.Object{deployment("web", 1), pod("web-a", "Deployment", "web", created)}
+		build()
+
+		_, err := reconciler.restartAffectedPods(ctx, cr, changes, "test")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cr.Status.Canary.Targets).To(Equal([]configv1.CanaryTarget{{Kind: "Deployment", Name: "web"}}))
+	})
+
+	It("should restart a fraction of the pods with the delete policy", func() {
+		cr.Spec.RestartPolicy = configv1.RestartPolicyDelete
+		cr.Spec.Canary.Percent = 50
+		created := time.Now().Add(-time.Hour)
+		objects = []client.Object{
+			deployment("web", 4),
+			pod("web-a", "Deployment", "web", created),
+			pod("web-b", "Deployment", "web", created),
+			pod("web-c", "Deployment", "web", created),
+			pod("web-d", "Deployment", "web", created),
+		}
+		build()
+
+		result, err := reconciler.restartAffectedPods(ctx, cr, changes, "test")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.Restarted).To(HaveLen(2))
+		Expect(cr.Status.Canary.Targets).To(Equal([]configv1.CanaryTarget{
+			{Kind: "Pod", Name: "web-a"}, {Kind: "Pod", Name: "web-b"},
+		}))
+		start := cr.Status.Canary.StartTime.Time
+		replace(pod("web-e", "Deployment", "web", start.Add(time.Second)))
+		replace(pod("web-f", "Deployment", "web", start.Add(time.Second)))
+
+		result, err = reconciler.progressCanary(ctx, cr, changes, start.Add(5*time.Minute))
+		Expect(err).NotTo(HaveOccurred())
+		pods := make([]string, 0, len(result.Restarted))
+		for _, restart := range result.Restarted {
+			pods = append(pods, restart.PodName)
+		}
+		Expect(pods).To(Equal([]string{"web-c", "web-d"}))
+	})
+})
@@ -0,0 +1,58 @@
+package controller
+
+import (
+	"slices"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	configv1 "github.com/shehbazk/config-reloader-operator/api/v1"
+	"github.com/shehbazk/config-reloader-operator/internal/revision"
+)
+
+// changeAttribution returns the field manager that last wrote the data of a
+// watched resource, nil when the resource has no managed fields
+func changeAttribution(kind string, obj metav1.Object) *configv1.ChangeAttribution {
+	entry := revision.LastDataWrite(kind, obj)
+	if entry == nil {
+		return nil
+	}
+	return &configv1.ChangeAttribution{
+		Manager:   entry.Manager,
+		Operation: string(entry.Operation),
+		Time:      entry.Time.DeepCopy(),
+	}
+}
+
+// isIgnoredChange reports whether a change was written by one of the field
+// managers ignored by the ConfigReloader
+func isIgnoredChange(cr *configv1.ConfigReloader, change resourceChange) bool {
+	return change.ChangedBy != nil && slices.Contains(cr.Spec.IgnoredFieldManagers, change.ChangedBy.Manager)
+}
+
+// filterIgnoredChanges returns the changes that trigger a reload and emits a
+// ChangeIgnored Event for the ones written by an ignored field manager or
+// leaving the selected keys untouched
+func (r *ConfigReloaderReconciler) filterIgnoredChanges(
+	cr *configv1.ConfigReloader,
+	changes []resourceChange,
+) []resourceChange {
+	var triggering []resourceChange
+	for _, change := range changes {
+		switch {
+		case isIgnoredChange(cr, change):
+			r.recordEventf(cr, corev1.EventTypeNormal, EventReasonChangeIgnored,
+				"%s %s/%s changed by ignored field manager %s (resourceVersion %s -> %s)",
+				change.Kind, change.Namespace, change.Name, change.ChangedBy.Manager,
+				versionOrNone(change.OldVersion), change.NewVersion)
+		case change.KeysUnchanged:
+			r.recordEventf(cr, corev1.EventTypeNormal, EventReasonChangeIgnored,
+				"%s %s/%s changed without changing the selected keys (resourceVersion %s -> %s)",
+				change.Kind, change.Namespace, change.Name,
+				versionOrNone(change.OldVersion), change.NewVersion)
+		default:
+			triggering = append(triggering, change)
+		}
+	}
+	return triggering
+}
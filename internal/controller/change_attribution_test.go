@@ -0,0 +1,84 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+
+	configv1 "github.com/shehbazk/config-reloader-operator/api/v1"
+)
+
+var _ = Describe("Change attribution", func() {
+	It("should attribute a change to the last writer of the data", func() {
+		written := metav1.NewTime(time.Now().Add(-time.Minute))
+		cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{ManagedFields: []metav1.ManagedFieldsEntry{
+			{
+				Manager:   "kubectl-edit",
+				Operation: metav1.ManagedFieldsOperationUpdate,
+				Time:      &written,
+				FieldsV1:  &metav1.FieldsV1{Raw: []byte(`{"f:data":{"f:key":{}}}`)},
+			},
+			{
+				Manager:   "labeler",
+				Operation: metav1.ManagedFieldsOperationApply,
+				Time:      &metav1.Time{Time: time.Now()},
+				FieldsV1:  &metav1.FieldsV1{Raw: []byte(`{"f:metadata":{"f:labels":{}}}`)},
+			},
+		}}}
+
+		Expect(changeAttribution("ConfigMap", cm)).To(Equal(&configv1.ChangeAttribution{
+			Manager:   "kubectl-edit",
+			Operation: "Update",
+			Time:      &written,
+		}))
+		Expect(changeAttribution("ConfigMap", &corev1.ConfigMap{})).To(BeNil())
+	})
+
+	It("should name the field manager in change descriptions", func() {
+		changes := []resourceChange{
+			{Kind: "ConfigMap", Name: "app", Namespace: "default", ChangedBy: &configv1.ChangeAttribution{Manager: "helm"}},
+			{Kind: "Secret", Name: "creds", Namespace: "default"},
+		}
+		Expect(describeChanges(changes)).To(Equal("ConfigMap default/app changed by helm, Secret default/creds changed"))
+	})
+
+	It("should drop changes made by ignored field managers", func() {
+		recorder := record.NewFakeRecorder(10)
+		reconciler := &ConfigReloaderReconciler{Recorder: recorder}
+		cr := &configv1.ConfigReloader{Spec: configv1.ConfigReloaderSpec{IgnoredFieldManagers: []string{"argocd-controller"}}}
+
+		byArgo := resourceChange{Kind: "ConfigMap", Name: "synced", Namespace: "default", NewVersion: "2",
+			ChangedBy: &configv1.ChangeAttribution{Manager: "argocd-controller"}}
+		byHuman := resourceChange{Kind: "ConfigMap", Name: "edited", Namespace: "default", NewVersion: "3",
+			ChangedBy: &configv1.ChangeAttribution{Manager: "kubectl-edit"}}
+		unknown := resourceChange{Kind: "Secret", Name: "creds", Namespace: "default", NewVersion: "4"}
+
+		Expect(reconciler.filterIgnoredChanges(cr, []resourceChange{byArgo, byHuman, unknown})).
+			To(Equal([]resourceChange{byHuman, unknown}))
+		Expect(recorder.Events).To(Receive(And(
+			ContainSubstring(EventReasonChangeIgnored),
+			ContainSubstring("ConfigMap default/synced changed by ignored field manager argocd-controller"),
+		)))
+		Expect(recorder.Events).NotTo(Receive())
+	})
+})
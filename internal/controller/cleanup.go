@@ -0,0 +1,284 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	configv1 "github.com/shehbazk/config-reloader-operator/api/v1"
+)
+
+const (
+	// restartAnnotationPrefix prefixes the pod template annotations added to
+	// workloads by annotation restarts
+	restartAnnotationPrefix = "config.dev/restarted-at-"
+
+	// cleanupTimeout is how long the cleanup of a deleted ConfigReloader is
+	// retried before its finalizer is removed anyway
+	cleanupTimeout = 5 * time.Minute
+	// cleanupRetryInterval is how often a failed cleanup is retried
+	cleanupRetryInterval = 10 * time.Second
+)
+
+// +kubebuilder:rbac:groups=config.dev,resources=reloadevents;configrevisions,verbs=deletecollection
+
+// handleDeletion cleans up after a deleted ConfigReloader according to its
+// deletion policy, then removes its finalizer. Progress is reported in
+// status.cleanup and the finalizer is removed once cleanupTimeout has passed
+// even if some targets could not be cleaned up.
+func (r *ConfigReloaderReconciler) handleDeletion(
+	ctx context.Context,
+	cr *configv1.ConfigReloader,
+) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+	if !controllerutil.ContainsFinalizer(cr, ConfigReloaderFinalizer) {
+		return ctrl.Result{}, nil
+	}
+
+	if cr.Status.Cleanup == nil {
+		logger.Info("Handling ConfigReloader deletion", "deletionPolicy", deletionPolicy(cr))
+		// The plan is recorded before anything is changed so that a final
+		// reload is not repeated if the cleanup is retried
+		cr.Status.Cleanup = r.planCleanup(ctx, cr)
+		r.updateCondition(cr, "Ready", metav1.ConditionFalse, "CleanupInProgress", "ConfigReloader is being deleted")
+		if err := r.Status().Update(ctx, cr); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	r.runCleanup(ctx, cr)
+
+	cleanup := cr.Status.Cleanup
+	switch {
+	case len(cleanup.Pending) == 0 && cleanup.HistoryDeleted:
+		logger.Info("Cleaned up deleted ConfigReloader", "cleaned", cleanup.Cleaned)
+		r.recordEventf(cr, corev1.EventTypeNormal, EventReasonCleanupCompleted,
+			"Cleaned up %d targets", cleanup.Cleaned)
+	case time.Since(cr.DeletionTimestamp.Time) >= cleanupTimeout:
+		logger.Info("Giving up cleanup of deleted ConfigReloader", "pending", len(cleanup.Pending),
+			"lastError", cleanup.LastError)
+		r.recordEventf(cr, corev1.EventTypeWarning, EventReasonCleanupFailed,
+			"Gave up cleaning up %d targets after %s: %s", len(cleanup.Pending), cleanupTimeout, cleanup.LastError)
+	default:
+		r.updateCondition(cr, "Ready", metav1.ConditionFalse, "CleanupInProgress",
+			fmt.Sprintf("%d targets left to clean up: %s", len(cleanup.Pending), cleanup.LastError))
+		if err := r.Status().Update(ctx, cr); err != nil {
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{RequeueAfter: cleanupRetryInterval}, nil
+	}
+
+	deleteStatusMetrics(cr)
+	r.rollouts.forget(cr)
+	controllerutil.RemoveFinalizer(cr, ConfigReloaderFinalizer)
+	return ctrl.Result{}, r.Update(ctx, cr)
+}
+
+// deletionPolicy returns the deletion policy of the ConfigReloader,
+// defaulting to Retain for objects created before the field existed
+func deletionPolicy(cr *configv1.ConfigReloader) configv1.DeletionPolicy {
+	if cr.Spec.DeletionPolicy == "" {
+		return configv1.DeletionPolicyRetain
+	}
+	return cr.Spec.DeletionPolicy
+}
+
+// planCleanup lists the targets to clean up: the workloads and standalone
+// pods restarted so far, and with the Reload policy the targets of a final
+// reload
+func (r *ConfigReloaderReconciler) planCleanup(ctx context.Context, cr *configv1.ConfigReloader) *configv1.CleanupStatus {
+	cleanup := &configv1.CleanupStatus{}
+	policy := deletionPolicy(cr)
+	if policy == configv1.DeletionPolicyRetain {
+		return cleanup
+	}
+
+	index := make(map[string]int)
+	add := func(kind, name, namespace string, reload bool) {
+		key := failedTargetKey(namespace, kind, name)
+		if i, ok := index[key]; ok {
+			cleanup.Pending[i].Reload = cleanup.Pending[i].Reload || reload
+			return
+		}
+		index[key] = len(cleanup.Pending)
+		cleanup.Pending = append(cleanup.Pending, configv1.CleanupTarget{
+			Kind:      kind,
+			Name:      name,
+			Namespace: namespace,
+			Reload:    reload,
+		})
+	}
+
+	for _, target := range cr.Status.Targets {
+		add(target.Kind, target.Name, target.Namespace, false)
+	}
+
+	if policy != configv1.DeletionPolicyReload || cr.Spec.Suspend || r.isDryRun(cr) {
+		return cleanup
+	}
+	// A final reload would apply the changes held for approval without an
+	// approval, so they are discarded with the ConfigReloader
+	if pending := cr.Status.PendingApproval; pending != nil {
+		log.FromContext(ctx).Info("Skipping final reload of changes held for approval", "id", pending.ID)
+		r.recordEventf(cr, corev1.EventTypeWarning, EventReasonApprovalDiscarded,
+			"Reload %s is discarded and no final reload is performed: the ConfigReloader was deleted before "+
+				"it was approved: %s", pending.ID, pending.Cause)
+		return cleanup
+	}
+	targets, _, err := r.planRestarts(ctx, cr)
+	if err != nil {
+		log.FromContext(ctx).Error(err, "failed to plan final reload")
+		cleanup.LastError = fmt.Sprintf("failed to plan final reload: %v", err)
+		return cleanup
+	}
+	for _, target := range targets {
+		switch {
+		case target.Policy == configv1.RestartPolicyDelete:
+			add("Pod", target.Pod.Name, target.Pod.Namespace, true)
+		case target.WorkloadKind != "":
+			add(target.WorkloadKind, target.WorkloadName, target.Pod.Namespace, true)
+		}
+		// Standalone pods cannot be restarted with the annotation policy
+	}
+	return cleanup
+}
+
+// runCleanup cleans up the pending targets and deletes the history of the
+// ConfigReloader, recording the progress in status.cleanup
+func (r *ConfigReloaderReconciler) runCleanup(ctx context.Context, cr *configv1.ConfigReloader) {
+	logger := log.FromContext(ctx)
+	cleanup := cr.Status.Cleanup
+	cleanup.LastError = ""
+
+	pending := cleanup.Pending[:0]
+	for _, target := range cleanup.Pending {
+		if err := r.cleanTarget(ctx, cr, target); err != nil {
+			logger.Error(err, "failed to clean up target", "kind", target.Kind, "name", target.Name,
+				"namespace", target.Namespace)
+			cleanup.LastError = err.Error()
+			pending = append(pending, target)
+			continue
+		}
+		cleanup.Cleaned++
+	}
+	cleanup.Pending = pending
+	if len(cleanup.Pending) == 0 {
+		cleanup.Pending = nil
+	}
+
+	if !cleanup.HistoryDeleted {
+		if err := r.deleteHistory(ctx, cr); err != nil {
+			logger.Error(err, "failed to delete history")
+			cleanup.LastError = err.Error()
+			return
+		}
+		cleanup.HistoryDeleted = true
+	}
+}
+
+// cleanTarget removes the annotations added by the operator from a target,
+// restarting it a last time when requested
+func (r *ConfigReloaderReconciler) cleanTarget(
+	ctx context.Context,
+	cr *configv1.ConfigReloader,
+	target configv1.CleanupTarget,
+) error {
+	key := types.NamespacedName{Namespace: target.Namespace, Name: target.Name}
+
+	if target.Kind == "Pod" {
+		var pod corev1.Pod
+		if err := r.Get(ctx, key, &pod); err != nil {
+			if apierrors.IsNotFound(err) {
+				return nil
+			}
+			return fmt.Errorf("failed to get Pod %s/%s: %w", target.Namespace, target.Name, err)
+		}
+		if target.Reload {
+			if err := r.Delete(ctx, &pod); client.IgnoreNotFound(err) != nil {
+				return fmt.Errorf("failed to delete Pod %s/%s: %w", target.Namespace, target.Name, err)
+			}
+			r.recordEventf(&pod, corev1.EventTypeNormal, EventReasonRestartTriggered,
+				"Pod deleted by ConfigReloader %s/%s: final reload on deletion", cr.Namespace, cr.Name)
+			return nil
+		}
+		if _, ok := pod.Annotations[ReloadAnnotation]; !ok {
+			return nil
+		}
+		delete(pod.Annotations, ReloadAnnotation)
+		if err := r.Update(ctx, &pod); client.IgnoreNotFound(err) != nil {
+			return fmt.Errorf("failed to update Pod %s/%s: %w", target.Namespace, target.Name, err)
+		}
+		return nil
+	}
+
+	workload, template := newWorkload(target.Kind)
+	if workload == nil {
+		// Nothing is added to other kinds
+		return nil
+	}
+	if err := r.Get(ctx, key, workload); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to get %s %s/%s: %w", target.Kind, target.Namespace, target.Name, err)
+	}
+
+	// The final reload uses an annotation keyed by the deletion time, so a
+	// retried cleanup does not restart the workload again
+	var keep string
+	if target.Reload {
+		keep = fmt.Sprintf("%s%d", restartAnnotationPrefix, cr.DeletionTimestamp.Unix())
+	}
+	changed := false
+	for name := range template.Annotations {
+		if strings.HasPrefix(name, restartAnnotationPrefix) && name != keep {
+			delete(template.Annotations, name)
+			changed = true
+		}
+	}
+	if keep != "" && template.Annotations[keep] == "" {
+		if template.Annotations == nil {
+			template.Annotations = make(map[string]string)
+		}
+		template.Annotations[keep] = cr.DeletionTimestamp.UTC().Format("2006-01-02T15:04:05Z")
+		changed = true
+	}
+	if !changed {
+		return nil
+	}
+
+	if err := r.Update(ctx, workload); client.IgnoreNotFound(err) != nil {
+		return fmt.Errorf("failed to update %s %s/%s: %w", target.Kind, target.Namespace, target.Name, err)
+	}
+	if target.Reload {
+		r.recordEventf(workload, corev1.EventTypeNormal, EventReasonRestartTriggered,
+			"Rolling restart triggered by ConfigReloader %s/%s: final reload on deletion", cr.Namespace, cr.Name)
+	}
+	return nil
+}
+
+// deleteHistory deletes the ReloadEvents and ConfigRevisions of the
+// ConfigReloader without waiting for the garbage collector
+func (r *ConfigReloaderReconciler) deleteHistory(ctx context.Context, cr *configv1.ConfigReloader) error {
+	opts := []client.DeleteAllOfOption{
+		client.InNamespace(cr.Namespace),
+		historyLabels(cr),
+	}
+	if err := r.DeleteAllOf(ctx, &configv1.ReloadEvent{}, opts...); err != nil {
+		return fmt.Errorf("failed to delete ReloadEvents of %s/%s: %w", cr.Namespace, cr.Name, err)
+	}
+	if err := r.DeleteAllOf(ctx, &configv1.ConfigRevision{}, opts...); err != nil {
+		return fmt.Errorf("failed to delete ConfigRevisions of %s/%s: %w", cr.Namespace, cr.Name, err)
+	}
+	return nil
+}
@@ -0,0 +1,296 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	configv1 "github.com/shehbazk/config-reloader-operator/api/v1"
+)
+
+var _ = Describe("Deletion cleanup", func() {
+	var (
+		ctx          context.Context
+		scheme       *runtime.Scheme
+		cr           *configv1.ConfigReloader
+		deployment   *appsv1.Deployment
+		standalone   *corev1.Pod
+		interceptors interceptor.Funcs
+		reconciler   *ConfigReloaderReconciler
+	)
+
+	// build creates the reconciler once the spec adjusted the objects
+	build := func() {
+		owned := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            "web-1",
+				Namespace:       "default",
+				OwnerReferences: []metav1.OwnerReference{{APIVersion: "apps/v1", Kind: "Deployment", Name: "web"}},
+			},
+			Spec: corev1.PodSpec{
+				Containers: []corev1.Container{{Name: "app"}},
+				Volumes: []corev1.Volume{{Name: "config", VolumeSource: corev1.VolumeSource{
+					ConfigMap: &corev1.ConfigMapVolumeSource{LocalObjectReference: corev1.LocalObjectReference{Name: "app"}},
+				}}},
+			},
+		}
+		event := &configv1.ReloadEvent{ObjectMeta: metav1.ObjectMeta{
+			Name: "cleanup-event", Namespace: "default", Labels: historyLabels(cr),
+		}}
+		revision := &configv1.ConfigRevision{ObjectMeta: metav1.ObjectMeta{
+			Name: "cleanup-revision", Namespace: "default", Labels: historyLabels(cr),
+		}}
+		c := fake.NewClientBuilder().WithScheme(scheme).
+			WithObjects(cr, deployment, standalone, owned, event, revision).
+			WithStatusSubresource(&configv1.ConfigReloader{}).
+			WithInterceptorFuncs(interceptors).
+			Build()
+		reconciler = &ConfigReloaderReconciler{Client: c, Scheme: scheme}
+	}
+
+	// handleDeletion runs the deletion handler on the stored ConfigReloader
+	handleDeletion := func() time.Duration {
+		var current configv1.ConfigReloader
+		Expect(reconciler.Get(ctx, client.ObjectKeyFromObject(cr), &current)).To(Succeed())
+		result, err := reconciler.handleDeletion(ctx, &current)
+		Expect(err).NotTo(HaveOccurred())
+		return result.RequeueAfter
+	}
+
+	templateAnnotations := func() map[string]string {
+		var current appsv1.Deployment
+		Expect(reconciler.Get(ctx, client.ObjectKeyFromObject(deployment), &current)).To(Succeed())
+		return current.Spec.Template.Annotations
+	}
+
+	historyLeft := func() int {
+		var events configv1.ReloadEventList
+		Expect(reconciler.List(ctx, &events, historyLabels(cr))).To(Succeed())
+		var revisions configv1.ConfigRevisionList
+		Expect(reconciler.List(ctx, &revisions, historyLabels(cr))).To(Succeed())
+		return len(events.Items) + len(revisions.Items)
+	}
+
+	deleted := func() bool {
+		err := reconciler.Get(ctx, client.ObjectKeyFromObject(cr), &configv1.ConfigReloader{})
+		return apierrors.IsNotFound(err)
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		scheme = runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+		Expect(configv1.AddToScheme(scheme)).To(Succeed())
+
+		cr = &configv1.ConfigReloader{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              "cleanup",
+				Namespace:         "default",
+				Finalizers:        []string{ConfigReloaderFinalizer},
+				DeletionTimestamp: &metav1.Time{Time: time.Now().Truncate(time.Second)},
+			},
+			Spec: configv1.ConfigReloaderSpec{
+				ConfigMaps:     []configv1.ResourceRef{{Name: "app"}},
+				RestartPolicy:  configv1.RestartPolicyAnnotation,
+				DeletionPolicy: configv1.DeletionPolicyCleanup,
+			},
+			Status: configv1.ConfigReloaderStatus{
+				Targets: []configv1.TargetStatus{
+					{Kind: "Deployment", Name: "web", Namespace: "default"},
+					{Kind: "Pod", Name: "standalone", Namespace: "default"},
+					{Kind: "Deployment", Name: "gone", Namespace: "default"},
+				},
+			},
+		}
+		deployment = &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+			Spec: appsv1.DeploymentSpec{
+				Template: corev1.PodTemplateSpec{
+					ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{
+						restartAnnotationPrefix + "1700000000": "2023-11-14T22:13:20Z",
+						restartAnnotationPrefix + "1700000100": "2023-11-14T22:15:00Z",
+						"example.com/owner":                    "team-a",
+					}},
+				},
+			},
+		}
+		standalone = &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "standalone",
+				Namespace:   "default",
+				Annotations: map[string]string{ReloadAnnotation: "2023-11-14T22:15:00Z"},
+			},
+		}
+		interceptors = interceptor.Funcs{}
+	})
+
+	It("should remove the annotations added by the operator and the history", func() {
+		build()
+		Expect(handleDeletion()).To(BeZero())
+
+		Expect(templateAnnotations()).To(Equal(map[string]string{"example.com/owner": "team-a"}))
+		var pod corev1.Pod
+		Expect(reconciler.Get(ctx, client.ObjectKeyFromObject(standalone), &pod)).To(Succeed())
+		Expect(pod.Annotations).NotTo(HaveKey(ReloadAnnotation))
+		Expect(historyLeft()).To(BeZero())
+		Expect(deleted()).To(BeTrue())
+	})
+
+	It("should leave the workloads untouched with the Retain policy", func() {
+		cr.Spec.DeletionPolicy = configv1.DeletionPolicyRetain
+		build()
+		Expect(handleDeletion()).To(BeZero())
+
+		Expect(templateAnnotations()).To(HaveLen(3))
+		Expect(historyLeft()).To(BeZero())
+		Expect(deleted()).To(BeTrue())
+	})
+
+	It("should default to the Retain policy", func() {
+		cr.Spec.DeletionPolicy = ""
+		build()
+		Expect(handleDeletion()).To(BeZero())
+
+		Expect(templateAnnotations()).To(HaveLen(3))
+		var pod corev1.Pod
+		Expect(reconciler.Get(ctx, client.ObjectKeyFromObject(standalone), &pod)).To(Succeed())
+		Expect(pod.Annotations).To(HaveKey(ReloadAnnotation))
+		Expect(historyLeft()).To(BeZero())
+		Expect(deleted()).To(BeTrue())
+	})
+
+	It("should delete the history of a ConfigReloader with a long name", func() {
+		cr.Name = strings.Repeat("cleanup-", 9)
+		build()
+		Expect(historyLeft()).To(Equal(2))
+		Expect(handleDeletion()).To(BeZero())
+
+		Expect(historyLeft()).To(BeZero())
+		Expect(deleted()).To(BeTrue())
+	})
+
+	It("should restart the affected workloads once with the Reload policy", func() {
+		cr.Spec.DeletionPolicy = configv1.DeletionPolicyReload
+		build()
+		Expect(handleDeletion()).To(BeZero())
+
+		final := fmt.Sprintf("%s%d", restartAnnotationPrefix, cr.DeletionTimestamp.Unix())
+		Expect(templateAnnotations()).To(HaveKey(final))
+		Expect(templateAnnotations()).To(HaveLen(2))
+		Expect(deleted()).To(BeTrue())
+
+		By("not restarting again when the cleanup is retried")
+		var before appsv1.Deployment
+		Expect(reconciler.Get(ctx, client.ObjectKeyFromObject(deployment), &before)).To(Succeed())
+		Expect(reconciler.cleanTarget(ctx, cr, configv1.CleanupTarget{
+			Kind: "Deployment", Name: "web", Namespace: "default", Reload: true,
+		})).To(Succeed())
+		var after appsv1.Deployment
+		Expect(reconciler.Get(ctx, client.ObjectKeyFromObject(deployment), &after)).To(Succeed())
+		Expect(after.ResourceVersion).To(Equal(before.ResourceVersion))
+	})
+
+	It("should delete the affected pods once with the Reload and delete policies", func() {
+		cr.Spec.DeletionPolicy = configv1.DeletionPolicyReload
+		cr.Spec.RestartPolicy = configv1.RestartPolicyDelete
+		build()
+		Expect(handleDeletion()).To(BeZero())
+
+		err := reconciler.Get(ctx, client.ObjectKey{Namespace: "default", Name: "web-1"}, &corev1.Pod{})
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+	})
+
+	It("should not reload a suspended ConfigReloader", func() {
+		cr.Spec.DeletionPolicy = configv1.DeletionPolicyReload
+		cr.Spec.Suspend = true
+		build()
+		Expect(handleDeletion()).To(BeZero())
+
+		Expect(templateAnnotations()).To(Equal(map[string]string{"example.com/owner": "team-a"}))
+	})
+
+	It("should not reload changes held for approval", func() {
+		cr.Spec.DeletionPolicy = configv1.DeletionPolicyReload
+		cr.Spec.Approval = &configv1.ApprovalPolicy{}
+		cr.Status.PendingApproval = &configv1.PendingApproval{ID: "abcd1234", Cause: "ConfigMap default/app changed"}
+		build()
+		recorder := record.NewFakeRecorder(10)
+		reconciler.Recorder = recorder
+		Expect(handleDeletion()).To(BeZero())
+
+		Expect(templateAnnotations()).To(Equal(map[string]string{"example.com/owner": "team-a"}))
+		Expect(recorder.Events).To(Receive(HavePrefix("Warning ApprovalDiscarded Reload abcd1234 is discarded")))
+	})
+
+	rejectDeploymentUpdates := func(ctx context.Context, c client.WithWatch, obj client.Object,
+		opts ...client.UpdateOption) error {
+		if _, ok := obj.(*appsv1.Deployment); ok {
+			return errors.New("update rejected")
+		}
+		return c.Update(ctx, obj, opts...)
+	}
+
+	It("should report progress and retry failed targets", func() {
+		interceptors.Update = rejectDeploymentUpdates
+		build()
+		Expect(handleDeletion()).To(Equal(cleanupRetryInterval))
+
+		var current configv1.ConfigReloader
+		Expect(reconciler.Get(ctx, client.ObjectKeyFromObject(cr), &current)).To(Succeed())
+		Expect(current.Finalizers).To(ContainElement(ConfigReloaderFinalizer))
+		Expect(current.Status.Cleanup).NotTo(BeNil())
+		Expect(current.Status.Cleanup.Pending).To(ConsistOf(configv1.CleanupTarget{
+			Kind: "Deployment", Name: "web", Namespace: "default",
+		}))
+		Expect(current.Status.Cleanup.Cleaned).To(Equal(int32(2)))
+		Expect(current.Status.Cleanup.HistoryDeleted).To(BeTrue())
+		Expect(current.Status.Cleanup.LastError).To(ContainSubstring("update rejected"))
+
+		By("retrying only the pending targets")
+		Expect(handleDeletion()).To(Equal(cleanupRetryInterval))
+		Expect(reconciler.Get(ctx, client.ObjectKeyFromObject(cr), &current)).To(Succeed())
+		Expect(current.Status.Cleanup.Pending).To(HaveLen(1))
+		Expect(current.Status.Cleanup.Cleaned).To(Equal(int32(2)))
+	})
+
+	It("should remove the finalizer once the cleanup timed out", func() {
+		interceptors.Update = rejectDeploymentUpdates
+		cr.DeletionTimestamp = &metav1.Time{Time: time.Now().Add(-cleanupTimeout)}
+		build()
+		Expect(handleDeletion()).To(BeZero())
+
+		Expect(deleted()).To(BeTrue())
+		Expect(templateAnnotations()).To(HaveLen(3))
+	})
+})
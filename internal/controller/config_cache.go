@@ -0,0 +1,127 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ConfigCacheOptions bounds the memory used to cache ConfigMaps and Secrets.
+// Watched resources left out of the cache are read from the API server, and
+// their changes are only noticed by the periodic reconcile.
+type ConfigCacheOptions struct {
+	// LabelSelector restricts the cached ConfigMaps and Secrets
+	LabelSelector labels.Selector
+	// Namespaces restricts the cached ConfigMaps and Secrets, all namespaces
+	// when empty
+	Namespaces []string
+	// MetadataOnly caches only the metadata of ConfigMaps and Secrets. Their
+	// data is read from the API server when a restart needs it.
+	MetadataOnly bool
+}
+
+// ByObject returns the cache options for ConfigMaps and Secrets, nil when the
+// cache is not restricted
+func (o ConfigCacheOptions) ByObject() map[client.Object]cache.ByObject {
+	if !o.restricted() {
+		return nil
+	}
+
+	byObject := cache.ByObject{Label: o.LabelSelector}
+	if len(o.Namespaces) > 0 {
+		byObject.Namespaces = make(map[string]cache.Config, len(o.Namespaces))
+		for _, namespace := range o.Namespaces {
+			byObject.Namespaces[namespace] = cache.Config{}
+		}
+	}
+	// Options are keyed by GVK, so they also apply to metadata-only informers
+	return map[client.Object]cache.ByObject{
+		&corev1.ConfigMap{}: byObject,
+		&corev1.Secret{}:    byObject,
+	}
+}
+
+// restricted reports whether some ConfigMaps or Secrets are left out of the cache
+func (o ConfigCacheOptions) restricted() bool {
+	return (o.LabelSelector != nil && !o.LabelSelector.Empty()) || len(o.Namespaces) > 0
+}
+
+// cached reports whether the cache holds the namespace
+func (o ConfigCacheOptions) cached(namespace string) bool {
+	return len(o.Namespaces) == 0 || slices.Contains(o.Namespaces, namespace)
+}
+
+// newWatchedObject returns an empty ConfigMap or Secret
+func newWatchedObject(kind string) (client.Object, error) {
+	switch kind {
+	case "ConfigMap":
+		return &corev1.ConfigMap{}, nil
+	case "Secret":
+		return &corev1.Secret{}, nil
+	}
+	return nil, fmt.Errorf("unsupported kind %s", kind)
+}
+
+// getWatchedMeta returns the metadata of a watched ConfigMap or Secret, which
+// is all that is needed to detect and attribute changes
+func (r *ConfigReloaderReconciler) getWatchedMeta(
+	ctx context.Context,
+	kind string,
+	key types.NamespacedName,
+) (metav1.Object, error) {
+	if !r.ConfigCache.MetadataOnly {
+		return r.getWatchedObject(ctx, kind, key)
+	}
+
+	obj := &metav1.PartialObjectMetadata{}
+	obj.SetGroupVersionKind(corev1.SchemeGroupVersion.WithKind(kind))
+	return obj, r.getWatched(ctx, key, obj)
+}
+
+// getWatchedObject returns a watched ConfigMap or Secret with its data
+func (r *ConfigReloaderReconciler) getWatchedObject(
+	ctx context.Context,
+	kind string,
+	key types.NamespacedName,
+) (client.Object, error) {
+	obj, err := newWatchedObject(kind)
+	if err != nil {
+		return nil, err
+	}
+	if r.ConfigCache.MetadataOnly {
+		// Reading the full object through the cache would start an informer
+		// holding the data of every ConfigMap or Secret
+		return obj, r.apiReader().Get(ctx, key, obj)
+	}
+	return obj, r.getWatched(ctx, key, obj)
+}
+
+// getWatched reads obj from the cache, or from the API server when the cache
+// leaves it out
+func (r *ConfigReloaderReconciler) getWatched(ctx context.Context, key types.NamespacedName, obj client.Object) error {
+	if !r.ConfigCache.cached(key.Namespace) {
+		return r.apiReader().Get(ctx, key, obj)
+	}
+
+	err := r.Get(ctx, key, obj)
+	if apierrors.IsNotFound(err) && r.ConfigCache.restricted() {
+		return r.apiReader().Get(ctx, key, obj)
+	}
+	return err
+}
+
+// apiReader returns the reader used for uncached reads
+func (r *ConfigReloaderReconciler) apiReader() client.Reader {
+	if r.APIReader != nil {
+		return r.APIReader
+	}
+	return r.Client
+}
@@ -0,0 +1,171 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"strings"
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var _ = Describe("Config cache", func() {
+	var (
+		ctx        context.Context
+		cm         *corev1.ConfigMap
+		reconciler *ConfigReloaderReconciler
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		cm = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default", ResourceVersion: "7"},
+			Data:       map[string]string{"key": "value"},
+		}
+		// The cache does not hold the ConfigMap, only the API server does
+		reconciler = &ConfigReloaderReconciler{
+			Client:    fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).Build(),
+			APIReader: fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).WithObjects(cm).Build(),
+		}
+	})
+
+	It("should only restrict the cache when asked to", func() {
+		Expect(ConfigCacheOptions{}.ByObject()).To(BeNil())
+		Expect(ConfigCacheOptions{MetadataOnly: true}.ByObject()).To(BeNil())
+
+		selector := labels.SelectorFromSet(labels.Set{"config.dev/watched": "true"})
+		byObject := ConfigCacheOptions{LabelSelector: selector, Namespaces: []string{"a", "b"}}.ByObject()
+		Expect(byObject).To(HaveLen(2))
+		for _, options := range byObject {
+			Expect(options.Label).To(Equal(selector))
+			Expect(options.Namespaces).To(HaveKey("a"))
+			Expect(options.Namespaces).To(HaveKey("b"))
+		}
+	})
+
+	It("should read resources left out by a label selector from the API server", func() {
+		key := types.NamespacedName{Name: "app", Namespace: "default"}
+		_, err := reconciler.getWatchedObject(ctx, "ConfigMap", key)
+		Expect(err).To(HaveOccurred())
+
+		reconciler.ConfigCache.LabelSelector = labels.SelectorFromSet(labels.Set{"config.dev/watched": "true"})
+		obj, err := reconciler.getWatchedObject(ctx, "ConfigMap", key)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(obj.(*corev1.ConfigMap).Data).To(HaveKeyWithValue("key", "value"))
+	})
+
+	It("should read resources in namespaces left out of the cache from the API server", func() {
+		reconciler.ConfigCache.Namespaces = []string{"other"}
+		meta, err := reconciler.getWatchedMeta(ctx, "ConfigMap", types.NamespacedName{Name: "app", Namespace: "default"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(meta.GetResourceVersion()).To(Equal("7"))
+	})
+
+	It("should only read metadata from the cache in metadata-only mode", func() {
+		reconciler.ConfigCache.MetadataOnly = true
+		reconciler.Client = fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).WithObjects(cm.DeepCopy()).Build()
+		reconciler.APIReader = fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).WithObjects(cm.DeepCopy()).Build()
+		key := types.NamespacedName{Name: "app", Namespace: "default"}
+
+		meta, err := reconciler.getWatchedMeta(ctx, "ConfigMap", key)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(meta).To(BeAssignableToTypeOf(&metav1.PartialObjectMetadata{}))
+		Expect(watchedKind(meta.(*metav1.PartialObjectMetadata))).To(Equal("ConfigMap"))
+
+		// Data is read from the API server even when the cache could serve it
+		Expect(reconciler.Client.Delete(ctx, cm.DeepCopy())).To(Succeed())
+		obj, err := reconciler.getWatchedObject(ctx, "ConfigMap", key)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(obj.(*corev1.ConfigMap).Data).To(HaveKeyWithValue("key", "value"))
+	})
+})
+
+// BenchmarkConfigCacheStoreMemory reports the heap used per ConfigMap held in
+// the store backing an informer, when full objects or only their metadata are
+// stored. Each ConfigMap holds dataSize bytes of data, as many configuration
+// files do. The informer itself, its watch and its indexes are left out.
+func BenchmarkConfigCacheStoreMemory(b *testing.B) {
+	const count = 2000
+
+	newConfigMap := func(i, dataSize int) *corev1.ConfigMap {
+		return &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            fmt.Sprintf("config-%d", i),
+				Namespace:       "default",
+				ResourceVersion: fmt.Sprint(i),
+				Labels:          map[string]string{"app": fmt.Sprintf("app-%d", i)},
+				ManagedFields: []metav1.ManagedFieldsEntry{{
+					Manager:   "kubectl-client-side-apply",
+					Operation: metav1.ManagedFieldsOperationUpdate,
+					FieldsV1:  &metav1.FieldsV1{Raw: []byte(`{"f:data":{"f:app.conf":{}}}`)},
+				}},
+			},
+			Data: map[string]string{"app.conf": strings.Repeat("x", dataSize)},
+		}
+	}
+	metadataOf := func(cm *corev1.ConfigMap) *metav1.PartialObjectMetadata {
+		return &metav1.PartialObjectMetadata{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "ConfigMap"},
+			ObjectMeta: *cm.ObjectMeta.DeepCopy(),
+		}
+	}
+
+	for _, dataSize := range []int{1 << 10, 16 << 10} {
+		for _, metadataOnly := range []bool{false, true} {
+			name := "full"
+			if metadataOnly {
+				name = "metadata"
+			}
+			b.Run(fmt.Sprintf("%s/data=%dKiB", name, dataSize>>10), func(b *testing.B) {
+				var perObject float64
+				for b.Loop() {
+					var before, after runtime.MemStats
+					runtime.GC()
+					runtime.ReadMemStats(&before)
+
+					store := cache.NewStore(cache.MetaNamespaceKeyFunc)
+					for i := range count {
+						var obj interface{} = newConfigMap(i, dataSize)
+						if metadataOnly {
+							obj = metadataOf(obj.(*corev1.ConfigMap))
+						}
+						if err := store.Add(obj); err != nil {
+							b.Fatal(err)
+						}
+					}
+
+					runtime.GC()
+					runtime.ReadMemStats(&after)
+					perObject = float64(after.HeapAlloc-before.HeapAlloc) / count
+					runtime.KeepAlive(store)
+				}
+				b.ReportMetric(perObject, "B/object")
+			})
+		}
+	}
+}
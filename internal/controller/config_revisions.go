@@ -0,0 +1,148 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	configv1 "github.com/shehbazk/config-reloader-operator/api/v1"
+	"github.com/shehbazk/config-reloader-operator/internal/revision"
+)
+
+// revisionHistoryLimit returns the number of ConfigRevisions kept per watched
+// resource, 0 when revision history is disabled
+func revisionHistoryLimit(cr *configv1.ConfigReloader) int {
+	if cr.Spec.RevisionHistoryLimit == nil {
+		return 0
+	}
+	return int(*cr.Spec.RevisionHistoryLimit)
+}
+
+// recordConfigRevisions snapshots the changed resources as ConfigRevisions,
+// sets the keys that changed since the previous revision on the changes, and
+// prunes the revisions beyond the history limit. Failures are logged and do
+// not fail the reconcile.
+func (r *ConfigReloaderReconciler) recordConfigRevisions(
+	ctx context.Context,
+	cr *configv1.ConfigReloader,
+	changes []resourceChange,
+) {
+	limit := revisionHistoryLimit(cr)
+	if limit == 0 || len(changes) == 0 {
+		return
+	}
+	logger := log.FromContext(ctx)
+
+	var revisions configv1.ConfigRevisionList
+	if err := r.List(ctx, &revisions, client.InNamespace(cr.Namespace),
+		historyLabels(cr)); err != nil {
+		logger.Error(err, "failed to list ConfigRevisions")
+		return
+	}
+
+	for i := range changes {
+		change := &changes[i]
+		snapshot, err := r.snapshot(ctx, *change)
+		if err != nil {
+			logger.Error(err, "failed to snapshot watched resource", "kind", change.Kind, "name", change.Name)
+			continue
+		}
+
+		history := resourceRevisions(revisions.Items, change.Kind, change.Name, change.Namespace)
+		created, err := r.createConfigRevision(ctx, cr, snapshot, history)
+		if err != nil {
+			logger.Error(err, "failed to record ConfigRevision", "kind", change.Kind, "name", change.Name)
+			continue
+		}
+		keep := limit
+		if created != nil {
+			change.Keys = created.Spec.Changes
+			keep--
+		}
+		r.pruneConfigRevisions(ctx, history, keep)
+	}
+}
+
+// snapshot returns the current state of a changed resource
+func (r *ConfigReloaderReconciler) snapshot(ctx context.Context, change resourceChange) (configv1.ConfigRevisionSpec, error) {
+	obj, err := r.getWatchedObject(ctx, change.Kind, types.NamespacedName{Name: change.Name, Namespace: change.Namespace})
+	if err != nil {
+		return configv1.ConfigRevisionSpec{}, fmt.Errorf("failed to get %s %s/%s: %w", change.Kind, change.Namespace, change.Name, err)
+	}
+	switch obj := obj.(type) {
+	case *corev1.ConfigMap:
+		return revision.ForConfigMap(obj), nil
+	case *corev1.Secret:
+		return revision.ForSecret(obj), nil
+	}
+	return configv1.ConfigRevisionSpec{}, fmt.Errorf("unsupported kind %s", change.Kind)
+}
+
+// resourceRevisions returns the revisions of a resource, newest first
+func resourceRevisions(revisions []configv1.ConfigRevision, kind, name, namespace string) []configv1.ConfigRevision {
+	var history []configv1.ConfigRevision
+	for _, rev := range revisions {
+		resource := rev.Spec.Resource
+		if resource.Kind == kind && resource.Name == name && resource.Namespace == namespace {
+			history = append(history, rev)
+		}
+	}
+	sort.Slice(history, func(i, j int) bool {
+		return history[i].Spec.Revision > history[j].Spec.Revision
+	})
+	return history
+}
+
+// createConfigRevision records snapshot as the next revision of its resource
+// unless the latest revision already holds the same data, and returns the
+// created revision, if any
+func (r *ConfigReloaderReconciler) createConfigRevision(
+	ctx context.Context,
+	cr *configv1.ConfigReloader,
+	snapshot configv1.ConfigRevisionSpec,
+	history []configv1.ConfigRevision,
+) (*configv1.ConfigRevision, error) {
+	snapshot.ConfigReloader = cr.Name
+	snapshot.ObservedTime = metav1.Now()
+	snapshot.Revision = 1
+	if len(history) > 0 {
+		latest := &history[0].Spec
+		if latest.Resource.ResourceVersion == snapshot.Resource.ResourceVersion || latest.Hash == snapshot.Hash {
+			return nil, nil
+		}
+		snapshot.Revision = latest.Revision + 1
+		snapshot.Changes = revision.Changes(latest, &snapshot)
+	}
+
+	rev := &configv1.ConfigRevision{
+		ObjectMeta: historyObjectMeta(cr),
+		Spec:       snapshot,
+	}
+	if err := controllerutil.SetControllerReference(cr, rev, r.Scheme); err != nil {
+		return nil, fmt.Errorf("failed to set owner of ConfigRevision: %w", err)
+	}
+	if err := r.Create(ctx, rev); err != nil {
+		return nil, fmt.Errorf("failed to create ConfigRevision: %w", err)
+	}
+	log.FromContext(ctx).V(1).Info("Recorded ConfigRevision", "configRevision", rev.Name,
+		"kind", snapshot.Resource.Kind, "name", snapshot.Resource.Name, "revision", snapshot.Revision)
+	return rev, nil
+}
+
+// pruneConfigRevisions deletes the revisions of history, ordered newest first,
+// beyond the first keep
+func (r *ConfigReloaderReconciler) pruneConfigRevisions(ctx context.Context, history []configv1.ConfigRevision, keep int) {
+	logger := log.FromContext(ctx)
+	for i := keep; i < len(history); i++ {
+		if err := r.Delete(ctx, &history[i]); client.IgnoreNotFound(err) != nil {
+			logger.Error(err, "failed to delete ConfigRevision", "configRevision", history[i].Name)
+		}
+	}
+}
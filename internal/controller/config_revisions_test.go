@@ -0,0 +1,127 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	configv1 "github.com/shehbazk/config-reloader-operator/api/v1"
+)
+
+var _ = Describe("Config revisions", func() {
+	var (
+		ctx        context.Context
+		cr         *configv1.ConfigReloader
+		cm         *corev1.ConfigMap
+		reconciler *ConfigReloaderReconciler
+	)
+
+	listRevisions := func() []configv1.ConfigRevision {
+		var revisions configv1.ConfigRevisionList
+		Expect(reconciler.List(ctx, &revisions, historyLabels(cr))).To(Succeed())
+		return resourceRevisions(revisions.Items, "ConfigMap", cm.Name, cm.Namespace)
+	}
+
+	// update changes the ConfigMap data and records the change
+	update := func(data map[string]string) resourceChange {
+		cm.Data = data
+		Expect(reconciler.Update(ctx, cm)).To(Succeed())
+		changes := []resourceChange{
+			{Kind: "ConfigMap", Name: cm.Name, Namespace: cm.Namespace, NewVersion: cm.ResourceVersion},
+		}
+		reconciler.recordConfigRevisions(ctx, cr, changes)
+		return changes[0]
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		scheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+		Expect(configv1.AddToScheme(scheme)).To(Succeed())
+
+		cr = &configv1.ConfigReloader{
+			ObjectMeta: metav1.ObjectMeta{Name: "revisions", Namespace: "default", UID: "revisions-uid"},
+			Spec: configv1.ConfigReloaderSpec{
+				ConfigMaps:           []configv1.ResourceRef{{Name: "app"}},
+				RestartPolicy:        configv1.RestartPolicyDelete,
+				RevisionHistoryLimit: ptr.To[int32](2),
+			},
+		}
+		cm = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default"},
+			Data:       map[string]string{"a": "1"},
+		}
+		reconciler = &ConfigReloaderReconciler{
+			Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(cm).Build(),
+			Scheme: scheme,
+		}
+	})
+
+	It("should record numbered snapshots with the changed keys", func() {
+		Expect(update(map[string]string{"a": "1"}).Keys).To(BeEmpty())
+		change := update(map[string]string{"a": "2", "b": "1"})
+
+		revisions := listRevisions()
+		Expect(revisions).To(HaveLen(2))
+		Expect(revisions[0].Spec.Revision).To(Equal(int64(2)))
+		Expect(revisions[0].Spec.Data).To(Equal(map[string]string{"a": "2", "b": "1"}))
+		Expect(revisions[0].Spec.Changes).To(Equal([]configv1.KeyChange{
+			{Key: "a", Type: configv1.KeyModified},
+			{Key: "b", Type: configv1.KeyAdded},
+		}))
+		Expect(change.Keys).To(Equal(revisions[0].Spec.Changes))
+		Expect(revisions[0].OwnerReferences).To(HaveLen(1))
+		Expect(revisions[1].Spec.Revision).To(Equal(int64(1)))
+		Expect(revisions[1].Spec.Changes).To(BeEmpty())
+	})
+
+	It("should not record unchanged data", func() {
+		update(map[string]string{"a": "1"})
+		reconciler.recordConfigRevisions(ctx, cr, []resourceChange{
+			{Kind: "ConfigMap", Name: cm.Name, Namespace: cm.Namespace, NewVersion: cm.ResourceVersion},
+		})
+
+		Expect(listRevisions()).To(HaveLen(1))
+	})
+
+	It("should keep the newest revisions up to the limit", func() {
+		update(map[string]string{"a": "1"})
+		update(map[string]string{"a": "2"})
+		update(map[string]string{"a": "3"})
+
+		revisions := listRevisions()
+		Expect(revisions).To(HaveLen(2))
+		Expect(revisions[0].Spec.Revision).To(Equal(int64(3)))
+		Expect(revisions[1].Spec.Revision).To(Equal(int64(2)))
+	})
+
+	It("should record nothing when disabled", func() {
+		cr.Spec.RevisionHistoryLimit = nil
+		update(map[string]string{"a": "2"})
+
+		Expect(listRevisions()).To(BeEmpty())
+	})
+})
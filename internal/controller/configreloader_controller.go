@@ -2,27 +2,81 @@ package controller
 
 import (
 	"context"
+	"fmt"
 	"time"
 
+	"go.opentelemetry.io/otel/trace"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/ptr"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	configv1 "github.com/shehbazk/config-reloader-operator/api/v1"
+	"github.com/shehbazk/config-reloader-operator/internal/sharding"
 )
 
 const (
 	ConfigReloaderFinalizer = "config.dev/finalizer"
 	ReloadAnnotation        = "config.dev/last-reload"
+	// ReloadRequestAnnotation on a ConfigReloader forces a reload once per distinct value
+	ReloadRequestAnnotation = "config.dev/reload-requested-at"
+
+	// rolloutPollInterval is how often progressing rollouts are checked
+	rolloutPollInterval = 15 * time.Second
 )
 
 // ConfigReloaderReconciler reconciles a ConfigReloader object
 type ConfigReloaderReconciler struct {
 	client.Client
-	Scheme *runtime.Scheme
+	Scheme   *runtime.Scheme
+	Recorder record.EventRecorder
+
+	// DryRun forces dry-run mode for every ConfigReloader
+	DryRun bool
+
+	// ConfigCache describes how the manager caches ConfigMaps and Secrets
+	ConfigCache ConfigCacheOptions
+	// APIReader reads the ConfigMaps and Secrets left out of the cache
+	APIReader client.Reader
+
+	// Shards restricts the reconciled ConfigReloaders to the shards held by
+	// this replica. Every ConfigReloader is reconciled when nil.
+	Shards *sharding.Coordinator
+
+	// TracerProvider provides the tracer of the change propagation spans. The
+	// global provider, a no-op unless tracing is set up, is used when nil.
+	TracerProvider trace.TracerProvider
+
+	// Notifier sends the notifications of the ReloadNotifiers. No
+	// notification is sent when nil.
+	Notifier Notifier
+
+	// MetricsQuerier runs the queries of canary analyses. A client of the
+	// Prometheus query API is used when nil.
+	MetricsQuerier MetricsQuerier
+
+	// AnalysisAddresses lists the Prometheus addresses canary analyses may
+	// query. Canaries whose analysis uses another address are aborted.
+	AnalysisAddresses []string
+
+	// WebhooksDisabled is set when the admission webhooks are not served.
+	// Approvals are then never honoured, as nothing checks who gave them.
+	WebhooksDisabled bool
+
+	// indexed is set once the field indexes are registered with the cache
+	indexed bool
+	// rollouts holds the restart spans of the rollouts being followed
+	rollouts rolloutTraces
 }
 
 // +kubebuilder:rbac:groups=config.dev,resources=configreloaders,verbs=get;list;watch;create;update;patch;delete
@@ -35,13 +89,25 @@ type ConfigReloaderReconciler struct {
 // +kubebuilder:rbac:groups=apps,resources=statefulsets,verbs=get;list;watch;update;patch
 // +kubebuilder:rbac:groups=apps,resources=daemonsets,verbs=get;list;watch;update;patch
 // +kubebuilder:rbac:groups=apps,resources=replicasets,verbs=get;list;watch;update;patch
+// +kubebuilder:rbac:groups="",resources=events,verbs=create;patch
+// +kubebuilder:rbac:groups=config.dev,resources=reloadevents,verbs=get;list;watch;create;delete
+// +kubebuilder:rbac:groups=config.dev,resources=configrevisions,verbs=get;list;watch;create;delete
+// +kubebuilder:rbac:groups=config.dev,resources=reloadnotifiers,verbs=get;list;watch
+// +kubebuilder:rbac:groups=config.dev,resources=reloadnotifiers/status,verbs=get;update;patch
 
 func (r *ConfigReloaderReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	logger := log.FromContext(ctx)
 
+	reader, release, ok := r.claimConfigReloader(req.NamespacedName)
+	if !ok {
+		// Reconciled by the replica holding its shard
+		return ctrl.Result{}, nil
+	}
+	defer release()
+
 	// Fetch the ConfigReloader instance
 	var configReloader configv1.ConfigReloader
-	if err := r.Get(ctx, req.NamespacedName, &configReloader); err != nil {
+	if err := reader.Get(ctx, req.NamespacedName, &configReloader); err != nil {
 		logger.Error(err, "unable to fetch ConfigReloader")
 		return ctrl.Result{}, client.IgnoreNotFound(err)
 	}
@@ -65,56 +131,232 @@ func (r *ConfigReloaderReconciler) reconcileConfigReloader(
 	cr *configv1.ConfigReloader,
 ) (ctrl.Result, error) {
 	logger := log.FromContext(ctx)
+	start := time.Now()
 
-	hasChanges, err := r.checkForChanges(ctx, cr)
+	changes, watchedResources, err := r.checkForChanges(ctx, cr)
 	if err != nil {
+		r.recordEventf(cr, corev1.EventTypeWarning, EventReasonCheckFailed, "Failed to check watched resources: %v", err)
 		r.updateCondition(cr, "Ready", metav1.ConditionFalse, "CheckFailed", err.Error())
+		cr.Status.ObservedGeneration = cr.Generation
 		return ctrl.Result{RequeueAfter: time.Minute * 5}, r.Status().Update(ctx, cr)
 	}
 
+	r.refreshTargetStatuses(ctx, cr)
+	r.recordConfigRevisions(ctx, cr, changes)
+
+	changes = r.filterIgnoredChanges(cr, changes)
+	hasChanges := len(changes) > 0
+	r.recordChanges(cr, changes)
+	observeChanges(changes)
 	if hasChanges {
-		logger.Info("Detected changes in watched resources, restarting pods")
+		r.notifyChangeDetected(ctx, cr, changes)
+	}
+
+	reloadRequest, reloadRequested := r.pendingReloadRequest(cr)
+
+	if cr.Spec.Suspend {
+		if hasChanges {
+			logger.Info("Detected changes in watched resources while suspended, deferring restart")
+			r.recordEventf(cr, corev1.EventTypeNormal, EventReasonRestartSkipped,
+				"Restart deferred while suspended: %s", describeChanges(changes))
+			cr.Status.PendingReload = true
+		}
+		r.updateCondition(cr, "Suspended", metav1.ConditionTrue, "Suspended", "Restarts are paused")
+		r.refreshConflicts(ctx, cr)
+	} else {
+		applyPending := cr.Status.PendingReload && cr.Spec.ApplyPendingOnResume
+
+		var result *restartResult
+		var retried map[string]bool
+		var cause string
+		reload := hasChanges || reloadRequested || applyPending
+		if reload {
+			switch {
+			case hasChanges:
+				cause = describeChanges(changes)
+			case reloadRequested:
+				cause = fmt.Sprintf("manual reload requested at %s", reloadRequest)
+			default:
+				cause = "changes detected while suspended"
+			}
+		}
+		if r.requiresApproval(cr) {
+			reload, changes, cause = r.gateOnApproval(ctx, cr, reload, changes, cause, start)
+		} else if cr.Spec.Approval == nil && cr.Status.PendingApproval != nil {
+			reload, changes, cause = r.releaseHeldReload(ctx, cr, reload, changes, cause)
+		}
+		if reload {
+			logger.Info("Restarting affected pods", "cause", cause)
+
+			if !r.isDryRun(cr) {
+				r.notifyReloadStarted(ctx, cr, changes, cause)
+			}
+			reloadCtx, span := r.startReloadSpan(ctx, cr, changes, cause, start)
+			result, err = r.restartAffectedPods(reloadCtx, cr, changes, cause)
+			endReloadSpan(span, result, err)
+		} else if canaryBaking(cr) {
+			r.refreshConflicts(ctx, cr)
+			changes = changedResourceChanges(cr.Status.Canary.Resources)
+			cause = cr.Status.Canary.Cause
+			result, err = r.progressCanary(ctx, cr, changes, start)
+		} else {
+			r.refreshConflicts(ctx, cr)
+			if retried = dueFailedRestarts(cr, start); len(retried) > 0 {
+				logger.Info("Retrying failed restarts", "targets", len(retried))
+				cause = "retrying failed restarts"
+
+				if !r.isDryRun(cr) {
+					r.notifyReloadStarted(ctx, cr, nil, cause)
+				}
+				reloadCtx, span := r.startReloadSpan(ctx, cr, nil, cause, start)
+				result, err = r.retryFailedRestarts(reloadCtx, cr, retried)
+				endReloadSpan(span, result, err)
+			}
+		}
+
+		if (result != nil || err != nil) && !r.isDryRun(cr) {
+			event := newReloadEvent(cr, changes, cause, result, err, start)
+			r.recordReloadEvent(ctx, cr, event)
+			r.notifyReloadOutcome(ctx, cr, event)
+		}
 
-		restarted, err := r.restartAffectedPods(ctx, cr)
 		if err != nil {
+			r.recordEventf(cr, corev1.EventTypeWarning, EventReasonRestartFailed, "Failed to restart pods: %v", err)
+			observeRestartFailure(cr.Namespace, failureReasonPlan)
 			r.updateCondition(cr, "Ready", metav1.ConditionFalse, "RestartFailed", err.Error())
+			updateTargetSummary(cr)
+			cr.Status.ObservedGeneration = cr.Generation
 			return ctrl.Result{RequeueAfter: time.Minute * 5}, r.Status().Update(ctx, cr)
 		}
 
-		now := metav1.Now()
-		cr.Status.LastReloadTime = &now
-		cr.Status.PodsRestarted = append(cr.Status.PodsRestarted, restarted...)
+		if result != nil && !r.isDryRun(cr) {
+			r.recordRestartResult(ctx, cr, result, retried, start)
+		}
 
-		if len(cr.Status.PodsRestarted) > 10 {
-			cr.Status.PodsRestarted = cr.Status.PodsRestarted[len(cr.Status.PodsRestarted)-10:]
+		if reloadRequested {
+			cr.Status.LastReloadRequest = reloadRequest
 		}
+		cr.Status.PendingReload = false
+		r.updateCondition(cr, "Suspended", metav1.ConditionFalse, "Active", "Restarts are enabled")
 	}
 
-	r.updateWatchedResourcesStatus(ctx, cr)
+	if !r.isDryRun(cr) {
+		cr.Status.DryRunPlan = nil
+	}
+
+	cr.Status.WatchedResources = watchedResources
+	r.pruneReloadEvents(ctx, cr, time.Now())
 
-	r.updateCondition(cr, "Ready", metav1.ConditionTrue, "ReconcileSuccess", "ConfigReloader is ready")
+	if len(cr.Status.FailedRestarts) > 0 {
+		message := failedRestartsMessage(cr.Status.FailedRestarts)
+		r.updateCondition(cr, "Degraded", metav1.ConditionTrue, "RestartFailed", message)
+		r.updateCondition(cr, "Ready", metav1.ConditionFalse, "RestartFailed", message)
+	} else {
+		r.updateCondition(cr, "Degraded", metav1.ConditionFalse, "RestartSucceeded", "No failed restarts")
+		r.updateCondition(cr, "Ready", metav1.ConditionTrue, "ReconcileSuccess", "ConfigReloader is ready")
+	}
+	updateTargetSummary(cr)
+	updateStatusMetrics(cr)
+	cr.Status.ObservedGeneration = cr.Generation
 
 	if err := r.Status().Update(ctx, cr); err != nil {
 		return ctrl.Result{}, err
 	}
 
 	// Requeue to check for changes periodically
-	return ctrl.Result{RequeueAfter: time.Minute * 2}, nil
+	requeueAfter := time.Minute * 2
+	if len(cr.Status.FailedRestarts) > 0 && !cr.Spec.Suspend {
+		// Retry only the failed targets once their backoff expires
+		requeueAfter = nextRetryAfter(cr, time.Now())
+	}
+	if cr.Status.Summary.Progressing > 0 {
+		requeueAfter = min(requeueAfter, rolloutPollInterval)
+	}
+	if canaryBaking(cr) && !cr.Spec.Suspend {
+		// Check the canaries until they baked long enough
+		requeueAfter = min(requeueAfter, rolloutPollInterval, max(time.Until(canaryBakeEnd(cr)), time.Second))
+	}
+	if pending := cr.Status.PendingApproval; pending != nil && !cr.Spec.Suspend {
+		// Discard the held changes once they expire
+		requeueAfter = min(requeueAfter, max(time.Until(pending.ExpirationTime.Time), time.Second))
+	}
+	return ctrl.Result{RequeueAfter: requeueAfter}, nil
 }
 
-func (r *ConfigReloaderReconciler) handleDeletion(
+// recordRestartResult records the outcome of a restart in status. retried
+// holds the failed targets that were retried, or nil for a full restart.
+func (r *ConfigReloaderReconciler) recordRestartResult(
 	ctx context.Context,
 	cr *configv1.ConfigReloader,
-) (ctrl.Result, error) {
+	result *restartResult,
+	retried map[string]bool,
+	start time.Time,
+) {
 	logger := log.FromContext(ctx)
-	logger.Info("Handling ConfigReloader deletion")
+	now := metav1.Now()
+
+	if len(result.Failed) > 0 {
+		err := result.Err()
+		logger.Error(err, "some restarts failed", "failed", len(result.Failed), "restarted", len(result.Restarted))
+		r.recordEventf(cr, corev1.EventTypeWarning, EventReasonRestartFailed,
+			"%d restart targets failed: %v", len(result.Failed), err)
+	}
+	updateFailedRestarts(cr, result, retried, now)
+
+	configHash, err := r.configHash(ctx, cr)
+	if err != nil {
+		logger.Error(err, "failed to hash watched resources")
+	}
+	recordTargetStatuses(cr, result, configHash, now)
 
-	controllerutil.RemoveFinalizer(cr, ConfigReloaderFinalizer)
-	return ctrl.Result{}, r.Update(ctx, cr)
+	if len(result.Restarted) == 0 && len(result.Failed) > 0 {
+		return
+	}
+
+	observeReconcileToRestart(cr.Spec.RestartPolicy, start)
+
+	cr.Status.LastReloadTime = &now
+	cr.Status.PodsRestarted = append(cr.Status.PodsRestarted, result.Restarted...)
+
+	if len(cr.Status.PodsRestarted) > 10 {
+		cr.Status.PodsRestarted = cr.Status.PodsRestarted[len(cr.Status.PodsRestarted)-10:]
+	}
+}
+
+// pendingReloadRequest returns the manual reload request set on the
+// ConfigReloader and whether it has not been handled yet
+func (r *ConfigReloaderReconciler) pendingReloadRequest(cr *configv1.ConfigReloader) (string, bool) {
+	value := cr.Annotations[ReloadRequestAnnotation]
+	if value == "" || value == cr.Status.LastReloadRequest {
+		return value, false
+	}
+	return value, true
 }
 
 func (r *ConfigReloaderReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).
+	if err := SetupIndexes(context.Background(), mgr.GetFieldIndexer()); err != nil {
+		return err
+	}
+	r.indexed = true
+
+	var watchOpts []builder.WatchesOption
+	if r.ConfigCache.MetadataOnly {
+		watchOpts = append(watchOpts, builder.OnlyMetadata)
+	}
+
+	changes := &ConfigMapSecretHandler{Client: mgr.GetClient()}
+	b := ctrl.NewControllerManagedBy(mgr).
 		For(&configv1.ConfigReloader{}).
-		Complete(r)
+		// Overlapping ConfigReloaders refresh their Conflicting condition
+		Watches(&configv1.ConfigReloader{}, handler.EnqueueRequestsFromMapFunc(r.overlappingConfigReloaders),
+			builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		Watches(&corev1.ConfigMap{}, changes, watchOpts...).
+		Watches(&corev1.Secret{}, changes, watchOpts...)
+	if r.Shards != nil {
+		// Every replica reconciles the ConfigReloaders of its own shards
+		b = b.WatchesRawSource(source.Channel(r.Shards.Acquired(),
+			handler.EnqueueRequestsFromMapFunc(r.shardConfigReloaders))).
+			WithOptions(controller.Options{NeedLeaderElection: ptr.To(false)})
+	}
+	return b.Complete(r)
 }
@@ -21,8 +21,11 @@ import (
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -81,4 +84,172 @@ var _ = Describe("ConfigReloader Controller", func() {
 			// Example: If you expect a certain status condition after reconciliation, verify it here.
 		})
 	})
+
+	Context("When running in dry-run mode", func() {
+		const resourceName = "dry-run-reloader"
+
+		ctx := context.Background()
+
+		typeNamespacedName := types.NamespacedName{Name: resourceName, Namespace: "default"}
+		configMap := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "dry-run-config", Namespace: "default"},
+			Data:       map[string]string{"key": "value"},
+		}
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "dry-run-pod", Namespace: "default"},
+			Spec: corev1.PodSpec{
+				Containers: []corev1.Container{{
+					Name:  "app",
+					Image: "busybox",
+					EnvFrom: []corev1.EnvFromSource{{
+						ConfigMapRef: &corev1.ConfigMapEnvSource{
+							LocalObjectReference: corev1.LocalObjectReference{Name: "dry-run-config"},
+						},
+					}},
+				}},
+			},
+		}
+
+		BeforeEach(func() {
+			Expect(k8sClient.Create(ctx, configMap.DeepCopy())).To(Succeed())
+			Expect(k8sClient.Create(ctx, pod.DeepCopy())).To(Succeed())
+			Expect(k8sClient.Create(ctx, &configv1.ConfigReloader{
+				ObjectMeta: metav1.ObjectMeta{Name: resourceName, Namespace: "default"},
+				Spec: configv1.ConfigReloaderSpec{
+					ConfigMaps:    []configv1.ResourceRef{{Name: "dry-run-config"}},
+					RestartPolicy: configv1.RestartPolicyDelete,
+					DryRun:        true,
+				},
+			})).To(Succeed())
+		})
+
+		AfterEach(func() {
+			resource := &configv1.ConfigReloader{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed())
+			resource.Finalizers = nil
+			Expect(k8sClient.Update(ctx, resource)).To(Succeed())
+			Expect(k8sClient.Delete(ctx, resource)).To(Succeed())
+			Expect(k8sClient.Delete(ctx, configMap.DeepCopy())).To(Succeed())
+			Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, pod.DeepCopy()))).To(Succeed())
+		})
+
+		It("should report the plan without deleting pods", func() {
+			recorder := record.NewFakeRecorder(10)
+			controllerReconciler := &ConfigReloaderReconciler{
+				Client:   k8sClient,
+				Scheme:   k8sClient.Scheme(),
+				Recorder: recorder,
+			}
+
+			By("adding the finalizer and then reconciling the change")
+			for range 2 {
+				_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+				Expect(err).NotTo(HaveOccurred())
+			}
+
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(pod), &corev1.Pod{})).To(Succeed())
+
+			resource := &configv1.ConfigReloader{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed())
+			Expect(resource.Status.DryRunPlan).To(HaveLen(1))
+			Expect(resource.Status.DryRunPlan[0].PodName).To(Equal("dry-run-pod"))
+			Expect(resource.Status.DryRunPlan[0].Policy).To(Equal(configv1.RestartPolicyDelete))
+			Expect(resource.Status.PodsRestarted).To(BeEmpty())
+			Expect(resource.Status.LastReloadTime).To(BeNil())
+			Expect(recorder.Events).To(Receive(ContainSubstring(EventReasonChangeDetected)))
+			Expect(recorder.Events).To(Receive(And(
+				ContainSubstring(EventReasonChangeDetected),
+				ContainSubstring("ConfigReloader default/dry-run-reloader detected a change"),
+			)))
+			Expect(recorder.Events).To(Receive(ContainSubstring(EventReasonDryRunRestart)))
+		})
+	})
+
+	Context("When the ConfigReloader is suspended", func() {
+		const resourceName = "suspended-reloader"
+
+		ctx := context.Background()
+
+		typeNamespacedName := types.NamespacedName{Name: resourceName, Namespace: "default"}
+		configMap := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "suspended-config", Namespace: "default"},
+			Data:       map[string]string{"key": "value"},
+		}
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "suspended-pod", Namespace: "default"},
+			Spec: corev1.PodSpec{
+				Containers: []corev1.Container{{Name: "app", Image: "busybox"}},
+				Volumes: []corev1.Volume{{
+					Name: "config",
+					VolumeSource: corev1.VolumeSource{
+						ConfigMap: &corev1.ConfigMapVolumeSource{
+							LocalObjectReference: corev1.LocalObjectReference{Name: "suspended-config"},
+						},
+					},
+				}},
+			},
+		}
+
+		BeforeEach(func() {
+			Expect(k8sClient.Create(ctx, configMap.DeepCopy())).To(Succeed())
+			Expect(k8sClient.Create(ctx, pod.DeepCopy())).To(Succeed())
+			Expect(k8sClient.Create(ctx, &configv1.ConfigReloader{
+				ObjectMeta: metav1.ObjectMeta{Name: resourceName, Namespace: "default"},
+				Spec: configv1.ConfigReloaderSpec{
+					ConfigMaps:           []configv1.ResourceRef{{Name: "suspended-config"}},
+					RestartPolicy:        configv1.RestartPolicyDelete,
+					Suspend:              true,
+					ApplyPendingOnResume: true,
+				},
+			})).To(Succeed())
+		})
+
+		AfterEach(func() {
+			resource := &configv1.ConfigReloader{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed())
+			resource.Finalizers = nil
+			Expect(k8sClient.Update(ctx, resource)).To(Succeed())
+			Expect(k8sClient.Delete(ctx, resource)).To(Succeed())
+			Expect(k8sClient.Delete(ctx, configMap.DeepCopy())).To(Succeed())
+			Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, pod.DeepCopy()))).To(Succeed())
+		})
+
+		It("should defer restarts until resumed", func() {
+			controllerReconciler := &ConfigReloaderReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+			}
+
+			for range 2 {
+				_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+				Expect(err).NotTo(HaveOccurred())
+			}
+
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(pod), &corev1.Pod{})).To(Succeed())
+			resource := &configv1.ConfigReloader{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed())
+			Expect(resource.Status.PendingReload).To(BeTrue())
+
+			By("resuming the ConfigReloader")
+			resource.Spec.Suspend = false
+			Expect(k8sClient.Update(ctx, resource)).To(Succeed())
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed())
+			Expect(resource.Status.PendingReload).To(BeFalse())
+			Expect(resource.Status.PodsRestarted).To(HaveLen(1))
+			Expect(resource.Status.ObservedGeneration).To(Equal(resource.Generation))
+			Expect(resource.Status.Targets).To(HaveLen(1))
+			Expect(resource.Status.Targets[0].Kind).To(Equal("Pod"))
+			Expect(resource.Status.Targets[0].LastConfigHash).NotTo(BeEmpty())
+			Expect(resource.Status.Summary.Complete).To(Equal(int32(1)))
+
+			var events configv1.ReloadEventList
+			Expect(k8sClient.List(ctx, &events, client.InNamespace("default"),
+				client.MatchingLabels{ConfigReloaderLabel: resourceName})).To(Succeed())
+			Expect(events.Items).To(HaveLen(1))
+			Expect(events.Items[0].Status.Outcome).To(Equal(configv1.ReloadSucceeded))
+		})
+	})
 })
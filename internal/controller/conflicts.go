@@ -0,0 +1,304 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	configv1 "github.com/shehbazk/config-reloader-operator/api/v1"
+)
+
+// overlap is another ConfigReloader selecting some of the pods of a
+// ConfigReloader
+type overlap struct {
+	ConfigReloader *configv1.ConfigReloader
+	// Pods holds the names of the shared pods
+	Pods map[string]bool
+}
+
+// Precedes reports whether a restarts the workloads it shares with b: the
+// ConfigReloader with the highest priority, then the first by name
+func Precedes(a, b *configv1.ConfigReloader) bool {
+	if a.Spec.Priority != b.Spec.Priority {
+		return a.Spec.Priority > b.Spec.Priority
+	}
+	return a.Name < b.Name
+}
+
+// selectedPods returns the names of the pods among pods that the
+// ConfigReloader selects, without listing its own pods
+func (r *ConfigReloaderReconciler) selectedPods(cr *configv1.ConfigReloader, pods []*corev1.Pod) (map[string]bool, error) {
+	selector := labels.Everything()
+	if cr.Spec.Selector != nil {
+		var err error
+		if selector, err = metav1.LabelSelectorAsSelector(cr.Spec.Selector); err != nil {
+			return nil, fmt.Errorf("invalid selector: %w", err)
+		}
+	}
+
+	watchedCMs, watchedSecrets := buildWatchedResourcesMaps(cr)
+	selected := make(map[string]bool)
+	for _, pod := range pods {
+		if pod.Namespace == cr.Namespace && selector.Matches(labels.Set(pod.Labels)) &&
+			r.podUsesWatchedResources(pod, watchedCMs, watchedSecrets) {
+			selected[pod.Name] = true
+		}
+	}
+	return selected, nil
+}
+
+// findOverlaps returns the other ConfigReloaders in the namespace selecting
+// any of the pods, ordered by precedence. ConfigReloaders being deleted are
+// left out. The pods shared with another ConfigReloader are among the given
+// pods, so they are matched against its selector and watched resources
+// instead of listing the pods of every ConfigReloader.
+func (r *ConfigReloaderReconciler) findOverlaps(
+	ctx context.Context,
+	cr *configv1.ConfigReloader,
+	pods []*corev1.Pod,
+) ([]overlap, error) {
+	logger := log.FromContext(ctx)
+	if len(pods) == 0 {
+		return nil, nil
+	}
+
+	var configReloaders configv1.ConfigReloaderList
+	if err := r.List(ctx, &configReloaders, client.InNamespace(cr.Namespace)); err != nil {
+		return nil, fmt.Errorf("failed to list ConfigReloaders in %s: %w", cr.Namespace, err)
+	}
+
+	var overlaps []overlap
+	for i := range configReloaders.Items {
+		other := &configReloaders.Items[i]
+		if other.Name == cr.Name || other.DeletionTimestamp != nil {
+			continue
+		}
+		shared, err := r.selectedPods(other, pods)
+		if err != nil {
+			logger.Error(err, "failed to resolve pods of ConfigReloader", "configReloader", other.Name)
+			continue
+		}
+		if len(shared) > 0 {
+			overlaps = append(overlaps, overlap{ConfigReloader: other, Pods: shared})
+		}
+	}
+
+	sort.Slice(overlaps, func(i, j int) bool {
+		return Precedes(overlaps[i].ConfigReloader, overlaps[j].ConfigReloader)
+	})
+	return overlaps, nil
+}
+
+// detectConflicts finds the ConfigReloaders overlapping with the pods and
+// reports them in the Conflicting condition. The condition is left as is when
+// the overlaps cannot be resolved.
+func (r *ConfigReloaderReconciler) detectConflicts(
+	ctx context.Context,
+	cr *configv1.ConfigReloader,
+	pods []*corev1.Pod,
+) []overlap {
+	overlaps, err := r.findOverlaps(ctx, cr, pods)
+	if err != nil {
+		log.FromContext(ctx).Error(err, "failed to detect overlapping ConfigReloaders")
+		return nil
+	}
+
+	if len(overlaps) == 0 {
+		r.updateCondition(cr, "Conflicting", metav1.ConditionFalse, "NoOverlap",
+			"No other ConfigReloader selects the same pods")
+		return nil
+	}
+
+	names := make([]string, 0, len(overlaps))
+	preceded := false
+	for _, o := range overlaps {
+		names = append(names, fmt.Sprintf("%s (priority %d, %d pods)",
+			o.ConfigReloader.Name, o.ConfigReloader.Spec.Priority, len(o.Pods)))
+		preceded = preceded || Precedes(o.ConfigReloader, cr)
+	}
+	message := fmt.Sprintf("Pods also selected by %s", strings.Join(names, ", "))
+	if preceded {
+		message += "; shared workloads are left to the ConfigReloader with precedence when it reacts to the same change"
+	} else {
+		message += "; this ConfigReloader takes precedence"
+	}
+	r.updateCondition(cr, "Conflicting", metav1.ConditionTrue, "OverlappingTargets", message)
+	return overlaps
+}
+
+// refreshConflicts updates the Conflicting condition from the pods currently
+// selected by the ConfigReloader
+func (r *ConfigReloaderReconciler) refreshConflicts(ctx context.Context, cr *configv1.ConfigReloader) {
+	pods, err := r.affectedPods(ctx, cr)
+	if err != nil {
+		log.FromContext(ctx).Error(err, "failed to resolve affected pods")
+		return
+	}
+	r.detectConflicts(ctx, cr, pods)
+}
+
+// reactsTo reports whether the ConfigReloader restarts its targets for any of
+// the changes, applying its own ignored field managers and selected keys
+func (r *ConfigReloaderReconciler) reactsTo(
+	ctx context.Context,
+	cr *configv1.ConfigReloader,
+	changes []resourceChange,
+) bool {
+	if cr.Spec.Suspend || r.isDryRun(cr) {
+		return false
+	}
+	for _, change := range changes {
+		if isIgnoredChange(cr, change) {
+			continue
+		}
+		ref, ok := watchedRef(cr, change.Kind, change.Namespace, change.Name)
+		if !ok {
+			continue
+		}
+		if len(ref.Keys) == 0 || r.selectedKeysChanged(ctx, cr, change, ref.Keys) {
+			return true
+		}
+	}
+	return false
+}
+
+// watchedRef returns the reference of the ConfigReloader to a watched resource
+func watchedRef(cr *configv1.ConfigReloader, kind, namespace, name string) (configv1.ResourceRef, bool) {
+	refs := cr.Spec.ConfigMaps
+	if kind == "Secret" {
+		refs = cr.Spec.Secrets
+	}
+	for _, ref := range refs {
+		refNamespace := ref.Namespace
+		if refNamespace == "" {
+			refNamespace = cr.Namespace
+		}
+		if ref.Name == name && refNamespace == namespace {
+			return ref, true
+		}
+	}
+	return configv1.ResourceRef{}, false
+}
+
+// selectedKeysChanged reports whether a change touches the keys another
+// ConfigReloader selects. This is only known while that ConfigReloader has
+// recorded the version the change started from. False is returned otherwise,
+// so that a shared workload is restarted twice rather than not at all.
+func (r *ConfigReloaderReconciler) selectedKeysChanged(
+	ctx context.Context,
+	cr *configv1.ConfigReloader,
+	change resourceChange,
+	keys []string,
+) bool {
+	var recorded *configv1.WatchedResource
+	for i, watched := range cr.Status.WatchedResources {
+		if watched.Kind == change.Kind && watched.Name == change.Name && watched.Namespace == change.Namespace {
+			recorded = &cr.Status.WatchedResources[i]
+		}
+	}
+	if recorded == nil || recorded.KeysHash == "" || recorded.ResourceVersion != change.OldVersion {
+		return false
+	}
+
+	obj, err := r.getWatchedObject(ctx, change.Kind, types.NamespacedName{Namespace: change.Namespace, Name: change.Name})
+	if err != nil {
+		log.FromContext(ctx).Error(err, "failed to read the keys selected by an overlapping ConfigReloader",
+			"kind", change.Kind, "name", change.Name, "configReloader", cr.Name)
+		return false
+	}
+	return selectedKeysHash(obj, keys) != recorded.KeysHash
+}
+
+// deferToOverlaps drops the targets shared with an overlapping ConfigReloader
+// that takes precedence and reacts to the same changes, so that a workload is
+// restarted once per change
+func (r *ConfigReloaderReconciler) deferToOverlaps(
+	ctx context.Context,
+	cr *configv1.ConfigReloader,
+	targets []restartTarget,
+	overlaps []overlap,
+	changes []resourceChange,
+) []restartTarget {
+	logger := log.FromContext(ctx)
+
+	var winners []overlap
+	for _, o := range overlaps {
+		if Precedes(o.ConfigReloader, cr) && r.reactsTo(ctx, o.ConfigReloader, changes) {
+			winners = append(winners, o)
+		}
+	}
+	if len(winners) == 0 {
+		return targets
+	}
+
+	kept := make([]restartTarget, 0, len(targets))
+	deferred := make(map[string]bool)
+	for _, target := range targets {
+		var winner *configv1.ConfigReloader
+		for _, o := range winners {
+			if o.Pods[target.Pod.Name] {
+				winner = o.ConfigReloader
+				break
+			}
+		}
+		if winner == nil {
+			kept = append(kept, target)
+			continue
+		}
+
+		kind, name := target.WorkloadKind, target.WorkloadName
+		if kind == "" {
+			kind, name = "Pod", target.Pod.Name
+		}
+		if key := failedTargetKey(target.Pod.Namespace, kind, name); !deferred[key] {
+			deferred[key] = true
+			logger.Info("Restart left to overlapping ConfigReloader", "kind", kind, "name", name,
+				"configReloader", winner.Name, "priority", winner.Spec.Priority)
+			r.recordEventf(cr, corev1.EventTypeNormal, EventReasonRestartSkipped,
+				"%s %s/%s is restarted by ConfigReloader %s with priority %d",
+				kind, target.Pod.Namespace, name, winner.Name, winner.Spec.Priority)
+		}
+	}
+	return kept
+}
+
+// overlappingConfigReloaders maps a ConfigReloader to the other
+// ConfigReloaders selecting some of its pods, so that they refresh their
+// Conflicting condition when it is created, changed or deleted. Updates map
+// both the old and the new object, so the ConfigReloaders that no longer
+// overlap are refreshed too.
+func (r *ConfigReloaderReconciler) overlappingConfigReloaders(ctx context.Context, obj client.Object) []reconcile.Request {
+	logger := log.FromContext(ctx)
+	cr, ok := obj.(*configv1.ConfigReloader)
+	if !ok {
+		return nil
+	}
+
+	pods, err := r.affectedPods(ctx, cr)
+	if err != nil {
+		logger.Error(err, "failed to resolve affected pods", "configReloader", cr.Name)
+		return nil
+	}
+	overlaps, err := r.findOverlaps(ctx, cr, pods)
+	if err != nil {
+		logger.Error(err, "failed to detect overlapping ConfigReloaders", "configReloader", cr.Name)
+		return nil
+	}
+
+	requests := make([]reconcile.Request, 0, len(overlaps))
+	for _, o := range overlaps {
+		requests = append(requests, reconcile.Request{
+			NamespacedName: types.NamespacedName{Namespace: o.ConfigReloader.Namespace, Name: o.ConfigReloader.Name},
+		})
+	}
+	return requests
+}
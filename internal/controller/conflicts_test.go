@@ -0,0 +1,256 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	configv1 "github.com/shehbazk/config-reloader-operator/api/v1"
+)
+
+var _ = Describe("Overlapping ConfigReloaders", func() {
+	var (
+		ctx          context.Context
+		scheme       *runtime.Scheme
+		high         *configv1.ConfigReloader
+		low          *configv1.ConfigReloader
+		interceptors interceptor.Funcs
+		objects      []client.Object
+		reconciler   *ConfigReloaderReconciler
+	)
+
+	configReloader := func(name string, priority int32, configMaps ...string) *configv1.ConfigReloader {
+		cr := &configv1.ConfigReloader{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+			Spec: configv1.ConfigReloaderSpec{
+				Selector:      &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+				RestartPolicy: configv1.RestartPolicyAnnotation,
+				Priority:      priority,
+			},
+		}
+		for _, name := range configMaps {
+			cr.Spec.ConfigMaps = append(cr.Spec.ConfigMaps, configv1.ResourceRef{Name: name})
+		}
+		return cr
+	}
+
+	changed := func(configMaps ...string) []resourceChange {
+		changes := make([]resourceChange, 0, len(configMaps))
+		for _, name := range configMaps {
+			changes = append(changes, resourceChange{Kind: "ConfigMap", Name: name, Namespace: "default", NewVersion: "2"})
+		}
+		return changes
+	}
+
+	// build creates the reconciler once the spec adjusted the ConfigReloaders
+	build := func() {
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            "web-1",
+				Namespace:       "default",
+				Labels:          map[string]string{"app": "web"},
+				OwnerReferences: []metav1.OwnerReference{{APIVersion: "apps/v1", Kind: "Deployment", Name: "web"}},
+			},
+			Spec: corev1.PodSpec{
+				Containers: []corev1.Container{{Name: "app", EnvFrom: []corev1.EnvFromSource{
+					{ConfigMapRef: &corev1.ConfigMapEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: "shared"}}},
+					{ConfigMapRef: &corev1.ConfigMapEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: "extra"}}},
+				}}},
+			},
+		}
+		deployment := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"}}
+		c := fake.NewClientBuilder().WithScheme(scheme).
+			WithObjects(append(objects, high, low, pod, deployment)...).
+			WithInterceptorFuncs(interceptors).
+			Build()
+		reconciler = &ConfigReloaderReconciler{Client: c, Scheme: scheme}
+	}
+
+	// restarted reports whether the Deployment was restarted
+	restarted := func() bool {
+		var deployment appsv1.Deployment
+		Expect(reconciler.Get(ctx, client.ObjectKey{Namespace: "default", Name: "web"}, &deployment)).To(Succeed())
+		return len(deployment.Spec.Template.Annotations) > 0
+	}
+
+	restart := func(cr *configv1.ConfigReloader, changes []resourceChange) *restartResult {
+		result, err := reconciler.restartAffectedPods(ctx, cr, changes, "test")
+		Expect(err).NotTo(HaveOccurred())
+		return result
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		scheme = runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+		Expect(configv1.AddToScheme(scheme)).To(Succeed())
+
+		high = configReloader("high", 10, "shared")
+		low = configReloader("low", 0, "shared", "extra")
+		interceptors = interceptor.Funcs{}
+		objects = nil
+	})
+
+	It("should leave a shared workload to the ConfigReloader with the highest priority", func() {
+		build()
+
+		result := restart(low, changed("shared"))
+		Expect(result.Restarted).To(BeEmpty())
+		Expect(restarted()).To(BeFalse())
+		conflicting := meta.FindStatusCondition(low.Status.Conditions, "Conflicting")
+		Expect(conflicting).NotTo(BeNil())
+		Expect(conflicting.Status).To(Equal(metav1.ConditionTrue))
+		Expect(conflicting.Message).To(ContainSubstring("high (priority 10, 1 pods)"))
+
+		result = restart(high, changed("shared"))
+		Expect(result.Restarted).To(HaveLen(1))
+		Expect(restarted()).To(BeTrue())
+		conflicting = meta.FindStatusCondition(high.Status.Conditions, "Conflicting")
+		Expect(conflicting).NotTo(BeNil())
+		Expect(conflicting.Status).To(Equal(metav1.ConditionTrue))
+		Expect(conflicting.Message).To(ContainSubstring("this ConfigReloader takes precedence"))
+	})
+
+	It("should restart a shared workload for changes the other ConfigReloader does not react to", func() {
+		build()
+
+		result := restart(low, changed("extra"))
+		Expect(result.Restarted).To(HaveLen(1))
+		Expect(restarted()).To(BeTrue())
+	})
+
+	Context("when the ConfigReloader with precedence selects keys", func() {
+		var shared *corev1.ConfigMap
+
+		BeforeEach(func() {
+			shared = &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: "shared", Namespace: "default", UID: "uid"},
+				Data:       map[string]string{"level": "info", "banner": "hello"},
+			}
+			high.Spec.ConfigMaps[0].Keys = []string{"level"}
+			high.Status.WatchedResources = []configv1.WatchedResource{{
+				Kind: "ConfigMap", Name: "shared", Namespace: "default", ResourceVersion: "1",
+				KeysHash: selectedKeysHash(shared, []string{"level"}),
+			}}
+			objects = []client.Object{shared}
+		})
+
+		// changedFrom returns the change of the shared ConfigMap from the version 1
+		changedFrom := func() []resourceChange {
+			return []resourceChange{{Kind: "ConfigMap", Name: "shared", Namespace: "default", OldVersion: "1", NewVersion: "2"}}
+		}
+
+		It("should restart a shared workload when the selected keys did not change", func() {
+			shared.Data["banner"] = "bye"
+			build()
+
+			Expect(restart(low, changedFrom()).Restarted).To(HaveLen(1))
+			Expect(restarted()).To(BeTrue())
+		})
+
+		It("should leave a shared workload to it when a selected key changed", func() {
+			shared.Data["level"] = "debug"
+			build()
+
+			Expect(restart(low, changedFrom()).Restarted).To(BeEmpty())
+			Expect(restarted()).To(BeFalse())
+		})
+
+		It("should restart a shared workload once it recorded the new version", func() {
+			shared.Data["level"] = "debug"
+			high.Status.WatchedResources[0].ResourceVersion = "2"
+			build()
+
+			Expect(restart(low, changedFrom()).Restarted).To(HaveLen(1))
+		})
+	})
+
+	It("should not defer to a suspended ConfigReloader", func() {
+		high.Spec.Suspend = true
+		build()
+
+		result := restart(low, changed("shared"))
+		Expect(result.Restarted).To(HaveLen(1))
+		Expect(restarted()).To(BeTrue())
+	})
+
+	It("should break priority ties by name", func() {
+		high.Spec.Priority = 0
+		build()
+
+		Expect(Precedes(high, low)).To(BeTrue())
+		Expect(restart(low, changed("shared")).Restarted).To(BeEmpty())
+		Expect(restart(high, changed("shared")).Restarted).To(HaveLen(1))
+	})
+
+	It("should clear the condition once the overlap is gone", func() {
+		build()
+		reconciler.refreshConflicts(ctx, low)
+		Expect(meta.IsStatusConditionTrue(low.Status.Conditions, "Conflicting")).To(BeTrue())
+
+		Expect(reconciler.Delete(ctx, high)).To(Succeed())
+		reconciler.refreshConflicts(ctx, low)
+		conflicting := meta.FindStatusCondition(low.Status.Conditions, "Conflicting")
+		Expect(conflicting).NotTo(BeNil())
+		Expect(conflicting.Status).To(Equal(metav1.ConditionFalse))
+	})
+
+	It("should enqueue only the ConfigReloaders selecting the same pods", func() {
+		build()
+		unrelated := configReloader("unrelated", 0, "shared")
+		unrelated.Spec.Selector.MatchLabels["app"] = "db"
+		Expect(reconciler.Create(ctx, unrelated)).To(Succeed())
+
+		requests := reconciler.overlappingConfigReloaders(ctx, high)
+		Expect(requests).To(HaveLen(1))
+		Expect(requests[0].Name).To(Equal("low"))
+		Expect(reconciler.overlappingConfigReloaders(ctx, unrelated)).To(BeEmpty())
+	})
+
+	It("should not list the pods of the other ConfigReloaders", func() {
+		podLists := 0
+		interceptors.List = func(ctx context.Context, c client.WithWatch, list client.ObjectList,
+			opts ...client.ListOption) error {
+			if _, ok := list.(*corev1.PodList); ok {
+				podLists++
+			}
+			return c.List(ctx, list, opts...)
+		}
+		build()
+		for _, name := range []string{"a", "b", "c"} {
+			Expect(reconciler.Create(ctx, configReloader(name, 0, "shared"))).To(Succeed())
+		}
+
+		reconciler.refreshConflicts(ctx, low)
+		Expect(podLists).To(Equal(1))
+		conflicting := meta.FindStatusCondition(low.Status.Conditions, "Conflicting")
+		Expect(conflicting).NotTo(BeNil())
+		Expect(conflicting.Message).To(ContainSubstring("a (priority 0, 1 pods)"))
+	})
+})
@@ -0,0 +1,135 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	configv1 "github.com/shehbazk/config-reloader-operator/api/v1"
+)
+
+// Event reasons emitted by the ConfigReloader controller
+const (
+	EventReasonChangeDetected     = "ChangeDetected"
+	EventReasonChangeIgnored      = "ChangeIgnored"
+	EventReasonCheckFailed        = "CheckFailed"
+	EventReasonRestartTriggered   = "RestartTriggered"
+	EventReasonRestartSkipped     = "RestartSkipped"
+	EventReasonRestartFailed      = "RestartFailed"
+	EventReasonDryRunRestart      = "DryRunRestart"
+	EventReasonCleanupCompleted   = "CleanupCompleted"
+	EventReasonCleanupFailed      = "CleanupFailed"
+	EventReasonApprovalPending    = "ApprovalPending"
+	EventReasonReloadApproved     = "ReloadApproved"
+	EventReasonApprovalExpired    = "ApprovalExpired"
+	EventReasonApprovalUnverified = "ApprovalUnverified"
+	EventReasonApprovalDiscarded  = "ApprovalDiscarded"
+	EventReasonCanaryStarted      = "CanaryStarted"
+	EventReasonCanaryPromoted     = "CanaryPromoted"
+	EventReasonCanaryAborted      = "CanaryAborted"
+)
+
+// recordEventf emits an Event on obj when an EventRecorder is configured
+func (r *ConfigReloaderReconciler) recordEventf(
+	obj runtime.Object,
+	eventType, reason, messageFmt string,
+	args ...interface{},
+) {
+	if r.Recorder == nil {
+		return
+	}
+	r.Recorder.Eventf(obj, eventType, reason, messageFmt, args...)
+}
+
+// recordChanges emits a ChangeDetected Event on the ConfigReloader for every
+// watched resource whose version changed
+func (r *ConfigReloaderReconciler) recordChanges(cr *configv1.ConfigReloader, changes []resourceChange) {
+	for _, change := range changes {
+		r.recordEventf(cr, corev1.EventTypeNormal, EventReasonChangeDetected, "%s", describeChange(change))
+	}
+}
+
+// recordTargetChanges emits a ChangeDetected Event on every workload and
+// standalone pod about to be restarted for the changes, so that describing
+// them shows which change rolled them
+func (r *ConfigReloaderReconciler) recordTargetChanges(
+	ctx context.Context,
+	cr *configv1.ConfigReloader,
+	targets []restartTarget,
+	changes []resourceChange,
+) {
+	if r.Recorder == nil || len(changes) == 0 {
+		return
+	}
+	described := make([]string, 0, len(changes))
+	for _, change := range changes {
+		described = append(described, describeChange(change))
+	}
+
+	seen := make(map[string]bool, len(targets))
+	for _, target := range targets {
+		kind, name := targetStatusRef(target)
+		if seen[kind+"/"+name] {
+			continue
+		}
+		seen[kind+"/"+name] = true
+
+		var obj client.Object = target.Pod
+		if target.WorkloadKind != "" {
+			if obj = r.eventWorkload(ctx, target.Pod.Namespace, kind, name); obj == nil {
+				continue
+			}
+		}
+		r.recordEventf(obj, corev1.EventTypeNormal, EventReasonChangeDetected,
+			"ConfigReloader %s/%s detected a change: %s", cr.Namespace, cr.Name, strings.Join(described, ", "))
+	}
+}
+
+// eventWorkload returns the workload an Event is emitted on, or nil when it
+// cannot be read
+func (r *ConfigReloaderReconciler) eventWorkload(ctx context.Context, namespace, kind, name string) client.Object {
+	workload, _ := newWorkload(kind)
+	if workload == nil {
+		return nil
+	}
+	if err := r.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, workload); err != nil {
+		return nil
+	}
+	return workload
+}
+
+// describeChange describes a changed resource with its versions
+func describeChange(change resourceChange) string {
+	return fmt.Sprintf("%s %s/%s changed%s (resourceVersion %s -> %s)",
+		change.Kind, change.Namespace, change.Name, changedBy(change),
+		versionOrNone(change.OldVersion), change.NewVersion)
+}
+
+// describeChanges summarises the changed resources for Event and log messages
+func describeChanges(changes []resourceChange) string {
+	names := make([]string, 0, len(changes))
+	for _, change := range changes {
+		names = append(names, fmt.Sprintf("%s %s/%s changed%s", change.Kind, change.Namespace, change.Name, changedBy(change)))
+	}
+	return strings.Join(names, ", ")
+}
+
+// changedBy returns " by <manager>" for attributed changes
+func changedBy(change resourceChange) string {
+	if change.ChangedBy == nil || change.ChangedBy.Manager == "" {
+		return ""
+	}
+	return " by " + change.ChangedBy.Manager
+}
+
+func versionOrNone(version string) string {
+	if version == "" {
+		return "<none>"
+	}
+	return version
+}
@@ -0,0 +1,190 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	configv1 "github.com/shehbazk/config-reloader-operator/api/v1"
+)
+
+var _ = Describe("Restart Events", func() {
+	const changeDetected = "Normal ChangeDetected ConfigReloader default/web detected a change: " +
+		"ConfigMap default/app changed (resourceVersion 1 -> 2)"
+
+	var (
+		ctx          context.Context
+		scheme       *runtime.Scheme
+		cr           *configv1.ConfigReloader
+		objects      []client.Object
+		interceptors interceptor.Funcs
+		changes      []resourceChange
+	)
+
+	// pod returns a pod using the watched ConfigMap, owned by the given
+	// workload when ownerKind is set
+	pod := func(name, ownerKind, owner string) *corev1.Pod {
+		p := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+			Spec: corev1.PodSpec{
+				Containers: []corev1.Container{{Name: "app", EnvFrom: []corev1.EnvFromSource{
+					{ConfigMapRef: &corev1.ConfigMapEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: "app"}}},
+				}}},
+			},
+		}
+		if ownerKind != "" {
+			p.OwnerReferences = []metav1.OwnerReference{{APIVersion: "apps/v1", Kind: ownerKind, Name: owner}}
+		}
+		return p
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		scheme = runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+		Expect(configv1.AddToScheme(scheme)).To(Succeed())
+
+		cr = &configv1.ConfigReloader{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+			Spec: configv1.ConfigReloaderSpec{
+				ConfigMaps:    []configv1.ResourceRef{{Name: "app"}},
+				RestartPolicy: configv1.RestartPolicyAnnotation,
+			},
+		}
+		objects = []client.Object{
+			&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"}},
+			pod("web-1", "Deployment", "web"),
+			pod("web-2", "Deployment", "web"),
+		}
+		interceptors = interceptor.Funcs{}
+		changes = []resourceChange{{Kind: "ConfigMap", Name: "app", Namespace: "default", OldVersion: "1", NewVersion: "2"}}
+	})
+
+	// reload restarts the affected pods and returns the emitted Events
+	reload := func() []string {
+		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objects...).
+			WithInterceptorFuncs(interceptors).
+			Build()
+		recorder := record.NewFakeRecorder(20)
+		reconciler := &ConfigReloaderReconciler{Client: c, Scheme: scheme, Recorder: recorder}
+		_, err := reconciler.restartAffectedPods(ctx, cr, changes, "ConfigMap default/app changed")
+		Expect(err).NotTo(HaveOccurred())
+
+		var events []string
+		for len(recorder.Events) > 0 {
+			events = append(events, <-recorder.Events)
+		}
+		return events
+	}
+
+	It("should record the change and the restart on the workload once", func() {
+		events := reload()
+		Expect(events).To(ContainElement(changeDetected))
+		Expect(events).To(ContainElement(HavePrefix(
+			"Normal RestartTriggered Rolling restart triggered by ConfigReloader default/web")))
+
+		detected := 0
+		for _, event := range events {
+			if event == changeDetected {
+				detected++
+			}
+		}
+		Expect(detected).To(Equal(1))
+	})
+
+	It("should record the change and the deletion on standalone pods", func() {
+		cr.Spec.RestartPolicy = configv1.RestartPolicyDelete
+		objects = []client.Object{pod("standalone", "", "")}
+
+		events := reload()
+		Expect(events).To(ContainElement(changeDetected))
+		Expect(events).To(ContainElement(HavePrefix("Normal RestartTriggered Pod deleted by ConfigReloader default/web")))
+	})
+
+	It("should record a skipped restart on standalone pods with the annotation policy", func() {
+		objects = []client.Object{pod("standalone", "", "")}
+
+		events := reload()
+		Expect(events).To(ContainElement(HavePrefix(
+			"Warning RestartSkipped ConfigReloader default/web annotated the pod but cannot restart standalone pods")))
+	})
+
+	It("should record a skipped restart on pods of workloads that cannot be rolled", func() {
+		objects = []client.Object{pod("job-1", "Job", "migrate")}
+
+		events := reload()
+		Expect(events).To(ContainElement(
+			"Normal RestartSkipped ConfigReloader default/web cannot restart pods owned by Job migrate with annotation policy"))
+		Expect(events).NotTo(ContainElement(changeDetected))
+	})
+
+	It("should record a failed workload restart on the workload and the pod", func() {
+		interceptors.Update = func(ctx context.Context, c client.WithWatch, obj client.Object,
+			opts ...client.UpdateOption) error {
+			if _, ok := obj.(*appsv1.Deployment); ok {
+				return errors.New("forbidden")
+			}
+			return c.Update(ctx, obj, opts...)
+		}
+
+		events := reload()
+		Expect(events).To(ContainElement(
+			"Warning RestartFailed Rolling restart by ConfigReloader default/web failed: " +
+				"failed to update Deployment default/web: forbidden"))
+		Expect(events).To(ContainElement(HavePrefix(
+			"Warning RestartFailed ConfigReloader default/web failed to restart Deployment web")))
+	})
+
+	It("should record a failed annotation on standalone pods", func() {
+		objects = []client.Object{pod("standalone", "", "")}
+		interceptors.Update = func(ctx context.Context, c client.WithWatch, obj client.Object,
+			opts ...client.UpdateOption) error {
+			return errors.New("forbidden")
+		}
+
+		events := reload()
+		Expect(events).To(ContainElement(
+			"Warning RestartFailed ConfigReloader default/web failed to annotate the pod: forbidden"))
+	})
+
+	It("should record a failed deletion on the pod", func() {
+		cr.Spec.RestartPolicy = configv1.RestartPolicyDelete
+		interceptors.Delete = func(ctx context.Context, c client.WithWatch, obj client.Object,
+			opts ...client.DeleteOption) error {
+			return errors.New("forbidden")
+		}
+
+		events := reload()
+		Expect(events).To(ContainElement(
+			"Warning RestartFailed ConfigReloader default/web failed to delete the pod: forbidden"))
+		Expect(events).To(ContainElement(
+			"Warning RestartFailed Failed to delete pod default/web-1: forbidden"))
+	})
+})
@@ -0,0 +1,244 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	configv1 "github.com/shehbazk/config-reloader-operator/api/v1"
+)
+
+// Relations between the nodes of the dependency graph
+const (
+	// RelationTriggers links a watched ConfigMap or Secret to its ConfigReloader
+	RelationTriggers = "triggers"
+	// RelationRestarts links a ConfigReloader to the workloads, or pods without
+	// a workload, it restarts
+	RelationRestarts = "restarts"
+	// RelationRuns links a workload to its pods
+	RelationRuns = "runs"
+	// RelationUses links a watched ConfigMap or Secret to the workloads whose
+	// pod template references it, whether a ConfigReloader restarts them or not
+	RelationUses = "uses"
+)
+
+// Graph is the ConfigMap/Secret → ConfigReloader → workload → pod dependency
+// graph, with nodes and edges ordered by ID. Watched resources are also linked
+// to the workloads using them.
+type Graph struct {
+	Nodes []GraphNode `json:"nodes"`
+	Edges []GraphEdge `json:"edges"`
+}
+
+// GraphNode is a ConfigMap, Secret, ConfigReloader, workload or pod
+type GraphNode struct {
+	// ID is kind/namespace/name
+	ID        string `json:"id"`
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+}
+
+// GraphEdge links two nodes by ID
+type GraphEdge struct {
+	From     string `json:"from"`
+	To       string `json:"to"`
+	Relation string `json:"relation"`
+}
+
+// graphBuilder deduplicates the nodes and edges of a graph
+type graphBuilder struct {
+	nodes map[string]GraphNode
+	edges map[GraphEdge]bool
+}
+
+func (b *graphBuilder) node(kind, namespace, name string) string {
+	id := kind + "/" + namespace + "/" + name
+	b.nodes[id] = GraphNode{ID: id, Kind: kind, Namespace: namespace, Name: name}
+	return id
+}
+
+// nodeFromKey adds the node of a namespace/name key
+func (b *graphBuilder) nodeFromKey(kind, key string) string {
+	namespace, name, _ := strings.Cut(key, "/")
+	return b.node(kind, namespace, name)
+}
+
+func (b *graphBuilder) edge(from, to, relation string) {
+	b.edges[GraphEdge{From: from, To: to, Relation: relation}] = true
+}
+
+func (b *graphBuilder) graph() *Graph {
+	g := &Graph{Nodes: make([]GraphNode, 0, len(b.nodes)), Edges: make([]GraphEdge, 0, len(b.edges))}
+	for _, node := range b.nodes {
+		g.Nodes = append(g.Nodes, node)
+	}
+	for edge := range b.edges {
+		g.Edges = append(g.Edges, edge)
+	}
+	sort.Slice(g.Nodes, func(i, j int) bool {
+		return g.Nodes[i].ID < g.Nodes[j].ID
+	})
+	sort.Slice(g.Edges, func(i, j int) bool {
+		if g.Edges[i].From != g.Edges[j].From {
+			return g.Edges[i].From < g.Edges[j].From
+		}
+		return g.Edges[i].To < g.Edges[j].To
+	})
+	return g
+}
+
+// BuildGraph returns the dependency graph of the ConfigReloaders in the
+// namespace, or in all namespaces when empty. Everything is read through the
+// client of the reconciler, so the manager's informer cache serves the graph.
+// Watched resources in other namespaces are part of the graph.
+func (r *ConfigReloaderReconciler) BuildGraph(ctx context.Context, namespace string) (*Graph, error) {
+	logger := log.FromContext(ctx)
+
+	var configReloaders configv1.ConfigReloaderList
+	if err := r.List(ctx, &configReloaders, client.InNamespace(namespace)); err != nil {
+		return nil, fmt.Errorf("failed to list ConfigReloaders: %w", err)
+	}
+
+	b := &graphBuilder{nodes: make(map[string]GraphNode), edges: make(map[GraphEdge]bool)}
+	for i := range configReloaders.Items {
+		cr := &configReloaders.Items[i]
+		crID := b.node("ConfigReloader", cr.Namespace, cr.Name)
+
+		watchedCMs, watchedSecrets := buildWatchedResourcesMaps(cr)
+		for key := range watchedCMs {
+			b.edge(b.nodeFromKey("ConfigMap", key), crID, RelationTriggers)
+			r.addUsers(ctx, b, "ConfigMap", key)
+		}
+		for key := range watchedSecrets {
+			b.edge(b.nodeFromKey("Secret", key), crID, RelationTriggers)
+			r.addUsers(ctx, b, "Secret", key)
+		}
+
+		pods, err := r.affectedPods(ctx, cr)
+		if err != nil {
+			// A ConfigReloader with an invalid selector still shows its triggers
+			logger.Error(err, "failed to resolve affected pods", "configReloader", cr.Namespace+"/"+cr.Name)
+			continue
+		}
+		for _, pod := range pods {
+			podID := b.node("Pod", pod.Namespace, pod.Name)
+			if len(pod.OwnerReferences) == 0 {
+				b.edge(crID, podID, RelationRestarts)
+				continue
+			}
+			kind, name, err := r.resolveOwningWorkload(ctx, pod)
+			if err != nil {
+				logger.Error(err, "failed to resolve owning workload", "pod", pod.Namespace+"/"+pod.Name)
+				b.edge(crID, podID, RelationRestarts)
+				continue
+			}
+			workloadID := b.node(kind, pod.Namespace, name)
+			b.edge(crID, workloadID, RelationRestarts)
+			b.edge(workloadID, podID, RelationRuns)
+		}
+	}
+	return b.graph(), nil
+}
+
+// addUsers links a watched ConfigMap or Secret to the workloads using it. The
+// users are only looked up once the field indexes are registered.
+func (r *ConfigReloaderReconciler) addUsers(ctx context.Context, b *graphBuilder, kind, key string) {
+	if !r.indexed {
+		return
+	}
+	resourceID := b.nodeFromKey(kind, key)
+	namespace, name, _ := strings.Cut(key, "/")
+	users, err := FindUsers(ctx, r, kind, namespace, name)
+	if err != nil {
+		log.FromContext(ctx).Error(err, "failed to find the users", "kind", kind, "resource", key)
+		return
+	}
+	for _, deployment := range users.Deployments {
+		b.edge(resourceID, b.node("Deployment", deployment.Namespace, deployment.Name), RelationUses)
+	}
+	for _, statefulSet := range users.StatefulSets {
+		b.edge(resourceID, b.node("StatefulSet", statefulSet.Namespace, statefulSet.Name), RelationUses)
+	}
+	for _, daemonSet := range users.DaemonSets {
+		b.edge(resourceID, b.node("DaemonSet", daemonSet.Namespace, daemonSet.Name), RelationUses)
+	}
+}
+
+// graphShapes are the Graphviz shapes of the node kinds, workloads are drawn
+// as components
+var graphShapes = map[string]string{
+	"ConfigMap":      "note",
+	"Secret":         "note",
+	"ConfigReloader": "box",
+	"Pod":            "ellipse",
+}
+
+// WriteDOT writes the graph in the Graphviz DOT language
+func (g *Graph) WriteDOT(w io.Writer) error {
+	if _, err := fmt.Fprintln(w, "digraph config {\n  rankdir=LR;"); err != nil {
+		return err
+	}
+	for _, node := range g.Nodes {
+		shape, ok := graphShapes[node.Kind]
+		if !ok {
+			shape = "component"
+		}
+		label := fmt.Sprintf("%s\n%s/%s", node.Kind, node.Namespace, node.Name)
+		if _, err := fmt.Fprintf(w, "  %q [label=%q, shape=%s];\n", node.ID, label, shape); err != nil {
+			return err
+		}
+	}
+	for _, edge := range g.Edges {
+		if _, err := fmt.Fprintf(w, "  %q -> %q [label=%q];\n", edge.From, edge.To, edge.Relation); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintln(w, "}")
+	return err
+}
+
+// GraphHandler serves the dependency graph. The namespace query parameter
+// restricts it to the ConfigReloaders of a namespace, and format selects json
+// (the default) or dot.
+func (r *ConfigReloaderReconciler) GraphHandler() http.Handler {
+	logger := log.Log.WithName("graph")
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		format := req.URL.Query().Get("format")
+		if format != "" && format != "json" && format != "dot" {
+			http.Error(w, fmt.Sprintf("unsupported format %q, expected json or dot", format), http.StatusBadRequest)
+			return
+		}
+
+		ctx := log.IntoContext(req.Context(), logger)
+		graph, err := r.BuildGraph(ctx, req.URL.Query().Get("namespace"))
+		if err != nil {
+			logger.Error(err, "failed to build dependency graph")
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		if format == "dot" {
+			w.Header().Set("Content-Type", "text/vnd.graphviz; charset=utf-8")
+			err = graph.WriteDOT(w)
+		} else {
+			w.Header().Set("Content-Type", "application/json")
+			err = json.NewEncoder(w).Encode(graph)
+		}
+		if err != nil {
+			logger.Error(err, "failed to write dependency graph")
+		}
+	})
+}
@@ -0,0 +1,207 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	configv1 "github.com/shehbazk/config-reloader-operator/api/v1"
+)
+
+var _ = Describe("Dependency graph", func() {
+	var (
+		ctx        context.Context
+		reconciler *ConfigReloaderReconciler
+	)
+
+	envFrom := func(configMap string) []corev1.Container {
+		return []corev1.Container{{Name: "app", EnvFrom: []corev1.EnvFromSource{
+			{ConfigMapRef: &corev1.ConfigMapEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: configMap}}},
+		}}}
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		scheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+		Expect(configv1.AddToScheme(scheme)).To(Succeed())
+
+		web := &configv1.ConfigReloader{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+			Spec: configv1.ConfigReloaderSpec{
+				ConfigMaps: []configv1.ResourceRef{{Name: "web-config"}},
+				Secrets:    []configv1.ResourceRef{{Name: "tls", Namespace: "shared"}},
+				Selector:   &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+			},
+		}
+		other := &configv1.ConfigReloader{
+			ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "other"},
+			Spec:       configv1.ConfigReloaderSpec{ConfigMaps: []configv1.ResourceRef{{Name: "api-config"}}},
+		}
+		deployment := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"}}
+		replicaSet := &appsv1.ReplicaSet{ObjectMeta: metav1.ObjectMeta{
+			Name:            "web-7d9c6b5f4",
+			Namespace:       "default",
+			OwnerReferences: []metav1.OwnerReference{{APIVersion: "apps/v1", Kind: "Deployment", Name: "web"}},
+		}}
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            "web-7d9c6b5f4-x2kqp",
+				Namespace:       "default",
+				Labels:          map[string]string{"app": "web"},
+				OwnerReferences: []metav1.OwnerReference{{APIVersion: "apps/v1", Kind: "ReplicaSet", Name: "web-7d9c6b5f4"}},
+			},
+			Spec: corev1.PodSpec{Containers: envFrom("web-config")},
+		}
+		standalone := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "debug", Namespace: "default", Labels: map[string]string{"app": "web"}},
+			Spec:       corev1.PodSpec{Containers: envFrom("web-config")},
+		}
+		unrelated := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "default", Labels: map[string]string{"app": "web"}},
+			Spec:       corev1.PodSpec{Containers: envFrom("db-config")},
+		}
+		c := fake.NewClientBuilder().WithScheme(scheme).
+			WithObjects(web, other, deployment, replicaSet, pod, standalone, unrelated).
+			Build()
+		reconciler = &ConfigReloaderReconciler{Client: c, Scheme: scheme}
+	})
+
+	It("should link the watched resources, ConfigReloaders, workloads and pods", func() {
+		graph, err := reconciler.BuildGraph(ctx, "default")
+		Expect(err).NotTo(HaveOccurred())
+
+		var ids []string
+		for _, node := range graph.Nodes {
+			ids = append(ids, node.ID)
+		}
+		Expect(ids).To(Equal([]string{
+			"ConfigMap/default/web-config",
+			"ConfigReloader/default/web",
+			"Deployment/default/web",
+			"Pod/default/debug",
+			"Pod/default/web-7d9c6b5f4-x2kqp",
+			"Secret/shared/tls",
+		}))
+		Expect(graph.Edges).To(Equal([]GraphEdge{
+			{From: "ConfigMap/default/web-config", To: "ConfigReloader/default/web", Relation: RelationTriggers},
+			{From: "ConfigReloader/default/web", To: "Deployment/default/web", Relation: RelationRestarts},
+			{From: "ConfigReloader/default/web", To: "Pod/default/debug", Relation: RelationRestarts},
+			{From: "Deployment/default/web", To: "Pod/default/web-7d9c6b5f4-x2kqp", Relation: RelationRuns},
+			{From: "Secret/shared/tls", To: "ConfigReloader/default/web", Relation: RelationTriggers},
+		}))
+	})
+
+	It("should link the watched resources to the workloads using them once indexed", func() {
+		scheme := reconciler.Scheme
+		web := &configv1.ConfigReloader{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+			Spec: configv1.ConfigReloaderSpec{
+				ConfigMaps: []configv1.ResourceRef{{Name: "web-config"}},
+				Selector:   &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+			},
+		}
+		template := func(configMap string) corev1.PodTemplateSpec {
+			return corev1.PodTemplateSpec{Spec: corev1.PodSpec{Containers: envFrom(configMap)}}
+		}
+		// The batch StatefulSet uses the ConfigMap without being selected
+		batch := &appsv1.StatefulSet{
+			ObjectMeta: metav1.ObjectMeta{Name: "batch", Namespace: "default"},
+			Spec:       appsv1.StatefulSetSpec{Template: template("web-config")},
+		}
+		db := &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "default"},
+			Spec:       appsv1.DeploymentSpec{Template: template("db-config")},
+		}
+		builder := fake.NewClientBuilder().WithScheme(scheme).WithObjects(web, batch, db)
+		for _, obj := range []client.Object{&corev1.Pod{}, &appsv1.Deployment{}, &appsv1.StatefulSet{}, &appsv1.DaemonSet{}} {
+			builder = builder.WithIndex(obj, PodConfigMapIndex, indexPodConfigMaps).
+				WithIndex(obj, PodSecretIndex, indexPodSecrets)
+		}
+		reconciler = &ConfigReloaderReconciler{Client: builder.Build(), Scheme: scheme, indexed: true}
+
+		graph, err := reconciler.BuildGraph(ctx, "default")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(graph.Edges).To(Equal([]GraphEdge{
+			{From: "ConfigMap/default/web-config", To: "ConfigReloader/default/web", Relation: RelationTriggers},
+			{From: "ConfigMap/default/web-config", To: "StatefulSet/default/batch", Relation: RelationUses},
+		}))
+	})
+
+	It("should include every namespace when none is given", func() {
+		graph, err := reconciler.BuildGraph(ctx, "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(graph.Nodes).To(ContainElement(GraphNode{
+			ID: "ConfigReloader/other/api", Kind: "ConfigReloader", Namespace: "other", Name: "api",
+		}))
+		Expect(graph.Edges).To(ContainElement(GraphEdge{
+			From: "ConfigMap/other/api-config", To: "ConfigReloader/other/api", Relation: RelationTriggers,
+		}))
+	})
+
+	Describe("GraphHandler", func() {
+		serve := func(method, target string) *httptest.ResponseRecorder {
+			recorder := httptest.NewRecorder()
+			reconciler.GraphHandler().ServeHTTP(recorder, httptest.NewRequest(method, target, nil))
+			return recorder
+		}
+
+		It("should serve the graph of a namespace as JSON", func() {
+			recorder := serve(http.MethodGet, "/graph?namespace=other")
+			Expect(recorder.Code).To(Equal(http.StatusOK))
+			Expect(recorder.Header().Get("Content-Type")).To(Equal("application/json"))
+
+			var graph Graph
+			Expect(json.Unmarshal(recorder.Body.Bytes(), &graph)).To(Succeed())
+			Expect(graph.Nodes).To(HaveLen(2))
+			Expect(graph.Edges).To(Equal([]GraphEdge{
+				{From: "ConfigMap/other/api-config", To: "ConfigReloader/other/api", Relation: RelationTriggers},
+			}))
+		})
+
+		It("should serve the graph as Graphviz DOT", func() {
+			recorder := serve(http.MethodGet, "/graph?namespace=other&format=dot")
+			Expect(recorder.Code).To(Equal(http.StatusOK))
+			Expect(recorder.Header().Get("Content-Type")).To(HavePrefix("text/vnd.graphviz"))
+			Expect(recorder.Body.String()).To(Equal(`digraph config {
+  rankdir=LR;
+  "ConfigMap/other/api-config" [label="ConfigMap\nother/api-config", shape=note];
+  "ConfigReloader/other/api" [label="ConfigReloader\nother/api", shape=box];
+  "ConfigMap/other/api-config" -> "ConfigReloader/other/api" [label="triggers"];
+}
+`))
+		})
+
+		It("should reject unknown formats and methods", func() {
+			Expect(serve(http.MethodGet, "/graph?format=svg").Code).To(Equal(http.StatusBadRequest))
+			Expect(serve(http.MethodPost, "/graph").Code).To(Equal(http.StatusMethodNotAllowed))
+		})
+	})
+})
@@ -4,6 +4,7 @@ import (
 	"context"
 
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/util/workqueue"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -12,8 +13,11 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	configv1 "github.com/shehbazk/config-reloader-operator/api/v1"
+	"github.com/shehbazk/config-reloader-operator/internal/podrefs"
 )
 
+// ConfigMapSecretHandler enqueues the ConfigReloaders watching a changed
+// ConfigMap or Secret
 type ConfigMapSecretHandler struct {
 	Client client.Client
 }
@@ -38,72 +42,61 @@ func (h *ConfigMapSecretHandler) Generic(ctx context.Context, evt event.TypedGen
 	h.enqueueConfigReloaders(ctx, evt.Object, q)
 }
 
+// enqueueConfigReloaders enqueues the ConfigReloaders watching obj, found
+// with the field indexes registered by SetupIndexes
 func (h *ConfigMapSecretHandler) enqueueConfigReloaders(ctx context.Context, obj client.Object,
 	q workqueue.TypedRateLimitingInterface[reconcile.Request]) {
 	logger := log.FromContext(ctx)
 
-	var configReloaderList configv1.ConfigReloaderList
-	if err := h.Client.List(ctx, &configReloaderList); err != nil {
-		logger.Error(err, "failed to list ConfigReloaders")
-		return
-	}
-
 	resourceName := obj.GetName()
 	resourceNamespace := obj.GetNamespace()
-	var resourceKind string
+	var resourceKind, index string
 
-	switch obj.(type) {
-	case *corev1.ConfigMap:
-		resourceKind = "ConfigMap"
-	case *corev1.Secret:
-		resourceKind = "Secret"
+	switch watchedKind(obj) {
+	case "ConfigMap":
+		resourceKind, index = "ConfigMap", ConfigMapRefIndex
+	case "Secret":
+		resourceKind, index = "Secret", SecretRefIndex
 	default:
 		return
 	}
 
-	for _, cr := range configReloaderList.Items {
-		if h.configReloaderWatchesResource(&cr, resourceKind, resourceName, resourceNamespace) {
-			logger.Info("Enqueueing ConfigReloader due to resource change",
-				"configReloader", cr.Name,
-				"namespace", cr.Namespace,
-				"resourceKind", resourceKind,
-				"resourceName", resourceName,
-				"resourceNamespace", resourceNamespace)
-
-			q.Add(reconcile.Request{
-				NamespacedName: types.NamespacedName{
-					Name:      cr.Name,
-					Namespace: cr.Namespace,
-				},
-			})
-		}
+	var configReloaderList configv1.ConfigReloaderList
+	if err := h.Client.List(ctx, &configReloaderList,
+		client.MatchingFields{index: podrefs.Key(resourceNamespace, resourceName)}); err != nil {
+		logger.Error(err, "failed to list ConfigReloaders", "index", index)
+		return
 	}
-}
 
-func (h *ConfigMapSecretHandler) configReloaderWatchesResource(cr *configv1.ConfigReloader, resourceKind, resourceName, resourceNamespace string) bool {
-	if resourceKind == "ConfigMap" {
-		for _, cmRef := range cr.Spec.ConfigMaps {
-			namespace := cmRef.Namespace
-			if namespace == "" {
-				namespace = cr.Namespace
-			}
-			if cmRef.Name == resourceName && namespace == resourceNamespace {
-				return true
-			}
-		}
+	for _, cr := range configReloaderList.Items {
+		logger.Info("Enqueueing ConfigReloader due to resource change",
+			"configReloader", cr.Name,
+			"namespace", cr.Namespace,
+			"resourceKind", resourceKind,
+			"resourceName", resourceName,
+			"resourceNamespace", resourceNamespace)
+
+		q.Add(reconcile.Request{
+			NamespacedName: types.NamespacedName{
+				Name:      cr.Name,
+				Namespace: cr.Namespace,
+			},
+		})
 	}
+}
 
-	if resourceKind == "Secret" {
-		for _, secretRef := range cr.Spec.Secrets {
-			namespace := secretRef.Namespace
-			if namespace == "" {
-				namespace = cr.Namespace
-			}
-			if secretRef.Name == resourceName && namespace == resourceNamespace {
-				return true
-			}
+// watchedKind returns the kind of a ConfigMap or Secret, which metadata-only
+// watches deliver as PartialObjectMetadata
+func watchedKind(obj client.Object) string {
+	switch o := obj.(type) {
+	case *corev1.ConfigMap:
+		return "ConfigMap"
+	case *corev1.Secret:
+		return "Secret"
+	case *metav1.PartialObjectMetadata:
+		if o.GroupVersionKind().Group == corev1.GroupName {
+			return o.Kind
 		}
 	}
-
-	return false
+	return ""
 }
@@ -0,0 +1,207 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	configv1 "github.com/shehbazk/config-reloader-operator/api/v1"
+)
+
+var _ = Describe("ConfigMapSecretHandler", func() {
+	var (
+		ctx     context.Context
+		handler *ConfigMapSecretHandler
+		queue   workqueue.TypedRateLimitingInterface[reconcile.Request]
+	)
+
+	newConfigReloader := func(name, namespace string, configMaps, secrets []configv1.ResourceRef) *configv1.ConfigReloader {
+		return &configv1.ConfigReloader{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+			Spec:       configv1.ConfigReloaderSpec{ConfigMaps: configMaps, Secrets: secrets},
+		}
+	}
+
+	// queued drains the queue
+	queued := func() []types.NamespacedName {
+		var names []types.NamespacedName
+		for queue.Len() > 0 {
+			req, _ := queue.Get()
+			names = append(names, req.NamespacedName)
+			queue.Done(req)
+		}
+		return names
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		scheme := runtime.NewScheme()
+		Expect(configv1.AddToScheme(scheme)).To(Succeed())
+
+		c := fake.NewClientBuilder().WithScheme(scheme).
+			WithIndex(&configv1.ConfigReloader{}, ConfigMapRefIndex, indexConfigMapRefs).
+			WithIndex(&configv1.ConfigReloader{}, SecretRefIndex, indexSecretRefs).
+			WithObjects(
+				newConfigReloader("local", "default", []configv1.ResourceRef{{Name: "app"}}, nil),
+				newConfigReloader("remote", "other", []configv1.ResourceRef{{Name: "app", Namespace: "default"}}, nil),
+				newConfigReloader("secrets", "default", nil, []configv1.ResourceRef{{Name: "app"}}),
+				newConfigReloader("unrelated", "default", []configv1.ResourceRef{{Name: "db"}}, nil),
+			).Build()
+		handler = &ConfigMapSecretHandler{Client: c}
+		queue = workqueue.NewTypedRateLimitingQueue(workqueue.DefaultTypedControllerRateLimiter[reconcile.Request]())
+		DeferCleanup(queue.ShutDown)
+	})
+
+	It("should enqueue the ConfigReloaders watching a ConfigMap, in any namespace", func() {
+		cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default"}}
+		handler.enqueueConfigReloaders(ctx, cm, queue)
+
+		Expect(queued()).To(ConsistOf(
+			types.NamespacedName{Name: "local", Namespace: "default"},
+			types.NamespacedName{Name: "remote", Namespace: "other"},
+		))
+	})
+
+	It("should look up Secrets separately from ConfigMaps", func() {
+		secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default"}}
+		handler.enqueueConfigReloaders(ctx, secret, queue)
+
+		Expect(queued()).To(ConsistOf(types.NamespacedName{Name: "secrets", Namespace: "default"}))
+	})
+
+	It("should enqueue nothing for resources that are not watched", func() {
+		cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "other"}}
+		handler.enqueueConfigReloaders(ctx, cm, queue)
+
+		Expect(queued()).To(BeEmpty())
+	})
+})
+
+// indexerClient serves ConfigReloader lists from a client-go indexer like the
+// manager's informer cache does. The fake client filters field selectors by
+// scanning every object, so it cannot show the cost of an indexed lookup.
+// Without indexes, every object is scanned as the handler used to do.
+type indexerClient struct {
+	client.Client
+	store   cache.Indexer
+	indexed bool
+}
+
+func newIndexerClient(indexed bool, crs []*configv1.ConfigReloader) *indexerClient {
+	indexers := cache.Indexers{}
+	if indexed {
+		indexers[ConfigMapRefIndex] = func(obj interface{}) ([]string, error) {
+			return indexConfigMapRefs(obj.(client.Object)), nil
+		}
+		indexers[SecretRefIndex] = func(obj interface{}) ([]string, error) {
+			return indexSecretRefs(obj.(client.Object)), nil
+		}
+	}
+	store := cache.NewIndexer(cache.MetaNamespaceKeyFunc, indexers)
+	for _, cr := range crs {
+		if err := store.Add(cr); err != nil {
+			panic(err)
+		}
+	}
+	return &indexerClient{store: store, indexed: indexed}
+}
+
+func (r *indexerClient) List(_ context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	listOpts := (&client.ListOptions{}).ApplyOptions(opts)
+	requirement := listOpts.FieldSelector.Requirements()[0]
+
+	var objs []interface{}
+	if r.indexed {
+		var err error
+		if objs, err = r.store.ByIndex(requirement.Field, requirement.Value); err != nil {
+			return err
+		}
+	} else {
+		index := indexConfigMapRefs
+		if requirement.Field == SecretRefIndex {
+			index = indexSecretRefs
+		}
+		for _, obj := range r.store.List() {
+			for _, key := range index(obj.(client.Object)) {
+				if key == requirement.Value {
+					objs = append(objs, obj)
+					break
+				}
+			}
+		}
+	}
+
+	items := make([]configv1.ConfigReloader, 0, len(objs))
+	for _, obj := range objs {
+		items = append(items, *obj.(*configv1.ConfigReloader))
+	}
+	list.(*configv1.ConfigReloaderList).Items = items
+	return nil
+}
+
+// BenchmarkEnqueueConfigReloaders compares resolving the ConfigReloaders of a
+// changed ConfigMap with and without the field indexes. Every ConfigReloader
+// watches its own ConfigMap and one shared by its namespace.
+func BenchmarkEnqueueConfigReloaders(b *testing.B) {
+	const namespaces = 10
+
+	for _, count := range []int{100, 1000, 5000} {
+		crs := make([]*configv1.ConfigReloader, count)
+		for i := range crs {
+			crs[i] = &configv1.ConfigReloader{
+				ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("cr-%d", i), Namespace: fmt.Sprintf("ns-%d", i%namespaces)},
+				Spec: configv1.ConfigReloaderSpec{
+					ConfigMaps: []configv1.ResourceRef{{Name: fmt.Sprintf("cm-%d", i)}, {Name: "shared"}},
+				},
+			}
+		}
+		changed := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{
+			Name: fmt.Sprintf("cm-%d", count/2), Namespace: fmt.Sprintf("ns-%d", (count/2)%namespaces),
+		}}
+
+		for _, indexed := range []bool{false, true} {
+			name := "scan"
+			if indexed {
+				name = "indexed"
+			}
+			b.Run(fmt.Sprintf("%s/crs=%d", name, count), func(b *testing.B) {
+				handler := &ConfigMapSecretHandler{Client: newIndexerClient(indexed, crs)}
+				queue := workqueue.NewTypedRateLimitingQueue(workqueue.DefaultTypedControllerRateLimiter[reconcile.Request]())
+				defer queue.ShutDown()
+
+				ctx := context.Background()
+				for b.Loop() {
+					handler.enqueueConfigReloaders(ctx, changed, queue)
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	configv1 "github.com/shehbazk/config-reloader-operator/api/v1"
+	"github.com/shehbazk/config-reloader-operator/internal/podrefs"
+)
+
+// Field indexes on ConfigReloaders, keyed by the namespace/name of every
+// watched ConfigMap or Secret
+const (
+	ConfigMapRefIndex = "spec.configMaps"
+	SecretRefIndex    = "spec.secrets"
+)
+
+// Field indexes on pods and workloads, keyed by the namespace/name of every
+// ConfigMap or Secret referenced by their pod spec
+const (
+	PodConfigMapIndex = "spec.configMapRefs"
+	PodSecretIndex    = "spec.secretRefs"
+)
+
+// SetupIndexes registers the field indexes used to find the ConfigReloaders
+// watching a ConfigMap or Secret, and the pods and workloads using one
+func SetupIndexes(ctx context.Context, indexer client.FieldIndexer) error {
+	if err := indexer.IndexField(ctx, &configv1.ConfigReloader{}, ConfigMapRefIndex, indexConfigMapRefs); err != nil {
+		return fmt.Errorf("failed to index %s: %w", ConfigMapRefIndex, err)
+	}
+	if err := indexer.IndexField(ctx, &configv1.ConfigReloader{}, SecretRefIndex, indexSecretRefs); err != nil {
+		return fmt.Errorf("failed to index %s: %w", SecretRefIndex, err)
+	}
+
+	for _, obj := range []client.Object{&corev1.Pod{}, &appsv1.Deployment{}, &appsv1.StatefulSet{}, &appsv1.DaemonSet{}} {
+		if err := indexer.IndexField(ctx, obj, PodConfigMapIndex, indexPodConfigMaps); err != nil {
+			return fmt.Errorf("failed to index %s of %T: %w", PodConfigMapIndex, obj, err)
+		}
+		if err := indexer.IndexField(ctx, obj, PodSecretIndex, indexPodSecrets); err != nil {
+			return fmt.Errorf("failed to index %s of %T: %w", PodSecretIndex, obj, err)
+		}
+	}
+	return nil
+}
+
+func indexConfigMapRefs(obj client.Object) []string {
+	cr := obj.(*configv1.ConfigReloader)
+	return refKeys(cr, cr.Spec.ConfigMaps)
+}
+
+func indexSecretRefs(obj client.Object) []string {
+	cr := obj.(*configv1.ConfigReloader)
+	return refKeys(cr, cr.Spec.Secrets)
+}
+
+// refKeys returns the namespace/name keys of refs, defaulting the namespace
+// to the one of the ConfigReloader
+func refKeys(cr *configv1.ConfigReloader, refs []configv1.ResourceRef) []string {
+	if len(refs) == 0 {
+		return nil
+	}
+	keys := make([]string, 0, len(refs))
+	for _, ref := range refs {
+		namespace := ref.Namespace
+		if namespace == "" {
+			namespace = cr.Namespace
+		}
+		keys = append(keys, podrefs.Key(namespace, ref.Name))
+	}
+	return keys
+}
+
+func indexPodConfigMaps(obj client.Object) []string {
+	return podRefKeys(obj, podrefs.KindConfigMap)
+}
+
+func indexPodSecrets(obj client.Object) []string {
+	return podRefKeys(obj, podrefs.KindSecret)
+}
+
+// podRefKeys returns the namespace/name keys of the resources of the given
+// kind referenced by the pod spec of a pod or workload
+func podRefKeys(obj client.Object, kind string) []string {
+	spec := podSpecOf(obj)
+	if spec == nil {
+		return nil
+	}
+
+	var keys []string
+	seen := make(map[string]bool)
+	podrefs.Walk(spec, func(ref podrefs.Reference) bool {
+		key := podrefs.Key(obj.GetNamespace(), ref.Name)
+		if ref.Kind == kind && !seen[key] {
+			seen[key] = true
+			keys = append(keys, key)
+		}
+		return true
+	})
+	return keys
+}
+
+// podSpecOf returns the pod spec of a pod, or the pod template spec of a workload
+func podSpecOf(obj client.Object) *corev1.PodSpec {
+	switch o := obj.(type) {
+	case *corev1.Pod:
+		return &o.Spec
+	case *appsv1.Deployment:
+		return &o.Spec.Template.Spec
+	case *appsv1.StatefulSet:
+		return &o.Spec.Template.Spec
+	case *appsv1.DaemonSet:
+		return &o.Spec.Template.Spec
+	}
+	return nil
+}
+
+// podRefIndex returns the pod index holding references to the given kind
+func podRefIndex(kind string) (string, error) {
+	switch kind {
+	case podrefs.KindConfigMap:
+		return PodConfigMapIndex, nil
+	case podrefs.KindSecret:
+		return PodSecretIndex, nil
+	}
+	return "", fmt.Errorf("unsupported kind %s", kind)
+}
+
+// Users are the pods and workloads whose pod spec references a ConfigMap or
+// Secret
+type Users struct {
+	Pods         []corev1.Pod
+	Deployments  []appsv1.Deployment
+	StatefulSets []appsv1.StatefulSet
+	DaemonSets   []appsv1.DaemonSet
+}
+
+// FindUsers returns the pods and workloads using a ConfigMap or Secret. The
+// reader must serve the indexes registered by SetupIndexes.
+func FindUsers(ctx context.Context, reader client.Reader, kind, namespace, name string) (*Users, error) {
+	index, err := podRefIndex(kind)
+	if err != nil {
+		return nil, err
+	}
+	opts := []client.ListOption{
+		client.InNamespace(namespace),
+		client.MatchingFields{index: podrefs.Key(namespace, name)},
+	}
+
+	var (
+		pods         corev1.PodList
+		deployments  appsv1.DeploymentList
+		statefulSets appsv1.StatefulSetList
+		daemonSets   appsv1.DaemonSetList
+	)
+	for _, list := range []client.ObjectList{&pods, &deployments, &statefulSets, &daemonSets} {
+		if err := reader.List(ctx, list, opts...); err != nil {
+			return nil, fmt.Errorf("failed to list users of %s %s/%s: %w", kind, namespace, name, err)
+		}
+	}
+
+	return &Users{
+		Pods:         pods.Items,
+		Deployments:  deployments.Items,
+		StatefulSets: statefulSets.Items,
+		DaemonSets:   daemonSets.Items,
+	}, nil
+}
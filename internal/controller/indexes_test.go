@@ -0,0 +1,144 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	configv1 "github.com/shehbazk/config-reloader-operator/api/v1"
+)
+
+var _ = Describe("Pod indexes", func() {
+	var (
+		ctx        context.Context
+		reconciler *ConfigReloaderReconciler
+	)
+
+	podSpec := func(configMap, secret string) corev1.PodSpec {
+		spec := corev1.PodSpec{Containers: []corev1.Container{{Name: "app"}}}
+		if configMap != "" {
+			spec.Volumes = append(spec.Volumes, corev1.Volume{Name: "config", VolumeSource: corev1.VolumeSource{
+				ConfigMap: &corev1.ConfigMapVolumeSource{LocalObjectReference: corev1.LocalObjectReference{Name: configMap}},
+			}})
+			// A second reference to the same ConfigMap is indexed once
+			spec.Containers[0].EnvFrom = append(spec.Containers[0].EnvFrom, corev1.EnvFromSource{
+				ConfigMapRef: &corev1.ConfigMapEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: configMap}},
+			})
+		}
+		if secret != "" {
+			spec.Containers[0].Env = append(spec.Containers[0].Env, corev1.EnvVar{Name: "TOKEN", ValueFrom: &corev1.EnvVarSource{
+				SecretKeyRef: &corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: secret}, Key: "token"},
+			}})
+		}
+		return spec
+	}
+
+	pod := func(name, configMap, secret string, labels map[string]string) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", Labels: labels},
+			Spec:       podSpec(configMap, secret),
+		}
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		scheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+		Expect(configv1.AddToScheme(scheme)).To(Succeed())
+
+		builder := fake.NewClientBuilder().WithScheme(scheme)
+		for _, obj := range []client.Object{&corev1.Pod{}, &appsv1.Deployment{}, &appsv1.StatefulSet{}, &appsv1.DaemonSet{}} {
+			builder = builder.WithIndex(obj, PodConfigMapIndex, indexPodConfigMaps).
+				WithIndex(obj, PodSecretIndex, indexPodSecrets)
+		}
+		c := builder.WithObjects(
+			pod("web-b", "app", "", map[string]string{"app": "web"}),
+			pod("web-a", "", "creds", map[string]string{"app": "web"}),
+			pod("worker", "app", "creds", map[string]string{"app": "worker"}),
+			pod("unrelated", "other", "", map[string]string{"app": "web"}),
+			&appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+				Spec:       appsv1.DeploymentSpec{Template: corev1.PodTemplateSpec{Spec: podSpec("", "creds")}},
+			},
+		).Build()
+		reconciler = &ConfigReloaderReconciler{Client: c, Scheme: scheme}
+	})
+
+	It("should key pods and workload templates by the resources they reference", func() {
+		p := pod("p", "app", "creds", nil)
+		Expect(indexPodConfigMaps(p)).To(Equal([]string{"default/app"}))
+		Expect(indexPodSecrets(p)).To(Equal([]string{"default/creds"}))
+
+		deployment := &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "d", Namespace: "prod"},
+			Spec:       appsv1.DeploymentSpec{Template: corev1.PodTemplateSpec{Spec: podSpec("app", "")}},
+		}
+		Expect(indexPodConfigMaps(deployment)).To(Equal([]string{"prod/app"}))
+		Expect(indexPodSecrets(deployment)).To(BeEmpty())
+	})
+
+	DescribeTable("should find the affected pods with and without the indexes",
+		func(indexed bool) {
+			reconciler.indexed = indexed
+			cr := &configv1.ConfigReloader{
+				ObjectMeta: metav1.ObjectMeta{Name: "cr", Namespace: "default"},
+				Spec: configv1.ConfigReloaderSpec{
+					ConfigMaps: []configv1.ResourceRef{{Name: "app"}},
+					Secrets:    []configv1.ResourceRef{{Name: "creds"}},
+					Selector:   &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+				},
+			}
+
+			pods, err := reconciler.affectedPods(ctx, cr)
+			Expect(err).NotTo(HaveOccurred())
+			names := make([]string, 0, len(pods))
+			for _, p := range pods {
+				names = append(names, p.Name)
+			}
+			Expect(names).To(Equal([]string{"web-a", "web-b"}))
+		},
+		Entry("indexed", true),
+		Entry("scanned", false),
+	)
+
+	It("should find the users of a Secret", func() {
+		users, err := FindUsers(ctx, reconciler, "Secret", "default", "creds")
+		Expect(err).NotTo(HaveOccurred())
+
+		var pods []string
+		for _, p := range users.Pods {
+			pods = append(pods, p.Name)
+		}
+		Expect(pods).To(ConsistOf("web-a", "worker"))
+		Expect(users.Deployments).To(HaveLen(1))
+		Expect(users.Deployments[0].Name).To(Equal("web"))
+		Expect(users.StatefulSets).To(BeEmpty())
+
+		_, err = FindUsers(ctx, reconciler, "Pod", "default", "creds")
+		Expect(err).To(HaveOccurred())
+	})
+})
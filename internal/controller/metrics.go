@@ -0,0 +1,147 @@
+package controller
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	configv1 "github.com/shehbazk/config-reloader-operator/api/v1"
+)
+
+// Restart failure reasons used as the reason label of restartFailuresTotal
+const (
+	failureReasonPlan            = "plan"
+	failureReasonOwnerResolution = "owner_resolution"
+	failureReasonWorkloadUpdate  = "workload_update"
+	failureReasonPodAnnotate     = "pod_annotate"
+	failureReasonPodDelete       = "pod_delete"
+)
+
+var (
+	changesDetectedTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "configreloader_changes_detected_total",
+			Help: "Number of changes detected in watched ConfigMaps and Secrets",
+		},
+		[]string{"resource_kind", "resource_namespace", "resource_name"},
+	)
+
+	restartsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "configreloader_restarts_total",
+			Help: "Number of workload restarts triggered by config changes",
+		},
+		[]string{"namespace", "workload_kind", "workload_name", "policy"},
+	)
+
+	restartFailuresTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "configreloader_restart_failures_total",
+			Help: "Number of failed restart attempts by reason",
+		},
+		[]string{"namespace", "reason"},
+	)
+
+	reconcileToRestartSeconds = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "configreloader_reconcile_to_restart_seconds",
+			Help:    "Time from the start of a reconcile to the completion of the restarts it triggered",
+			Buckets: prometheus.ExponentialBuckets(0.01, 2, 12),
+		},
+		[]string{"policy"},
+	)
+
+	lastReloadTimestampSeconds = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "configreloader_last_reload_timestamp_seconds",
+			Help: "Unix timestamp of the last reload performed by a ConfigReloader",
+		},
+		[]string{"namespace", "configreloader"},
+	)
+
+	pendingReloads = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "configreloader_pending_reloads",
+			Help: "Whether a ConfigReloader has changes deferred while suspended",
+		},
+		[]string{"namespace", "configreloader"},
+	)
+
+	watchedResources = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "configreloader_watched_resources",
+			Help: "Number of ConfigMaps and Secrets watched by a ConfigReloader",
+		},
+		[]string{"namespace", "configreloader", "kind"},
+	)
+)
+
+func init() {
+	metrics.Registry.MustRegister(
+		changesDetectedTotal,
+		restartsTotal,
+		restartFailuresTotal,
+		reconcileToRestartSeconds,
+		lastReloadTimestampSeconds,
+		pendingReloads,
+		watchedResources,
+	)
+}
+
+// observeChanges counts the detected changes per resource
+func observeChanges(changes []resourceChange) {
+	for _, change := range changes {
+		changesDetectedTotal.WithLabelValues(change.Kind, change.Namespace, change.Name).Inc()
+	}
+}
+
+// observeRestart counts a restart of the given workload. Standalone pods are
+// reported with the Pod kind and no name, so that the series do not grow
+// with the names of the pods.
+func observeRestart(namespace, workloadKind, workloadName string, policy configv1.RestartPolicy) {
+	if workloadKind == "Pod" {
+		workloadName = ""
+	}
+	restartsTotal.WithLabelValues(namespace, workloadKind, workloadName, string(policy)).Inc()
+}
+
+// observeRestartFailure counts a failed restart attempt
+func observeRestartFailure(namespace, reason string) {
+	restartFailuresTotal.WithLabelValues(namespace, reason).Inc()
+}
+
+// observeReconcileToRestart records how long it took to complete the restarts
+// triggered by a reconcile that started at start
+func observeReconcileToRestart(policy configv1.RestartPolicy, start time.Time) {
+	reconcileToRestartSeconds.WithLabelValues(string(policy)).Observe(time.Since(start).Seconds())
+}
+
+// updateStatusMetrics refreshes the gauges derived from the ConfigReloader status
+func updateStatusMetrics(cr *configv1.ConfigReloader) {
+	if cr.Status.LastReloadTime != nil {
+		lastReloadTimestampSeconds.WithLabelValues(cr.Namespace, cr.Name).Set(float64(cr.Status.LastReloadTime.Unix()))
+	}
+
+	pending := 0.0
+	if cr.Status.PendingReload {
+		pending = 1
+	}
+	pendingReloads.WithLabelValues(cr.Namespace, cr.Name).Set(pending)
+
+	counts := map[string]int{"ConfigMap": 0, "Secret": 0}
+	for _, watched := range cr.Status.WatchedResources {
+		counts[watched.Kind]++
+	}
+	for kind, count := range counts {
+		watchedResources.WithLabelValues(cr.Namespace, cr.Name, kind).Set(float64(count))
+	}
+}
+
+// deleteStatusMetrics removes the per-ConfigReloader series once it is deleted
+func deleteStatusMetrics(cr *configv1.ConfigReloader) {
+	labels := prometheus.Labels{"namespace": cr.Namespace, "configreloader": cr.Name}
+	lastReloadTimestampSeconds.DeletePartialMatch(labels)
+	pendingReloads.DeletePartialMatch(labels)
+	watchedResources.DeletePartialMatch(labels)
+}
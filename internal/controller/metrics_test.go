@@ -0,0 +1,151 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	configv1 "github.com/shehbazk/config-reloader-operator/api/v1"
+)
+
+var _ = Describe("Metrics", func() {
+	// The metrics are global, the specs use their own namespace and compare
+	// the series before and after the reload
+	const namespace = "metrics"
+
+	var (
+		ctx          context.Context
+		scheme       *runtime.Scheme
+		cr           *configv1.ConfigReloader
+		objects      []client.Object
+		interceptors interceptor.Funcs
+		changes      []resourceChange
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		scheme = runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+		Expect(configv1.AddToScheme(scheme)).To(Succeed())
+
+		cr = &configv1.ConfigReloader{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: namespace},
+			Spec: configv1.ConfigReloaderSpec{
+				ConfigMaps:    []configv1.ResourceRef{{Name: "app"}},
+				RestartPolicy: configv1.RestartPolicyAnnotation,
+			},
+		}
+		objects = []client.Object{
+			&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: namespace}},
+			&corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:            "web-1",
+					Namespace:       namespace,
+					OwnerReferences: []metav1.OwnerReference{{APIVersion: "apps/v1", Kind: "Deployment", Name: "web"}},
+				},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{Name: "app", EnvFrom: []corev1.EnvFromSource{
+						{ConfigMapRef: &corev1.ConfigMapEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: "app"}}},
+					}}},
+				},
+			},
+		}
+		interceptors = interceptor.Funcs{}
+		changes = []resourceChange{{Kind: "ConfigMap", Name: "app", Namespace: namespace, OldVersion: "1", NewVersion: "2"}}
+	})
+
+	reload := func() *restartResult {
+		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objects...).
+			WithInterceptorFuncs(interceptors).
+			Build()
+		reconciler := &ConfigReloaderReconciler{Client: c, Scheme: scheme}
+		result, err := reconciler.restartAffectedPods(ctx, cr, changes, "ConfigMap metrics/app changed")
+		Expect(err).NotTo(HaveOccurred())
+		return result
+	}
+
+	It("should count a reload per restarted workload", func() {
+		restarts := restartsTotal.WithLabelValues(namespace, "Deployment", "web", string(configv1.RestartPolicyAnnotation))
+		failures := restartFailuresTotal.WithLabelValues(namespace, failureReasonWorkloadUpdate)
+		before, failuresBefore := testutil.ToFloat64(restarts), testutil.ToFloat64(failures)
+
+		result := reload()
+		Expect(result.Restarted).To(HaveLen(1))
+		Expect(testutil.ToFloat64(restarts)).To(Equal(before + 1))
+		Expect(testutil.ToFloat64(failures)).To(Equal(failuresBefore))
+	})
+
+	It("should count standalone pods without their names", func() {
+		cr.Spec.RestartPolicy = configv1.RestartPolicyDelete
+		standalone := objects[1].(*corev1.Pod)
+		standalone.OwnerReferences = nil
+		objects = []client.Object{standalone}
+		restarts := restartsTotal.WithLabelValues(namespace, "Pod", "", string(configv1.RestartPolicyDelete))
+		before := testutil.ToFloat64(restarts)
+
+		result := reload()
+		Expect(result.Restarted).To(HaveLen(1))
+		Expect(testutil.ToFloat64(restarts)).To(Equal(before + 1))
+		Expect(restartsTotal.DeleteLabelValues(namespace, "Pod", standalone.Name,
+			string(configv1.RestartPolicyDelete))).To(BeFalse())
+	})
+
+	It("should count a failed restart by reason", func() {
+		interceptors.Update = func(ctx context.Context, c client.WithWatch, obj client.Object,
+			opts ...client.UpdateOption) error {
+			if _, ok := obj.(*appsv1.Deployment); ok {
+				return errors.New("forbidden")
+			}
+			return c.Update(ctx, obj, opts...)
+		}
+		restarts := restartsTotal.WithLabelValues(namespace, "Deployment", "web", string(configv1.RestartPolicyAnnotation))
+		failures := restartFailuresTotal.WithLabelValues(namespace, failureReasonWorkloadUpdate)
+		before, failuresBefore := testutil.ToFloat64(restarts), testutil.ToFloat64(failures)
+
+		result := reload()
+		Expect(result.Failed).To(HaveLen(1))
+		Expect(testutil.ToFloat64(failures)).To(Equal(failuresBefore + 1))
+		Expect(testutil.ToFloat64(restarts)).To(Equal(before))
+	})
+
+	It("should count a failed pod deletion by reason", func() {
+		cr.Spec.RestartPolicy = configv1.RestartPolicyDelete
+		interceptors.Delete = func(ctx context.Context, c client.WithWatch, obj client.Object,
+			opts ...client.DeleteOption) error {
+			return errors.New("forbidden")
+		}
+		failures := restartFailuresTotal.WithLabelValues(namespace, failureReasonPodDelete)
+		before := testutil.ToFloat64(failures)
+
+		result := reload()
+		Expect(result.Failed).To(HaveLen(1))
+		Expect(testutil.ToFloat64(failures)).To(Equal(before + 1))
+	})
+})
@@ -0,0 +1,75 @@
+package controller
+
+import (
+	"context"
+
+	configv1 "github.com/shehbazk/config-reloader-operator/api/v1"
+	"github.com/shehbazk/config-reloader-operator/internal/notify"
+)
+
+// Notifier delivers the notifications about the reloads of ConfigReloaders,
+// implemented by notify.Dispatcher
+type Notifier interface {
+	Notify(ctx context.Context, cr *configv1.ConfigReloader, n notify.Notification)
+}
+
+// notifyChangeDetected notifies the changes detected in the watched
+// resources, including while suspended or in dry-run
+func (r *ConfigReloaderReconciler) notifyChangeDetected(
+	ctx context.Context,
+	cr *configv1.ConfigReloader,
+	changes []resourceChange,
+) {
+	if r.Notifier == nil {
+		return
+	}
+	r.Notifier.Notify(ctx, cr, notify.Notification{
+		Event:     configv1.NotificationChangeDetected,
+		Cause:     describeChanges(changes),
+		Resources: reloadEventResources(changes),
+	})
+}
+
+// notifyReloadStarted notifies a reload about to restart the targets
+func (r *ConfigReloaderReconciler) notifyReloadStarted(
+	ctx context.Context,
+	cr *configv1.ConfigReloader,
+	changes []resourceChange,
+	cause string,
+) {
+	if r.Notifier == nil {
+		return
+	}
+	r.Notifier.Notify(ctx, cr, notify.Notification{
+		Event:     configv1.NotificationReloadStarted,
+		Cause:     cause,
+		Resources: reloadEventResources(changes),
+	})
+}
+
+// notifyReloadOutcome notifies the outcome of a reload described by its
+// ReloadEvent. Partially failed reloads are notified as failed.
+func (r *ConfigReloaderReconciler) notifyReloadOutcome(
+	ctx context.Context,
+	cr *configv1.ConfigReloader,
+	event *configv1.ReloadEvent,
+) {
+	if r.Notifier == nil {
+		return
+	}
+	n := notify.Notification{
+		Event:         configv1.NotificationReloadCompleted,
+		Cause:         event.Spec.Cause,
+		Resources:     event.Spec.Resources,
+		Outcome:       event.Status.Outcome,
+		RestartedPods: event.Status.RestartedPods,
+		FailedTargets: event.Status.FailedTargets,
+		Targets:       event.Status.Targets,
+		Error:         event.Status.Error,
+		Time:          event.Status.CompletionTime.Time,
+	}
+	if event.Status.Outcome != configv1.ReloadSucceeded {
+		n.Event = configv1.NotificationReloadFailed
+	}
+	r.Notifier.Notify(ctx, cr, n)
+}
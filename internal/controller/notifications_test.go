@@ -0,0 +1,105 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	configv1 "github.com/shehbazk/config-reloader-operator/api/v1"
+	"github.com/shehbazk/config-reloader-operator/internal/notify"
+)
+
+// recordingNotifier records the notifications raised by the reconciler
+type recordingNotifier struct {
+	notifications []notify.Notification
+}
+
+func (n *recordingNotifier) Notify(_ context.Context, _ *configv1.ConfigReloader, notification notify.Notification) {
+	n.notifications = append(n.notifications, notification)
+}
+
+var _ = Describe("Reload notifications", func() {
+	var (
+		ctx        context.Context
+		cr         *configv1.ConfigReloader
+		notifier   *recordingNotifier
+		reconciler *ConfigReloaderReconciler
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		cr = &configv1.ConfigReloader{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"}}
+		notifier = &recordingNotifier{}
+		reconciler = &ConfigReloaderReconciler{Notifier: notifier}
+	})
+
+	It("should describe the detected changes", func() {
+		changes := []resourceChange{{Kind: "ConfigMap", Name: "app", Namespace: "default", OldVersion: "1", NewVersion: "2"}}
+		reconciler.notifyChangeDetected(ctx, cr, changes)
+
+		Expect(notifier.notifications).To(ConsistOf(notify.Notification{
+			Event: configv1.NotificationChangeDetected,
+			Cause: describeChanges(changes),
+			Resources: []configv1.ReloadEventResource{{
+				Kind: "ConfigMap", Name: "app", Namespace: "default", PreviousVersion: "1", Version: "2",
+			}},
+		}))
+	})
+
+	It("should notify partially failed reloads as failed", func() {
+		debug := restartTarget{
+			Pod:    &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "debug", Namespace: "default"}},
+			Policy: configv1.RestartPolicyDelete,
+		}
+		web := restartTarget{
+			Pod:    &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "web-abc", Namespace: "default"}},
+			Policy: configv1.RestartPolicyDelete,
+		}
+		result := &restartResult{
+			Restarted:        []configv1.PodRestart{{PodName: "web-abc"}},
+			RestartedTargets: []restartTarget{web},
+			Failed:           []restartFailure{{Target: debug, Err: errors.New("forbidden")}},
+		}
+		reconciler.notifyReloadOutcome(ctx, cr, newReloadEvent(cr, nil, "manual", result, nil, time.Now()))
+		reconciler.notifyReloadOutcome(ctx, cr, newReloadEvent(cr, nil, "manual", &restartResult{}, nil, time.Now()))
+
+		Expect(notifier.notifications).To(HaveLen(2))
+		Expect(notifier.notifications[0]).To(And(
+			HaveField("Event", configv1.NotificationReloadFailed),
+			HaveField("Outcome", configv1.ReloadPartiallyFailed),
+			HaveField("RestartedPods", int32(1)),
+			HaveField("FailedTargets", int32(1)),
+		))
+		Expect(notifier.notifications[1]).To(And(
+			HaveField("Event", configv1.NotificationReloadCompleted),
+			HaveField("Cause", "manual"),
+		))
+	})
+
+	It("should not notify without a Notifier", func() {
+		reconciler.Notifier = nil
+		reconciler.notifyReloadStarted(ctx, cr, nil, "manual")
+		Expect(notifier.notifications).To(BeEmpty())
+	})
+})
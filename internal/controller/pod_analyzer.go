@@ -1,111 +1,123 @@
 package controller
 
 import (
+	"context"
+	"fmt"
+	"sort"
+
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	configv1 "github.com/shehbazk/config-reloader-operator/api/v1"
+	"github.com/shehbazk/config-reloader-operator/internal/podrefs"
 )
 
+// podUsesWatchedResources reports whether the pod consumes any of the watched
+// ConfigMaps or Secrets through volumes, envFrom or env vars
 func (r *ConfigReloaderReconciler) podUsesWatchedResources(
 	pod *corev1.Pod,
 	watchedCMs, watchedSecrets map[string]bool,
 ) bool {
-	// Check volumes
-	if r.podVolumesUseWatchedResources(pod, watchedCMs, watchedSecrets) {
-		return true
-	}
+	return podrefs.Uses(&pod.Spec, pod.Namespace, watchedCMs, watchedSecrets)
+}
 
-	if r.containersUseWatchedResources(pod.Spec.Containers, pod.Namespace, watchedCMs, watchedSecrets) {
-		return true
+// affectedPods returns the pods in the namespace of the ConfigReloader that
+// match its selector and use any of the watched resources, ordered by name.
+// Pods are looked up with the pod indexes once they are registered, and by
+// analysing every selected pod otherwise.
+func (r *ConfigReloaderReconciler) affectedPods(ctx context.Context, cr *configv1.ConfigReloader) ([]*corev1.Pod, error) {
+	listOpts := []client.ListOption{
+		client.InNamespace(cr.Namespace),
 	}
 
-	if r.containersUseWatchedResources(pod.Spec.InitContainers, pod.Namespace, watchedCMs, watchedSecrets) {
-		return true
+	if cr.Spec.Selector != nil {
+		selector, err := metav1.LabelSelectorAsSelector(cr.Spec.Selector)
+		if err != nil {
+			return nil, fmt.Errorf("invalid selector: %w", err)
+		}
+		listOpts = append(listOpts, client.MatchingLabelsSelector{Selector: selector})
 	}
 
-	return false
-}
-
-func (r *ConfigReloaderReconciler) podVolumesUseWatchedResources(
-	pod *corev1.Pod,
-	watchedCMs, watchedSecrets map[string]bool,
-) bool {
-	for _, volume := range pod.Spec.Volumes {
-		if volume.ConfigMap != nil {
-			key := pod.Namespace + "/" + volume.ConfigMap.Name
-			if watchedCMs[key] {
-				return true
-			}
+	watchedCMs, watchedSecrets := buildWatchedResourcesMaps(cr)
+	if !r.indexed {
+		var podList corev1.PodList
+		if err := r.List(ctx, &podList, listOpts...); err != nil {
+			return nil, fmt.Errorf("failed to list pods: %w", err)
 		}
-		if volume.Secret != nil {
-			key := pod.Namespace + "/" + volume.Secret.SecretName
-			if watchedSecrets[key] {
-				return true
+
+		var pods []*corev1.Pod
+		for i := range podList.Items {
+			if r.podUsesWatchedResources(&podList.Items[i], watchedCMs, watchedSecrets) {
+				pods = append(pods, &podList.Items[i])
 			}
 		}
+		sortPods(pods)
+		return pods, nil
 	}
-	return false
-}
 
-func (r *ConfigReloaderReconciler) containersUseWatchedResources(
-	containers []corev1.Container,
-	namespace string,
-	watchedCMs, watchedSecrets map[string]bool,
-) bool {
-	for _, container := range containers {
-		// Check envFrom
-		if r.containerEnvFromUseWatchedResources(container, namespace, watchedCMs, watchedSecrets) {
-			return true
+	byName := make(map[string]*corev1.Pod)
+	lookup := func(index string, watched map[string]bool) error {
+		for key := range watched {
+			var podList corev1.PodList
+			opts := append([]client.ListOption{client.MatchingFields{index: key}}, listOpts...)
+			if err := r.List(ctx, &podList, opts...); err != nil {
+				return fmt.Errorf("failed to list pods using %s: %w", key, err)
+			}
+			for i := range podList.Items {
+				byName[podList.Items[i].Name] = &podList.Items[i]
+			}
 		}
+		return nil
+	}
+	if err := lookup(PodConfigMapIndex, watchedCMs); err != nil {
+		return nil, err
+	}
+	if err := lookup(PodSecretIndex, watchedSecrets); err != nil {
+		return nil, err
+	}
 
-		// Check individual env vars
-		if r.containerEnvVarsUseWatchedResources(container, namespace, watchedCMs, watchedSecrets) {
-			return true
-		}
+	pods := make([]*corev1.Pod, 0, len(byName))
+	for _, pod := range byName {
+		pods = append(pods, pod)
 	}
-	return false
+	sortPods(pods)
+	return pods, nil
 }
 
-func (r *ConfigReloaderReconciler) containerEnvFromUseWatchedResources(
-	container corev1.Container,
-	namespace string,
-	watchedCMs, watchedSecrets map[string]bool,
-) bool {
-	for _, envFrom := range container.EnvFrom {
-		if envFrom.ConfigMapRef != nil {
-			key := namespace + "/" + envFrom.ConfigMapRef.Name
-			if watchedCMs[key] {
-				return true
-			}
+func sortPods(pods []*corev1.Pod) {
+	sort.Slice(pods, func(i, j int) bool {
+		return pods[i].Name < pods[j].Name
+	})
+}
+
+// TriggeringReferences returns the references of a pod spec in the namespace
+// of the ConfigReloader that make it restart pods with the labels, or nil when
+// the pods are not selected or use none of the watched resources
+func TriggeringReferences(
+	cr *configv1.ConfigReloader,
+	podLabels map[string]string,
+	spec *corev1.PodSpec,
+) ([]podrefs.Reference, error) {
+	if cr.Spec.Selector != nil {
+		selector, err := metav1.LabelSelectorAsSelector(cr.Spec.Selector)
+		if err != nil {
+			return nil, fmt.Errorf("invalid selector: %w", err)
 		}
-		if envFrom.SecretRef != nil {
-			key := namespace + "/" + envFrom.SecretRef.Name
-			if watchedSecrets[key] {
-				return true
-			}
+		if !selector.Matches(labels.Set(podLabels)) {
+			return nil, nil
 		}
 	}
-	return false
-}
 
-func (r *ConfigReloaderReconciler) containerEnvVarsUseWatchedResources(
-	container corev1.Container,
-	namespace string,
-	watchedCMs, watchedSecrets map[string]bool,
-) bool {
-	for _, env := range container.Env {
-		if env.ValueFrom != nil {
-			if env.ValueFrom.ConfigMapKeyRef != nil {
-				key := namespace + "/" + env.ValueFrom.ConfigMapKeyRef.Name
-				if watchedCMs[key] {
-					return true
-				}
-			}
-			if env.ValueFrom.SecretKeyRef != nil {
-				key := namespace + "/" + env.ValueFrom.SecretKeyRef.Name
-				if watchedSecrets[key] {
-					return true
-				}
-			}
+	watchedCMs, watchedSecrets := buildWatchedResourcesMaps(cr)
+	var refs []podrefs.Reference
+	podrefs.Walk(spec, func(ref podrefs.Reference) bool {
+		key := podrefs.Key(cr.Namespace, ref.Name)
+		if ref.Kind == podrefs.KindConfigMap && watchedCMs[key] || ref.Kind == podrefs.KindSecret && watchedSecrets[key] {
+			refs = append(refs, ref)
 		}
-	}
-	return false
+		return true
+	})
+	return refs, nil
 }
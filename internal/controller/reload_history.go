@@ -0,0 +1,213 @@
+package controller
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"strings"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	configv1 "github.com/shehbazk/config-reloader-operator/api/v1"
+)
+
+const (
+	// ConfigReloaderLabel is set on ReloadEvents to the label value of the
+	// name of their ConfigReloader, see ConfigReloaderLabelValue
+	ConfigReloaderLabel = "config.dev/configreloader"
+	// ConfigReloaderAnnotation is set on ReloadEvents to the full name of their
+	// ConfigReloader
+	ConfigReloaderAnnotation = "config.dev/configreloader-name"
+
+	defaultHistoryLimit = 10
+)
+
+// ConfigReloaderLabelValue returns the value of ConfigReloaderLabel for a
+// ConfigReloader. Names longer than a label value are truncated and suffixed
+// with a hash of the full name so that they stay distinct.
+func ConfigReloaderLabelValue(name string) string {
+	if len(name) <= validation.LabelValueMaxLength {
+		return name
+	}
+	sum := sha256.Sum256([]byte(name))
+	suffix := hex.EncodeToString(sum[:])[:10]
+	prefix := strings.TrimRight(name[:validation.LabelValueMaxLength-len(suffix)-1], "-_.")
+	return prefix + "-" + suffix
+}
+
+// historyObjectMeta returns the metadata of a ReloadEvent or ConfigRevision
+// recorded for the ConfigReloader
+func historyObjectMeta(cr *configv1.ConfigReloader) metav1.ObjectMeta {
+	return metav1.ObjectMeta{
+		GenerateName: cr.Name + "-",
+		Namespace:    cr.Namespace,
+		Labels:       map[string]string{ConfigReloaderLabel: ConfigReloaderLabelValue(cr.Name)},
+		Annotations:  map[string]string{ConfigReloaderAnnotation: cr.Name},
+	}
+}
+
+// historyLabels selects the ReloadEvents and ConfigRevisions of the ConfigReloader
+func historyLabels(cr *configv1.ConfigReloader) client.MatchingLabels {
+	return client.MatchingLabels{ConfigReloaderLabel: ConfigReloaderLabelValue(cr.Name)}
+}
+
+// historyLimit returns the number of ReloadEvents kept for the ConfigReloader
+func historyLimit(cr *configv1.ConfigReloader) int {
+	if cr.Spec.HistoryLimit == nil {
+		return defaultHistoryLimit
+	}
+	return int(*cr.Spec.HistoryLimit)
+}
+
+// newReloadEvent describes a reload that started at start. restartErr is the
+// error that prevented the reload from being planned.
+func newReloadEvent(
+	cr *configv1.ConfigReloader,
+	changes []resourceChange,
+	cause string,
+	result *restartResult,
+	restartErr error,
+	start time.Time,
+) *configv1.ReloadEvent {
+	now := metav1.Now()
+
+	event := &configv1.ReloadEvent{
+		ObjectMeta: historyObjectMeta(cr),
+		Spec: configv1.ReloadEventSpec{
+			ConfigReloader: cr.Name,
+			Cause:          cause,
+			Policy:         cr.Spec.RestartPolicy,
+		},
+		Status: configv1.ReloadEventStatus{
+			StartTime:      metav1.NewTime(start),
+			CompletionTime: now,
+			Duration:       metav1.Duration{Duration: now.Sub(start)},
+		},
+	}
+	event.Spec.Resources = reloadEventResources(changes)
+
+	if restartErr != nil {
+		event.Status.Outcome = configv1.ReloadFailed
+		event.Status.Error = restartErr.Error()
+	} else {
+		event.Status.Targets = reloadEventTargets(result)
+		event.Status.RestartedPods = int32(len(result.Restarted))
+		for _, target := range event.Status.Targets {
+			if target.Error != "" {
+				event.Status.FailedTargets++
+			}
+		}
+
+		switch {
+		case event.Status.FailedTargets == 0:
+			event.Status.Outcome = configv1.ReloadSucceeded
+		case event.Status.RestartedPods > 0:
+			event.Status.Outcome = configv1.ReloadPartiallyFailed
+		default:
+			event.Status.Outcome = configv1.ReloadFailed
+		}
+	}
+	return event
+}
+
+// reloadEventResources describes the changes that triggered a reload
+func reloadEventResources(changes []resourceChange) []configv1.ReloadEventResource {
+	var resources []configv1.ReloadEventResource
+	for _, change := range changes {
+		resources = append(resources, configv1.ReloadEventResource{
+			Kind:            change.Kind,
+			Name:            change.Name,
+			Namespace:       change.Namespace,
+			PreviousVersion: change.OldVersion,
+			Version:         change.NewVersion,
+			ChangedBy:       change.ChangedBy,
+		})
+	}
+	return resources
+}
+
+// recordReloadEvent creates the ReloadEvent of a reload. Failures are logged
+// and do not fail the reconcile.
+func (r *ConfigReloaderReconciler) recordReloadEvent(
+	ctx context.Context,
+	cr *configv1.ConfigReloader,
+	event *configv1.ReloadEvent,
+) {
+	if historyLimit(cr) == 0 {
+		return
+	}
+	logger := log.FromContext(ctx)
+
+	if err := controllerutil.SetControllerReference(cr, event, r.Scheme); err != nil {
+		logger.Error(err, "failed to set owner of ReloadEvent")
+		return
+	}
+	if err := r.Create(ctx, event); err != nil {
+		logger.Error(err, "failed to record ReloadEvent")
+		return
+	}
+	logger.V(1).Info("Recorded ReloadEvent", "reloadEvent", event.Name, "outcome", event.Status.Outcome)
+}
+
+// reloadEventTargets groups the restarted and failed pods of result by target
+func reloadEventTargets(result *restartResult) []configv1.ReloadEventTarget {
+	var targets []configv1.ReloadEventTarget
+	index := make(map[string]int)
+
+	entry := func(target restartTarget) *configv1.ReloadEventTarget {
+		kind, name := targetStatusRef(target)
+		key := failedTargetKey(target.Pod.Namespace, kind, name)
+		if i, ok := index[key]; ok {
+			return &targets[i]
+		}
+		targets = append(targets, configv1.ReloadEventTarget{Kind: kind, Name: name, Namespace: target.Pod.Namespace})
+		index[key] = len(targets) - 1
+		return &targets[index[key]]
+	}
+
+	for _, target := range result.RestartedTargets {
+		entry(target).Pods++
+	}
+	for _, failure := range result.Failed {
+		if target := entry(failure.Target); target.Error == "" {
+			target.Error = failure.Err.Error()
+		}
+	}
+	return targets
+}
+
+// pruneReloadEvents deletes the ReloadEvents of the ConfigReloader beyond its
+// history limit or older than its history TTL
+func (r *ConfigReloaderReconciler) pruneReloadEvents(ctx context.Context, cr *configv1.ConfigReloader, now time.Time) {
+	logger := log.FromContext(ctx)
+
+	var events configv1.ReloadEventList
+	if err := r.List(ctx, &events, client.InNamespace(cr.Namespace),
+		historyLabels(cr)); err != nil {
+		logger.Error(err, "failed to list ReloadEvents")
+		return
+	}
+
+	// Newest first
+	sort.Slice(events.Items, func(i, j int) bool {
+		return events.Items[j].Status.StartTime.Before(&events.Items[i].Status.StartTime)
+	})
+
+	limit := historyLimit(cr)
+	for i := range events.Items {
+		event := &events.Items[i]
+		expired := cr.Spec.HistoryTTL != nil && now.Sub(event.Status.StartTime.Time) > cr.Spec.HistoryTTL.Duration
+		if i < limit && !expired {
+			continue
+		}
+		if err := r.Delete(ctx, event); client.IgnoreNotFound(err) != nil {
+			logger.Error(err, "failed to delete ReloadEvent", "reloadEvent", event.Name)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	configv1 "github.com/shehbazk/config-reloader-operator/api/v1"
+)
+
+var _ = Describe("Reload history", func() {
+	var (
+		ctx        context.Context
+		cr         *configv1.ConfigReloader
+		reconciler *ConfigReloaderReconciler
+	)
+
+	listEvents := func() []configv1.ReloadEvent {
+		var events configv1.ReloadEventList
+		Expect(reconciler.List(ctx, &events, historyLabels(cr))).To(Succeed())
+		return events.Items
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		scheme := runtime.NewScheme()
+		Expect(configv1.AddToScheme(scheme)).To(Succeed())
+
+		cr = &configv1.ConfigReloader{
+			ObjectMeta: metav1.ObjectMeta{Name: "history", Namespace: "default", UID: "history-uid"},
+			Spec:       configv1.ConfigReloaderSpec{RestartPolicy: configv1.RestartPolicyDelete},
+		}
+		reconciler = &ConfigReloaderReconciler{
+			Client: fake.NewClientBuilder().WithScheme(scheme).Build(),
+			Scheme: scheme,
+		}
+	})
+
+	It("should record the triggering resources, targets and outcome", func() {
+		web := restartTarget{
+			Pod:          &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "web-abc", Namespace: "default"}},
+			WorkloadKind: "Deployment",
+			WorkloadName: "web",
+			Policy:       configv1.RestartPolicyDelete,
+		}
+		debug := restartTarget{
+			Pod:    &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "debug", Namespace: "default"}},
+			Policy: configv1.RestartPolicyDelete,
+		}
+		result := &restartResult{
+			Restarted:        []configv1.PodRestart{{PodName: "web-abc"}},
+			RestartedTargets: []restartTarget{web},
+			Failed:           []restartFailure{{Target: debug, Err: errors.New("forbidden")}},
+		}
+		changes := []resourceChange{{Kind: "ConfigMap", Name: "app", Namespace: "default", OldVersion: "1", NewVersion: "2"}}
+
+		reconciler.recordReloadEvent(ctx, cr, newReloadEvent(cr, changes, describeChanges(changes), result, nil, time.Now()))
+
+		events := listEvents()
+		Expect(events).To(HaveLen(1))
+		event := events[0]
+		Expect(metav1.IsControlledBy(&event, cr)).To(BeTrue())
+		Expect(event.Spec.Resources).To(ConsistOf(configv1.ReloadEventResource{
+			Kind: "ConfigMap", Name: "app", Namespace: "default", PreviousVersion: "1", Version: "2",
+		}))
+		Expect(event.Status.Outcome).To(Equal(configv1.ReloadPartiallyFailed))
+		Expect(event.Status.RestartedPods).To(Equal(int32(1)))
+		Expect(event.Status.FailedTargets).To(Equal(int32(1)))
+		Expect(event.Status.Targets).To(ConsistOf(
+			configv1.ReloadEventTarget{Kind: "Deployment", Name: "web", Namespace: "default", Pods: 1},
+			configv1.ReloadEventTarget{Kind: "Pod", Name: "debug", Namespace: "default", Error: "forbidden"},
+		))
+	})
+
+	It("should not record anything when the history limit is zero", func() {
+		cr.Spec.HistoryLimit = ptr.To[int32](0)
+		reconciler.recordReloadEvent(ctx, cr, newReloadEvent(cr, nil, "manual", &restartResult{}, nil, time.Now()))
+		Expect(listEvents()).To(BeEmpty())
+	})
+
+	It("should label the events of ConfigReloaders with names longer than a label value", func() {
+		long := strings.Repeat("a", 70)
+		other := long[:69] + "b"
+		value := ConfigReloaderLabelValue(long)
+		Expect(validation.IsValidLabelValue(value)).To(BeEmpty())
+		Expect(value).NotTo(Equal(ConfigReloaderLabelValue(other)))
+		Expect(ConfigReloaderLabelValue("history")).To(Equal("history"))
+
+		cr.Name = long
+		reconciler.recordReloadEvent(ctx, cr, newReloadEvent(cr, nil, "manual", &restartResult{}, nil, time.Now()))
+		events := listEvents()
+		Expect(events).To(HaveLen(1))
+		Expect(events[0].Labels).To(HaveKeyWithValue(ConfigReloaderLabel, value))
+		Expect(events[0].Annotations).To(HaveKeyWithValue(ConfigReloaderAnnotation, long))
+	})
+
+	It("should keep the newest events within the limit and TTL", func() {
+		now := time.Now()
+		for i := range 5 {
+			reconciler.recordReloadEvent(ctx, cr, newReloadEvent(cr, nil, "manual", &restartResult{}, nil,
+				now.Add(-time.Duration(i)*time.Hour)))
+		}
+		Expect(listEvents()).To(HaveLen(5))
+
+		cr.Spec.HistoryLimit = ptr.To[int32](3)
+		reconciler.pruneReloadEvents(ctx, cr, now)
+		Expect(listEvents()).To(HaveLen(3))
+
+		cr.Spec.HistoryTTL = &metav1.Duration{Duration: 90 * time.Minute}
+		reconciler.pruneReloadEvents(ctx, cr, now)
+		events := listEvents()
+		Expect(events).To(HaveLen(2))
+		for _, event := range events {
+			Expect(now.Sub(event.Status.StartTime.Time)).To(BeNumerically("<=", 90*time.Minute))
+		}
+	})
+})
@@ -2,73 +2,208 @@ package controller
 
 import (
 	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"hash"
+	"sort"
 
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	configv1 "github.com/shehbazk/config-reloader-operator/api/v1"
 )
 
-func (r *ConfigReloaderReconciler) checkForChanges(ctx context.Context, cr *configv1.ConfigReloader) (bool, error) {
-	hasChanges := false
+// resourceChange describes a watched resource whose version differs from
+// the one recorded in status
+type resourceChange struct {
+	Kind       string
+	Name       string
+	Namespace  string
+	OldVersion string
+	NewVersion string
+	// ChangedBy is the field manager that last wrote the data
+	ChangedBy *configv1.ChangeAttribution
+	// KeysUnchanged is set when the reference selects keys and none of them
+	// changed
+	KeysUnchanged bool
+	// Keys lists the data keys that changed, when known from the revision
+	// history
+	Keys []configv1.KeyChange
+}
 
-	for _, cmRef := range cr.Spec.ConfigMaps {
-		namespace := cmRef.Namespace
-		if namespace == "" {
-			namespace = cr.Namespace
-		}
+// checkForChanges reads every watched resource once and returns the changes
+// since the versions recorded in status, together with the status entries of
+// the versions it read. The entries are only written to status once the
+// changes are handled, so that a later version is never recorded unchecked.
+func (r *ConfigReloaderReconciler) checkForChanges(
+	ctx context.Context,
+	cr *configv1.ConfigReloader,
+) ([]resourceChange, []configv1.WatchedResource, error) {
+	var changes []resourceChange
+	watchedResources := make([]configv1.WatchedResource, 0, len(cr.Spec.ConfigMaps)+len(cr.Spec.Secrets))
+	now := metav1.Now()
 
-		var cm corev1.ConfigMap
-		if err := r.Get(ctx, types.NamespacedName{Name: cmRef.Name, Namespace: namespace}, &cm); err != nil {
-			return false, fmt.Errorf("failed to get ConfigMap %s/%s: %w", namespace, cmRef.Name, err)
+	check := func(kind string, ref configv1.ResourceRef) error {
+		watched, change, err := r.checkWatchedResource(ctx, cr, kind, ref, now)
+		if err != nil {
+			return err
 		}
-
-		if r.hasResourceChanged(cr, "ConfigMap", cmRef.Name, namespace, cm.ResourceVersion) {
-			hasChanges = true
+		watchedResources = append(watchedResources, watched)
+		if change != nil {
+			changes = append(changes, *change)
+		}
+		return nil
+	}
+	for _, cmRef := range cr.Spec.ConfigMaps {
+		if err := check("ConfigMap", cmRef); err != nil {
+			return nil, nil, err
 		}
 	}
-
 	for _, secretRef := range cr.Spec.Secrets {
-		namespace := secretRef.Namespace
-		if namespace == "" {
-			namespace = cr.Namespace
+		if err := check("Secret", secretRef); err != nil {
+			return nil, nil, err
 		}
+	}
 
-		var secret corev1.Secret
-		if err := r.Get(ctx, types.NamespacedName{Name: secretRef.Name, Namespace: namespace}, &secret); err != nil {
-			return false, fmt.Errorf("failed to get Secret %s/%s: %w", namespace, secretRef.Name, err)
-		}
+	return changes, watchedResources, nil
+}
 
-		if r.hasResourceChanged(cr, "Secret", secretRef.Name, namespace, secret.ResourceVersion) {
-			hasChanges = true
-		}
+// checkWatchedResource reads a watched resource and returns its status entry
+// and its change since the entry recorded in status, if any. The
+// resourceVersion and the hash of the selected keys come from the same read.
+func (r *ConfigReloaderReconciler) checkWatchedResource(
+	ctx context.Context,
+	cr *configv1.ConfigReloader,
+	kind string,
+	ref configv1.ResourceRef,
+	now metav1.Time,
+) (configv1.WatchedResource, *resourceChange, error) {
+	namespace := ref.Namespace
+	if namespace == "" {
+		namespace = cr.Namespace
+	}
+	key := types.NamespacedName{Name: ref.Name, Namespace: namespace}
+
+	// The data is only needed to hash the selected keys
+	var obj metav1.Object
+	var err error
+	if len(ref.Keys) > 0 {
+		obj, err = r.getWatchedObject(ctx, kind, key)
+	} else {
+		obj, err = r.getWatchedMeta(ctx, kind, key)
+	}
+	if err != nil {
+		return configv1.WatchedResource{}, nil, fmt.Errorf("failed to get %s %s/%s: %w", kind, namespace, ref.Name, err)
+	}
+
+	watched := configv1.WatchedResource{
+		Kind:            kind,
+		Name:            ref.Name,
+		Namespace:       namespace,
+		ResourceVersion: obj.GetResourceVersion(),
+		LastUpdateTime:  r.lastUpdateTime(cr, kind, obj, now),
+		LastChangedBy:   changeAttribution(kind, obj),
+	}
+	if len(ref.Keys) > 0 {
+		watched.KeysHash = selectedKeysHash(obj.(client.Object), ref.Keys)
 	}
 
-	return hasChanges, nil
+	oldVersion, changed := r.hasResourceChanged(cr, kind, ref.Name, namespace, watched.ResourceVersion)
+	if !changed {
+		return watched, nil, nil
+	}
+	change := &resourceChange{
+		Kind:       kind,
+		Name:       ref.Name,
+		Namespace:  namespace,
+		OldVersion: oldVersion,
+		NewVersion: watched.ResourceVersion,
+		ChangedBy:  watched.LastChangedBy,
+	}
+	if len(ref.Keys) > 0 {
+		previous := recordedKeysHash(cr, kind, ref.Name, namespace)
+		change.KeysUnchanged = previous != "" && previous == watched.KeysHash
+	}
+	return watched, change, nil
 }
 
+// hasResourceChanged returns the previously seen resourceVersion and whether
+// it differs from the current one
 func (r *ConfigReloaderReconciler) hasResourceChanged(
 	cr *configv1.ConfigReloader,
 	kind, name, namespace, resourceVersion string,
-) bool {
+) (string, bool) {
 	for _, watched := range cr.Status.WatchedResources {
 		if watched.Kind == kind && watched.Name == name && watched.Namespace == namespace {
-			return watched.ResourceVersion != resourceVersion
+			return watched.ResourceVersion, watched.ResourceVersion != resourceVersion
 		}
 	}
 	// First time seeing this resource, so it's "changed" (new)
-	return true
+	return "", true
 }
 
-func (r *ConfigReloaderReconciler) updateWatchedResourcesStatus(
-	ctx context.Context,
+// recordedKeysHash returns the hash of the selected keys of a watched
+// resource recorded in status
+func recordedKeysHash(cr *configv1.ConfigReloader, kind, name, namespace string) string {
+	for _, watched := range cr.Status.WatchedResources {
+		if watched.Kind == kind && watched.Name == name && watched.Namespace == namespace {
+			return watched.KeysHash
+		}
+	}
+	return ""
+}
+
+// selectedKeysHash returns a hash of the selected keys of a watched
+// ConfigMap or Secret. It is keyed with the UID of the resource so that the
+// hash recorded in status cannot be compared with hashes of common values.
+func selectedKeysHash(obj client.Object, keys []string) string {
+	data := watchedData(obj)
+	selected := make(map[string]string, len(keys))
+	for _, k := range keys {
+		if value, ok := data[k]; ok {
+			selected[k] = value
+		}
+	}
+	h := hmac.New(sha256.New, []byte(obj.GetUID()))
+	hashData(h, selected)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// lastUpdateTime returns when a watched resource was last modified. The time
+// of the latest write recorded in its managed fields is used, falling back to
+// the time the current version was first seen.
+func (r *ConfigReloaderReconciler) lastUpdateTime(
 	cr *configv1.ConfigReloader,
-) {
-	totalResources := len(cr.Spec.ConfigMaps) + len(cr.Spec.Secrets)
-	watchedResources := make([]configv1.WatchedResource, 0, totalResources)
-	now := metav1.Now()
+	kind string,
+	obj metav1.Object,
+	now metav1.Time,
+) *metav1.Time {
+	var last *metav1.Time
+	for _, entry := range obj.GetManagedFields() {
+		if entry.Time != nil && (last == nil || entry.Time.After(last.Time)) {
+			last = entry.Time.DeepCopy()
+		}
+	}
+	if last != nil {
+		return last
+	}
+
+	for _, watched := range cr.Status.WatchedResources {
+		if watched.Kind == kind && watched.Name == obj.GetName() && watched.Namespace == obj.GetNamespace() &&
+			watched.ResourceVersion == obj.GetResourceVersion() && watched.LastUpdateTime != nil {
+			return watched.LastUpdateTime
+		}
+	}
+	return &now
+}
+
+// configHash returns a hash of the data of every watched ConfigMap and Secret
+func (r *ConfigReloaderReconciler) configHash(ctx context.Context, cr *configv1.ConfigReloader) (string, error) {
+	h := sha256.New()
 
 	for _, cmRef := range cr.Spec.ConfigMaps {
 		namespace := cmRef.Namespace
@@ -76,19 +211,15 @@ func (r *ConfigReloaderReconciler) updateWatchedResourcesStatus(
 			namespace = cr.Namespace
 		}
 
-		var cm corev1.ConfigMap
-		if err := r.Get(ctx, types.NamespacedName{Name: cmRef.Name, Namespace: namespace}, &cm); err != nil {
-			// Resource not found, skip it
-			continue
+		obj, err := r.getWatchedObject(ctx, "ConfigMap", types.NamespacedName{Name: cmRef.Name, Namespace: namespace})
+		if err != nil {
+			return "", fmt.Errorf("failed to get ConfigMap %s/%s: %w", namespace, cmRef.Name, err)
 		}
+		cm := obj.(*corev1.ConfigMap)
 
-		watchedResources = append(watchedResources, configv1.WatchedResource{
-			Kind:            "ConfigMap",
-			Name:            cmRef.Name,
-			Namespace:       namespace,
-			ResourceVersion: cm.ResourceVersion,
-			LastUpdateTime:  &now,
-		})
+		fmt.Fprintf(h, "ConfigMap/%s/%s\n", namespace, cmRef.Name)
+		hashData(h, cm.Data)
+		hashData(h, binaryStrings(cm.BinaryData))
 	}
 
 	for _, secretRef := range cr.Spec.Secrets {
@@ -97,27 +228,57 @@ func (r *ConfigReloaderReconciler) updateWatchedResourcesStatus(
 			namespace = cr.Namespace
 		}
 
-		var secret corev1.Secret
-		if err := r.Get(ctx, types.NamespacedName{Name: secretRef.Name, Namespace: namespace}, &secret); err != nil {
-			continue
+		obj, err := r.getWatchedObject(ctx, "Secret", types.NamespacedName{Name: secretRef.Name, Namespace: namespace})
+		if err != nil {
+			return "", fmt.Errorf("failed to get Secret %s/%s: %w", namespace, secretRef.Name, err)
+		}
+		fmt.Fprintf(h, "Secret/%s/%s\n", namespace, secretRef.Name)
+		hashData(h, watchedData(obj))
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// watchedData returns the data of a ConfigMap or Secret by key, binary
+// values included
+func watchedData(obj client.Object) map[string]string {
+	switch obj := obj.(type) {
+	case *corev1.ConfigMap:
+		data := binaryStrings(obj.BinaryData)
+		for key, value := range obj.Data {
+			data[key] = value
 		}
+		return data
+	case *corev1.Secret:
+		return binaryStrings(obj.Data)
+	}
+	return nil
+}
+
+func binaryStrings(data map[string][]byte) map[string]string {
+	out := make(map[string]string, len(data))
+	for key, value := range data {
+		out[key] = string(value)
+	}
+	return out
+}
 
-		watchedResources = append(watchedResources, configv1.WatchedResource{
-			Kind:            "Secret",
-			Name:            secretRef.Name,
-			Namespace:       namespace,
-			ResourceVersion: secret.ResourceVersion,
-			LastUpdateTime:  &now,
-		})
+// hashData writes the entries of data to h in key order
+func hashData(h hash.Hash, data map[string]string) {
+	keys := make([]string, 0, len(data))
+	for key := range data {
+		keys = append(keys, key)
 	}
+	sort.Strings(keys)
 
-	cr.Status.WatchedResources = watchedResources
+	for _, key := range keys {
+		fmt.Fprintf(h, "%d:%s%d:%s", len(key), key, len(data[key]), data[key])
+	}
+	fmt.Fprint(h, "\n")
 }
 
 // Map for quick lookup of watched resources
-func (r *ConfigReloaderReconciler) buildWatchedResourcesMaps(
-	cr *configv1.ConfigReloader,
-) (map[string]bool, map[string]bool) {
+func buildWatchedResourcesMaps(cr *configv1.ConfigReloader) (map[string]bool, map[string]bool) {
 	watchedCMs := make(map[string]bool)
 	watchedSecrets := make(map[string]bool)
 
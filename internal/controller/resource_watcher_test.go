@@ -0,0 +1,127 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	configv1 "github.com/shehbazk/config-reloader-operator/api/v1"
+)
+
+var _ = Describe("Selected keys", func() {
+	var (
+		ctx        context.Context
+		cm         *corev1.ConfigMap
+		cr         *configv1.ConfigReloader
+		reconciler *ConfigReloaderReconciler
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		scheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+
+		cm = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default", UID: "uid"},
+			Data:       map[string]string{"level": "info", "banner": "hello"},
+		}
+		cr = &configv1.ConfigReloader{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+			Spec: configv1.ConfigReloaderSpec{
+				ConfigMaps: []configv1.ResourceRef{{Name: "app", Keys: []string{"level"}}},
+			},
+		}
+		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(cm).Build()
+		reconciler = &ConfigReloaderReconciler{Client: c, Scheme: scheme}
+
+		_, watched, err := reconciler.checkForChanges(ctx, cr)
+		Expect(err).NotTo(HaveOccurred())
+		cr.Status.WatchedResources = watched
+		Expect(cr.Status.WatchedResources).To(HaveLen(1))
+		Expect(cr.Status.WatchedResources[0].KeysHash).NotTo(BeEmpty())
+	})
+
+	// update writes the data of the ConfigMap and returns the detected change
+	update := func(key, value string) resourceChange {
+		cm.Data[key] = value
+		Expect(reconciler.Update(ctx, cm)).To(Succeed())
+
+		changes, _, err := reconciler.checkForChanges(ctx, cr)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(changes).To(HaveLen(1))
+		return changes[0]
+	}
+
+	It("should ignore changes leaving the selected keys untouched", func() {
+		change := update("banner", "bye")
+		Expect(change.KeysUnchanged).To(BeTrue())
+		Expect(reconciler.filterIgnoredChanges(cr, []resourceChange{change})).To(BeEmpty())
+	})
+
+	It("should report changes of a selected key", func() {
+		Expect(update("level", "debug").KeysUnchanged).To(BeFalse())
+	})
+
+	It("should report the removal of a selected key", func() {
+		delete(cm.Data, "level")
+		Expect(reconciler.Update(ctx, cm)).To(Succeed())
+
+		changes, _, err := reconciler.checkForChanges(ctx, cr)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(changes).To(HaveLen(1))
+		Expect(changes[0].KeysUnchanged).To(BeFalse())
+	})
+
+	It("should record the new version of a change it ignored", func() {
+		cm.Data["banner"] = "bye"
+		Expect(reconciler.Update(ctx, cm)).To(Succeed())
+		_, watched, err := reconciler.checkForChanges(ctx, cr)
+		Expect(err).NotTo(HaveOccurred())
+		cr.Status.WatchedResources = watched
+
+		changes, _, err := reconciler.checkForChanges(ctx, cr)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(changes).To(BeEmpty())
+	})
+
+	It("should record the version it checked when a selected key changes later", func() {
+		cm.Data["banner"] = "bye"
+		Expect(reconciler.Update(ctx, cm)).To(Succeed())
+		changes, watched, err := reconciler.checkForChanges(ctx, cr)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(changes[0].KeysUnchanged).To(BeTrue())
+
+		By("changing a selected key before the status is written")
+		cm.Data["level"] = "debug"
+		Expect(reconciler.Update(ctx, cm)).To(Succeed())
+		cr.Status.WatchedResources = watched
+		Expect(cr.Status.WatchedResources[0].ResourceVersion).To(Equal(changes[0].NewVersion))
+
+		changes, _, err = reconciler.checkForChanges(ctx, cr)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(changes).To(HaveLen(1))
+		Expect(changes[0].KeysUnchanged).To(BeFalse())
+	})
+})
@@ -0,0 +1,155 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	configv1 "github.com/shehbazk/config-reloader-operator/api/v1"
+)
+
+const (
+	restartBackoffBase = 10 * time.Second
+	restartBackoffMax  = 5 * time.Minute
+)
+
+// restartBackoff returns the delay before retrying a target that already
+// failed the given number of times
+func restartBackoff(attempts int32) time.Duration {
+	backoff := restartBackoffBase
+	for i := int32(1); i < attempts && backoff < restartBackoffMax; i++ {
+		backoff *= 2
+	}
+	return min(backoff, restartBackoffMax)
+}
+
+// failedTargetRef returns the unit that is retried for a restart target: the
+// owning workload for the annotation policy, the pod itself otherwise
+func failedTargetRef(target restartTarget) (string, string) {
+	if target.Policy == configv1.RestartPolicyAnnotation && target.WorkloadKind != "" {
+		return target.WorkloadKind, target.WorkloadName
+	}
+	return "Pod", target.Pod.Name
+}
+
+func failedTargetKey(namespace, kind, name string) string {
+	return namespace + "/" + kind + "/" + name
+}
+
+// dueFailedRestarts returns the keys of the failed targets whose backoff has expired
+func dueFailedRestarts(cr *configv1.ConfigReloader, now time.Time) map[string]bool {
+	due := make(map[string]bool)
+	for _, failed := range cr.Status.FailedRestarts {
+		if failed.LastAttemptTime == nil || !now.Before(failed.LastAttemptTime.Add(restartBackoff(failed.Attempts))) {
+			due[failedTargetKey(failed.Namespace, failed.Kind, failed.Name)] = true
+		}
+	}
+	return due
+}
+
+// retryFailedRestarts re-plans the restart and only retries the targets in due
+func (r *ConfigReloaderReconciler) retryFailedRestarts(
+	ctx context.Context,
+	cr *configv1.ConfigReloader,
+	due map[string]bool,
+) (*restartResult, error) {
+	targets, failures, err := r.planRestarts(ctx, cr)
+	if err != nil {
+		return nil, err
+	}
+
+	selected := make([]restartTarget, 0, len(due))
+	for _, target := range targets {
+		kind, name := failedTargetRef(target)
+		if due[failedTargetKey(target.Pod.Namespace, kind, name)] {
+			selected = append(selected, target)
+		}
+	}
+
+	var planFailures []restartFailure
+	for _, failure := range failures {
+		kind, name := failedTargetRef(failure.Target)
+		if due[failedTargetKey(failure.Target.Pod.Namespace, kind, name)] {
+			planFailures = append(planFailures, failure)
+		}
+	}
+
+	result := r.executeRestarts(ctx, cr, selected, "retrying failed restarts")
+	result.Failed = append(planFailures, result.Failed...)
+	return result, nil
+}
+
+// updateFailedRestarts records the failures of result in status. When retried
+// is nil the result covers every target and replaces the previous failures,
+// otherwise only the retried entries are replaced.
+func updateFailedRestarts(
+	cr *configv1.ConfigReloader,
+	result *restartResult,
+	retried map[string]bool,
+	now metav1.Time,
+) {
+	previous := make(map[string]configv1.FailedRestart, len(cr.Status.FailedRestarts))
+	failedRestarts := make([]configv1.FailedRestart, 0, len(cr.Status.FailedRestarts)+len(result.Failed))
+
+	for _, failed := range cr.Status.FailedRestarts {
+		key := failedTargetKey(failed.Namespace, failed.Kind, failed.Name)
+		previous[key] = failed
+		if retried != nil && !retried[key] {
+			failedRestarts = append(failedRestarts, failed)
+		}
+	}
+
+	seen := make(map[string]bool, len(result.Failed))
+	for _, failure := range result.Failed {
+		kind, name := failedTargetRef(failure.Target)
+		key := failedTargetKey(failure.Target.Pod.Namespace, kind, name)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		attempts := int32(1)
+		if prev, ok := previous[key]; ok && retried[key] {
+			attempts = prev.Attempts + 1
+		}
+
+		failedRestarts = append(failedRestarts, configv1.FailedRestart{
+			Kind:            kind,
+			Name:            name,
+			Namespace:       failure.Target.Pod.Namespace,
+			Attempts:        attempts,
+			LastError:       failure.Err.Error(),
+			LastAttemptTime: &now,
+		})
+	}
+
+	if len(failedRestarts) == 0 {
+		failedRestarts = nil
+	}
+	cr.Status.FailedRestarts = failedRestarts
+}
+
+// nextRetryAfter returns how long until the earliest failed target is due
+func nextRetryAfter(cr *configv1.ConfigReloader, now time.Time) time.Duration {
+	next := restartBackoffMax
+	for _, failed := range cr.Status.FailedRestarts {
+		if failed.LastAttemptTime == nil {
+			return time.Second
+		}
+		next = min(next, failed.LastAttemptTime.Add(restartBackoff(failed.Attempts)).Sub(now))
+	}
+	return max(next, time.Second)
+}
+
+// failedRestartsMessage lists the failing targets for the Degraded condition
+func failedRestartsMessage(failedRestarts []configv1.FailedRestart) string {
+	parts := make([]string, 0, len(failedRestarts))
+	for _, failed := range failedRestarts {
+		parts = append(parts, fmt.Sprintf("%s %s/%s (%d attempts): %s",
+			failed.Kind, failed.Namespace, failed.Name, failed.Attempts, failed.LastError))
+	}
+	return "Failed to restart " + strings.Join(parts, "; ")
+}
@@ -0,0 +1,81 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	configv1 "github.com/shehbazk/config-reloader-operator/api/v1"
+)
+
+var _ = Describe("Restart backoff", func() {
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "web-abc", Namespace: "default"}}
+	target := restartTarget{
+		Pod:          pod,
+		WorkloadKind: "Deployment",
+		WorkloadName: "web",
+		Policy:       configv1.RestartPolicyAnnotation,
+	}
+
+	It("should double the delay up to the maximum", func() {
+		Expect(restartBackoff(1)).To(Equal(restartBackoffBase))
+		Expect(restartBackoff(2)).To(Equal(2 * restartBackoffBase))
+		Expect(restartBackoff(3)).To(Equal(4 * restartBackoffBase))
+		Expect(restartBackoff(20)).To(Equal(restartBackoffMax))
+	})
+
+	It("should only retry failed targets once their backoff expired", func() {
+		cr := &configv1.ConfigReloader{}
+		now := metav1.Now()
+
+		By("recording the failures of a full restart")
+		updateFailedRestarts(cr, &restartResult{Failed: []restartFailure{
+			{Target: target, Err: errors.New("conflict")},
+			{Target: target, Err: errors.New("conflict")},
+		}}, nil, now)
+		Expect(cr.Status.FailedRestarts).To(HaveLen(1))
+		Expect(cr.Status.FailedRestarts[0].Kind).To(Equal("Deployment"))
+		Expect(cr.Status.FailedRestarts[0].Attempts).To(Equal(int32(1)))
+
+		Expect(dueFailedRestarts(cr, now.Time)).To(BeEmpty())
+		due := dueFailedRestarts(cr, now.Add(restartBackoffBase))
+		Expect(due).To(HaveKey("default/Deployment/web"))
+
+		By("counting the attempts of a failed retry")
+		updateFailedRestarts(cr, &restartResult{Failed: []restartFailure{
+			{Target: target, Err: errors.New("forbidden")},
+		}}, due, now)
+		Expect(cr.Status.FailedRestarts[0].Attempts).To(Equal(int32(2)))
+		Expect(cr.Status.FailedRestarts[0].LastError).To(Equal("forbidden"))
+		Expect(nextRetryAfter(cr, now.Time)).To(Equal(2 * restartBackoffBase))
+
+		By("clearing the failures after a successful retry")
+		updateFailedRestarts(cr, &restartResult{}, due, now)
+		Expect(cr.Status.FailedRestarts).To(BeNil())
+	})
+
+	It("should retry standalone pods individually", func() {
+		kind, name := failedTargetRef(restartTarget{Pod: pod, Policy: configv1.RestartPolicyDelete})
+		Expect(kind).To(Equal("Pod"))
+		Expect(name).To(Equal("web-abc"))
+	})
+})
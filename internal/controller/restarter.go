@@ -6,89 +6,290 @@ import (
 	"time"
 
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"sigs.k8s.io/controller-runtime/pkg/client"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 
 	configv1 "github.com/shehbazk/config-reloader-operator/api/v1"
 )
 
+// restartTarget is a pod selected for restart together with the workload
+// that owns it and the policy that will be applied to it
+type restartTarget struct {
+	Pod          *corev1.Pod
+	WorkloadKind string
+	WorkloadName string
+	Policy       configv1.RestartPolicy
+}
+
+// restartFailure is a restart target that could not be restarted
+type restartFailure struct {
+	Target restartTarget
+	Err    error
+}
+
+// restartResult is the outcome of restarting a set of targets. A failing
+// target does not stop the remaining ones from being restarted.
+type restartResult struct {
+	Restarted []configv1.PodRestart
+	// RestartedTargets holds the target of every entry in Restarted
+	RestartedTargets []restartTarget
+	Failed           []restartFailure
+}
+
+// Err aggregates the errors of all failed targets
+func (res *restartResult) Err() error {
+	errs := make([]error, 0, len(res.Failed))
+	for _, failure := range res.Failed {
+		errs = append(errs, failure.Err)
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// restartAffectedPods restarts the pods affected by the changes. Workloads
+// shared with an overlapping ConfigReloader that takes precedence and reacts
+// to the same changes are left to it. Only the canaries are restarted when
+// the ConfigReloader uses the canary strategy.
 func (r *ConfigReloaderReconciler) restartAffectedPods(ctx context.Context,
-	cr *configv1.ConfigReloader) ([]configv1.PodRestart, error) {
-	logger := log.FromContext(ctx)
+	cr *configv1.ConfigReloader, changes []resourceChange, cause string) (*restartResult, error) {
+	targets, failures, err := r.planReload(ctx, cr, changes)
+	if err != nil {
+		return nil, err
+	}
+	r.recordTargetChanges(ctx, cr, targets, changes)
 
-	restartedPods := make([]configv1.PodRestart, 0, 10)
-	watchedCMs, watchedSecrets := r.buildWatchedResourcesMaps(cr)
+	if r.isDryRun(cr) {
+		r.reportDryRunPlan(ctx, cr, targets)
+		return &restartResult{}, nil
+	}
 
-	var podList corev1.PodList
-	listOpts := []client.ListOption{
-		client.InNamespace(cr.Namespace),
+	if cr.Spec.Canary != nil {
+		targets = r.startCanary(ctx, cr, targets, changes, cause, time.Now())
 	}
 
-	if cr.Spec.Selector != nil {
-		selector, err := metav1.LabelSelectorAsSelector(cr.Spec.Selector)
-		if err != nil {
-			return nil, fmt.Errorf("invalid selector: %w", err)
-		}
-		listOpts = append(listOpts, client.MatchingLabelsSelector{Selector: selector})
+	result := r.executeRestarts(ctx, cr, targets, cause)
+	result.Failed = append(failures, result.Failed...)
+	return result, nil
+}
+
+// planReload plans the restarts of a reload, leaving out the targets
+// deferred to overlapping ConfigReloaders
+func (r *ConfigReloaderReconciler) planReload(ctx context.Context,
+	cr *configv1.ConfigReloader, changes []resourceChange) ([]restartTarget, []restartFailure, error) {
+	targets, failures, err := r.planRestarts(ctx, cr)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	if err := r.List(ctx, &podList, listOpts...); err != nil {
-		return nil, fmt.Errorf("failed to list pods: %w", err)
+	pods := make([]*corev1.Pod, 0, len(targets)+len(failures))
+	for _, target := range targets {
+		pods = append(pods, target.Pod)
+	}
+	for _, failure := range failures {
+		pods = append(pods, failure.Target.Pod)
 	}
+	overlaps := r.detectConflicts(ctx, cr, pods)
+	return r.deferToOverlaps(ctx, cr, targets, overlaps, changes), failures, nil
+}
 
+// executeRestarts applies the restart policy to every target and collects
+// the per-target outcome
+func (r *ConfigReloaderReconciler) executeRestarts(
+	ctx context.Context,
+	cr *configv1.ConfigReloader,
+	targets []restartTarget,
+	cause string,
+) *restartResult {
+	logger := log.FromContext(ctx)
+
+	result := &restartResult{Restarted: make([]configv1.PodRestart, 0, len(targets))}
+	restartedWorkloads := make(map[string]bool)
 	now := metav1.Now()
-	restartAnnotation := fmt.Sprintf("config.dev/restarted-at-%d", now.Unix())
+	restartAnnotation := fmt.Sprintf("%s%d", restartAnnotationPrefix, now.Unix())
+
+	// Pods of a workload are traced together, in the order they were first restarted
+	var spans []*workloadSpan
+	spansByWorkload := make(map[string]*workloadSpan)
+
+	for _, target := range targets {
+		logger.Info("Processing pod for restart", "pod", target.Pod.Name, "namespace", target.Pod.Namespace)
+
+		targetStart := time.Now()
+		var restartInfo *configv1.PodRestart
+		var err error
+		switch target.Policy {
+		case configv1.RestartPolicyAnnotation:
+			restartInfo, err = r.handleAnnotationRestart(ctx, cr, target, restartAnnotation, cause, now)
+		case configv1.RestartPolicyDelete:
+			restartInfo, err = r.handleDeleteRestart(ctx, cr, target.Pod, cause, now)
+		}
 
-	for _, pod := range podList.Items {
-		// Check if pod uses watched resources
-		if !r.podUsesWatchedResources(&pod, watchedCMs, watchedSecrets) {
+		// Standalone pods count as their own workload
+		kind, name := targetStatusRef(target)
+		span, ok := spansByWorkload[kind+"/"+name]
+		if !ok {
+			span = &workloadSpan{Kind: kind, Name: name, Namespace: target.Pod.Namespace, Policy: target.Policy}
+			spansByWorkload[kind+"/"+name] = span
+			spans = append(spans, span)
+		}
+		span.add(targetStart, err)
+
+		if err != nil {
+			result.Failed = append(result.Failed, restartFailure{Target: target, Err: err})
 			continue
 		}
+		if restartInfo == nil {
+			continue
+		}
+		result.Restarted = append(result.Restarted, *restartInfo)
+		result.RestartedTargets = append(result.RestartedTargets, target)
 
-		logger.Info("Processing pod for restart", "pod", pod.Name, "namespace", pod.Namespace)
+		// Count each workload once per reload
+		if key := kind + "/" + name; !restartedWorkloads[key] {
+			restartedWorkloads[key] = true
+			observeRestart(target.Pod.Namespace, kind, name, target.Policy)
+		}
+	}
 
-		switch cr.Spec.RestartPolicy {
-		case configv1.RestartPolicyAnnotation:
-			restartInfo := r.handleAnnotationRestart(ctx, &pod, restartAnnotation, now)
-			if restartInfo != nil {
-				restartedPods = append(restartedPods, *restartInfo)
-			}
+	r.emitRestartSpans(ctx, cr, spans)
 
-		case configv1.RestartPolicyDelete:
-			restartInfo := r.handleDeleteRestart(ctx, &pod, now)
-			if restartInfo != nil {
-				restartedPods = append(restartedPods, *restartInfo)
+	r.recordEventf(cr, corev1.EventTypeNormal, EventReasonRestartTriggered,
+		"Restarted %d of %d affected pods using %s policy: %s",
+		len(result.Restarted), len(targets), cr.Spec.RestartPolicy, cause)
+
+	return result
+}
+
+// planRestarts resolves the pods affected by the watched resources and the
+// workloads that own them. It never mutates the cluster so that dry runs and
+// real runs share the same plan.
+func (r *ConfigReloaderReconciler) planRestarts(ctx context.Context,
+	cr *configv1.ConfigReloader) ([]restartTarget, []restartFailure, error) {
+	logger := log.FromContext(ctx)
+
+	pods, err := r.affectedPods(ctx, cr)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	targets := make([]restartTarget, 0, 10)
+	var failures []restartFailure
+	for _, pod := range pods {
+		target := restartTarget{Pod: pod, Policy: cr.Spec.RestartPolicy}
+
+		if len(pod.OwnerReferences) > 0 {
+			kind, name, err := r.resolveOwningWorkload(ctx, pod)
+			if err != nil {
+				logger.Error(err, "failed to resolve owning workload", "pod", pod.Name)
+				observeRestartFailure(pod.Namespace, failureReasonOwnerResolution)
+				r.recordEventf(pod, corev1.EventTypeWarning, EventReasonRestartFailed,
+					"ConfigReloader %s/%s could not resolve the owning workload: %v", cr.Namespace, cr.Name, err)
+				failures = append(failures, restartFailure{Target: target, Err: err})
+				continue
+			}
+			if !isRestartableWorkload(kind) && target.Policy == configv1.RestartPolicyAnnotation {
+				logger.Info("Unsupported controller type for annotation restart",
+					"kind", kind, "name", name)
+				r.recordEventf(pod, corev1.EventTypeNormal, EventReasonRestartSkipped,
+					"ConfigReloader %s/%s cannot restart pods owned by %s %s with annotation policy",
+					cr.Namespace, cr.Name, kind, name)
+				continue
 			}
+			target.WorkloadKind, target.WorkloadName = kind, name
 		}
+
+		targets = append(targets, target)
 	}
 
-	return restartedPods, nil
+	return targets, failures, nil
+}
+
+// isDryRun reports whether restarts for the ConfigReloader should only be planned
+func (r *ConfigReloaderReconciler) isDryRun(cr *configv1.ConfigReloader) bool {
+	return r.DryRun || cr.Spec.DryRun
+}
+
+// reportDryRunPlan records the planned restarts in status and as Events
+func (r *ConfigReloaderReconciler) reportDryRunPlan(
+	ctx context.Context,
+	cr *configv1.ConfigReloader,
+	targets []restartTarget,
+) {
+	logger := log.FromContext(ctx)
+
+	plan := make([]configv1.PlannedRestart, 0, len(targets))
+	for _, target := range targets {
+		logger.Info("Dry run: would restart pod",
+			"pod", target.Pod.Name,
+			"namespace", target.Pod.Namespace,
+			"workloadKind", target.WorkloadKind,
+			"workloadName", target.WorkloadName,
+			"policy", target.Policy)
+
+		plan = append(plan, configv1.PlannedRestart{
+			PodName:      target.Pod.Name,
+			Namespace:    target.Pod.Namespace,
+			WorkloadKind: target.WorkloadKind,
+			WorkloadName: target.WorkloadName,
+			Policy:       target.Policy,
+		})
+
+		if target.WorkloadKind != "" {
+			r.recordEventf(cr, corev1.EventTypeNormal, EventReasonDryRunRestart,
+				"Would restart pod %s/%s via %s %s using %s policy",
+				target.Pod.Namespace, target.Pod.Name, target.WorkloadKind, target.WorkloadName, target.Policy)
+		} else {
+			r.recordEventf(cr, corev1.EventTypeNormal, EventReasonDryRunRestart,
+				"Would restart standalone pod %s/%s using %s policy",
+				target.Pod.Namespace, target.Pod.Name, target.Policy)
+		}
+	}
+
+	if len(plan) == 0 {
+		r.recordEventf(cr, corev1.EventTypeNormal, EventReasonDryRunRestart, "No pods would be restarted")
+	}
+
+	cr.Status.DryRunPlan = plan
 }
 
 // handleAnnotationRestart handles restart via annotation updates
 func (r *ConfigReloaderReconciler) handleAnnotationRestart(
 	ctx context.Context,
-	pod *corev1.Pod,
-	restartAnnotation string,
+	cr *configv1.ConfigReloader,
+	target restartTarget,
+	restartAnnotation, cause string,
 	now metav1.Time,
-) *configv1.PodRestart {
+) (*configv1.PodRestart, error) {
 	logger := log.FromContext(ctx)
+	pod := target.Pod
 
 	// Handle controller-managed pods
-	if len(pod.OwnerReferences) > 0 {
-		restarted, err := r.restartControllerManagedPod(ctx, pod, restartAnnotation)
+	if target.WorkloadKind != "" {
+		workload, err := r.restartWorkload(ctx, pod.Namespace, target.WorkloadKind, target.WorkloadName, restartAnnotation)
 		if err != nil {
 			logger.Error(err, "failed to restart controller-managed pod", "pod", pod.Name)
-			return nil
+			observeRestartFailure(pod.Namespace, failureReasonWorkloadUpdate)
+			r.recordEventf(cr, corev1.EventTypeWarning, EventReasonRestartFailed,
+				"Failed to restart %s %s/%s: %v", target.WorkloadKind, pod.Namespace, target.WorkloadName, err)
+			r.recordEventf(pod, corev1.EventTypeWarning, EventReasonRestartFailed,
+				"ConfigReloader %s/%s failed to restart %s %s: %v",
+				cr.Namespace, cr.Name, target.WorkloadKind, target.WorkloadName, err)
+			if workload := r.eventWorkload(ctx, pod.Namespace, target.WorkloadKind, target.WorkloadName); workload != nil {
+				r.recordEventf(workload, corev1.EventTypeWarning, EventReasonRestartFailed,
+					"Rolling restart by ConfigReloader %s/%s failed: %v", cr.Namespace, cr.Name, err)
+			}
+			return nil, err
 		}
-		if restarted {
+		if workload != nil {
+			r.recordEventf(workload, corev1.EventTypeNormal, EventReasonRestartTriggered,
+				"Rolling restart triggered by ConfigReloader %s/%s: %s", cr.Namespace, cr.Name, cause)
 			return &configv1.PodRestart{
 				PodName:     pod.Name,
 				Namespace:   pod.Namespace,
 				RestartTime: &now,
 				Reason:      "ConfigMap/Secret changed - controller updated",
-			}
+			}, nil
 		}
 	} else {
 		logger.Info("Standalone pod detected with annotation restart policy - this won't restart the pod",
@@ -101,64 +302,61 @@ func (r *ConfigReloaderReconciler) handleAnnotationRestart(
 
 		if err := r.Update(ctx, pod); err != nil {
 			logger.Error(err, "failed to update pod annotation", "pod", pod.Name)
-			return nil
+			observeRestartFailure(pod.Namespace, failureReasonPodAnnotate)
+			r.recordEventf(cr, corev1.EventTypeWarning, EventReasonRestartFailed,
+				"Failed to annotate pod %s/%s: %v", pod.Namespace, pod.Name, err)
+			r.recordEventf(pod, corev1.EventTypeWarning, EventReasonRestartFailed,
+				"ConfigReloader %s/%s failed to annotate the pod: %v", cr.Namespace, cr.Name, err)
+			return nil, fmt.Errorf("failed to annotate pod %s/%s: %w", pod.Namespace, pod.Name, err)
 		}
 
+		r.recordEventf(pod, corev1.EventTypeWarning, EventReasonRestartSkipped,
+			"ConfigReloader %s/%s annotated the pod but cannot restart standalone pods with annotation policy: %s",
+			cr.Namespace, cr.Name, cause)
+
 		return &configv1.PodRestart{
 			PodName:     pod.Name,
 			Namespace:   pod.Namespace,
 			RestartTime: &now,
 			Reason:      "ConfigMap/Secret changed - annotation updated (pod not restarted)",
-		}
+		}, nil
 	}
 
-	return nil
+	return nil, nil
 }
 
 func (r *ConfigReloaderReconciler) handleDeleteRestart(
 	ctx context.Context,
+	cr *configv1.ConfigReloader,
 	pod *corev1.Pod,
+	cause string,
 	now metav1.Time,
-) *configv1.PodRestart {
+) (*configv1.PodRestart, error) {
 	logger := log.FromContext(ctx)
 
 	logger.Info("Deleting pod for restart", "pod", pod.Name, "namespace", pod.Namespace)
 
 	if err := r.Delete(ctx, pod); err != nil {
+		if apierrors.IsNotFound(err) {
+			// The pod is already gone, nothing left to restart
+			return nil, nil
+		}
 		logger.Error(err, "failed to delete pod", "pod", pod.Name)
-		return nil
+		observeRestartFailure(pod.Namespace, failureReasonPodDelete)
+		r.recordEventf(cr, corev1.EventTypeWarning, EventReasonRestartFailed,
+			"Failed to delete pod %s/%s: %v", pod.Namespace, pod.Name, err)
+		r.recordEventf(pod, corev1.EventTypeWarning, EventReasonRestartFailed,
+			"ConfigReloader %s/%s failed to delete the pod: %v", cr.Namespace, cr.Name, err)
+		return nil, fmt.Errorf("failed to delete pod %s/%s: %w", pod.Namespace, pod.Name, err)
 	}
 
+	r.recordEventf(pod, corev1.EventTypeNormal, EventReasonRestartTriggered,
+		"Pod deleted by ConfigReloader %s/%s: %s", cr.Namespace, cr.Name, cause)
+
 	return &configv1.PodRestart{
 		PodName:     pod.Name,
 		Namespace:   pod.Namespace,
 		RestartTime: &now,
 		Reason:      "ConfigMap/Secret changed - pod deleted",
-	}
-}
-
-func (r *ConfigReloaderReconciler) restartControllerManagedPod(
-	ctx context.Context,
-	pod *corev1.Pod,
-	restartAnnotation string,
-) (bool, error) {
-	logger := log.FromContext(ctx)
-
-	for _, ownerRef := range pod.OwnerReferences {
-		switch ownerRef.Kind {
-		case "Deployment":
-			return r.restartDeployment(ctx, pod.Namespace, ownerRef.Name, restartAnnotation)
-		case "StatefulSet":
-			return r.restartStatefulSet(ctx, pod.Namespace, ownerRef.Name, restartAnnotation)
-		case "DaemonSet":
-			return r.restartDaemonSet(ctx, pod.Namespace, ownerRef.Name, restartAnnotation)
-		case "ReplicaSet":
-			return r.restartReplicaSet(ctx, pod.Namespace, ownerRef.Name, restartAnnotation)
-		default:
-			logger.Info("Unsupported controller type for annotation restart",
-				"kind", ownerRef.Kind, "name", ownerRef.Name)
-		}
-	}
-
-	return false, nil
+	}, nil
 }
@@ -0,0 +1,130 @@
+package controller
+
+import (
+	"context"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	configv1 "github.com/shehbazk/config-reloader-operator/api/v1"
+)
+
+// workloadRolloutState returns the rollout state of a restarted workload. It
+// follows the checks of kubectl rollout status. Kinds whose rollout cannot be
+// observed are reported as complete.
+func (r *ConfigReloaderReconciler) workloadRolloutState(
+	ctx context.Context,
+	namespace, kind, name string,
+) (configv1.RolloutState, error) {
+	key := types.NamespacedName{Name: name, Namespace: namespace}
+
+	switch kind {
+	case "Deployment":
+		deployment := &appsv1.Deployment{}
+		if err := r.Get(ctx, key, deployment); err != nil {
+			return "", err
+		}
+		return deploymentRolloutState(deployment), nil
+	case "StatefulSet":
+		statefulSet := &appsv1.StatefulSet{}
+		if err := r.Get(ctx, key, statefulSet); err != nil {
+			return "", err
+		}
+		return statefulSetRolloutState(statefulSet), nil
+	case "DaemonSet":
+		daemonSet := &appsv1.DaemonSet{}
+		if err := r.Get(ctx, key, daemonSet); err != nil {
+			return "", err
+		}
+		return daemonSetRolloutState(daemonSet), nil
+	case "ReplicaSet":
+		replicaSet := &appsv1.ReplicaSet{}
+		if err := r.Get(ctx, key, replicaSet); err != nil {
+			return "", err
+		}
+		return replicaSetRolloutState(replicaSet), nil
+	case "Pod":
+		pod := &corev1.Pod{}
+		if err := r.Get(ctx, key, pod); err != nil {
+			return "", err
+		}
+	}
+	return configv1.RolloutComplete, nil
+}
+
+func deploymentRolloutState(deployment *appsv1.Deployment) configv1.RolloutState {
+	if deployment.Generation > deployment.Status.ObservedGeneration {
+		return configv1.RolloutProgressing
+	}
+	for _, condition := range deployment.Status.Conditions {
+		if condition.Type == appsv1.DeploymentProgressing && condition.Reason == "ProgressDeadlineExceeded" {
+			return configv1.RolloutFailed
+		}
+	}
+
+	replicas := replicasOrDefault(deployment.Spec.Replicas)
+	status := deployment.Status
+	if status.UpdatedReplicas < replicas || status.Replicas > status.UpdatedReplicas ||
+		status.AvailableReplicas < status.UpdatedReplicas {
+		return configv1.RolloutProgressing
+	}
+	return configv1.RolloutComplete
+}
+
+func statefulSetRolloutState(statefulSet *appsv1.StatefulSet) configv1.RolloutState {
+	if statefulSet.Spec.UpdateStrategy.Type == appsv1.OnDeleteStatefulSetStrategyType {
+		// Pods are only replaced when deleted, there is no rollout to follow
+		return configv1.RolloutComplete
+	}
+	if statefulSet.Generation > statefulSet.Status.ObservedGeneration {
+		return configv1.RolloutProgressing
+	}
+
+	replicas := replicasOrDefault(statefulSet.Spec.Replicas)
+	status := statefulSet.Status
+	if status.ReadyReplicas < replicas {
+		return configv1.RolloutProgressing
+	}
+	if rolling := statefulSet.Spec.UpdateStrategy.RollingUpdate; rolling != nil && rolling.Partition != nil {
+		if status.UpdatedReplicas < replicas-*rolling.Partition {
+			return configv1.RolloutProgressing
+		}
+		return configv1.RolloutComplete
+	}
+	if status.UpdateRevision != status.CurrentRevision {
+		return configv1.RolloutProgressing
+	}
+	return configv1.RolloutComplete
+}
+
+func daemonSetRolloutState(daemonSet *appsv1.DaemonSet) configv1.RolloutState {
+	if daemonSet.Spec.UpdateStrategy.Type == appsv1.OnDeleteDaemonSetStrategyType {
+		return configv1.RolloutComplete
+	}
+	if daemonSet.Generation > daemonSet.Status.ObservedGeneration {
+		return configv1.RolloutProgressing
+	}
+
+	status := daemonSet.Status
+	if status.UpdatedNumberScheduled < status.DesiredNumberScheduled ||
+		status.NumberAvailable < status.DesiredNumberScheduled {
+		return configv1.RolloutProgressing
+	}
+	return configv1.RolloutComplete
+}
+
+func replicaSetRolloutState(replicaSet *appsv1.ReplicaSet) configv1.RolloutState {
+	if replicaSet.Generation > replicaSet.Status.ObservedGeneration ||
+		replicaSet.Status.AvailableReplicas < replicasOrDefault(replicaSet.Spec.Replicas) {
+		return configv1.RolloutProgressing
+	}
+	return configv1.RolloutComplete
+}
+
+func replicasOrDefault(replicas *int32) int32 {
+	if replicas == nil {
+		return 1
+	}
+	return *replicas
+}
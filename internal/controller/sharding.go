@@ -0,0 +1,61 @@
+package controller
+
+import (
+	"context"
+	"strconv"
+
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	configv1 "github.com/shehbazk/config-reloader-operator/api/v1"
+	"github.com/shehbazk/config-reloader-operator/internal/sharding"
+)
+
+// claimConfigReloader claims the shard of the ConfigReloader when sharding is
+// enabled. It returns the reader to fetch the ConfigReloader with, a function
+// releasing the claim, and false when another replica reconciles it.
+func (r *ConfigReloaderReconciler) claimConfigReloader(key types.NamespacedName) (client.Reader, func(), bool) {
+	if r.Shards == nil {
+		return r.Client, func() {}, true
+	}
+
+	claim, ok := r.Shards.Claim(key)
+	if !ok {
+		return nil, nil, false
+	}
+	if claim.First {
+		// The previous holder of the shard may have recorded a restart
+		// that is not in the cache yet
+		return r.apiReader(), claim.Release, true
+	}
+	return r.Client, claim.Release, true
+}
+
+// shardConfigReloaders maps the Lease of a shard acquired by this replica to
+// the ConfigReloaders of the shard
+func (r *ConfigReloaderReconciler) shardConfigReloaders(ctx context.Context, lease client.Object) []reconcile.Request {
+	logger := log.FromContext(ctx)
+
+	index, err := strconv.Atoi(lease.GetLabels()[sharding.ShardLabel])
+	if err != nil {
+		logger.Error(err, "invalid shard lease", "lease", lease.GetName())
+		return nil
+	}
+
+	var configReloaders configv1.ConfigReloaderList
+	if err := r.List(ctx, &configReloaders); err != nil {
+		logger.Error(err, "failed to list ConfigReloaders of shard", "shard", index)
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for _, cr := range configReloaders.Items {
+		key := types.NamespacedName{Namespace: cr.Namespace, Name: cr.Name}
+		if r.Shards.ShardOf(key) == index {
+			requests = append(requests, reconcile.Request{NamespacedName: key})
+		}
+	}
+	return requests
+}
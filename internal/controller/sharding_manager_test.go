@@ -0,0 +1,197 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/ptr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/config"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
+
+	configv1 "github.com/shehbazk/config-reloader-operator/api/v1"
+	"github.com/shehbazk/config-reloader-operator/internal/sharding"
+)
+
+var _ = Describe("Sharded managers", Ordered, func() {
+	const count = 12
+
+	type replica struct {
+		coordinator *sharding.Coordinator
+		stop        context.CancelFunc
+		done        chan struct{}
+	}
+
+	var replicas []*replica
+
+	// startReplica runs a manager reconciling the ConfigReloaders of the
+	// shards it acquires, as a replica of the operator does
+	startReplica := func(identity string) *replica {
+		mgr, err := ctrl.NewManager(cfg, ctrl.Options{
+			Scheme:                 scheme.Scheme,
+			Metrics:                metricsserver.Options{BindAddress: "0"},
+			HealthProbeBindAddress: "0",
+			Controller:             config.Controller{SkipNameValidation: ptr.To(true)},
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		leaseClient, err := client.New(cfg, client.Options{Scheme: scheme.Scheme})
+		Expect(err).NotTo(HaveOccurred())
+		coordinator, err := sharding.New(leaseClient, sharding.Options{
+			Name:          "sharded-test",
+			Namespace:     "default",
+			Identity:      identity,
+			Shards:        8,
+			LeaseDuration: 4 * time.Second,
+			RenewDeadline: 3 * time.Second,
+			RetryPeriod:   500 * time.Millisecond,
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(mgr.Add(coordinator)).To(Succeed())
+
+		Expect((&ConfigReloaderReconciler{
+			Client:    mgr.GetClient(),
+			Scheme:    mgr.GetScheme(),
+			APIReader: mgr.GetAPIReader(),
+			Shards:    coordinator,
+		}).SetupWithManager(mgr)).To(Succeed())
+
+		mgrCtx, stop := context.WithCancel(ctx)
+		r := &replica{coordinator: coordinator, stop: stop, done: make(chan struct{})}
+		go func() {
+			defer GinkgoRecover()
+			defer close(r.done)
+			Expect(mgr.Start(mgrCtx)).To(Succeed())
+		}()
+		return r
+	}
+
+	stopReplica := func(r *replica) {
+		r.stop()
+		<-r.done
+	}
+
+	// owners returns how many replicas own every ConfigReloader
+	owners := func(running ...*replica) map[string]int {
+		owners := make(map[string]int)
+		for i := range count {
+			key := types.NamespacedName{Namespace: "default", Name: fmt.Sprintf("sharded-%d", i)}
+			for _, r := range running {
+				if r.coordinator.Owns(key) {
+					owners[key.Name]++
+				}
+			}
+		}
+		return owners
+	}
+
+	reconciled := func(name string) bool {
+		var cr configv1.ConfigReloader
+		if err := k8sClient.Get(ctx, types.NamespacedName{Namespace: "default", Name: name}, &cr); err != nil {
+			return false
+		}
+		return controllerutil.ContainsFinalizer(&cr, ConfigReloaderFinalizer) && cr.Status.ObservedGeneration == cr.Generation
+	}
+
+	BeforeAll(func() {
+		replicas = []*replica{startReplica("replica-a"), startReplica("replica-b")}
+		for i := range count {
+			Expect(k8sClient.Create(ctx, &configv1.ConfigReloader{
+				ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("sharded-%d", i), Namespace: "default"},
+			})).To(Succeed())
+		}
+	})
+
+	AfterAll(func() {
+		By("removing the ConfigReloaders while the replicas run to clear their finalizers")
+		for i := range count {
+			cr := &configv1.ConfigReloader{
+				ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("sharded-%d", i), Namespace: "default"},
+			}
+			Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, cr))).To(Succeed())
+		}
+		Eventually(func(g Gomega) {
+			for i := range count {
+				key := types.NamespacedName{Namespace: "default", Name: fmt.Sprintf("sharded-%d", i)}
+				err := k8sClient.Get(ctx, key, &configv1.ConfigReloader{})
+				g.Expect(errors.IsNotFound(err)).To(BeTrue())
+			}
+		}, 30*time.Second).Should(Succeed())
+
+		for _, r := range replicas {
+			stopReplica(r)
+		}
+	})
+
+	It("should split the ConfigReloaders between the replicas", func() {
+		Eventually(func(g Gomega) {
+			g.Expect(replicas[0].coordinator.Owned()).NotTo(BeEmpty())
+			g.Expect(replicas[1].coordinator.Owned()).NotTo(BeEmpty())
+			g.Expect(len(replicas[0].coordinator.Owned()) + len(replicas[1].coordinator.Owned())).To(Equal(8))
+		}, 30*time.Second).Should(Succeed())
+
+		Expect(owners(replicas...)).To(HaveLen(count))
+		Expect(owners(replicas...)).To(HaveEach(1))
+
+		for i := range count {
+			Eventually(reconciled, 30*time.Second).WithArguments(fmt.Sprintf("sharded-%d", i)).Should(BeTrue())
+		}
+	})
+
+	It("should rebalance when a replica joins", func() {
+		replicas = append(replicas, startReplica("replica-c"))
+
+		Eventually(func(g Gomega) {
+			for _, r := range replicas {
+				g.Expect(r.coordinator.Owned()).NotTo(BeEmpty())
+			}
+			g.Expect(owners(replicas...)).To(HaveLen(count))
+			g.Expect(owners(replicas...)).To(HaveEach(1))
+		}, 30*time.Second).Should(Succeed())
+	})
+
+	It("should take over the shards of a replica that leaves", func() {
+		stopReplica(replicas[0])
+		replicas = replicas[1:]
+
+		Eventually(func(g Gomega) {
+			g.Expect(len(replicas[0].coordinator.Owned()) + len(replicas[1].coordinator.Owned())).To(Equal(8))
+			g.Expect(owners(replicas...)).To(HaveLen(count))
+			g.Expect(owners(replicas...)).To(HaveEach(1))
+		}, 30*time.Second).Should(Succeed())
+
+		By("reconciling ConfigReloaders created after the handover")
+		Expect(k8sClient.Create(ctx, &configv1.ConfigReloader{
+			ObjectMeta: metav1.ObjectMeta{Name: "sharded-late", Namespace: "default"},
+		})).To(Succeed())
+		Eventually(reconciled, 30*time.Second).WithArguments("sharded-late").Should(BeTrue())
+		Expect(k8sClient.Delete(ctx, &configv1.ConfigReloader{
+			ObjectMeta: metav1.ObjectMeta{Name: "sharded-late", Namespace: "default"},
+		})).To(Succeed())
+	})
+})
@@ -0,0 +1,150 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	configv1 "github.com/shehbazk/config-reloader-operator/api/v1"
+	"github.com/shehbazk/config-reloader-operator/internal/sharding"
+)
+
+var _ = Describe("Sharding", func() {
+	const shards = 4
+
+	var (
+		ctx        context.Context
+		c          client.Client
+		apiReader  client.Reader
+		reconciler *ConfigReloaderReconciler
+	)
+
+	newCoordinator := func(identity string) *sharding.Coordinator {
+		coordinator, err := sharding.New(c, sharding.Options{
+			Name:          "config-reloader",
+			Namespace:     "config-reloader-system",
+			Identity:      identity,
+			Shards:        shards,
+			LeaseDuration: 15 * time.Second,
+			RenewDeadline: 10 * time.Second,
+			RetryPeriod:   time.Second,
+		})
+		Expect(err).NotTo(HaveOccurred())
+		return coordinator
+	}
+
+	start := func(coordinator *sharding.Coordinator) {
+		coordinatorCtx, cancel := context.WithCancel(ctx)
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			_ = coordinator.Start(coordinatorCtx)
+		}()
+		DeferCleanup(func() {
+			cancel()
+			<-done
+		})
+		Eventually(coordinator.Owned).Should(HaveLen(shards))
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		scheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+		Expect(configv1.AddToScheme(scheme)).To(Succeed())
+
+		var objs []client.Object
+		for i := range 20 {
+			objs = append(objs, &configv1.ConfigReloader{
+				ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("reloader-%d", i), Namespace: "default"},
+			})
+		}
+		c = fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).
+			WithStatusSubresource(&configv1.ConfigReloader{}).Build()
+		apiReader = fake.NewClientBuilder().WithScheme(scheme).Build()
+		reconciler = &ConfigReloaderReconciler{Client: c, Scheme: scheme, APIReader: apiReader}
+	})
+
+	It("should skip the ConfigReloaders of shards held by another replica", func() {
+		start(newCoordinator("other"))
+		reconciler.Shards = newCoordinator("self")
+
+		key := types.NamespacedName{Name: "reloader-0", Namespace: "default"}
+		_, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+		Expect(err).NotTo(HaveOccurred())
+
+		var cr configv1.ConfigReloader
+		Expect(c.Get(ctx, key, &cr)).To(Succeed())
+		Expect(cr.Finalizers).To(BeEmpty())
+	})
+
+	It("should reconcile the ConfigReloaders of its own shards", func() {
+		reconciler.Shards = newCoordinator("self")
+		start(reconciler.Shards)
+
+		key := types.NamespacedName{Name: "reloader-0", Namespace: "default"}
+		Expect(c.Get(ctx, key, &configv1.ConfigReloader{})).To(Succeed())
+
+		By("reading the ConfigReloader from the API server the first time")
+		reader, release, ok := reconciler.claimConfigReloader(key)
+		Expect(ok).To(BeTrue())
+		Expect(reader).To(BeIdenticalTo(apiReader))
+		release()
+
+		reader, release, ok = reconciler.claimConfigReloader(key)
+		Expect(ok).To(BeTrue())
+		Expect(reader).To(BeIdenticalTo(c))
+		release()
+
+		_, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+		Expect(err).NotTo(HaveOccurred())
+
+		var cr configv1.ConfigReloader
+		Expect(c.Get(ctx, key, &cr)).To(Succeed())
+		Expect(cr.Finalizers).To(ContainElement(ConfigReloaderFinalizer))
+	})
+
+	It("should enqueue the ConfigReloaders of an acquired shard", func() {
+		reconciler.Shards = newCoordinator("self")
+
+		var enqueued []types.NamespacedName
+		for index := range shards {
+			lease := &coordinationv1.Lease{ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{sharding.ShardLabel: strconv.Itoa(index)},
+			}}
+			for _, req := range reconciler.shardConfigReloaders(ctx, lease) {
+				Expect(reconciler.Shards.ShardOf(req.NamespacedName)).To(Equal(index))
+				enqueued = append(enqueued, req.NamespacedName)
+			}
+		}
+		Expect(enqueued).To(HaveLen(20))
+	})
+})
@@ -24,6 +24,10 @@ func (r *ConfigReloaderReconciler) updateCondition(
 		if existingCondition.Type == conditionType {
 			if existingCondition.Status != status {
 				cr.Status.Conditions[i] = condition
+			} else {
+				// Keep the transition time but report the latest reason
+				cr.Status.Conditions[i].Reason = reason
+				cr.Status.Conditions[i].Message = message
 			}
 			return
 		}
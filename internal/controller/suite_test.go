@@ -18,9 +18,13 @@ package controller
 
 import (
 	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -31,8 +35,11 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/envtest"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/conversion"
 
 	configv1 "github.com/shehbazk/config-reloader-operator/api/v1"
+	configv2 "github.com/shehbazk/config-reloader-operator/api/v2"
 	// +kubebuilder:scaffold:imports
 )
 
@@ -61,10 +68,15 @@ var _ = BeforeSuite(func() {
 	var err error
 	err = configv1.AddToScheme(scheme.Scheme)
 	Expect(err).NotTo(HaveOccurred())
+	err = configv2.AddToScheme(scheme.Scheme)
+	Expect(err).NotTo(HaveOccurred())
 
 	// +kubebuilder:scaffold:scheme
 
 	By("bootstrapping test environment")
+	// v2 is the storage version, envtest points the CRD conversion of the
+	// convertible kinds at the webhook server started below so that v1
+	// objects are stored without losing fields
 	testEnv = &envtest.Environment{
 		CRDDirectoryPaths:     []string{filepath.Join("..", "..", "config", "crd", "bases")},
 		ErrorIfCRDPathMissing: true,
@@ -80,11 +92,41 @@ var _ = BeforeSuite(func() {
 	Expect(err).NotTo(HaveOccurred())
 	Expect(cfg).NotTo(BeNil())
 
+	By("starting the conversion webhook")
+	startConversionWebhook()
+
 	k8sClient, err = client.New(cfg, client.Options{Scheme: scheme.Scheme})
 	Expect(err).NotTo(HaveOccurred())
 	Expect(k8sClient).NotTo(BeNil())
 })
 
+// startConversionWebhook serves the conversion webhook on the address and
+// certificates envtest configured the CRDs with, and waits until it accepts
+// connections
+func startConversionWebhook() {
+	options := testEnv.WebhookInstallOptions
+	server := webhook.NewServer(webhook.Options{
+		Host:    options.LocalServingHost,
+		Port:    options.LocalServingPort,
+		CertDir: options.LocalServingCertDir,
+	})
+	server.Register("/convert", conversion.NewWebhookHandler(scheme.Scheme))
+	go func() {
+		defer GinkgoRecover()
+		Expect(server.Start(ctx)).To(Succeed())
+	}()
+
+	address := fmt.Sprintf("%s:%d", options.LocalServingHost, options.LocalServingPort)
+	Eventually(func() error {
+		conn, err := tls.DialWithDialer(&net.Dialer{Timeout: time.Second}, "tcp", address,
+			&tls.Config{InsecureSkipVerify: true}) // nolint:gosec
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	}).Should(Succeed())
+}
+
 var _ = AfterSuite(func() {
 	By("tearing down the test environment")
 	cancel()
@@ -0,0 +1,115 @@
+package controller
+
+import (
+	"context"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	configv1 "github.com/shehbazk/config-reloader-operator/api/v1"
+)
+
+// targetStatusRef returns the unit reported in status.targets for a restart
+// target: the owning workload, or the pod itself for standalone pods
+func targetStatusRef(target restartTarget) (string, string) {
+	if target.WorkloadKind != "" {
+		return target.WorkloadKind, target.WorkloadName
+	}
+	return "Pod", target.Pod.Name
+}
+
+// recordTargetStatuses updates status.targets with the outcome of a restart
+// triggered by the watched resources with the given hash
+func recordTargetStatuses(cr *configv1.ConfigReloader, result *restartResult, configHash string, now metav1.Time) {
+	index := make(map[string]int, len(cr.Status.Targets))
+	for i, target := range cr.Status.Targets {
+		index[failedTargetKey(target.Namespace, target.Kind, target.Name)] = i
+	}
+
+	entry := func(target restartTarget) *configv1.TargetStatus {
+		kind, name := targetStatusRef(target)
+		key := failedTargetKey(target.Pod.Namespace, kind, name)
+		if i, ok := index[key]; ok {
+			return &cr.Status.Targets[i]
+		}
+		cr.Status.Targets = append(cr.Status.Targets, configv1.TargetStatus{
+			Kind:      kind,
+			Name:      name,
+			Namespace: target.Pod.Namespace,
+		})
+		index[key] = len(cr.Status.Targets) - 1
+		return &cr.Status.Targets[index[key]]
+	}
+
+	for _, target := range result.RestartedTargets {
+		status := entry(target)
+		status.LastConfigHash = configHash
+		status.LastRestartTime = &now
+		status.ConsecutiveFailures = 0
+		status.RolloutState = configv1.RolloutComplete
+		if target.WorkloadKind != "" && isRestartableWorkload(target.WorkloadKind) {
+			status.RolloutState = configv1.RolloutProgressing
+		}
+	}
+
+	// A workload counts as failed if any of its pods failed to restart
+	failed := make(map[string]bool, len(result.Failed))
+	for _, failure := range result.Failed {
+		kind, name := targetStatusRef(failure.Target)
+		key := failedTargetKey(failure.Target.Pod.Namespace, kind, name)
+		if failed[key] {
+			continue
+		}
+		failed[key] = true
+		status := entry(failure.Target)
+		status.ConsecutiveFailures++
+		status.RolloutState = configv1.RolloutFailed
+	}
+}
+
+// refreshTargetStatuses follows the rollouts started by previous restarts and
+// drops the targets that no longer exist
+func (r *ConfigReloaderReconciler) refreshTargetStatuses(ctx context.Context, cr *configv1.ConfigReloader) {
+	logger := log.FromContext(ctx)
+
+	targets := cr.Status.Targets[:0]
+	for _, target := range cr.Status.Targets {
+		state, err := r.workloadRolloutState(ctx, target.Namespace, target.Kind, target.Name)
+		switch {
+		case apierrors.IsNotFound(err):
+			r.rollouts.take(cr, target.Namespace, target.Kind, target.Name)
+			continue
+		case err != nil:
+			logger.Error(err, "failed to get rollout state", "kind", target.Kind, "name", target.Name)
+		case target.ConsecutiveFailures == 0:
+			// Failed restarts keep their state until a restart succeeds
+			if target.RolloutState == configv1.RolloutProgressing && state != configv1.RolloutProgressing {
+				r.endRolloutSpan(ctx, cr, target, state)
+			}
+			target.RolloutState = state
+		}
+		targets = append(targets, target)
+	}
+
+	if len(targets) == 0 {
+		targets = nil
+	}
+	cr.Status.Targets = targets
+}
+
+// updateTargetSummary counts the targets by rollout state
+func updateTargetSummary(cr *configv1.ConfigReloader) {
+	summary := configv1.TargetSummary{Total: int32(len(cr.Status.Targets))}
+	for _, target := range cr.Status.Targets {
+		switch target.RolloutState {
+		case configv1.RolloutProgressing:
+			summary.Progressing++
+		case configv1.RolloutComplete:
+			summary.Complete++
+		case configv1.RolloutFailed:
+			summary.Failed++
+		}
+	}
+	cr.Status.Summary = summary
+}
@@ -0,0 +1,161 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+
+	configv1 "github.com/shehbazk/config-reloader-operator/api/v1"
+)
+
+var _ = Describe("Target status", func() {
+	web := restartTarget{
+		Pod:          &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "web-abc", Namespace: "default"}},
+		WorkloadKind: "Deployment",
+		WorkloadName: "web",
+		Policy:       configv1.RestartPolicyDelete,
+	}
+	webOther := restartTarget{
+		Pod:          &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "web-def", Namespace: "default"}},
+		WorkloadKind: "Deployment",
+		WorkloadName: "web",
+		Policy:       configv1.RestartPolicyDelete,
+	}
+	standalone := restartTarget{
+		Pod:    &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "debug", Namespace: "default"}},
+		Policy: configv1.RestartPolicyDelete,
+	}
+
+	It("should report one entry per workload", func() {
+		cr := &configv1.ConfigReloader{}
+		now := metav1.Now()
+
+		recordTargetStatuses(cr, &restartResult{
+			RestartedTargets: []restartTarget{web, webOther, standalone},
+		}, "hash-1", now)
+		updateTargetSummary(cr)
+
+		Expect(cr.Status.Targets).To(HaveLen(2))
+		Expect(cr.Status.Targets[0].Kind).To(Equal("Deployment"))
+		Expect(cr.Status.Targets[0].RolloutState).To(Equal(configv1.RolloutProgressing))
+		Expect(cr.Status.Targets[0].LastConfigHash).To(Equal("hash-1"))
+		Expect(cr.Status.Targets[1].Kind).To(Equal("Pod"))
+		Expect(cr.Status.Targets[1].RolloutState).To(Equal(configv1.RolloutComplete))
+		Expect(cr.Status.Summary).To(Equal(configv1.TargetSummary{Total: 2, Progressing: 1, Complete: 1}))
+	})
+
+	It("should count consecutive failures once per workload", func() {
+		cr := &configv1.ConfigReloader{}
+		now := metav1.Now()
+		failed := &restartResult{Failed: []restartFailure{
+			{Target: web, Err: errors.New("conflict")},
+			{Target: webOther, Err: errors.New("conflict")},
+		}}
+
+		recordTargetStatuses(cr, failed, "hash-1", now)
+		recordTargetStatuses(cr, failed, "hash-1", now)
+		Expect(cr.Status.Targets).To(HaveLen(1))
+		Expect(cr.Status.Targets[0].ConsecutiveFailures).To(Equal(int32(2)))
+		Expect(cr.Status.Targets[0].RolloutState).To(Equal(configv1.RolloutFailed))
+		Expect(cr.Status.Targets[0].LastRestartTime).To(BeNil())
+
+		recordTargetStatuses(cr, &restartResult{RestartedTargets: []restartTarget{web}}, "hash-2", now)
+		Expect(cr.Status.Targets[0].ConsecutiveFailures).To(BeZero())
+		Expect(cr.Status.Targets[0].LastConfigHash).To(Equal("hash-2"))
+	})
+
+	It("should count the failures of existing and new targets of the same result", func() {
+		cr := &configv1.ConfigReloader{}
+		now := metav1.Now()
+		recordTargetStatuses(cr, &restartResult{Failed: []restartFailure{
+			{Target: web, Err: errors.New("conflict")},
+		}}, "hash-1", now)
+		Expect(cr.Status.Targets).To(HaveLen(1))
+
+		// The new standalone target grows status.targets between two failed
+		// pods of the existing Deployment
+		recordTargetStatuses(cr, &restartResult{Failed: []restartFailure{
+			{Target: web, Err: errors.New("conflict")},
+			{Target: standalone, Err: errors.New("forbidden")},
+			{Target: webOther, Err: errors.New("conflict")},
+		}}, "hash-1", now)
+		Expect(cr.Status.Targets).To(HaveLen(2))
+		Expect(cr.Status.Targets[0].Name).To(Equal("web"))
+		Expect(cr.Status.Targets[0].ConsecutiveFailures).To(Equal(int32(2)))
+		Expect(cr.Status.Targets[1].Name).To(Equal("debug"))
+		Expect(cr.Status.Targets[1].ConsecutiveFailures).To(Equal(int32(1)))
+		Expect(cr.Status.Targets[1].RolloutState).To(Equal(configv1.RolloutFailed))
+	})
+
+	It("should follow Deployment rollouts", func() {
+		deployment := &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Generation: 2},
+			Spec:       appsv1.DeploymentSpec{Replicas: ptr.To[int32](2)},
+			Status: appsv1.DeploymentStatus{
+				ObservedGeneration: 2,
+				Replicas:           3,
+				UpdatedReplicas:    2,
+				AvailableReplicas:  2,
+			},
+		}
+		Expect(deploymentRolloutState(deployment)).To(Equal(configv1.RolloutProgressing))
+
+		deployment.Status.Replicas = 2
+		Expect(deploymentRolloutState(deployment)).To(Equal(configv1.RolloutComplete))
+
+		deployment.Generation = 3
+		Expect(deploymentRolloutState(deployment)).To(Equal(configv1.RolloutProgressing))
+
+		deployment.Status.ObservedGeneration = 3
+		deployment.Status.Conditions = []appsv1.DeploymentCondition{{
+			Type:   appsv1.DeploymentProgressing,
+			Status: corev1.ConditionFalse,
+			Reason: "ProgressDeadlineExceeded",
+		}}
+		Expect(deploymentRolloutState(deployment)).To(Equal(configv1.RolloutFailed))
+	})
+
+	It("should follow StatefulSet and DaemonSet rollouts", func() {
+		statefulSet := &appsv1.StatefulSet{
+			Spec: appsv1.StatefulSetSpec{Replicas: ptr.To[int32](1)},
+			Status: appsv1.StatefulSetStatus{
+				ReadyReplicas:   1,
+				CurrentRevision: "web-1",
+				UpdateRevision:  "web-2",
+			},
+		}
+		Expect(statefulSetRolloutState(statefulSet)).To(Equal(configv1.RolloutProgressing))
+		statefulSet.Status.CurrentRevision = "web-2"
+		Expect(statefulSetRolloutState(statefulSet)).To(Equal(configv1.RolloutComplete))
+
+		daemonSet := &appsv1.DaemonSet{Status: appsv1.DaemonSetStatus{
+			DesiredNumberScheduled: 3,
+			UpdatedNumberScheduled: 2,
+			NumberAvailable:        3,
+		}}
+		Expect(daemonSetRolloutState(daemonSet)).To(Equal(configv1.RolloutProgressing))
+		daemonSet.Status.UpdatedNumberScheduled = 3
+		Expect(daemonSetRolloutState(daemonSet)).To(Equal(configv1.RolloutComplete))
+	})
+})
@@ -0,0 +1,235 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	configv1 "github.com/shehbazk/config-reloader-operator/api/v1"
+)
+
+// tracerName is the instrumentation scope of the spans of the controller
+const tracerName = "github.com/shehbazk/config-reloader-operator/internal/controller"
+
+// Attributes of the change propagation spans
+const (
+	attrConfigReloaderName      = attribute.Key("configreloader.name")
+	attrConfigReloaderNamespace = attribute.Key("configreloader.namespace")
+	attrCause                   = attribute.Key("configreloader.cause")
+	attrDryRun                  = attribute.Key("configreloader.dry_run")
+	attrRestartPolicy           = attribute.Key("configreloader.restart_policy")
+	attrResourceKind            = attribute.Key("configreloader.resource.kind")
+	attrResourceName            = attribute.Key("configreloader.resource.name")
+	attrResourceNamespace       = attribute.Key("configreloader.resource.namespace")
+	attrResourceOldVersion      = attribute.Key("configreloader.resource.old_version")
+	attrResourceNewVersion      = attribute.Key("configreloader.resource.new_version")
+	attrChangedBy               = attribute.Key("configreloader.resource.changed_by")
+	attrWorkloadKind            = attribute.Key("configreloader.workload.kind")
+	attrWorkloadName            = attribute.Key("configreloader.workload.name")
+	attrWorkloadNamespace       = attribute.Key("configreloader.workload.namespace")
+	attrPods                    = attribute.Key("configreloader.workload.pods")
+	attrRolloutState            = attribute.Key("configreloader.rollout.state")
+)
+
+// tracer returns the tracer of the change propagation spans
+func (r *ConfigReloaderReconciler) tracer() trace.Tracer {
+	provider := r.TracerProvider
+	if provider == nil {
+		provider = otel.GetTracerProvider()
+	}
+	return provider.Tracer(tracerName)
+}
+
+// startReloadSpan starts the root span of a change propagation, reaching back
+// to the start of the reconcile, with a child span per detected change
+func (r *ConfigReloaderReconciler) startReloadSpan(
+	ctx context.Context,
+	cr *configv1.ConfigReloader,
+	changes []resourceChange,
+	cause string,
+	start time.Time,
+) (context.Context, trace.Span) {
+	ctx, span := r.tracer().Start(ctx, "ConfigReloader reload",
+		trace.WithNewRoot(),
+		trace.WithTimestamp(start),
+		trace.WithAttributes(
+			attrConfigReloaderName.String(cr.Name),
+			attrConfigReloaderNamespace.String(cr.Namespace),
+			attrCause.String(cause),
+			attrDryRun.Bool(r.isDryRun(cr)),
+			attrRestartPolicy.String(string(cr.Spec.RestartPolicy)),
+		))
+
+	now := time.Now()
+	for _, change := range changes {
+		attrs := []attribute.KeyValue{
+			attrResourceKind.String(change.Kind),
+			attrResourceName.String(change.Name),
+			attrResourceNamespace.String(change.Namespace),
+			attrResourceOldVersion.String(change.OldVersion),
+			attrResourceNewVersion.String(change.NewVersion),
+		}
+		if change.ChangedBy != nil {
+			attrs = append(attrs, attrChangedBy.String(change.ChangedBy.Manager))
+		}
+		_, changeSpan := r.tracer().Start(ctx, fmt.Sprintf("%s changed", change.Kind),
+			trace.WithTimestamp(start), trace.WithAttributes(attrs...))
+		changeSpan.End(trace.WithTimestamp(now))
+	}
+	return ctx, span
+}
+
+// endReloadSpan ends the root span of a change propagation with its outcome
+func endReloadSpan(span trace.Span, result *restartResult, err error) {
+	switch {
+	case err != nil:
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	case result != nil && len(result.Failed) > 0:
+		span.SetStatus(codes.Error, fmt.Sprintf("%d targets failed to restart", len(result.Failed)))
+	}
+	span.End()
+}
+
+// workloadSpan collects the restarts of the pods of a workload into a single
+// span
+type workloadSpan struct {
+	Kind      string
+	Name      string
+	Namespace string
+	Policy    configv1.RestartPolicy
+	Start     time.Time
+	End       time.Time
+	Pods      int
+	Errs      []error
+}
+
+// add records the restart of a pod of the workload
+func (w *workloadSpan) add(start time.Time, err error) {
+	if w.Start.IsZero() {
+		w.Start = start
+	}
+	w.End = time.Now()
+	w.Pods++
+	if err != nil {
+		w.Errs = append(w.Errs, err)
+	}
+}
+
+// emitRestartSpans records a span per restarted workload, and remembers the
+// spans of the workloads whose rollout is followed so that its completion
+// joins the trace
+func (r *ConfigReloaderReconciler) emitRestartSpans(
+	ctx context.Context,
+	cr *configv1.ConfigReloader,
+	workloads []*workloadSpan,
+) {
+	for _, w := range workloads {
+		_, span := r.tracer().Start(ctx, fmt.Sprintf("restart %s", w.Kind),
+			trace.WithTimestamp(w.Start),
+			trace.WithAttributes(
+				attrWorkloadKind.String(w.Kind),
+				attrWorkloadName.String(w.Name),
+				attrWorkloadNamespace.String(w.Namespace),
+				attrRestartPolicy.String(string(w.Policy)),
+				attrPods.Int(w.Pods),
+			))
+		for _, err := range w.Errs {
+			span.RecordError(err)
+		}
+		if len(w.Errs) > 0 {
+			span.SetStatus(codes.Error, fmt.Sprintf("%d of %d pods failed to restart", len(w.Errs), w.Pods))
+		} else if isRestartableWorkload(w.Kind) {
+			r.rollouts.add(cr, w.Namespace, w.Kind, w.Name, span.SpanContext(), w.End)
+		}
+		span.End(trace.WithTimestamp(w.End))
+	}
+}
+
+// endRolloutSpan records the completion of a followed rollout in the trace of
+// the restart that started it
+func (r *ConfigReloaderReconciler) endRolloutSpan(
+	ctx context.Context,
+	cr *configv1.ConfigReloader,
+	target configv1.TargetStatus,
+	state configv1.RolloutState,
+) {
+	rollout, ok := r.rollouts.take(cr, target.Namespace, target.Kind, target.Name)
+	if !ok {
+		return
+	}
+	ctx = trace.ContextWithSpanContext(ctx, rollout.parent)
+	_, span := r.tracer().Start(ctx, fmt.Sprintf("rollout %s", target.Kind),
+		trace.WithTimestamp(rollout.start),
+		trace.WithAttributes(
+			attrWorkloadKind.String(target.Kind),
+			attrWorkloadName.String(target.Name),
+			attrWorkloadNamespace.String(target.Namespace),
+			attrRolloutState.String(string(state)),
+		))
+	if state == configv1.RolloutFailed {
+		span.SetStatus(codes.Error, "rollout failed")
+	}
+	span.End()
+}
+
+// rolloutTraces holds the restart spans of the rollouts being followed. They
+// are kept in memory, so rollouts in progress when the operator restarts are
+// not traced to completion.
+type rolloutTraces struct {
+	mu       sync.Mutex
+	rollouts map[string]pendingRollout
+}
+
+type pendingRollout struct {
+	parent trace.SpanContext
+	start  time.Time
+}
+
+func rolloutKey(cr *configv1.ConfigReloader, namespace, kind, name string) string {
+	return cr.Namespace + "/" + cr.Name + "/" + failedTargetKey(namespace, kind, name)
+}
+
+// add remembers the restart span of a workload, replacing the span of a
+// rollout it superseded. Spans that are not sampled are left out.
+func (t *rolloutTraces) add(cr *configv1.ConfigReloader, namespace, kind, name string,
+	parent trace.SpanContext, start time.Time) {
+	if !parent.IsSampled() {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.rollouts == nil {
+		t.rollouts = make(map[string]pendingRollout)
+	}
+	t.rollouts[rolloutKey(cr, namespace, kind, name)] = pendingRollout{parent: parent, start: start}
+}
+
+// take returns and forgets the restart span of a workload
+func (t *rolloutTraces) take(cr *configv1.ConfigReloader, namespace, kind, name string) (pendingRollout, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	key := rolloutKey(cr, namespace, kind, name)
+	rollout, ok := t.rollouts[key]
+	delete(t.rollouts, key)
+	return rollout, ok
+}
+
+// forget drops the rollouts of a deleted ConfigReloader
+func (t *rolloutTraces) forget(cr *configv1.ConfigReloader) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	prefix := cr.Namespace + "/" + cr.Name + "/"
+	for key := range t.rollouts {
+		if strings.HasPrefix(key, prefix) {
+			delete(t.rollouts, key)
+		}
+	}
+}
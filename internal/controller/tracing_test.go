@@ -0,0 +1,201 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	configv1 "github.com/shehbazk/config-reloader-operator/api/v1"
+)
+
+var _ = Describe("Tracing", func() {
+	var (
+		ctx        context.Context
+		c          client.Client
+		exporter   *tracetest.InMemoryExporter
+		reconciler *ConfigReloaderReconciler
+		key        types.NamespacedName
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		scheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+		Expect(configv1.AddToScheme(scheme)).To(Succeed())
+
+		key = types.NamespacedName{Name: "web", Namespace: "default"}
+		cr := &configv1.ConfigReloader{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+			Spec: configv1.ConfigReloaderSpec{
+				ConfigMaps:    []configv1.ResourceRef{{Name: "web-config"}},
+				Selector:      &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+				RestartPolicy: configv1.RestartPolicyAnnotation,
+			},
+			Status: configv1.ConfigReloaderStatus{
+				WatchedResources: []configv1.WatchedResource{
+					{Kind: "ConfigMap", Name: "web-config", Namespace: "default", ResourceVersion: "1"},
+				},
+			},
+		}
+		configMap := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "web-config", Namespace: "default"},
+			Data:       map[string]string{"LOG_LEVEL": "debug"},
+		}
+		deployment := &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default", Generation: 1},
+			Spec: appsv1.DeploymentSpec{
+				Replicas: ptr.To[int32](1),
+				Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+				Template: corev1.PodTemplateSpec{
+					ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": "web"}},
+					Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "app", Image: "nginx"}}},
+				},
+			},
+		}
+		replicaSet := &appsv1.ReplicaSet{ObjectMeta: metav1.ObjectMeta{
+			Name:            "web-7d9c6b5f4",
+			Namespace:       "default",
+			OwnerReferences: []metav1.OwnerReference{{APIVersion: "apps/v1", Kind: "Deployment", Name: "web"}},
+		}}
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            "web-7d9c6b5f4-x2kqp",
+				Namespace:       "default",
+				Labels:          map[string]string{"app": "web"},
+				OwnerReferences: []metav1.OwnerReference{{APIVersion: "apps/v1", Kind: "ReplicaSet", Name: "web-7d9c6b5f4"}},
+			},
+			Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "app", EnvFrom: []corev1.EnvFromSource{
+				{ConfigMapRef: &corev1.ConfigMapEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: "web-config"}}},
+			}}}},
+		}
+		c = fake.NewClientBuilder().WithScheme(scheme).
+			WithObjects(cr, configMap, deployment, replicaSet, pod).
+			WithStatusSubresource(&configv1.ConfigReloader{}, &appsv1.Deployment{}).
+			Build()
+
+		exporter = tracetest.NewInMemoryExporter()
+		reconciler = &ConfigReloaderReconciler{
+			Client:         c,
+			Scheme:         scheme,
+			APIReader:      c,
+			TracerProvider: sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)),
+		}
+	})
+
+	reconcileOnce := func() {
+		var cr configv1.ConfigReloader
+		Expect(c.Get(ctx, key, &cr)).To(Succeed())
+		_, err := reconciler.reconcileConfigReloader(ctx, &cr)
+		Expect(err).NotTo(HaveOccurred())
+	}
+
+	span := func(name string) tracetest.SpanStub {
+		for _, span := range exporter.GetSpans() {
+			if span.Name == name {
+				return span
+			}
+		}
+		Fail("no span named " + name)
+		return tracetest.SpanStub{}
+	}
+
+	attributes := func(span tracetest.SpanStub) map[attribute.Key]attribute.Value {
+		attrs := make(map[attribute.Key]attribute.Value, len(span.Attributes))
+		for _, attr := range span.Attributes {
+			attrs[attr.Key] = attr.Value
+		}
+		return attrs
+	}
+
+	It("should trace a change from detection to the completed rollout", func() {
+		By("restarting the Deployment when the ConfigMap changes")
+		reconcileOnce()
+
+		root := span("ConfigReloader reload")
+		Expect(root.Parent.IsValid()).To(BeFalse())
+		Expect(attributes(root)).To(HaveKeyWithValue(attrConfigReloaderName, attribute.StringValue("web")))
+		Expect(attributes(root)).To(HaveKeyWithValue(attrRestartPolicy, attribute.StringValue("annotation")))
+
+		change := span("ConfigMap changed")
+		Expect(change.Parent.SpanID()).To(Equal(root.SpanContext.SpanID()))
+		Expect(attributes(change)).To(HaveKeyWithValue(attrResourceName, attribute.StringValue("web-config")))
+		Expect(attributes(change)).To(HaveKeyWithValue(attrResourceOldVersion, attribute.StringValue("1")))
+		Expect(attributes(change)[attrResourceNewVersion].AsString()).NotTo(BeEmpty())
+
+		restart := span("restart Deployment")
+		Expect(restart.Parent.SpanID()).To(Equal(root.SpanContext.SpanID()))
+		Expect(attributes(restart)).To(HaveKeyWithValue(attrWorkloadName, attribute.StringValue("web")))
+		Expect(attributes(restart)).To(HaveKeyWithValue(attrPods, attribute.IntValue(1)))
+		Expect(restart.Status.Code).To(Equal(codes.Unset))
+
+		By("leaving the rollout untraced while it progresses")
+		exporter.Reset()
+		reconcileOnce()
+		Expect(exporter.GetSpans()).To(BeEmpty())
+
+		By("tracing the rollout completion in the same trace")
+		var deployment appsv1.Deployment
+		Expect(c.Get(ctx, key, &deployment)).To(Succeed())
+		deployment.Status = appsv1.DeploymentStatus{
+			ObservedGeneration: deployment.Generation,
+			Replicas:           1,
+			UpdatedReplicas:    1,
+			AvailableReplicas:  1,
+		}
+		Expect(c.Status().Update(ctx, &deployment)).To(Succeed())
+		reconcileOnce()
+
+		rollout := span("rollout Deployment")
+		Expect(rollout.SpanContext.TraceID()).To(Equal(root.SpanContext.TraceID()))
+		Expect(rollout.Parent.SpanID()).To(Equal(restart.SpanContext.SpanID()))
+		Expect(attributes(rollout)).To(HaveKeyWithValue(attrRolloutState, attribute.StringValue("Complete")))
+		Expect(rollout.StartTime).To(BeTemporally(">=", restart.EndTime))
+	})
+
+	It("should forget the rollouts of deleted ConfigReloaders", func() {
+		reconcileOnce()
+		Expect(reconciler.rollouts.rollouts).To(HaveLen(1))
+
+		var cr configv1.ConfigReloader
+		Expect(c.Get(ctx, key, &cr)).To(Succeed())
+		reconciler.rollouts.forget(&cr)
+		Expect(reconciler.rollouts.rollouts).To(BeEmpty())
+	})
+
+	It("should not record spans with the default no-op provider", func() {
+		reconciler.TracerProvider = nil
+		reconcileOnce()
+		Expect(exporter.GetSpans()).To(BeEmpty())
+		Expect(reconciler.rollouts.rollouts).To(BeEmpty())
+	})
+})
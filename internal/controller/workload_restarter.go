@@ -5,17 +5,100 @@ import (
 	"fmt"
 
 	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
+// resolveOwningWorkload returns the top-level workload that controls the pod.
+// ReplicaSets owned by a Deployment resolve to the Deployment. If none of the
+// owners is a supported workload the first owner is returned as is.
+func (r *ConfigReloaderReconciler) resolveOwningWorkload(
+	ctx context.Context,
+	pod *corev1.Pod,
+) (string, string, error) {
+	for _, ownerRef := range pod.OwnerReferences {
+		switch ownerRef.Kind {
+		case "Deployment", "StatefulSet", "DaemonSet":
+			return ownerRef.Kind, ownerRef.Name, nil
+		case "ReplicaSet":
+			replicaSet := &appsv1.ReplicaSet{}
+			key := types.NamespacedName{Name: ownerRef.Name, Namespace: pod.Namespace}
+			if err := r.Get(ctx, key, replicaSet); err != nil {
+				return "", "", fmt.Errorf("failed to get ReplicaSet %s/%s: %w", pod.Namespace, ownerRef.Name, err)
+			}
+			for _, rsOwner := range replicaSet.OwnerReferences {
+				if rsOwner.Kind == "Deployment" {
+					return rsOwner.Kind, rsOwner.Name, nil
+				}
+			}
+			return ownerRef.Kind, ownerRef.Name, nil
+		}
+	}
+
+	if len(pod.OwnerReferences) > 0 {
+		return pod.OwnerReferences[0].Kind, pod.OwnerReferences[0].Name, nil
+	}
+	return "", "", nil
+}
+
+// isRestartableWorkload reports whether a workload kind can be rolled by
+// updating its pod template
+func isRestartableWorkload(kind string) bool {
+	switch kind {
+	case "Deployment", "StatefulSet", "DaemonSet", "ReplicaSet":
+		return true
+	}
+	return false
+}
+
+// newWorkload returns an empty workload of the kind together with its pod
+// template, or nil if the kind is not supported
+func newWorkload(kind string) (client.Object, *corev1.PodTemplateSpec) {
+	switch kind {
+	case "Deployment":
+		deployment := &appsv1.Deployment{}
+		return deployment, &deployment.Spec.Template
+	case "StatefulSet":
+		statefulSet := &appsv1.StatefulSet{}
+		return statefulSet, &statefulSet.Spec.Template
+	case "DaemonSet":
+		daemonSet := &appsv1.DaemonSet{}
+		return daemonSet, &daemonSet.Spec.Template
+	case "ReplicaSet":
+		replicaSet := &appsv1.ReplicaSet{}
+		return replicaSet, &replicaSet.Spec.Template
+	}
+	return nil, nil
+}
+
+// restartWorkload rolls the workload by updating its pod template and returns
+// the updated object, or nil if the kind is not supported
+func (r *ConfigReloaderReconciler) restartWorkload(
+	ctx context.Context,
+	namespace, kind, name, restartAnnotation string,
+) (client.Object, error) {
+	switch kind {
+	case "Deployment":
+		return r.restartDeployment(ctx, namespace, name, restartAnnotation)
+	case "StatefulSet":
+		return r.restartStatefulSet(ctx, namespace, name, restartAnnotation)
+	case "DaemonSet":
+		return r.restartDaemonSet(ctx, namespace, name, restartAnnotation)
+	case "ReplicaSet":
+		return r.restartReplicaSet(ctx, namespace, name, restartAnnotation)
+	}
+	return nil, nil
+}
+
 func (r *ConfigReloaderReconciler) restartDeployment(
 	ctx context.Context,
 	namespace, name, restartAnnotation string,
-) (bool, error) {
+) (client.Object, error) {
 	deployment := &appsv1.Deployment{}
 	if err := r.Get(ctx, types.NamespacedName{Name: name, Namespace: namespace}, deployment); err != nil {
-		return false, fmt.Errorf("failed to get Deployment %s/%s: %w", namespace, name, err)
+		return nil, fmt.Errorf("failed to get Deployment %s/%s: %w", namespace, name, err)
 	}
 
 	if deployment.Spec.Template.Annotations == nil {
@@ -25,19 +108,19 @@ func (r *ConfigReloaderReconciler) restartDeployment(
 	deployment.Spec.Template.Annotations[restartAnnotation] = metav1.Now().Format("2006-01-02T15:04:05Z")
 
 	if err := r.Update(ctx, deployment); err != nil {
-		return false, fmt.Errorf("failed to update Deployment %s/%s: %w", namespace, name, err)
+		return nil, fmt.Errorf("failed to update Deployment %s/%s: %w", namespace, name, err)
 	}
 
-	return true, nil
+	return deployment, nil
 }
 
 func (r *ConfigReloaderReconciler) restartStatefulSet(
 	ctx context.Context,
 	namespace, name, restartAnnotation string,
-) (bool, error) {
+) (client.Object, error) {
 	statefulSet := &appsv1.StatefulSet{}
 	if err := r.Get(ctx, types.NamespacedName{Name: name, Namespace: namespace}, statefulSet); err != nil {
-		return false, fmt.Errorf("failed to get StatefulSet %s/%s: %w", namespace, name, err)
+		return nil, fmt.Errorf("failed to get StatefulSet %s/%s: %w", namespace, name, err)
 	}
 
 	if statefulSet.Spec.Template.Annotations == nil {
@@ -47,19 +130,19 @@ func (r *ConfigReloaderReconciler) restartStatefulSet(
 	statefulSet.Spec.Template.Annotations[restartAnnotation] = metav1.Now().Format("2006-01-02T15:04:05Z")
 
 	if err := r.Update(ctx, statefulSet); err != nil {
-		return false, fmt.Errorf("failed to update StatefulSet %s/%s: %w", namespace, name, err)
+		return nil, fmt.Errorf("failed to update StatefulSet %s/%s: %w", namespace, name, err)
 	}
 
-	return true, nil
+	return statefulSet, nil
 }
 
 func (r *ConfigReloaderReconciler) restartDaemonSet(
 	ctx context.Context,
 	namespace, name, restartAnnotation string,
-) (bool, error) {
+) (client.Object, error) {
 	daemonSet := &appsv1.DaemonSet{}
 	if err := r.Get(ctx, types.NamespacedName{Name: name, Namespace: namespace}, daemonSet); err != nil {
-		return false, fmt.Errorf("failed to get DaemonSet %s/%s: %w", namespace, name, err)
+		return nil, fmt.Errorf("failed to get DaemonSet %s/%s: %w", namespace, name, err)
 	}
 
 	if daemonSet.Spec.Template.Annotations == nil {
@@ -69,25 +152,19 @@ func (r *ConfigReloaderReconciler) restartDaemonSet(
 	daemonSet.Spec.Template.Annotations[restartAnnotation] = metav1.Now().Format("2006-01-02T15:04:05Z")
 
 	if err := r.Update(ctx, daemonSet); err != nil {
-		return false, fmt.Errorf("failed to update DaemonSet %s/%s: %w", namespace, name, err)
+		return nil, fmt.Errorf("failed to update DaemonSet %s/%s: %w", namespace, name, err)
 	}
 
-	return true, nil
+	return daemonSet, nil
 }
 
 func (r *ConfigReloaderReconciler) restartReplicaSet(
 	ctx context.Context,
 	namespace, name, restartAnnotation string,
-) (bool, error) {
+) (client.Object, error) {
 	replicaSet := &appsv1.ReplicaSet{}
 	if err := r.Get(ctx, types.NamespacedName{Name: name, Namespace: namespace}, replicaSet); err != nil {
-		return false, fmt.Errorf("failed to get ReplicaSet %s/%s: %w", namespace, name, err)
-	}
-
-	for _, ownerRef := range replicaSet.OwnerReferences {
-		if ownerRef.Kind == "Deployment" {
-			return r.restartDeployment(ctx, namespace, ownerRef.Name, restartAnnotation)
-		}
+		return nil, fmt.Errorf("failed to get ReplicaSet %s/%s: %w", namespace, name, err)
 	}
 
 	if replicaSet.Spec.Template.Annotations == nil {
@@ -97,8 +174,8 @@ func (r *ConfigReloaderReconciler) restartReplicaSet(
 	replicaSet.Spec.Template.Annotations[restartAnnotation] = metav1.Now().Format("2006-01-02T15:04:05Z")
 
 	if err := r.Update(ctx, replicaSet); err != nil {
-		return false, fmt.Errorf("failed to update ReplicaSet %s/%s: %w", namespace, name, err)
+		return nil, fmt.Errorf("failed to update ReplicaSet %s/%s: %w", namespace, name, err)
 	}
 
-	return true, nil
+	return replicaSet, nil
 }
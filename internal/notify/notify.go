@@ -0,0 +1,419 @@
+// Package notify delivers notifications about the reloads of ConfigReloaders
+// to the sinks configured by ReloadNotifiers.
+//
+// Notifications are matched against the ReloadNotifiers of the namespace of
+// their ConfigReloader when raised, and delivered in the background so that a
+// slow sink never delays a reload. Identical notifications raised within the
+// dedupe window of a ReloadNotifier are delivered once.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	configv1 "github.com/shehbazk/config-reloader-operator/api/v1"
+)
+
+const (
+	defaultMaxAttempts  = 3
+	defaultBackoff      = time.Second
+	defaultDedupeWindow = 5 * time.Minute
+
+	// queueSize bounds the notifications waiting for delivery, later ones are
+	// dropped
+	queueSize = 1000
+	workers   = 4
+)
+
+// Notification describes a step of a reload
+type Notification struct {
+	// ID is derived from the content of the notification, identical
+	// notifications share it
+	ID             string                         `json:"id"`
+	Event          configv1.NotificationEvent     `json:"event"`
+	ConfigReloader string                         `json:"configReloader"`
+	Namespace      string                         `json:"namespace"`
+	Cause          string                         `json:"cause,omitempty"`
+	Resources      []configv1.ReloadEventResource `json:"resources,omitempty"`
+	Outcome        configv1.ReloadOutcome         `json:"outcome,omitempty"`
+	RestartedPods  int32                          `json:"restartedPods,omitempty"`
+	FailedTargets  int32                          `json:"failedTargets,omitempty"`
+	Targets        []configv1.ReloadEventTarget   `json:"targets,omitempty"`
+	Error          string                         `json:"error,omitempty"`
+	Time           time.Time                      `json:"time"`
+}
+
+// key hashes the content of the notification, leaving out its time
+func (n *Notification) key() string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\x00%s\x00%s\x00%s\x00%s\x00%s\x00", n.Event, n.Namespace, n.ConfigReloader,
+		n.Cause, n.Outcome, n.Error)
+	for _, resource := range n.Resources {
+		fmt.Fprintf(h, "%s/%s/%s@%s\x00", resource.Kind, resource.Namespace, resource.Name, resource.Version)
+	}
+	fmt.Fprintf(h, "%d/%d", n.RestartedPods, n.FailedTargets)
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+// delivery is a notification to send to a ReloadNotifier
+type delivery struct {
+	Notifier     *configv1.ReloadNotifier
+	Notification Notification
+	// DedupeKey is forgotten when the delivery fails, so that the
+	// notification is sent again when raised again
+	DedupeKey string
+}
+
+// Dispatcher delivers notifications to the ReloadNotifiers. It is added to
+// the manager as a Runnable running the deliveries, on every replica.
+type Dispatcher struct {
+	client client.Client
+	// secrets reads the Secrets holding URLs and tokens, which the manager
+	// may not cache
+	secrets client.Reader
+	// allowedURLs lists the URL prefixes notifications may be posted to
+	allowedURLs []string
+	http        *http.Client
+	queue       chan delivery
+
+	mu sync.Mutex
+	// sent holds the expiry of the dedupe window of the notifications sent
+	sent map[string]time.Time
+}
+
+// NewDispatcher returns a Dispatcher listing the ReloadNotifiers with c,
+// reading their Secrets with secrets and only posting to the URLs starting
+// with one of allowedURLs
+func NewDispatcher(c client.Client, secrets client.Reader, allowedURLs []string) *Dispatcher {
+	return &Dispatcher{
+		client:      c,
+		secrets:     secrets,
+		allowedURLs: allowedURLs,
+		http:        &http.Client{Timeout: 10 * time.Second},
+		queue:       make(chan delivery, queueSize),
+		sent:        make(map[string]time.Time),
+	}
+}
+
+// Notify queues the notification for the ReloadNotifiers selecting the
+// ConfigReloader and its event. It never blocks, notifications are dropped
+// when the queue is full.
+func (d *Dispatcher) Notify(ctx context.Context, cr *configv1.ConfigReloader, n Notification) {
+	logger := log.FromContext(ctx)
+
+	var notifiers configv1.ReloadNotifierList
+	if err := d.client.List(ctx, &notifiers, client.InNamespace(cr.Namespace)); err != nil {
+		logger.Error(err, "failed to list ReloadNotifiers")
+		return
+	}
+
+	n.Namespace, n.ConfigReloader = cr.Namespace, cr.Name
+	if n.Time.IsZero() {
+		n.Time = time.Now()
+	}
+	n.ID = n.key()
+
+	for i := range notifiers.Items {
+		notifier := &notifiers.Items[i]
+		if notifier.DeletionTimestamp != nil || !selects(ctx, notifier, cr, n.Event) {
+			continue
+		}
+		dedupeKey := string(notifier.UID) + "/" + n.ID
+		if !d.claim(dedupeKey, dedupeWindow(notifier)) {
+			logger.V(1).Info("Skipping duplicate notification", "reloadNotifier", notifier.Name, "event", n.Event)
+			continue
+		}
+		select {
+		case d.queue <- delivery{Notifier: notifier.DeepCopy(), Notification: n, DedupeKey: dedupeKey}:
+		default:
+			d.forget(dedupeKey)
+			logger.Info("Dropping notification, the delivery queue is full",
+				"reloadNotifier", notifier.Name, "event", n.Event)
+		}
+	}
+}
+
+// selects reports whether the ReloadNotifier is interested in the event of
+// the ConfigReloader
+func selects(ctx context.Context, notifier *configv1.ReloadNotifier, cr *configv1.ConfigReloader,
+	event configv1.NotificationEvent) bool {
+	if len(notifier.Spec.Events) > 0 {
+		found := false
+		for _, e := range notifier.Spec.Events {
+			found = found || e == event
+		}
+		if !found {
+			return false
+		}
+	}
+	if notifier.Spec.Selector == nil {
+		return true
+	}
+	selector, err := metav1.LabelSelectorAsSelector(notifier.Spec.Selector)
+	if err != nil {
+		log.FromContext(ctx).Error(err, "invalid selector", "reloadNotifier", notifier.Name)
+		return false
+	}
+	return selector.Matches(labels.Set(cr.Labels))
+}
+
+func dedupeWindow(notifier *configv1.ReloadNotifier) time.Duration {
+	if notifier.Spec.DedupeWindow == nil {
+		return defaultDedupeWindow
+	}
+	return notifier.Spec.DedupeWindow.Duration
+}
+
+// claim records a notification about to be sent, and reports false when it
+// was already sent within its dedupe window
+func (d *Dispatcher) claim(key string, window time.Duration) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := time.Now()
+	for k, expiry := range d.sent {
+		if !now.Before(expiry) {
+			delete(d.sent, k)
+		}
+	}
+	if _, ok := d.sent[key]; ok {
+		return false
+	}
+	if window > 0 {
+		d.sent[key] = now.Add(window)
+	}
+	return true
+}
+
+func (d *Dispatcher) forget(key string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.sent, key)
+}
+
+// Start delivers the queued notifications until the context is cancelled
+func (d *Dispatcher) Start(ctx context.Context) error {
+	logger := log.FromContext(ctx).WithName("notify")
+	ctx = log.IntoContext(ctx, logger)
+
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case delivery := <-d.queue:
+					d.process(ctx, delivery)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	return nil
+}
+
+// NeedLeaderElection tells the manager to run the Dispatcher on every
+// replica, as every replica reconciling ConfigReloaders raises notifications
+func (d *Dispatcher) NeedLeaderElection() bool {
+	return false
+}
+
+// process delivers a notification and records the outcome in the status of
+// the ReloadNotifier
+func (d *Dispatcher) process(ctx context.Context, delivery delivery) {
+	logger := log.FromContext(ctx).WithValues("reloadNotifier", delivery.Notifier.Namespace+"/"+delivery.Notifier.Name,
+		"event", delivery.Notification.Event, "configReloader", delivery.Notification.ConfigReloader)
+
+	err := d.deliver(ctx, delivery.Notifier, &delivery.Notification)
+	if err != nil {
+		d.forget(delivery.DedupeKey)
+		logger.Error(err, "failed to deliver notification")
+	} else {
+		logger.V(1).Info("Delivered notification")
+	}
+
+	key := types.NamespacedName{Namespace: delivery.Notifier.Namespace, Name: delivery.Notifier.Name}
+	updateErr := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		var notifier configv1.ReloadNotifier
+		if err := d.client.Get(ctx, key, &notifier); err != nil {
+			return client.IgnoreNotFound(err)
+		}
+		now := metav1.Now()
+		if err != nil {
+			notifier.Status.Failed++
+			notifier.Status.LastFailureTime = &now
+			notifier.Status.LastError = err.Error()
+		} else {
+			notifier.Status.Delivered++
+			notifier.Status.LastDeliveryTime = &now
+		}
+		return d.client.Status().Update(ctx, &notifier)
+	})
+	if updateErr != nil {
+		logger.Error(updateErr, "failed to update ReloadNotifier status")
+	}
+}
+
+// deliver sends the notification, retrying network errors, 429 and 5xx
+// responses with exponential backoff
+func (d *Dispatcher) deliver(ctx context.Context, notifier *configv1.ReloadNotifier, n *Notification) error {
+	address, err := d.url(ctx, notifier)
+	if err != nil {
+		return err
+	}
+	if !d.urlAllowed(address) {
+		return errors.New("the URL is not allowed by the operator")
+	}
+	headers, err := d.headers(ctx, notifier)
+	if err != nil {
+		return err
+	}
+	p, err := render(notifier, n)
+	if err != nil {
+		return err
+	}
+
+	attempts := int(notifier.Spec.Retry.MaxAttempts)
+	if attempts <= 0 {
+		attempts = defaultMaxAttempts
+	}
+	backoff := defaultBackoff
+	if notifier.Spec.Retry.Backoff != nil {
+		backoff = notifier.Spec.Retry.Backoff.Duration
+	}
+
+	for attempt := 1; ; attempt++ {
+		retriable, err := d.send(ctx, address, p, headers)
+		if err == nil {
+			return nil
+		}
+		if !retriable || attempt >= attempts {
+			return fmt.Errorf("attempt %d of %d: %w", attempt, attempts, err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// send posts the payload once and reports whether a failure can be retried
+func (d *Dispatcher) send(ctx context.Context, address string, p *payload, headers map[string]string) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, address, bytes.NewReader(p.Body))
+	if err != nil {
+		return false, fmt.Errorf("invalid request: %w", withoutURL(err))
+	}
+	p.apply(req, headers)
+
+	resp, err := d.http.Do(req)
+	if err != nil {
+		return true, fmt.Errorf("failed to post notification: %w", withoutURL(err))
+	}
+	defer resp.Body.Close() //nolint:errcheck
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return false, nil
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return true, fmt.Errorf("unexpected response %s", resp.Status)
+	}
+	return false, fmt.Errorf("unexpected response %s", resp.Status)
+}
+
+// withoutURL drops the request URL from the errors of the HTTP client, as
+// the URLs read from Secrets embed tokens and the errors end up in status
+func withoutURL(err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return urlErr.Err
+	}
+	return err
+}
+
+// url returns the URL of the ReloadNotifier, read from its Secret if needed
+func (d *Dispatcher) url(ctx context.Context, notifier *configv1.ReloadNotifier) (string, error) {
+	if notifier.Spec.URLFrom == nil {
+		return notifier.Spec.URL, nil
+	}
+	return d.secretValue(ctx, notifier.Namespace, notifier.Spec.URLFrom)
+}
+
+// urlAllowed reports whether notifications may be posted to the address. Only
+// the URLs under the prefixes the operator was started with are allowed, so
+// that ReloadNotifiers cannot make it post to arbitrary URLs. A prefix matches
+// the scheme, the host and port, and whole segments of the path.
+func (d *Dispatcher) urlAllowed(address string) bool {
+	u, err := url.Parse(address)
+	if err != nil || u.User != nil || strings.Contains(u.Path+"/", "/../") {
+		return false
+	}
+	for _, allowed := range d.allowedURLs {
+		prefix, err := url.Parse(allowed)
+		if err != nil || prefix.Scheme != u.Scheme || !strings.EqualFold(prefix.Host, u.Host) {
+			continue
+		}
+		path := strings.TrimSuffix(prefix.Path, "/")
+		if u.Path == path || strings.HasPrefix(u.Path, path+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// headers returns the headers of the ReloadNotifier with its Authorization
+func (d *Dispatcher) headers(ctx context.Context, notifier *configv1.ReloadNotifier) (map[string]string, error) {
+	if notifier.Spec.Auth == nil {
+		return notifier.Spec.Headers, nil
+	}
+	token, err := d.secretValue(ctx, notifier.Namespace, &notifier.Spec.Auth.TokenFrom)
+	if err != nil {
+		return nil, err
+	}
+	scheme := notifier.Spec.Auth.Scheme
+	if scheme == "" {
+		scheme = "Bearer"
+	}
+
+	headers := make(map[string]string, len(notifier.Spec.Headers)+1)
+	for name, value := range notifier.Spec.Headers {
+		headers[name] = value
+	}
+	headers["Authorization"] = scheme + " " + token
+	return headers, nil
+}
+
+func (d *Dispatcher) secretValue(ctx context.Context, namespace string, selector *corev1.SecretKeySelector) (string, error) {
+	var secret corev1.Secret
+	if err := d.secrets.Get(ctx, types.NamespacedName{Namespace: namespace, Name: selector.Name}, &secret); err != nil {
+		return "", fmt.Errorf("failed to get Secret %s/%s: %w", namespace, selector.Name, err)
+	}
+	value, ok := secret.Data[selector.Key]
+	if !ok {
+		return "", fmt.Errorf("key %s not found in Secret %s/%s", selector.Key, namespace, selector.Name)
+	}
+	return string(value), nil
+}
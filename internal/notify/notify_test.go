@@ -0,0 +1,363 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	configv1 "github.com/shehbazk/config-reloader-operator/api/v1"
+)
+
+// receivedRequest is a request captured by the test receiver
+type receivedRequest struct {
+	Header http.Header
+	Body   string
+}
+
+var _ = Describe("Dispatcher", func() {
+	var (
+		ctx         context.Context
+		scheme      *runtime.Scheme
+		cr          *configv1.ConfigReloader
+		received    chan receivedRequest
+		statuses    chan int
+		server      *httptest.Server
+		allowedURLs []string
+		dispatcher  *Dispatcher
+		c           client.Client
+	)
+
+	BeforeEach(func() {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithCancel(context.Background())
+		DeferCleanup(cancel)
+
+		scheme = runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+		Expect(configv1.AddToScheme(scheme)).To(Succeed())
+
+		cr = &configv1.ConfigReloader{ObjectMeta: metav1.ObjectMeta{
+			Name: "web", Namespace: "default", Labels: map[string]string{"team": "web"},
+		}}
+
+		// The receiver answers with the queued statuses, then 200
+		received = make(chan receivedRequest, 10)
+		statuses = make(chan int, 10)
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			body, _ := io.ReadAll(req.Body)
+			received <- receivedRequest{Header: req.Header.Clone(), Body: string(body)}
+			select {
+			case status := <-statuses:
+				w.WriteHeader(status)
+			default:
+			}
+		}))
+		DeferCleanup(server.Close)
+		allowedURLs = []string{server.URL}
+	})
+
+	// start runs a Dispatcher with the objects
+	start := func(objs ...client.Object) {
+		c = fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).
+			WithStatusSubresource(&configv1.ReloadNotifier{}).Build()
+		dispatcher = NewDispatcher(c, c, allowedURLs)
+		go func() {
+			defer GinkgoRecover()
+			Expect(dispatcher.Start(ctx)).To(Succeed())
+		}()
+	}
+
+	notifier := func(name string, spec configv1.ReloadNotifierSpec) *configv1.ReloadNotifier {
+		if spec.URL == "" && spec.URLFrom == nil {
+			spec.URL = server.URL
+		}
+		spec.Retry.Backoff = &metav1.Duration{Duration: 10 * time.Millisecond}
+		return &configv1.ReloadNotifier{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", UID: types.UID(name)},
+			Spec:       spec,
+		}
+	}
+
+	completed := Notification{
+		Event:         configv1.NotificationReloadCompleted,
+		Cause:         "ConfigMap default/app changed",
+		Outcome:       configv1.ReloadSucceeded,
+		RestartedPods: 2,
+		Time:          time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+	}
+
+	status := func(name string) func() configv1.ReloadNotifierStatus {
+		return func() configv1.ReloadNotifierStatus {
+			var n configv1.ReloadNotifier
+			Expect(c.Get(ctx, types.NamespacedName{Namespace: "default", Name: name}, &n)).To(Succeed())
+			return n.Status
+		}
+	}
+
+	It("should post the notification as JSON and count the delivery", func() {
+		start(notifier("hooks", configv1.ReloadNotifierSpec{Type: configv1.NotifierWebhook}))
+
+		dispatcher.Notify(ctx, cr, completed)
+
+		var req receivedRequest
+		Eventually(received).Should(Receive(&req))
+		Expect(req.Header.Get("Content-Type")).To(Equal("application/json"))
+		var n Notification
+		Expect(json.Unmarshal([]byte(req.Body), &n)).To(Succeed())
+		Expect(n.Event).To(Equal(configv1.NotificationReloadCompleted))
+		Expect(n.ConfigReloader).To(Equal("web"))
+		Expect(n.Namespace).To(Equal("default"))
+		Expect(n.RestartedPods).To(Equal(int32(2)))
+		Expect(n.ID).NotTo(BeEmpty())
+
+		Eventually(status("hooks")).Should(HaveField("Delivered", int64(1)))
+	})
+
+	It("should render the template with an Authorization header from a Secret", func() {
+		start(
+			notifier("hooks", configv1.ReloadNotifierSpec{
+				Type:     configv1.NotifierWebhook,
+				Template: `{"text": {{ json .Title }}, "pods": {{ .RestartedPods }}}`,
+				Headers:  map[string]string{"X-Source": "config-reloader"},
+				Auth: &configv1.NotifierAuth{TokenFrom: corev1.SecretKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: "hooks"}, Key: "token",
+				}},
+			}),
+			&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "hooks", Namespace: "default"},
+				Data:       map[string][]byte{"token": []byte("s3cr3t")},
+			},
+		)
+
+		dispatcher.Notify(ctx, cr, completed)
+
+		var req receivedRequest
+		Eventually(received).Should(Receive(&req))
+		Expect(req.Body).To(MatchJSON(`{"text": "ConfigReloader default/web reloaded", "pods": 2}`))
+		Expect(req.Header.Get("Authorization")).To(Equal("Bearer s3cr3t"))
+		Expect(req.Header.Get("X-Source")).To(Equal("config-reloader"))
+	})
+
+	It("should read the URL from a Secret", func() {
+		start(
+			notifier("slack", configv1.ReloadNotifierSpec{
+				Type: configv1.NotifierSlack,
+				URLFrom: &corev1.SecretKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: "slack"}, Key: "url",
+				},
+			}),
+			&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "slack", Namespace: "default"},
+				Data:       map[string][]byte{"url": []byte(server.URL + "/services/T0/B0/x")},
+			},
+		)
+
+		dispatcher.Notify(ctx, cr, completed)
+
+		var req receivedRequest
+		Eventually(received).Should(Receive(&req))
+		Expect(req.Body).To(MatchJSON(`{"text": "ConfigReloader default/web reloaded\nCause: ConfigMap default/app changed\nRestarted 2 pods"}`))
+	})
+
+	It("should post a Teams message card", func() {
+		start(notifier("teams", configv1.ReloadNotifierSpec{Type: configv1.NotifierTeams}))
+
+		failed := completed
+		failed.Event = configv1.NotificationReloadFailed
+		failed.Outcome = configv1.ReloadFailed
+		failed.Error = "forbidden"
+		dispatcher.Notify(ctx, cr, failed)
+
+		var req receivedRequest
+		Eventually(received).Should(Receive(&req))
+		var card map[string]string
+		Expect(json.Unmarshal([]byte(req.Body), &card)).To(Succeed())
+		Expect(card).To(HaveKeyWithValue("@type", "MessageCard"))
+		Expect(card).To(HaveKeyWithValue("title", "ConfigReloader default/web failed to reload"))
+		Expect(card).To(HaveKeyWithValue("text", ContainSubstring("Error: forbidden")))
+	})
+
+	It("should post CloudEvents in binary content mode", func() {
+		start(notifier("events", configv1.ReloadNotifierSpec{Type: configv1.NotifierCloudEvents}))
+
+		dispatcher.Notify(ctx, cr, completed)
+
+		var req receivedRequest
+		Eventually(received).Should(Receive(&req))
+		Expect(req.Header.Get("ce-specversion")).To(Equal("1.0"))
+		Expect(req.Header.Get("ce-type")).To(Equal("dev.config.reloader.ReloadCompleted"))
+		Expect(req.Header.Get("ce-source")).To(Equal("/apis/config.dev/v1/namespaces/default/configreloaders/web"))
+		Expect(req.Header.Get("ce-time")).To(Equal("2024-05-01T12:00:00Z"))
+		Expect(req.Header.Get("ce-id")).NotTo(BeEmpty())
+		Expect(req.Header.Get("Content-Type")).To(Equal("application/json"))
+		Expect(req.Body).To(ContainSubstring(`"event":"ReloadCompleted"`))
+	})
+
+	It("should retry server errors", func() {
+		start(notifier("hooks", configv1.ReloadNotifierSpec{}))
+		statuses <- http.StatusServiceUnavailable
+		statuses <- http.StatusTooManyRequests
+
+		dispatcher.Notify(ctx, cr, completed)
+
+		Eventually(received).Should(HaveLen(3))
+		Eventually(status("hooks")).Should(And(HaveField("Delivered", int64(1)), HaveField("Failed", int64(0))))
+	})
+
+	It("should record the failure once the attempts are exhausted or not retriable", func() {
+		start(
+			notifier("flaky", configv1.ReloadNotifierSpec{Retry: configv1.NotifierRetry{MaxAttempts: 2}}),
+		)
+		for range 2 {
+			statuses <- http.StatusInternalServerError
+		}
+		dispatcher.Notify(ctx, cr, completed)
+		Eventually(status("flaky")).Should(And(
+			HaveField("Failed", int64(1)),
+			HaveField("LastError", ContainSubstring("attempt 2 of 2: unexpected response 500")),
+		))
+		Expect(received).To(HaveLen(2))
+
+		// A failed notification is not deduplicated
+		for range 2 {
+			<-received
+		}
+		statuses <- http.StatusBadRequest
+		dispatcher.Notify(ctx, cr, completed)
+		Eventually(status("flaky")).Should(HaveField("Failed", int64(2)))
+		Consistently(received, 100*time.Millisecond).Should(HaveLen(1))
+	})
+
+	It("should not record the URL read from a Secret in the failure", func() {
+		closed := httptest.NewServer(http.NotFoundHandler())
+		closed.Close()
+		allowedURLs = append(allowedURLs, closed.URL+"/services/")
+		start(
+			notifier("slack", configv1.ReloadNotifierSpec{
+				Type:  configv1.NotifierSlack,
+				Retry: configv1.NotifierRetry{MaxAttempts: 1},
+				URLFrom: &corev1.SecretKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: "slack"}, Key: "url",
+				},
+			}),
+			&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "slack", Namespace: "default"},
+				Data:       map[string][]byte{"url": []byte(closed.URL + "/services/T0/B0/s3cr3t")},
+			},
+		)
+
+		dispatcher.Notify(ctx, cr, completed)
+		Eventually(status("slack")).Should(HaveField("Failed", int64(1)))
+		Expect(status("slack")().LastError).To(ContainSubstring("failed to post notification"))
+		Expect(status("slack")().LastError).NotTo(ContainSubstring("s3cr3t"))
+	})
+
+	It("should not post to URLs that are not allowed", func() {
+		allowedURLs = []string{server.URL + "/services/"}
+		start(
+			notifier("outside", configv1.ReloadNotifierSpec{URL: server.URL + "/admin"}),
+			notifier("sibling", configv1.ReloadNotifierSpec{URL: server.URL + "/services-other/x"}),
+			notifier("traversal", configv1.ReloadNotifierSpec{URL: server.URL + "/services/../admin"}),
+			notifier("allowed", configv1.ReloadNotifierSpec{URL: server.URL + "/services/T0/B0/x"}),
+		)
+
+		dispatcher.Notify(ctx, cr, completed)
+		Eventually(status("allowed")).Should(HaveField("Delivered", int64(1)))
+		for _, name := range []string{"outside", "sibling", "traversal"} {
+			Eventually(status(name)).Should(HaveField("Failed", int64(1)))
+			Expect(status(name)().LastError).To(Equal("the URL is not allowed by the operator"))
+		}
+		Consistently(received, 100*time.Millisecond).Should(HaveLen(1))
+	})
+
+	It("should not post notifications when no URL is allowed", func() {
+		allowedURLs = nil
+		start(notifier("hooks", configv1.ReloadNotifierSpec{}))
+
+		dispatcher.Notify(ctx, cr, completed)
+		Eventually(status("hooks")).Should(HaveField("Failed", int64(1)))
+		Consistently(received, 100*time.Millisecond).Should(BeEmpty())
+	})
+
+	It("should send identical notifications once within the dedupe window", func() {
+		start(
+			notifier("hooks", configv1.ReloadNotifierSpec{}),
+			notifier("nodedupe", configv1.ReloadNotifierSpec{DedupeWindow: &metav1.Duration{}}),
+		)
+
+		dispatcher.Notify(ctx, cr, completed)
+		later := completed
+		later.Time = completed.Time.Add(time.Minute)
+		dispatcher.Notify(ctx, cr, later)
+		Eventually(status("hooks")).Should(HaveField("Delivered", int64(1)))
+		Eventually(status("nodedupe")).Should(HaveField("Delivered", int64(2)))
+
+		other := completed
+		other.Cause = "ConfigMap default/app changed again"
+		dispatcher.Notify(ctx, cr, other)
+		Eventually(status("hooks")).Should(HaveField("Delivered", int64(2)))
+	})
+
+	It("should only notify the selected ConfigReloaders and events", func() {
+		var notified atomic.Int32
+		start(
+			notifier("web", configv1.ReloadNotifierSpec{
+				Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"team": "web"}},
+				Events:   []configv1.NotificationEvent{configv1.NotificationReloadCompleted},
+			}),
+			notifier("api", configv1.ReloadNotifierSpec{
+				Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"team": "api"}},
+			}),
+			notifier("failures", configv1.ReloadNotifierSpec{
+				Events: []configv1.NotificationEvent{configv1.NotificationReloadFailed},
+			}),
+		)
+		go func() {
+			for range received {
+				notified.Add(1)
+			}
+		}()
+
+		dispatcher.Notify(ctx, cr, completed)
+		dispatcher.Notify(ctx, cr, Notification{Event: configv1.NotificationChangeDetected})
+
+		Eventually(status("web")).Should(HaveField("Delivered", int64(1)))
+		Consistently(notified.Load, 100*time.Millisecond).Should(Equal(int32(1)))
+		Expect(status("api")()).To(HaveField("Delivered", int64(0)))
+		Expect(status("failures")()).To(HaveField("Delivered", int64(0)))
+	})
+
+	It("should run on every replica", func() {
+		Expect(NewDispatcher(nil, nil, nil).NeedLeaderElection()).To(BeFalse())
+	})
+})
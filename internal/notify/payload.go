@@ -0,0 +1,179 @@
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"text/template"
+	"time"
+
+	configv1 "github.com/shehbazk/config-reloader-operator/api/v1"
+)
+
+// cloudEventTypePrefix prefixes the type of the CloudEvents, followed by the
+// notification event
+const cloudEventTypePrefix = "dev.config.reloader."
+
+// templateFuncs are available to the templates of ReloadNotifiers
+var templateFuncs = template.FuncMap{
+	// json renders a value as JSON, to embed strings in JSON payloads safely
+	"json": func(value interface{}) (string, error) {
+		data, err := json.Marshal(value)
+		return string(data), err
+	},
+}
+
+// payload is the body and headers of a notification request
+type payload struct {
+	Body        []byte
+	ContentType string
+	Headers     map[string]string
+}
+
+// render formats the notification for the type of the ReloadNotifier
+func render(notifier *configv1.ReloadNotifier, n *Notification) (*payload, error) {
+	var text string
+	if notifier.Spec.Template != "" {
+		tmpl, err := template.New(notifier.Name).Funcs(templateFuncs).Option("missingkey=error").
+			Parse(notifier.Spec.Template)
+		if err != nil {
+			return nil, fmt.Errorf("invalid template: %w", err)
+		}
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, n); err != nil {
+			return nil, fmt.Errorf("failed to render template: %w", err)
+		}
+		text = buf.String()
+	}
+
+	switch notifier.Spec.Type {
+	case configv1.NotifierSlack:
+		if text == "" {
+			text = n.Summary()
+		}
+		return jsonPayload(map[string]string{"text": text})
+	case configv1.NotifierTeams:
+		if text == "" {
+			text = n.Details()
+		}
+		return jsonPayload(map[string]string{
+			"@type":      "MessageCard",
+			"@context":   "https://schema.org/extensions",
+			"summary":    n.Title(),
+			"title":      n.Title(),
+			"themeColor": n.color(),
+			"text":       text,
+		})
+	case configv1.NotifierCloudEvents:
+		p, err := dataPayload(n, text)
+		if err != nil {
+			return nil, err
+		}
+		// Binary content mode, the attributes of the event are headers
+		p.Headers = map[string]string{
+			"ce-specversion": "1.0",
+			"ce-id":          n.ID,
+			"ce-source":      fmt.Sprintf("/apis/config.dev/v1/namespaces/%s/configreloaders/%s", n.Namespace, n.ConfigReloader),
+			"ce-type":        cloudEventTypePrefix + string(n.Event),
+			"ce-subject":     n.ConfigReloader,
+			"ce-time":        n.Time.UTC().Format(time.RFC3339Nano),
+		}
+		return p, nil
+	default:
+		return dataPayload(n, text)
+	}
+}
+
+// dataPayload is the rendered template, or the notification as JSON
+func dataPayload(n *Notification, text string) (*payload, error) {
+	if text != "" {
+		contentType := "text/plain; charset=utf-8"
+		if json.Valid([]byte(text)) {
+			contentType = "application/json"
+		}
+		return &payload{Body: []byte(text), ContentType: contentType}, nil
+	}
+	return jsonPayload(n)
+}
+
+func jsonPayload(value interface{}) (*payload, error) {
+	body, err := json.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal notification: %w", err)
+	}
+	return &payload{Body: body, ContentType: "application/json"}, nil
+}
+
+// apply sets the body and headers of the payload on the request. Headers of
+// the ReloadNotifier take precedence over the defaults.
+func (p *payload) apply(req *http.Request, headers map[string]string) {
+	req.Header.Set("Content-Type", p.ContentType)
+	req.Header.Set("User-Agent", "config-reloader-operator")
+	for name, value := range p.Headers {
+		req.Header.Set(name, value)
+	}
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+}
+
+// Title is a one line description of the notification
+func (n *Notification) Title() string {
+	subject := fmt.Sprintf("ConfigReloader %s/%s", n.Namespace, n.ConfigReloader)
+	switch n.Event {
+	case configv1.NotificationChangeDetected:
+		return subject + " detected a config change"
+	case configv1.NotificationReloadStarted:
+		return subject + " is reloading"
+	case configv1.NotificationReloadCompleted:
+		return subject + " reloaded"
+	case configv1.NotificationReloadFailed:
+		return subject + " failed to reload"
+	}
+	return subject
+}
+
+// Details describes the cause and outcome of the notification
+func (n *Notification) Details() string {
+	var lines []string
+	if n.Cause != "" {
+		lines = append(lines, "Cause: "+n.Cause)
+	}
+	switch n.Event {
+	case configv1.NotificationReloadCompleted:
+		lines = append(lines, fmt.Sprintf("Restarted %d pods", n.RestartedPods))
+	case configv1.NotificationReloadFailed:
+		if n.Error != "" {
+			lines = append(lines, "Error: "+n.Error)
+		} else {
+			lines = append(lines, fmt.Sprintf("Restarted %d pods, %d targets failed", n.RestartedPods, n.FailedTargets))
+		}
+		for _, target := range n.Targets {
+			if target.Error != "" {
+				lines = append(lines, fmt.Sprintf("%s %s/%s: %s", target.Kind, target.Namespace, target.Name, target.Error))
+			}
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Summary is the title followed by the details
+func (n *Notification) Summary() string {
+	if details := n.Details(); details != "" {
+		return n.Title() + "\n" + details
+	}
+	return n.Title()
+}
+
+// color is the theme color of Teams message cards
+func (n *Notification) color() string {
+	switch n.Event {
+	case configv1.NotificationReloadCompleted:
+		return "2EB67D"
+	case configv1.NotificationReloadFailed:
+		return "E01E5A"
+	}
+	return "36C5F0"
+}
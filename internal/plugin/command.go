@@ -0,0 +1,211 @@
+package plugin
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	configv1 "github.com/shehbazk/config-reloader-operator/api/v1"
+	"github.com/shehbazk/config-reloader-operator/internal/analyzer"
+)
+
+var scheme = runtime.NewScheme()
+
+func init() {
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(configv1.AddToScheme(scheme))
+}
+
+// NewCommand returns the kubectl-reloader command. The cluster and namespace
+// are taken from the kubeconfig like kubectl does.
+func NewCommand() *cobra.Command {
+	return newCommand(&Plugin{})
+}
+
+// newCommand returns the kubectl-reloader command running p. A client already
+// set on p is used instead of the kubeconfig.
+func newCommand(p *Plugin) *cobra.Command {
+	loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
+	overrides := &clientcmd.ConfigOverrides{}
+
+	root := &cobra.Command{
+		Use:          "kubectl-reloader",
+		Short:        "Inspect and drive the reloads of ConfigReloaders",
+		SilenceUsage: true,
+		PersistentPreRunE: func(cmd *cobra.Command, _ []string) error {
+			p.Out = cmd.OutOrStdout()
+			if p.Client != nil {
+				if overrides.Context.Namespace != "" {
+					p.Namespace = overrides.Context.Namespace
+				}
+				return nil
+			}
+
+			config := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loadingRules, overrides)
+			restConfig, err := config.ClientConfig()
+			if err != nil {
+				return fmt.Errorf("failed to load kubeconfig: %w", err)
+			}
+			if p.Namespace, _, err = config.Namespace(); err != nil {
+				return fmt.Errorf("failed to get namespace from kubeconfig: %w", err)
+			}
+			if p.Client, err = client.New(restConfig, client.Options{Scheme: scheme}); err != nil {
+				return fmt.Errorf("failed to create client: %w", err)
+			}
+			return nil
+		},
+	}
+
+	flags := root.PersistentFlags()
+	flags.StringVar(&loadingRules.ExplicitPath, "kubeconfig", "", "Path to the kubeconfig file to use")
+	flags.StringVar(&overrides.CurrentContext, "context", "", "The name of the kubeconfig context to use")
+	flags.StringVarP(&overrides.Context.Namespace, "namespace", "n", "", "The namespace to operate in")
+	flags.BoolVarP(&p.AllNamespaces, "all-namespaces", "A", false,
+		"List ConfigReloaders and ReloadEvents across all namespaces")
+
+	root.AddCommand(&cobra.Command{
+		Use:   "status",
+		Short: "Show the ConfigReloaders, their watched resources and last reload",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			return p.Status(cmd.Context())
+		},
+	})
+
+	root.AddCommand(&cobra.Command{
+		Use:   "why (TYPE NAME | TYPE/NAME)",
+		Short: "Show which ConfigReloaders restart a pod or workload, and why",
+		Example: "  kubectl reloader why deployment/web\n" +
+			"  kubectl reloader why pod web-7d9c6b5f4-x2kqp",
+		Args: cobra.RangeArgs(1, 2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			kind, name, ok := strings.Cut(args[0], "/")
+			switch {
+			case len(args) == 2 && !ok:
+				name = args[1]
+			case len(args) == 1 && ok:
+			default:
+				return fmt.Errorf("expected TYPE NAME or TYPE/NAME, got %q", strings.Join(args, " "))
+			}
+			return p.Why(cmd.Context(), kind, name)
+		},
+	})
+
+	root.AddCommand(&cobra.Command{
+		Use:   "trigger NAME",
+		Short: "Request a manual reload of a ConfigReloader",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return p.Trigger(cmd.Context(), args[0])
+		},
+	})
+
+	root.AddCommand(&cobra.Command{
+		Use:   "approve NAME",
+		Short: "Approve the reload held by a ConfigReloader until it is approved",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return p.Approve(cmd.Context(), args[0])
+		},
+	})
+
+	root.AddCommand(&cobra.Command{
+		Use:   "pause NAME",
+		Short: "Suspend the restarts of a ConfigReloader",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return p.SetSuspended(cmd.Context(), args[0], true)
+		},
+	})
+
+	root.AddCommand(&cobra.Command{
+		Use:   "resume NAME",
+		Short: "Resume the restarts of a suspended ConfigReloader",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return p.SetSuspended(cmd.Context(), args[0], false)
+		},
+	})
+
+	var limit int
+	history := &cobra.Command{
+		Use:   "history [NAME]",
+		Short: "Show the ReloadEvents of a ConfigReloader, or of all of them, newest first",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var name string
+			if len(args) == 1 {
+				name = args[0]
+			}
+			return p.History(cmd.Context(), name, limit)
+		},
+	}
+	history.Flags().IntVar(&limit, "limit", 20, "Maximum number of ReloadEvents to show, 0 for all")
+	root.AddCommand(history)
+
+	var from string
+	diff := &cobra.Command{
+		Use:   "diff REVISION",
+		Short: "Show the keys a ConfigRevision changed since the previous revision of its resource",
+		Example: "  kubectl reloader diff configreloader-sample-x7k2p\n" +
+			"  kubectl reloader diff configreloader-sample-x7k2p --from configreloader-sample-b4n9q",
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return p.Diff(cmd.Context(), args[0], from)
+		},
+	}
+	diff.Flags().StringVar(&from, "from", "", "ConfigRevision to compare with instead of the previous revision")
+	root.AddCommand(diff)
+
+	root.AddCommand(newAnalyzeCommand(overrides))
+
+	return root
+}
+
+// newAnalyzeCommand returns the analyze command, which works on manifests
+// and does not need a cluster
+func newAnalyzeCommand(overrides *clientcmd.ConfigOverrides) *cobra.Command {
+	var (
+		output  string
+		volumes bool
+	)
+	analyze := &cobra.Command{
+		Use:   "analyze PATH...",
+		Short: "Find the workloads of rendered manifests needing restarts on config changes and suggest ConfigReloaders",
+		Example: "  helm template my-release ./chart | kubectl reloader analyze -\n" +
+			"  kubectl reloader analyze ./rendered -o yaml > configreloaders.yaml",
+		Args: cobra.MinimumNArgs(1),
+		// Overrides the root hook connecting to the cluster
+		PersistentPreRunE: func(*cobra.Command, []string) error {
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if output != "report" && output != "yaml" {
+				return fmt.Errorf("unsupported output %q, expected report or yaml", output)
+			}
+			namespace := overrides.Context.Namespace
+			if namespace == "" {
+				namespace = "default"
+			}
+			graph, err := analyzer.Load(namespace, args...)
+			if err != nil {
+				return err
+			}
+			if output == "yaml" {
+				return analyzer.WriteConfigReloaders(cmd.OutOrStdout(), graph.Suggest(volumes))
+			}
+			return graph.WriteReport(cmd.OutOrStdout())
+		},
+	}
+	analyze.Flags().StringVarP(&output, "output", "o", "report",
+		"Output format: report for the dependency graph, yaml for the suggested ConfigReloaders")
+	analyze.Flags().BoolVar(&volumes, "include-volumes", false,
+		"Also watch the ConfigMaps and Secrets consumed through volumes updated in place")
+	return analyze
+}
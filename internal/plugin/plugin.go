@@ -0,0 +1,416 @@
+// Package plugin implements the kubectl-reloader commands.
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/duration"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	configv1 "github.com/shehbazk/config-reloader-operator/api/v1"
+	"github.com/shehbazk/config-reloader-operator/internal/controller"
+	"github.com/shehbazk/config-reloader-operator/internal/podrefs"
+	"github.com/shehbazk/config-reloader-operator/internal/revision"
+)
+
+// Plugin runs the kubectl-reloader commands against a cluster
+type Plugin struct {
+	Client client.Client
+	// Namespace the commands operate in
+	Namespace string
+	// AllNamespaces lists the ConfigReloaders and ReloadEvents of every
+	// namespace in status and history
+	AllNamespaces bool
+	Out           io.Writer
+}
+
+// Status prints the ConfigReloaders with their watched resources, targets
+// and last reload
+func (p *Plugin) Status(ctx context.Context) error {
+	var configReloaders configv1.ConfigReloaderList
+	if err := p.Client.List(ctx, &configReloaders, p.listOptions()...); err != nil {
+		return fmt.Errorf("failed to list ConfigReloaders: %w", err)
+	}
+	if len(configReloaders.Items) == 0 {
+		return p.noneFound("ConfigReloaders")
+	}
+	sort.Slice(configReloaders.Items, func(i, j int) bool {
+		a, b := configReloaders.Items[i], configReloaders.Items[j]
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		return a.Name < b.Name
+	})
+
+	w := newTabWriter(p.Out)
+	p.printRow(w, "", "NAME", "READY", "STATE", "WATCHED", "TARGETS", "LAST RELOAD")
+	for _, cr := range configReloaders.Items {
+		ready := "Unknown"
+		if condition := meta.FindStatusCondition(cr.Status.Conditions, "Ready"); condition != nil {
+			ready = string(condition.Status)
+		}
+		p.printRow(w, cr.Namespace, cr.Name, ready, state(&cr), watched(&cr),
+			fmt.Sprintf("%d/%d", cr.Status.Summary.Complete, cr.Status.Summary.Total),
+			lastReload(&cr))
+	}
+	return w.Flush()
+}
+
+// Why prints the ConfigReloaders that restart a pod or workload and the
+// references that make them restart it, in order of precedence
+func (p *Plugin) Why(ctx context.Context, kind, name string) error {
+	obj, kind, err := workload(kind)
+	if err != nil {
+		return err
+	}
+	if err := p.Client.Get(ctx, types.NamespacedName{Namespace: p.Namespace, Name: name}, obj); err != nil {
+		return fmt.Errorf("failed to get %s %s/%s: %w", kind, p.Namespace, name, err)
+	}
+	template := podTemplate(obj)
+
+	var configReloaders configv1.ConfigReloaderList
+	if err := p.Client.List(ctx, &configReloaders, client.InNamespace(p.Namespace)); err != nil {
+		return fmt.Errorf("failed to list ConfigReloaders in %s: %w", p.Namespace, err)
+	}
+
+	type match struct {
+		cr   *configv1.ConfigReloader
+		refs []podrefs.Reference
+	}
+	var matches []match
+	for i := range configReloaders.Items {
+		cr := &configReloaders.Items[i]
+		if cr.DeletionTimestamp != nil {
+			continue
+		}
+		refs, err := controller.TriggeringReferences(cr, template.Labels, &template.Spec)
+		if err != nil {
+			return fmt.Errorf("failed to match ConfigReloader %s/%s: %w", cr.Namespace, cr.Name, err)
+		}
+		if len(refs) > 0 {
+			matches = append(matches, match{cr: cr, refs: refs})
+		}
+	}
+	if len(matches) == 0 {
+		_, err := fmt.Fprintf(p.Out, "No ConfigReloader restarts %s %s/%s\n", kind, p.Namespace, name)
+		return err
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		return controller.Precedes(matches[i].cr, matches[j].cr)
+	})
+
+	w := newTabWriter(p.Out)
+	p.printRow(w, "", "CONFIGRELOADER", "PRIORITY", "POLICY", "STATE", "REFERENCES")
+	for _, m := range matches {
+		refs := make([]string, 0, len(m.refs))
+		for _, ref := range m.refs {
+			refs = append(refs, describeReference(ref))
+		}
+		p.printRow(w, "", m.cr.Name, fmt.Sprint(m.cr.Spec.Priority), string(m.cr.Spec.RestartPolicy),
+			state(m.cr), strings.Join(refs, ", "))
+	}
+	return w.Flush()
+}
+
+// Trigger requests a manual reload of a ConfigReloader
+func (p *Plugin) Trigger(ctx context.Context, name string) error {
+	cr, err := p.get(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	patch := client.MergeFrom(cr.DeepCopy())
+	if cr.Annotations == nil {
+		cr.Annotations = make(map[string]string)
+	}
+	// Every distinct value triggers one reload
+	cr.Annotations[controller.ReloadRequestAnnotation] = time.Now().UTC().Format(time.RFC3339Nano)
+	if err := p.Client.Patch(ctx, cr, patch); err != nil {
+		return fmt.Errorf("failed to request reload of ConfigReloader %s/%s: %w", p.Namespace, name, err)
+	}
+
+	message := "reload requested"
+	if cr.Spec.Suspend {
+		message += ", it runs once the ConfigReloader is resumed"
+	}
+	_, err = fmt.Fprintf(p.Out, "configreloader/%s %s\n", name, message)
+	return err
+}
+
+// Approve approves the reload held by a ConfigReloader. The admission
+// webhook checks that the user is allowed to approve it.
+func (p *Plugin) Approve(ctx context.Context, name string) error {
+	cr, err := p.get(ctx, name)
+	if err != nil {
+		return err
+	}
+	pending := cr.Status.PendingApproval
+	if pending == nil {
+		return fmt.Errorf("ConfigReloader %s/%s has no reload waiting for approval", p.Namespace, name)
+	}
+
+	patch := client.MergeFrom(cr.DeepCopy())
+	if cr.Annotations == nil {
+		cr.Annotations = make(map[string]string)
+	}
+	cr.Annotations[configv1.ApproveAnnotation] = pending.ID
+	if err := p.Client.Patch(ctx, cr, patch); err != nil {
+		return fmt.Errorf("failed to approve reload of ConfigReloader %s/%s: %w", p.Namespace, name, err)
+	}
+	_, err = fmt.Fprintf(p.Out, "configreloader/%s reload %s approved: %s\n", name, pending.ID, pending.Cause)
+	return err
+}
+
+// SetSuspended pauses or resumes the restarts of a ConfigReloader
+func (p *Plugin) SetSuspended(ctx context.Context, name string, suspend bool) error {
+	cr, err := p.get(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	action := "resumed"
+	if suspend {
+		action = "paused"
+	}
+	if cr.Spec.Suspend == suspend {
+		_, err := fmt.Fprintf(p.Out, "configreloader/%s already %s\n", name, action)
+		return err
+	}
+
+	patch := client.MergeFrom(cr.DeepCopy())
+	cr.Spec.Suspend = suspend
+	if err := p.Client.Patch(ctx, cr, patch); err != nil {
+		return fmt.Errorf("failed to update ConfigReloader %s/%s: %w", p.Namespace, name, err)
+	}
+	_, err = fmt.Fprintf(p.Out, "configreloader/%s %s\n", name, action)
+	return err
+}
+
+// History prints the ReloadEvents of a ConfigReloader, or of every
+// ConfigReloader when name is empty, newest first. At most limit events are
+// printed when limit is positive.
+func (p *Plugin) History(ctx context.Context, name string, limit int) error {
+	opts := p.listOptions()
+	if name != "" {
+		opts = append(opts, client.MatchingLabels{controller.ConfigReloaderLabel: controller.ConfigReloaderLabelValue(name)})
+	}
+	var events configv1.ReloadEventList
+	if err := p.Client.List(ctx, &events, opts...); err != nil {
+		return fmt.Errorf("failed to list ReloadEvents: %w", err)
+	}
+	if len(events.Items) == 0 {
+		return p.noneFound("ReloadEvents")
+	}
+	sort.Slice(events.Items, func(i, j int) bool {
+		a, b := events.Items[i].Status.StartTime, events.Items[j].Status.StartTime
+		if !a.Equal(&b) {
+			return b.Before(&a)
+		}
+		return events.Items[i].Name > events.Items[j].Name
+	})
+	if limit > 0 && len(events.Items) > limit {
+		events.Items = events.Items[:limit]
+	}
+
+	w := newTabWriter(p.Out)
+	p.printRow(w, "", "CONFIGRELOADER", "OUTCOME", "RESTARTED", "FAILED", "DURATION", "AGE", "CAUSE")
+	for _, event := range events.Items {
+		p.printRow(w, event.Namespace, event.Spec.ConfigReloader, string(event.Status.Outcome),
+			fmt.Sprint(event.Status.RestartedPods), fmt.Sprint(event.Status.FailedTargets),
+			event.Status.Duration.Duration.String(), duration.HumanDuration(time.Since(event.Status.StartTime.Time)),
+			event.Spec.Cause)
+	}
+	return w.Flush()
+}
+
+// Diff prints the changes of a ConfigRevision since the previous revision of
+// the same resource, or since the revision named from when it is set
+func (p *Plugin) Diff(ctx context.Context, name, from string) error {
+	to, err := p.getRevision(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	var previous *configv1.ConfigRevision
+	if from != "" {
+		if previous, err = p.getRevision(ctx, from); err != nil {
+			return err
+		}
+		if previous.Spec.Resource.Kind != to.Spec.Resource.Kind || previous.Spec.Resource.Name != to.Spec.Resource.Name ||
+			previous.Spec.Resource.Namespace != to.Spec.Resource.Namespace {
+			return fmt.Errorf("ConfigRevisions %s and %s are not revisions of the same resource", from, name)
+		}
+	} else {
+		var revisions configv1.ConfigRevisionList
+		if err := p.Client.List(ctx, &revisions, client.InNamespace(p.Namespace),
+			client.MatchingLabels{controller.ConfigReloaderLabel: to.Labels[controller.ConfigReloaderLabel]}); err != nil {
+			return fmt.Errorf("failed to list ConfigRevisions: %w", err)
+		}
+		for i := range revisions.Items {
+			candidate := &revisions.Items[i]
+			if candidate.Spec.Resource.Kind == to.Spec.Resource.Kind && candidate.Spec.Resource.Name == to.Spec.Resource.Name &&
+				candidate.Spec.Resource.Namespace == to.Spec.Resource.Namespace && candidate.Spec.Revision < to.Spec.Revision &&
+				(previous == nil || candidate.Spec.Revision > previous.Spec.Revision) {
+				previous = candidate
+			}
+		}
+	}
+	if previous == nil {
+		// The first recorded revision is compared with an empty resource
+		previous = &configv1.ConfigRevision{Spec: configv1.ConfigRevisionSpec{Resource: to.Spec.Resource}}
+	}
+
+	_, err = fmt.Fprint(p.Out, revision.Diff(&previous.Spec, &to.Spec))
+	return err
+}
+
+func (p *Plugin) getRevision(ctx context.Context, name string) (*configv1.ConfigRevision, error) {
+	rev := &configv1.ConfigRevision{}
+	if err := p.Client.Get(ctx, types.NamespacedName{Namespace: p.Namespace, Name: name}, rev); err != nil {
+		return nil, fmt.Errorf("failed to get ConfigRevision %s/%s: %w", p.Namespace, name, err)
+	}
+	return rev, nil
+}
+
+func (p *Plugin) get(ctx context.Context, name string) (*configv1.ConfigReloader, error) {
+	cr := &configv1.ConfigReloader{}
+	if err := p.Client.Get(ctx, types.NamespacedName{Namespace: p.Namespace, Name: name}, cr); err != nil {
+		return nil, fmt.Errorf("failed to get ConfigReloader %s/%s: %w", p.Namespace, name, err)
+	}
+	return cr, nil
+}
+
+func (p *Plugin) listOptions() []client.ListOption {
+	if p.AllNamespaces {
+		return nil
+	}
+	return []client.ListOption{client.InNamespace(p.Namespace)}
+}
+
+func (p *Plugin) noneFound(resource string) error {
+	if p.AllNamespaces {
+		_, err := fmt.Fprintf(p.Out, "No %s found\n", resource)
+		return err
+	}
+	_, err := fmt.Fprintf(p.Out, "No %s found in %s namespace\n", resource, p.Namespace)
+	return err
+}
+
+// printRow prints a table row, prefixed with the namespace when listing
+// every namespace. The namespace of the header row is empty.
+func (p *Plugin) printRow(w io.Writer, namespace string, columns ...string) {
+	if p.AllNamespaces {
+		if namespace == "" {
+			namespace = "NAMESPACE"
+		}
+		columns = append([]string{namespace}, columns...)
+	}
+	fmt.Fprintln(w, strings.Join(columns, "\t"))
+}
+
+// newTabWriter returns a writer aligning columns like kubectl get
+func newTabWriter(out io.Writer) *tabwriter.Writer {
+	return tabwriter.NewWriter(out, 6, 4, 3, ' ', 0)
+}
+
+// workload returns an empty pod or workload of the kind and its canonical kind
+func workload(kind string) (client.Object, string, error) {
+	switch strings.ToLower(kind) {
+	case "pod", "pods", "po":
+		return &corev1.Pod{}, "Pod", nil
+	case "deployment", "deployments", "deploy":
+		return &appsv1.Deployment{}, "Deployment", nil
+	case "statefulset", "statefulsets", "sts":
+		return &appsv1.StatefulSet{}, "StatefulSet", nil
+	case "daemonset", "daemonsets", "ds":
+		return &appsv1.DaemonSet{}, "DaemonSet", nil
+	case "replicaset", "replicasets", "rs":
+		return &appsv1.ReplicaSet{}, "ReplicaSet", nil
+	}
+	return nil, "", fmt.Errorf("unsupported kind %q, expected a pod, deployment, statefulset, daemonset or replicaset", kind)
+}
+
+// podTemplate returns the labels and spec of a pod, or the pod template of a
+// workload
+func podTemplate(obj client.Object) *corev1.PodTemplateSpec {
+	switch o := obj.(type) {
+	case *corev1.Pod:
+		return &corev1.PodTemplateSpec{ObjectMeta: o.ObjectMeta, Spec: o.Spec}
+	case *appsv1.Deployment:
+		return &o.Spec.Template
+	case *appsv1.StatefulSet:
+		return &o.Spec.Template
+	case *appsv1.DaemonSet:
+		return &o.Spec.Template
+	case *appsv1.ReplicaSet:
+		return &o.Spec.Template
+	}
+	return &corev1.PodTemplateSpec{}
+}
+
+// state describes whether the ConfigReloader restarts its targets
+func state(cr *configv1.ConfigReloader) string {
+	switch {
+	case cr.Spec.Suspend && cr.Status.PendingReload:
+		return "Suspended (pending)"
+	case cr.Spec.Suspend:
+		return "Suspended"
+	case cr.Spec.DryRun:
+		return "DryRun"
+	case cr.Status.PendingApproval != nil:
+		return "AwaitingApproval"
+	case cr.Status.Canary != nil && cr.Status.Canary.Phase == configv1.CanaryPhaseBaking:
+		return "CanaryBaking"
+	}
+	return "Active"
+}
+
+// watched lists the ConfigMaps and Secrets watched by the ConfigReloader,
+// with their namespace when it differs from the ConfigReloader's
+func watched(cr *configv1.ConfigReloader) string {
+	var refs []string
+	add := func(kind string, ref configv1.ResourceRef) {
+		if ref.Namespace != "" && ref.Namespace != cr.Namespace {
+			refs = append(refs, fmt.Sprintf("%s/%s/%s", kind, ref.Namespace, ref.Name))
+			return
+		}
+		refs = append(refs, kind+"/"+ref.Name)
+	}
+	for _, ref := range cr.Spec.ConfigMaps {
+		add(podrefs.KindConfigMap, ref)
+	}
+	for _, ref := range cr.Spec.Secrets {
+		add(podrefs.KindSecret, ref)
+	}
+	if len(refs) == 0 {
+		return "<none>"
+	}
+	return strings.Join(refs, ",")
+}
+
+// describeReference describes how a pod spec consumes a ConfigMap or Secret
+func describeReference(ref podrefs.Reference) string {
+	if ref.Container == "" {
+		return fmt.Sprintf("%s/%s (%s)", ref.Kind, ref.Name, ref.Source)
+	}
+	return fmt.Sprintf("%s/%s (%s in %s)", ref.Kind, ref.Name, ref.Source, ref.Container)
+}
+
+// lastReload formats the time elapsed since the last reload like the AGE
+// column of kubectl get
+func lastReload(cr *configv1.ConfigReloader) string {
+	if cr.Status.LastReloadTime == nil {
+		return "<never>"
+	}
+	return duration.HumanDuration(time.Since(cr.Status.LastReloadTime.Time))
+}
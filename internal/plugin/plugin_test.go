@@ -0,0 +1,314 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugin
+
+import (
+	"bytes"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	configv1 "github.com/shehbazk/config-reloader-operator/api/v1"
+	"github.com/shehbazk/config-reloader-operator/internal/controller"
+	"github.com/shehbazk/config-reloader-operator/internal/revision"
+)
+
+var _ = Describe("kubectl-reloader", Ordered, func() {
+	const namespace = "reloader-plugin"
+
+	// run executes the command against the test environment
+	run := func(args ...string) (string, error) {
+		var out bytes.Buffer
+		cmd := newCommand(&Plugin{Client: k8sClient, Namespace: "default"})
+		cmd.SetOut(&out)
+		cmd.SetErr(&out)
+		cmd.SetArgs(append(args, "-n", namespace))
+		err := cmd.ExecuteContext(ctx)
+		return out.String(), err
+	}
+
+	configReloader := func(name string, priority int32, configMaps ...string) *configv1.ConfigReloader {
+		cr := &configv1.ConfigReloader{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+			Spec: configv1.ConfigReloaderSpec{
+				Selector:      &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+				Secrets:       []configv1.ResourceRef{{Name: "creds", Namespace: "shared"}},
+				RestartPolicy: configv1.RestartPolicyAnnotation,
+				Priority:      priority,
+			},
+		}
+		for _, name := range configMaps {
+			cr.Spec.ConfigMaps = append(cr.Spec.ConfigMaps, configv1.ResourceRef{Name: name})
+		}
+		return cr
+	}
+
+	reloadEvent := func(name, configReloader, cause string, age time.Duration) *configv1.ReloadEvent {
+		start := metav1.NewTime(time.Now().Add(-age).Truncate(time.Second))
+		return &configv1.ReloadEvent{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: namespace,
+				Labels:    map[string]string{controller.ConfigReloaderLabel: configReloader},
+			},
+			Spec: configv1.ReloadEventSpec{
+				ConfigReloader: configReloader,
+				Cause:          cause,
+				Policy:         configv1.RestartPolicyAnnotation,
+			},
+			Status: configv1.ReloadEventStatus{
+				Outcome:        configv1.ReloadSucceeded,
+				RestartedPods:  2,
+				StartTime:      start,
+				CompletionTime: start,
+			},
+		}
+	}
+
+	configRevision := func(name string, number int64, data map[string]string) *configv1.ConfigRevision {
+		spec := revision.ForConfigMap(&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: namespace},
+			Data:       data,
+		})
+		spec.ConfigReloader = "high"
+		spec.Revision = number
+		spec.ObservedTime = metav1.Now()
+		return &configv1.ConfigRevision{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: namespace,
+				Labels:    map[string]string{controller.ConfigReloaderLabel: "high"},
+			},
+			Spec: spec,
+		}
+	}
+
+	BeforeAll(func() {
+		Expect(k8sClient.Create(ctx, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: namespace}})).To(Succeed())
+
+		labels := map[string]string{"app": "web"}
+		Expect(k8sClient.Create(ctx, &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: namespace},
+			Spec: appsv1.DeploymentSpec{
+				Selector: &metav1.LabelSelector{MatchLabels: labels},
+				Template: corev1.PodTemplateSpec{
+					ObjectMeta: metav1.ObjectMeta{Labels: labels},
+					Spec: corev1.PodSpec{
+						Containers: []corev1.Container{{Name: "app", Image: "nginx", EnvFrom: []corev1.EnvFromSource{{
+							ConfigMapRef: &corev1.ConfigMapEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: "app"}},
+						}}}},
+						Volumes: []corev1.Volume{{Name: "extra", VolumeSource: corev1.VolumeSource{
+							ConfigMap: &corev1.ConfigMapVolumeSource{LocalObjectReference: corev1.LocalObjectReference{Name: "extra"}},
+						}}},
+					},
+				},
+			},
+		})).To(Succeed())
+		Expect(k8sClient.Create(ctx, &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "standalone", Namespace: namespace},
+			Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "app", Image: "nginx"}}},
+		})).To(Succeed())
+
+		Expect(k8sClient.Create(ctx, configReloader("low", 0, "app"))).To(Succeed())
+		Expect(k8sClient.Create(ctx, configReloader("high", 10, "app", "extra"))).To(Succeed())
+		Expect(k8sClient.Create(ctx, configReloader("other", 0, "unused"))).To(Succeed())
+
+		var high configv1.ConfigReloader
+		Expect(k8sClient.Get(ctx, client.ObjectKey{Namespace: namespace, Name: "high"}, &high)).To(Succeed())
+		lastReload := metav1.NewTime(time.Now().Add(-5 * time.Minute))
+		high.Status.LastReloadTime = &lastReload
+		high.Status.Summary = configv1.TargetSummary{Total: 2, Complete: 1}
+		high.Status.Conditions = []metav1.Condition{{
+			Type: "Ready", Status: metav1.ConditionTrue, Reason: "ReconcileSuccess", LastTransitionTime: lastReload,
+		}}
+		Expect(k8sClient.Status().Update(ctx, &high)).To(Succeed())
+
+		Expect(k8sClient.Create(ctx, reloadEvent("high-1", "high", "ConfigMap app changed", time.Hour))).To(Succeed())
+		Expect(k8sClient.Create(ctx, reloadEvent("high-2", "high", "manual reload requested", time.Minute))).To(Succeed())
+		Expect(k8sClient.Create(ctx, reloadEvent("low-1", "low", "ConfigMap app changed", 30*time.Minute))).To(Succeed())
+	})
+
+	It("should show the status of the ConfigReloaders", func() {
+		out, err := run("status")
+		Expect(err).NotTo(HaveOccurred())
+
+		lines := bytes.Split(bytes.TrimSpace([]byte(out)), []byte("\n"))
+		Expect(lines).To(HaveLen(4))
+		Expect(string(lines[0])).To(MatchRegexp(`^NAME\s+READY\s+STATE\s+WATCHED\s+TARGETS\s+LAST RELOAD$`))
+		Expect(string(lines[1])).To(MatchRegexp(
+			`^high\s+True\s+Active\s+ConfigMap/app,ConfigMap/extra,Secret/shared/creds\s+1/2\s+5m$`))
+		Expect(string(lines[2])).To(MatchRegexp(`^low\s+Unknown\s+Active\s+ConfigMap/app,Secret/shared/creds\s+0/0\s+<never>$`))
+
+		out, err = run("status", "-A")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(out).To(MatchRegexp(`NAMESPACE\s+NAME`))
+		Expect(out).To(MatchRegexp(namespace + `\s+high\s+True`))
+	})
+
+	It("should explain which ConfigReloaders restart a workload", func() {
+		out, err := run("why", "deployment/web")
+		Expect(err).NotTo(HaveOccurred())
+
+		lines := bytes.Split(bytes.TrimSpace([]byte(out)), []byte("\n"))
+		Expect(lines).To(HaveLen(3))
+		Expect(string(lines[0])).To(MatchRegexp(`^CONFIGRELOADER\s+PRIORITY\s+POLICY\s+STATE\s+REFERENCES$`))
+		Expect(string(lines[1])).To(MatchRegexp(
+			`^high\s+10\s+annotation\s+Active\s+ConfigMap/extra \(volume\), ConfigMap/app \(envFrom in app\)$`))
+		Expect(string(lines[2])).To(MatchRegexp(`^low\s+0\s+annotation\s+Active\s+ConfigMap/app \(envFrom in app\)$`))
+
+		out, err = run("why", "pod", "standalone")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(out).To(Equal("No ConfigReloader restarts Pod " + namespace + "/standalone\n"))
+
+		_, err = run("why", "service/web")
+		Expect(err).To(MatchError(ContainSubstring("unsupported kind")))
+		_, err = run("why", "deployment/web", "web")
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should request a manual reload", func() {
+		out, err := run("trigger", "low")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(out).To(Equal("configreloader/low reload requested\n"))
+
+		var cr configv1.ConfigReloader
+		Expect(k8sClient.Get(ctx, client.ObjectKey{Namespace: namespace, Name: "low"}, &cr)).To(Succeed())
+		first := cr.Annotations[controller.ReloadRequestAnnotation]
+		Expect(first).NotTo(BeEmpty())
+
+		_, err = run("trigger", "low")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(k8sClient.Get(ctx, client.ObjectKey{Namespace: namespace, Name: "low"}, &cr)).To(Succeed())
+		Expect(cr.Annotations[controller.ReloadRequestAnnotation]).NotTo(Equal(first))
+
+		_, err = run("trigger", "missing")
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should approve a held reload", func() {
+		_, err := run("approve", "other")
+		Expect(err).To(MatchError(ContainSubstring("has no reload waiting for approval")))
+
+		var cr configv1.ConfigReloader
+		Expect(k8sClient.Get(ctx, client.ObjectKey{Namespace: namespace, Name: "other"}, &cr)).To(Succeed())
+		now := metav1.Now()
+		cr.Status.PendingApproval = &configv1.PendingApproval{
+			ID: "abcd1234", Cause: "ConfigMap unused changed", RequestTime: now, ExpirationTime: now,
+		}
+		Expect(k8sClient.Status().Update(ctx, &cr)).To(Succeed())
+
+		out, err := run("status")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(out).To(MatchRegexp(`other\s+Unknown\s+AwaitingApproval\s+`))
+
+		out, err = run("approve", "other")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(out).To(Equal("configreloader/other reload abcd1234 approved: ConfigMap unused changed\n"))
+		Expect(k8sClient.Get(ctx, client.ObjectKey{Namespace: namespace, Name: "other"}, &cr)).To(Succeed())
+		Expect(cr.Annotations).To(HaveKeyWithValue(configv1.ApproveAnnotation, "abcd1234"))
+
+		cr.Status.PendingApproval = nil
+		Expect(k8sClient.Status().Update(ctx, &cr)).To(Succeed())
+	})
+
+	It("should pause and resume a ConfigReloader", func() {
+		suspended := func() bool {
+			var cr configv1.ConfigReloader
+			Expect(k8sClient.Get(ctx, client.ObjectKey{Namespace: namespace, Name: "other"}, &cr)).To(Succeed())
+			return cr.Spec.Suspend
+		}
+
+		out, err := run("pause", "other")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(out).To(Equal("configreloader/other paused\n"))
+		Expect(suspended()).To(BeTrue())
+
+		out, err = run("pause", "other")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(out).To(Equal("configreloader/other already paused\n"))
+
+		out, err = run("status")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(out).To(MatchRegexp(`other\s+Unknown\s+Suspended\s+`))
+
+		out, err = run("trigger", "other")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(out).To(ContainSubstring("runs once the ConfigReloader is resumed"))
+
+		out, err = run("resume", "other")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(out).To(Equal("configreloader/other resumed\n"))
+		Expect(suspended()).To(BeFalse())
+	})
+
+	It("should diff a ConfigRevision with the previous one", func() {
+		Expect(k8sClient.Create(ctx, configRevision("high-app-1", 1,
+			map[string]string{"config.yaml": "level: info\nformat: json\n"}))).To(Succeed())
+		Expect(k8sClient.Create(ctx, configRevision("high-app-2", 2,
+			map[string]string{"config.yaml": "level: debug\nformat: json\n", "extra": "on"}))).To(Succeed())
+		Expect(k8sClient.Create(ctx, configRevision("high-app-3", 3,
+			map[string]string{"config.yaml": "level: debug\nformat: json\n"}))).To(Succeed())
+
+		out, err := run("diff", "high-app-2")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(out).To(Equal("--- ConfigMap " + namespace + "/app revision 1\n" +
+			"+++ ConfigMap " + namespace + "/app revision 2\n" +
+			"config.yaml: modified\n" +
+			"  -level: info\n" +
+			"  +level: debug\n" +
+			"   format: json\n" +
+			"extra: added\n" +
+			"  +on\n"))
+
+		out, err = run("diff", "high-app-3", "--from", "high-app-1")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(out).To(ContainSubstring("revision 1\n"))
+		Expect(out).NotTo(ContainSubstring("extra"))
+
+		out, err = run("diff", "high-app-1")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(out).To(ContainSubstring("revision 0\n"))
+		Expect(out).To(ContainSubstring("config.yaml: added\n"))
+
+		_, err = run("diff", "missing")
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should show the reload history newest first", func() {
+		out, err := run("history")
+		Expect(err).NotTo(HaveOccurred())
+		lines := bytes.Split(bytes.TrimSpace([]byte(out)), []byte("\n"))
+		Expect(lines).To(HaveLen(4))
+		Expect(string(lines[0])).To(MatchRegexp(`^CONFIGRELOADER\s+OUTCOME\s+RESTARTED\s+FAILED\s+DURATION\s+AGE\s+CAUSE$`))
+		Expect(string(lines[1])).To(MatchRegexp(`^high\s+Succeeded\s+2\s+0\s+0s\s+\d+s\s+manual reload requested$`))
+		Expect(string(lines[2])).To(MatchRegexp(`^low\s+Succeeded\s+2\s+0\s+0s\s+30m\s+ConfigMap app changed$`))
+
+		out, err = run("history", "high", "--limit", "1")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(out).To(ContainSubstring("manual reload requested"))
+		Expect(out).NotTo(ContainSubstring("ConfigMap app changed"))
+
+		out, err = run("history", "other")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(out).To(Equal("No ReloadEvents found in " + namespace + " namespace\n"))
+	})
+})
@@ -0,0 +1,167 @@
+// Package podrefs walks pod specs for the ConfigMaps and Secrets they consume.
+package podrefs
+
+import (
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Kinds of the referenced resources
+const (
+	KindConfigMap = "ConfigMap"
+	KindSecret    = "Secret"
+)
+
+// Source describes how a pod consumes a ConfigMap or Secret
+type Source string
+
+const (
+	// SourceVolume is a ConfigMap or Secret volume, or a projected volume source
+	SourceVolume Source = "volume"
+	// SourceEnvFrom is a container envFrom reference
+	SourceEnvFrom Source = "envFrom"
+	// SourceEnv is a container env var valueFrom reference
+	SourceEnv Source = "env"
+)
+
+// Reference is a ConfigMap or Secret consumed by a pod spec
+type Reference struct {
+	Kind   string
+	Name   string
+	Source Source
+	// Container consuming the reference, empty for volumes
+	Container string
+	// Volume is the name of the volume, empty for env references
+	Volume string
+}
+
+// Key returns the namespace/name key used for lookups of watched resources
+func Key(namespace, name string) string {
+	return namespace + "/" + name
+}
+
+// Walk calls fn for every ConfigMap and Secret referenced by the pod spec in
+// volumes, containers and init containers. The walk stops when fn returns false.
+func Walk(spec *corev1.PodSpec, fn func(Reference) bool) {
+	if !walkVolumes(spec.Volumes, fn) {
+		return
+	}
+
+	if !walkContainers(spec.Containers, fn) {
+		return
+	}
+
+	walkContainers(spec.InitContainers, fn)
+}
+
+// References returns every ConfigMap and Secret referenced by the pod spec
+func References(spec *corev1.PodSpec) []Reference {
+	var refs []Reference
+	Walk(spec, func(ref Reference) bool {
+		refs = append(refs, ref)
+		return true
+	})
+	return refs
+}
+
+// Uses reports whether a pod spec in namespace references any of the watched
+// ConfigMaps or Secrets, keyed by namespace/name
+func Uses(spec *corev1.PodSpec, namespace string, watchedCMs, watchedSecrets map[string]bool) bool {
+	found := false
+	Walk(spec, func(ref Reference) bool {
+		key := Key(namespace, ref.Name)
+		if ref.Kind == KindConfigMap && watchedCMs[key] || ref.Kind == KindSecret && watchedSecrets[key] {
+			found = true
+		}
+		return !found
+	})
+	return found
+}
+
+func walkVolumes(volumes []corev1.Volume, fn func(Reference) bool) bool {
+	for _, volume := range volumes {
+		if volume.ConfigMap != nil {
+			if !fn(Reference{Kind: KindConfigMap, Name: volume.ConfigMap.Name, Source: SourceVolume, Volume: volume.Name}) {
+				return false
+			}
+		}
+		if volume.Secret != nil {
+			if !fn(Reference{Kind: KindSecret, Name: volume.Secret.SecretName, Source: SourceVolume, Volume: volume.Name}) {
+				return false
+			}
+		}
+		if volume.Projected != nil && !walkProjection(volume.Name, volume.Projected.Sources, fn) {
+			return false
+		}
+	}
+	return true
+}
+
+func walkProjection(volume string, sources []corev1.VolumeProjection, fn func(Reference) bool) bool {
+	for _, source := range sources {
+		if source.ConfigMap != nil {
+			if !fn(Reference{Kind: KindConfigMap, Name: source.ConfigMap.Name, Source: SourceVolume, Volume: volume}) {
+				return false
+			}
+		}
+		if source.Secret != nil {
+			if !fn(Reference{Kind: KindSecret, Name: source.Secret.Name, Source: SourceVolume, Volume: volume}) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func walkContainers(containers []corev1.Container, fn func(Reference) bool) bool {
+	for _, container := range containers {
+		// Check envFrom
+		if !walkContainerEnvFrom(container, fn) {
+			return false
+		}
+
+		// Check individual env vars
+		if !walkContainerEnvVars(container, fn) {
+			return false
+		}
+	}
+	return true
+}
+
+func walkContainerEnvFrom(container corev1.Container, fn func(Reference) bool) bool {
+	for _, envFrom := range container.EnvFrom {
+		if envFrom.ConfigMapRef != nil {
+			ref := Reference{Kind: KindConfigMap, Name: envFrom.ConfigMapRef.Name, Source: SourceEnvFrom, Container: container.Name}
+			if !fn(ref) {
+				return false
+			}
+		}
+		if envFrom.SecretRef != nil {
+			ref := Reference{Kind: KindSecret, Name: envFrom.SecretRef.Name, Source: SourceEnvFrom, Container: container.Name}
+			if !fn(ref) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func walkContainerEnvVars(container corev1.Container, fn func(Reference) bool) bool {
+	for _, env := range container.Env {
+		if env.ValueFrom == nil {
+			continue
+		}
+		if env.ValueFrom.ConfigMapKeyRef != nil {
+			ref := Reference{Kind: KindConfigMap, Name: env.ValueFrom.ConfigMapKeyRef.Name, Source: SourceEnv, Container: container.Name}
+			if !fn(ref) {
+				return false
+			}
+		}
+		if env.ValueFrom.SecretKeyRef != nil {
+			ref := Reference{Kind: KindSecret, Name: env.ValueFrom.SecretKeyRef.Name, Source: SourceEnv, Container: container.Name}
+			if !fn(ref) {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,142 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package podrefs
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/utils/ptr"
+)
+
+var _ = Describe("References", func() {
+	local := func(name string) corev1.LocalObjectReference {
+		return corev1.LocalObjectReference{Name: name}
+	}
+
+	DescribeTable("should find the references of a pod spec",
+		func(spec corev1.PodSpec, expected []Reference) {
+			Expect(References(&spec)).To(Equal(expected))
+		},
+		Entry("env vars",
+			corev1.PodSpec{Containers: []corev1.Container{{Name: "app", Env: []corev1.EnvVar{
+				{Name: "PLAIN", Value: "value"},
+				{Name: "LEVEL", ValueFrom: &corev1.EnvVarSource{
+					ConfigMapKeyRef: &corev1.ConfigMapKeySelector{LocalObjectReference: local("app"), Key: "level"},
+				}},
+				{Name: "TOKEN", ValueFrom: &corev1.EnvVarSource{
+					SecretKeyRef: &corev1.SecretKeySelector{LocalObjectReference: local("creds"), Key: "token"},
+				}},
+			}}}},
+			[]Reference{
+				{Kind: KindConfigMap, Name: "app", Source: SourceEnv, Container: "app"},
+				{Kind: KindSecret, Name: "creds", Source: SourceEnv, Container: "app"},
+			},
+		),
+		Entry("envFrom",
+			corev1.PodSpec{Containers: []corev1.Container{{Name: "app", EnvFrom: []corev1.EnvFromSource{
+				{ConfigMapRef: &corev1.ConfigMapEnvSource{LocalObjectReference: local("app")}},
+				{Prefix: "DB_", SecretRef: &corev1.SecretEnvSource{LocalObjectReference: local("db")}},
+			}}}},
+			[]Reference{
+				{Kind: KindConfigMap, Name: "app", Source: SourceEnvFrom, Container: "app"},
+				{Kind: KindSecret, Name: "db", Source: SourceEnvFrom, Container: "app"},
+			},
+		),
+		Entry("volumes",
+			corev1.PodSpec{Volumes: []corev1.Volume{
+				{Name: "config", VolumeSource: corev1.VolumeSource{
+					ConfigMap: &corev1.ConfigMapVolumeSource{LocalObjectReference: local("app")},
+				}},
+				{Name: "tls", VolumeSource: corev1.VolumeSource{
+					Secret: &corev1.SecretVolumeSource{SecretName: "tls"},
+				}},
+				{Name: "cache", VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}}},
+			}},
+			[]Reference{
+				{Kind: KindConfigMap, Name: "app", Source: SourceVolume, Volume: "config"},
+				{Kind: KindSecret, Name: "tls", Source: SourceVolume, Volume: "tls"},
+			},
+		),
+		Entry("projected volumes",
+			corev1.PodSpec{Volumes: []corev1.Volume{
+				{Name: "bundle", VolumeSource: corev1.VolumeSource{Projected: &corev1.ProjectedVolumeSource{
+					Sources: []corev1.VolumeProjection{
+						{ConfigMap: &corev1.ConfigMapProjection{LocalObjectReference: local("app")}},
+						{Secret: &corev1.SecretProjection{LocalObjectReference: local("creds")}},
+						{ServiceAccountToken: &corev1.ServiceAccountTokenProjection{Path: "token"}},
+					},
+				}}},
+			}},
+			[]Reference{
+				{Kind: KindConfigMap, Name: "app", Source: SourceVolume, Volume: "bundle"},
+				{Kind: KindSecret, Name: "creds", Source: SourceVolume, Volume: "bundle"},
+			},
+		),
+		Entry("optional references",
+			corev1.PodSpec{
+				Volumes: []corev1.Volume{{Name: "extra", VolumeSource: corev1.VolumeSource{
+					ConfigMap: &corev1.ConfigMapVolumeSource{LocalObjectReference: local("extra"), Optional: ptr.To(true)},
+				}}},
+				Containers: []corev1.Container{{Name: "app", EnvFrom: []corev1.EnvFromSource{
+					{SecretRef: &corev1.SecretEnvSource{LocalObjectReference: local("overrides"), Optional: ptr.To(true)}},
+				}}},
+			},
+			[]Reference{
+				{Kind: KindConfigMap, Name: "extra", Source: SourceVolume, Volume: "extra"},
+				{Kind: KindSecret, Name: "overrides", Source: SourceEnvFrom, Container: "app"},
+			},
+		),
+		Entry("init containers after containers",
+			corev1.PodSpec{
+				InitContainers: []corev1.Container{{Name: "init", EnvFrom: []corev1.EnvFromSource{
+					{ConfigMapRef: &corev1.ConfigMapEnvSource{LocalObjectReference: local("init")}},
+				}}},
+				Containers: []corev1.Container{{Name: "app", EnvFrom: []corev1.EnvFromSource{
+					{ConfigMapRef: &corev1.ConfigMapEnvSource{LocalObjectReference: local("app")}},
+				}}},
+			},
+			[]Reference{
+				{Kind: KindConfigMap, Name: "app", Source: SourceEnvFrom, Container: "app"},
+				{Kind: KindConfigMap, Name: "init", Source: SourceEnvFrom, Container: "init"},
+			},
+		),
+		Entry("no references", corev1.PodSpec{Containers: []corev1.Container{{Name: "app"}}}, nil),
+	)
+
+	DescribeTable("should report whether a pod spec uses a watched resource",
+		func(watchedCMs, watchedSecrets map[string]bool, expected bool) {
+			spec := &corev1.PodSpec{
+				Volumes: []corev1.Volume{{Name: "bundle", VolumeSource: corev1.VolumeSource{
+					Projected: &corev1.ProjectedVolumeSource{Sources: []corev1.VolumeProjection{
+						{Secret: &corev1.SecretProjection{LocalObjectReference: local("creds")}},
+					}},
+				}}},
+				Containers: []corev1.Container{{Name: "app", Env: []corev1.EnvVar{
+					{Name: "LEVEL", ValueFrom: &corev1.EnvVarSource{
+						ConfigMapKeyRef: &corev1.ConfigMapKeySelector{LocalObjectReference: local("app"), Key: "level"},
+					}},
+				}}},
+			}
+			Expect(Uses(spec, "default", watchedCMs, watchedSecrets)).To(Equal(expected))
+		},
+		Entry("watched ConfigMap", map[string]bool{"default/app": true}, nil, true),
+		Entry("watched projected Secret", nil, map[string]bool{"default/creds": true}, true),
+		Entry("Secret watched as a ConfigMap", map[string]bool{"default/creds": true}, nil, false),
+		Entry("resource in another namespace", map[string]bool{"other/app": true}, nil, false),
+	)
+})
@@ -0,0 +1,117 @@
+// Package promquery runs the instant queries of canary analyses against a
+// Prometheus-compatible HTTP API.
+//
+// Only the parts of the API needed to read instant vectors and scalars are
+// implemented, so that any backend serving /api/v1/query can be used, as well
+// as a local stub while testing.
+package promquery
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Client runs instant queries against the HTTP API of Prometheus or of a
+// compatible backend such as Thanos or VictoriaMetrics
+type Client struct {
+	http *http.Client
+}
+
+// NewClient returns a Client giving up on queries after timeout
+func NewClient(timeout time.Duration) *Client {
+	return &Client{http: &http.Client{Timeout: timeout}}
+}
+
+// response is the envelope of the query API
+type response struct {
+	Status    string `json:"status"`
+	ErrorType string `json:"errorType"`
+	Error     string `json:"error"`
+	Data      struct {
+		ResultType string          `json:"resultType"`
+		Result     json.RawMessage `json:"result"`
+	} `json:"data"`
+}
+
+// sample is a [timestamp, "value"] pair
+type sample [2]any
+
+// Query runs query at the current time and returns the value of every
+// sample of the result. NaN samples are left out.
+func (c *Client) Query(ctx context.Context, address, query string) ([]float64, error) {
+	endpoint, err := url.JoinPath(address, "/api/v1/query")
+	if err != nil {
+		return nil, fmt.Errorf("invalid address %q: %w", address, err)
+	}
+	form := url.Values{"query": {query}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the query response: %w", err)
+	}
+	var decoded response
+	if err := json.Unmarshal(body, &decoded); err != nil {
+		return nil, fmt.Errorf("unexpected query response with status %d: %w", resp.StatusCode, err)
+	}
+	if decoded.Status != "success" {
+		return nil, fmt.Errorf("query failed with %s: %s", decoded.ErrorType, decoded.Error)
+	}
+
+	var samples []sample
+	switch decoded.Data.ResultType {
+	case "vector":
+		var series []struct {
+			Value sample `json:"value"`
+		}
+		if err := json.Unmarshal(decoded.Data.Result, &series); err != nil {
+			return nil, fmt.Errorf("failed to decode the vector result: %w", err)
+		}
+		for _, s := range series {
+			samples = append(samples, s.Value)
+		}
+	case "scalar":
+		var s sample
+		if err := json.Unmarshal(decoded.Data.Result, &s); err != nil {
+			return nil, fmt.Errorf("failed to decode the scalar result: %w", err)
+		}
+		samples = append(samples, s)
+	default:
+		return nil, fmt.Errorf("unsupported result type %q, expected an instant vector or a scalar",
+			decoded.Data.ResultType)
+	}
+
+	values := make([]float64, 0, len(samples))
+	for _, s := range samples {
+		raw, ok := s[1].(string)
+		if !ok {
+			return nil, fmt.Errorf("unexpected sample value %v", s[1])
+		}
+		value, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return nil, fmt.Errorf("unexpected sample value %q: %w", raw, err)
+		}
+		if !math.IsNaN(value) {
+			values = append(values, value)
+		}
+	}
+	return values, nil
+}
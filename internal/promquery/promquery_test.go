@@ -0,0 +1,101 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package promquery
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Client", func() {
+	var (
+		ctx      context.Context
+		response string
+		queries  []string
+		server   *httptest.Server
+		c        *Client
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		queries = nil
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer GinkgoRecover()
+			Expect(r.URL.Path).To(Equal("/api/v1/query"))
+			Expect(r.ParseForm()).To(Succeed())
+			queries = append(queries, r.PostForm.Get("query"))
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(response))
+		}))
+		DeferCleanup(server.Close)
+		c = NewClient(time.Second)
+	})
+
+	It("should return the values of a vector", func() {
+		response = `{"status":"success","data":{"resultType":"vector","result":[
+			{"metric":{"pod":"a"},"value":[1700000000,"0.5"]},
+			{"metric":{"pod":"b"},"value":[1700000000,"NaN"]},
+			{"metric":{"pod":"c"},"value":[1700000000,"0"]}]}}`
+
+		values, err := c.Query(ctx, server.URL, `rate(errors_total[1m])`)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(values).To(Equal([]float64{0.5, 0}))
+		Expect(queries).To(Equal([]string{`rate(errors_total[1m])`}))
+	})
+
+	It("should return the value of a scalar", func() {
+		response = `{"status":"success","data":{"resultType":"scalar","result":[1700000000,"2"]}}`
+
+		values, err := c.Query(ctx, server.URL+"/", "scalar(up)")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(values).To(Equal([]float64{2}))
+	})
+
+	It("should return no values for an empty vector", func() {
+		response = `{"status":"success","data":{"resultType":"vector","result":[]}}`
+
+		values, err := c.Query(ctx, server.URL, "up == 0")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(values).To(BeEmpty())
+	})
+
+	It("should report query errors", func() {
+		response = `{"status":"error","errorType":"bad_data","error":"parse error"}`
+
+		_, err := c.Query(ctx, server.URL, "up ==")
+		Expect(err).To(MatchError(ContainSubstring("bad_data: parse error")))
+	})
+
+	It("should reject range results", func() {
+		response = `{"status":"success","data":{"resultType":"matrix","result":[]}}`
+
+		_, err := c.Query(ctx, server.URL, "up[5m]")
+		Expect(err).To(MatchError(ContainSubstring(`unsupported result type "matrix"`)))
+	})
+
+	It("should report responses that are not from a query API", func() {
+		response = `<html>not found</html>`
+
+		_, err := c.Query(ctx, server.URL, "up")
+		Expect(err).To(MatchError(ContainSubstring("unexpected query response with status 200")))
+	})
+})
@@ -0,0 +1,126 @@
+package revision
+
+import (
+	"fmt"
+	"strings"
+
+	configv1 "github.com/shehbazk/config-reloader-operator/api/v1"
+	"github.com/shehbazk/config-reloader-operator/internal/podrefs"
+)
+
+// Diff returns a human readable diff between two revisions of the same
+// resource. ConfigMap values are compared line by line, Secret values are
+// only reported as changed.
+func Diff(from, to *configv1.ConfigRevisionSpec) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "--- %s %s/%s revision %d\n", from.Resource.Kind, from.Resource.Namespace, from.Resource.Name, from.Revision)
+	fmt.Fprintf(&b, "+++ %s %s/%s revision %d\n", to.Resource.Kind, to.Resource.Namespace, to.Resource.Name, to.Revision)
+
+	for _, change := range Changes(from, to) {
+		switch {
+		case to.Resource.Kind == podrefs.KindSecret:
+			fmt.Fprintf(&b, "%s: %s (value hidden)\n", change.Key, strings.ToLower(string(change.Type)))
+		case isBinary(from, change.Key) || isBinary(to, change.Key):
+			fmt.Fprintf(&b, "%s: %s (binary value)\n", change.Key, strings.ToLower(string(change.Type)))
+		default:
+			oldValue, hadOld := from.Data[change.Key]
+			newValue, hasNew := to.Data[change.Key]
+			lines, ok := diffLines(splitLines(oldValue, hadOld), splitLines(newValue, hasNew))
+			if !ok {
+				fmt.Fprintf(&b, "%s: %s (value too large to diff)\n", change.Key, strings.ToLower(string(change.Type)))
+				continue
+			}
+			fmt.Fprintf(&b, "%s: %s\n", change.Key, strings.ToLower(string(change.Type)))
+			for _, line := range lines {
+				fmt.Fprintf(&b, "  %s\n", line)
+			}
+		}
+	}
+	return b.String()
+}
+
+func isBinary(spec *configv1.ConfigRevisionSpec, key string) bool {
+	_, ok := spec.BinaryData[key]
+	return ok
+}
+
+func splitLines(value string, present bool) []string {
+	if !present {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(value, "\n"), "\n")
+}
+
+// maxDiffCells bounds the size of the longest common subsequence table of
+// a value diff, about 8 MiB
+const maxDiffCells = 1 << 20
+
+// diffLines returns the lines of both values prefixed with "-" when only in
+// a, "+" when only in b and " " when in both, following their longest common
+// subsequence. The lines the values start and end with are matched first,
+// and false is returned when the lines left in between are too many to diff.
+func diffLines(a, b []string) ([]string, bool) {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	midA, midB := a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+	if (len(midA)+1)*(len(midB)+1) > maxDiffCells {
+		return nil, false
+	}
+
+	lines := make([]string, 0, len(a)+len(b)-prefix-suffix)
+	for _, line := range a[:prefix] {
+		lines = append(lines, " "+line)
+	}
+	lines = append(lines, diffMiddle(midA, midB)...)
+	for _, line := range a[len(a)-suffix:] {
+		lines = append(lines, " "+line)
+	}
+	return lines, true
+}
+
+// diffMiddle diffs two values through their longest common subsequence
+func diffMiddle(a, b []string) []string {
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	lines := make([]string, 0, len(a)+len(b))
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			lines = append(lines, " "+a[i])
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			lines = append(lines, "-"+a[i])
+			i++
+		default:
+			lines = append(lines, "+"+b[j])
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		lines = append(lines, "-"+a[i])
+	}
+	for ; j < len(b); j++ {
+		lines = append(lines, "+"+b[j])
+	}
+	return lines
+}
@@ -0,0 +1,190 @@
+// Package revision builds ConfigRevision snapshots of ConfigMaps and Secrets
+// and compares them.
+package revision
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	configv1 "github.com/shehbazk/config-reloader-operator/api/v1"
+	"github.com/shehbazk/config-reloader-operator/internal/podrefs"
+)
+
+// dataFields are the top-level fields holding the data of each kind
+var dataFields = map[string][]string{
+	podrefs.KindConfigMap: {"f:data", "f:binaryData"},
+	podrefs.KindSecret:    {"f:data", "f:stringData"},
+}
+
+// ForConfigMap returns a snapshot of the data of a ConfigMap
+func ForConfigMap(cm *corev1.ConfigMap) configv1.ConfigRevisionSpec {
+	values := make(map[string][]byte, len(cm.Data)+len(cm.BinaryData))
+	for key, value := range cm.Data {
+		values[key] = []byte(value)
+	}
+	for key, value := range cm.BinaryData {
+		values[key] = value
+	}
+
+	spec := configv1.ConfigRevisionSpec{
+		Resource: configv1.RevisionResource{
+			Kind:            podrefs.KindConfigMap,
+			Name:            cm.Name,
+			Namespace:       cm.Namespace,
+			ResourceVersion: cm.ResourceVersion,
+		},
+		Hash:      hashValues(sha256.New(), values),
+		Author:    Author(podrefs.KindConfigMap, cm),
+		KeyHashes: make(map[string]string, len(values)),
+	}
+	for key, value := range values {
+		spec.KeyHashes[key] = hashValue(sha256.New(), value)
+	}
+	if len(cm.Data) > 0 {
+		spec.Data = make(map[string]string, len(cm.Data))
+		for key, value := range cm.Data {
+			spec.Data[key] = value
+		}
+	}
+	if len(cm.BinaryData) > 0 {
+		spec.BinaryData = make(map[string][]byte, len(cm.BinaryData))
+		for key, value := range cm.BinaryData {
+			spec.BinaryData[key] = bytes.Clone(value)
+		}
+	}
+	return spec
+}
+
+// ForSecret returns a snapshot of a Secret that only holds its key names and
+// keyed hashes of its values. The values themselves are never recorded.
+func ForSecret(secret *corev1.Secret) configv1.ConfigRevisionSpec {
+	// Keying the hashes with the Secret UID prevents comparing them with
+	// precomputed hashes of common values
+	newHash := func() hash.Hash { return hmac.New(sha256.New, []byte(secret.UID)) }
+
+	spec := configv1.ConfigRevisionSpec{
+		Resource: configv1.RevisionResource{
+			Kind:            podrefs.KindSecret,
+			Name:            secret.Name,
+			Namespace:       secret.Namespace,
+			ResourceVersion: secret.ResourceVersion,
+		},
+		Hash:      hashValues(newHash(), secret.Data),
+		Author:    Author(podrefs.KindSecret, secret),
+		KeyHashes: make(map[string]string, len(secret.Data)),
+	}
+	for key, value := range secret.Data {
+		spec.KeyHashes[key] = hashValue(newHash(), value)
+	}
+	return spec
+}
+
+// Author returns the field manager that last wrote the data of a ConfigMap
+// or Secret of the given kind, empty when unknown
+func Author(kind string, obj metav1.Object) string {
+	if entry := LastDataWrite(kind, obj); entry != nil {
+		return entry.Manager
+	}
+	return ""
+}
+
+// LastDataWrite returns the most recent managed fields entry that owns the
+// data of a ConfigMap or Secret of the given kind, or the most recent entry
+// if none does
+func LastDataWrite(kind string, obj metav1.Object) *metav1.ManagedFieldsEntry {
+	var latest, latestOwner *metav1.ManagedFieldsEntry
+	entries := obj.GetManagedFields()
+	for i := range entries {
+		entry := &entries[i]
+		if latest == nil || newer(entry, latest) {
+			latest = entry
+		}
+		if ownsAny(entry, dataFields[kind]) && (latestOwner == nil || newer(entry, latestOwner)) {
+			latestOwner = entry
+		}
+	}
+
+	if latestOwner != nil {
+		return latestOwner
+	}
+	return latest
+}
+
+func newer(a, b *metav1.ManagedFieldsEntry) bool {
+	if a.Time == nil {
+		return false
+	}
+	return b.Time == nil || a.Time.After(b.Time.Time)
+}
+
+func ownsAny(entry *metav1.ManagedFieldsEntry, fields []string) bool {
+	if entry.FieldsV1 == nil {
+		return false
+	}
+	for _, field := range fields {
+		if bytes.Contains(entry.FieldsV1.Raw, []byte(`"`+field+`"`)) {
+			return true
+		}
+	}
+	return false
+}
+
+// Changes returns the keys that differ between two snapshots, in key order
+func Changes(previous, current *configv1.ConfigRevisionSpec) []configv1.KeyChange {
+	var changes []configv1.KeyChange
+	for _, key := range sortedKeys(previous.KeyHashes, current.KeyHashes) {
+		oldHash, inOld := previous.KeyHashes[key]
+		newHash, inNew := current.KeyHashes[key]
+		switch {
+		case !inOld:
+			changes = append(changes, configv1.KeyChange{Key: key, Type: configv1.KeyAdded})
+		case !inNew:
+			changes = append(changes, configv1.KeyChange{Key: key, Type: configv1.KeyRemoved})
+		case oldHash != newHash:
+			changes = append(changes, configv1.KeyChange{Key: key, Type: configv1.KeyModified})
+		}
+	}
+	return changes
+}
+
+func hashValues(h hash.Hash, values map[string][]byte) string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		fmt.Fprintf(h, "%d:%s%d:", len(key), key, len(values[key]))
+		h.Write(values[key])
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func hashValue(h hash.Hash, value []byte) string {
+	h.Write(value)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// sortedKeys returns the union of the keys of both maps in order
+func sortedKeys(a, b map[string]string) []string {
+	keys := make([]string, 0, len(a)+len(b))
+	for key := range a {
+		keys = append(keys, key)
+	}
+	for key := range b {
+		if _, ok := a[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
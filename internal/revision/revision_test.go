@@ -0,0 +1,208 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package revision
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	configv1 "github.com/shehbazk/config-reloader-operator/api/v1"
+	"github.com/shehbazk/config-reloader-operator/internal/podrefs"
+)
+
+var _ = Describe("Revisions", func() {
+	Context("ConfigMap snapshots", func() {
+		It("records the data and a hash per key", func() {
+			cm := &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default", ResourceVersion: "42"},
+				Data:       map[string]string{"a": "1"},
+				BinaryData: map[string][]byte{"b": {0x01}},
+			}
+
+			spec := ForConfigMap(cm)
+			Expect(spec.Resource).To(Equal(configv1.RevisionResource{
+				Kind: podrefs.KindConfigMap, Name: "app", Namespace: "default", ResourceVersion: "42",
+			}))
+			Expect(spec.Data).To(Equal(map[string]string{"a": "1"}))
+			Expect(spec.BinaryData).To(Equal(map[string][]byte{"b": {0x01}}))
+			Expect(spec.KeyHashes).To(HaveKey("a"))
+			Expect(spec.KeyHashes).To(HaveKey("b"))
+			Expect(spec.Hash).NotTo(BeEmpty())
+		})
+
+		It("hashes identical data identically", func() {
+			a := ForConfigMap(&corev1.ConfigMap{Data: map[string]string{"x": "1", "y": "2"}})
+			b := ForConfigMap(&corev1.ConfigMap{Data: map[string]string{"y": "2", "x": "1"}})
+			c := ForConfigMap(&corev1.ConfigMap{Data: map[string]string{"x": "1", "y": "3"}})
+			Expect(a.Hash).To(Equal(b.Hash))
+			Expect(a.Hash).NotTo(Equal(c.Hash))
+		})
+	})
+
+	Context("Secret snapshots", func() {
+		It("never records values", func() {
+			secret := &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "creds", Namespace: "default", UID: "uid-1"},
+				Data:       map[string][]byte{"password": []byte("hunter2")},
+			}
+
+			spec := ForSecret(secret)
+			Expect(spec.Data).To(BeEmpty())
+			Expect(spec.BinaryData).To(BeEmpty())
+			Expect(spec.KeyHashes).To(HaveKey("password"))
+			Expect(spec.KeyHashes["password"]).NotTo(ContainSubstring("hunter2"))
+		})
+
+		It("keys the hashes with the Secret UID", func() {
+			data := map[string][]byte{"password": []byte("hunter2")}
+			a := ForSecret(&corev1.Secret{ObjectMeta: metav1.ObjectMeta{UID: "uid-1"}, Data: data})
+			b := ForSecret(&corev1.Secret{ObjectMeta: metav1.ObjectMeta{UID: "uid-2"}, Data: data})
+			again := ForSecret(&corev1.Secret{ObjectMeta: metav1.ObjectMeta{UID: "uid-1"}, Data: data})
+
+			Expect(a.KeyHashes["password"]).NotTo(Equal(b.KeyHashes["password"]))
+			Expect(a.KeyHashes).To(Equal(again.KeyHashes))
+			Expect(a.Hash).To(Equal(again.Hash))
+		})
+	})
+
+	Context("Author", func() {
+		entry := func(manager string, at time.Time, fields string) metav1.ManagedFieldsEntry {
+			return metav1.ManagedFieldsEntry{
+				Manager:  manager,
+				Time:     &metav1.Time{Time: at},
+				FieldsV1: &metav1.FieldsV1{Raw: []byte(fields)},
+			}
+		}
+		now := time.Now()
+
+		It("returns the latest writer of the data", func() {
+			cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{ManagedFields: []metav1.ManagedFieldsEntry{
+				entry("kubectl", now.Add(-time.Hour), `{"f:data":{"f:a":{}}}`),
+				entry("helm", now.Add(-time.Minute), `{"f:data":{"f:b":{}}}`),
+				entry("labeler", now, `{"f:metadata":{"f:labels":{}}}`),
+			}}}
+			Expect(Author(podrefs.KindConfigMap, cm)).To(Equal("helm"))
+		})
+
+		It("falls back to the latest writer", func() {
+			cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{ManagedFields: []metav1.ManagedFieldsEntry{
+				entry("kubectl", now.Add(-time.Hour), `{"f:metadata":{}}`),
+				entry("labeler", now, `{"f:metadata":{"f:labels":{}}}`),
+			}}}
+			Expect(Author(podrefs.KindConfigMap, cm)).To(Equal("labeler"))
+		})
+
+		It("attributes Secret writes through stringData", func() {
+			secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{ManagedFields: []metav1.ManagedFieldsEntry{
+				entry("kubectl", now.Add(-time.Hour), `{"f:data":{"f:a":{}}}`),
+				entry("argocd-controller", now.Add(-time.Minute), `{"f:stringData":{"f:b":{}}}`),
+			}}}
+			Expect(Author(podrefs.KindSecret, secret)).To(Equal("argocd-controller"))
+		})
+
+		It("is empty without managed fields", func() {
+			Expect(Author(podrefs.KindConfigMap, &corev1.ConfigMap{})).To(BeEmpty())
+		})
+	})
+
+	Context("Changes", func() {
+		It("reports added, removed and modified keys in order", func() {
+			previous := ForConfigMap(&corev1.ConfigMap{Data: map[string]string{"a": "1", "b": "2", "c": "3"}})
+			current := ForConfigMap(&corev1.ConfigMap{Data: map[string]string{"a": "1", "b": "two", "d": "4"}})
+
+			Expect(Changes(&previous, &current)).To(Equal([]configv1.KeyChange{
+				{Key: "b", Type: configv1.KeyModified},
+				{Key: "c", Type: configv1.KeyRemoved},
+				{Key: "d", Type: configv1.KeyAdded},
+			}))
+		})
+
+		It("is empty for identical snapshots", func() {
+			spec := ForConfigMap(&corev1.ConfigMap{Data: map[string]string{"a": "1"}})
+			Expect(Changes(&spec, &spec)).To(BeEmpty())
+		})
+	})
+
+	Context("Diff", func() {
+		It("diffs ConfigMap values line by line", func() {
+			from := ForConfigMap(&corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default"},
+				Data:       map[string]string{"app.conf": "a=1\nb=2\nc=3\n"},
+			})
+			from.Revision = 1
+			to := ForConfigMap(&corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default"},
+				Data:       map[string]string{"app.conf": "a=1\nb=3\nc=3\n"},
+			})
+			to.Revision = 2
+
+			Expect(Diff(&from, &to)).To(Equal(
+				"--- ConfigMap default/app revision 1\n" +
+					"+++ ConfigMap default/app revision 2\n" +
+					"app.conf: modified\n" +
+					"   a=1\n" +
+					"  -b=2\n" +
+					"  +b=3\n" +
+					"   c=3\n"))
+		})
+
+		It("diffs the changed lines of large values", func() {
+			lines := make([]string, 20000)
+			for i := range lines {
+				lines[i] = fmt.Sprintf("line %d", i)
+			}
+			old := strings.Join(lines, "\n")
+			lines[10000] = "changed"
+			from := ForConfigMap(&corev1.ConfigMap{Data: map[string]string{"big": old}})
+			to := ForConfigMap(&corev1.ConfigMap{Data: map[string]string{"big": strings.Join(lines, "\n")}})
+
+			diff := Diff(&from, &to)
+			Expect(diff).To(ContainSubstring("  -line 10000\n  +changed\n"))
+			Expect(strings.Count(diff, "\n  ")).To(Equal(20001))
+		})
+
+		It("does not diff large values changed throughout", func() {
+			oldLines, newLines := make([]string, 2000), make([]string, 2000)
+			for i := range oldLines {
+				oldLines[i], newLines[i] = fmt.Sprintf("old %d", i), fmt.Sprintf("new %d", i)
+			}
+			from := ForConfigMap(&corev1.ConfigMap{Data: map[string]string{"big": strings.Join(oldLines, "\n")}})
+			to := ForConfigMap(&corev1.ConfigMap{Data: map[string]string{"big": strings.Join(newLines, "\n")}})
+
+			diff := Diff(&from, &to)
+			Expect(diff).To(HaveSuffix("big: modified (value too large to diff)\n"))
+			Expect(diff).NotTo(ContainSubstring("old 0"))
+		})
+
+		It("hides Secret values", func() {
+			meta := metav1.ObjectMeta{Name: "creds", Namespace: "default", UID: "uid-1"}
+			from := ForSecret(&corev1.Secret{ObjectMeta: meta, Data: map[string][]byte{"password": []byte("old")}})
+			to := ForSecret(&corev1.Secret{ObjectMeta: meta, Data: map[string][]byte{"password": []byte("new")}})
+
+			diff := Diff(&from, &to)
+			Expect(diff).To(ContainSubstring("password: modified (value hidden)"))
+			Expect(diff).NotTo(ContainSubstring("old"))
+			Expect(diff).NotTo(ContainSubstring("new"))
+		})
+	})
+})
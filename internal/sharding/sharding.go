@@ -0,0 +1,496 @@
+// Package sharding splits the ConfigReloaders between operator replicas.
+//
+// The keys of the ConfigReloaders are hashed into a fixed number of shards,
+// each guarded by a Lease. Every replica announces itself with a member
+// Lease, the shards are assigned to the live members by rendezvous hashing
+// and a replica only reconciles the ConfigReloaders of the shards whose Lease
+// it holds. A replica joining or leaving only moves the shards it gains or
+// loses. A shard changes hands only once its previous holder finished the
+// reconciles in flight and released the Lease, or once the Lease expired, so
+// a ConfigReloader is never reconciled by two replicas at the same time.
+package sharding
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	coordinationv1 "k8s.io/api/coordination/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/clock"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	// GroupLabel holds the name of the group of a member or shard Lease
+	GroupLabel = "config.dev/shard-group"
+	// RoleLabel tells member Leases from shard Leases
+	RoleLabel = "config.dev/shard-role"
+	// ShardLabel holds the index of a shard Lease
+	ShardLabel = "config.dev/shard"
+
+	RoleMember = "member"
+	RoleShard  = "shard"
+)
+
+// Options configures a Coordinator
+type Options struct {
+	// Name of the group, used as prefix of its Lease names
+	Name string
+	// Namespace of the Leases
+	Namespace string
+	// Identity of this replica, unique within the group
+	Identity string
+	// Shards is the number of shards the ConfigReloaders are split into
+	Shards int
+
+	// LeaseDuration is how long the Lease of a replica that stopped renewing
+	// it is waited for before it is taken over
+	LeaseDuration time.Duration
+	// RenewDeadline is how long a replica keeps reconciling a shard without
+	// renewing its Lease. It must be shorter than LeaseDuration.
+	RenewDeadline time.Duration
+	// RetryPeriod is how often the Leases are renewed and the shards rebalanced
+	RetryPeriod time.Duration
+}
+
+// Coordinator acquires and releases the shards of a replica. It is added to
+// the manager as a Runnable that runs on every replica.
+type Coordinator struct {
+	client client.Client
+	opts   Options
+	clock  clock.PassiveClock
+
+	shards []*shard
+	events chan event.GenericEvent
+}
+
+type shard struct {
+	index int
+	name  string
+
+	// inflight is held for reading by every Claim on the shard, and for
+	// writing while the shard is released
+	inflight sync.RWMutex
+
+	mu        sync.Mutex
+	held      bool
+	releasing bool
+	renewed   time.Time
+	synced    map[string]bool
+
+	// observed is the last version of the Lease seen and observedTime the
+	// local time it was first seen. The Lease expires once it stays
+	// unchanged for LeaseDuration.
+	observed     string
+	observedTime time.Time
+}
+
+// Claim is held while a ConfigReloader of an owned shard is reconciled
+type Claim struct {
+	shard *shard
+	key   string
+
+	// First is set for the first Claim on a key since its shard was
+	// acquired. The cache may not hold the last status written by the
+	// previous holder of the shard yet.
+	First bool
+}
+
+// New returns a Coordinator for the given options
+func New(c client.Client, opts Options) (*Coordinator, error) {
+	switch {
+	case opts.Name == "" || opts.Namespace == "" || opts.Identity == "":
+		return nil, errors.New("sharding requires a name, a namespace and an identity")
+	case opts.Shards < 1:
+		return nil, fmt.Errorf("invalid number of shards %d", opts.Shards)
+	case opts.RetryPeriod <= 0 || opts.RenewDeadline <= opts.RetryPeriod || opts.LeaseDuration <= opts.RenewDeadline:
+		return nil, errors.New("sharding requires RetryPeriod < RenewDeadline < LeaseDuration")
+	}
+
+	coordinator := &Coordinator{
+		client: c,
+		opts:   opts,
+		clock:  clock.RealClock{},
+		shards: make([]*shard, opts.Shards),
+		events: make(chan event.GenericEvent),
+	}
+	for i := range coordinator.shards {
+		coordinator.shards[i] = &shard{index: i, name: fmt.Sprintf("%s-shard-%d", opts.Name, i)}
+	}
+	return coordinator, nil
+}
+
+// ShardOf returns the shard of a ConfigReloader
+func (c *Coordinator) ShardOf(key types.NamespacedName) int {
+	h := fnv.New32a()
+	h.Write([]byte(key.String()))
+	return int(h.Sum32() % uint32(len(c.shards)))
+}
+
+// Owns reports whether this replica currently reconciles the ConfigReloader
+func (c *Coordinator) Owns(key types.NamespacedName) bool {
+	return c.shards[c.ShardOf(key)].valid(c.clock.Now(), c.opts.RenewDeadline)
+}
+
+// Owned returns the indexes of the shards held by this replica
+func (c *Coordinator) Owned() []int {
+	var owned []int
+	now := c.clock.Now()
+	for _, s := range c.shards {
+		if s.valid(now, c.opts.RenewDeadline) {
+			owned = append(owned, s.index)
+		}
+	}
+	return owned
+}
+
+// Claim returns a Claim on the shard of the ConfigReloader, or false when
+// the shard is not held by this replica. The shard is not released before
+// the Claim is.
+func (c *Coordinator) Claim(key types.NamespacedName) (*Claim, bool) {
+	s := c.shards[c.ShardOf(key)]
+	s.inflight.RLock()
+	if !s.valid(c.clock.Now(), c.opts.RenewDeadline) {
+		s.inflight.RUnlock()
+		return nil, false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return &Claim{shard: s, key: key.String(), First: !s.synced[key.String()]}, true
+}
+
+// Release ends the Claim
+func (cl *Claim) Release() {
+	cl.shard.mu.Lock()
+	if cl.shard.synced != nil {
+		cl.shard.synced[cl.key] = true
+	}
+	cl.shard.mu.Unlock()
+	cl.shard.inflight.RUnlock()
+}
+
+// Acquired returns the channel receiving the Lease of every shard acquired
+// by this replica, so that its ConfigReloaders are reconciled
+func (c *Coordinator) Acquired() <-chan event.GenericEvent {
+	return c.events
+}
+
+// NeedLeaderElection is false as every replica runs a Coordinator
+func (c *Coordinator) NeedLeaderElection() bool {
+	return false
+}
+
+// Start renews the Leases of this replica and rebalances the shards until
+// the context is done, then releases the shards held
+func (c *Coordinator) Start(ctx context.Context) error {
+	logger := log.FromContext(ctx).WithValues("identity", c.opts.Identity)
+	logger.Info("Starting shard coordinator", "shards", len(c.shards))
+
+	ticker := time.NewTicker(c.opts.RetryPeriod)
+	defer ticker.Stop()
+	for {
+		if err := c.sync(ctx); err != nil {
+			logger.Error(err, "failed to sync shards")
+		}
+
+		select {
+		case <-ctx.Done():
+			stopCtx, cancel := context.WithTimeout(context.Background(), c.opts.RenewDeadline)
+			defer cancel()
+			return c.stop(log.IntoContext(stopCtx, logger))
+		case <-ticker.C:
+		}
+	}
+}
+
+// sync renews the member Lease and acquires, renews and releases shards
+// according to the live members
+func (c *Coordinator) sync(ctx context.Context) error {
+	if err := c.renewMember(ctx); err != nil {
+		return err
+	}
+	members, err := c.members(ctx)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, s := range c.shards {
+		desired := ownerOf(members, s.index) == c.opts.Identity
+		if err := c.syncShard(ctx, s, desired); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// ownerOf returns the member a shard is assigned to: the member with the
+// highest hash of its identity and the shard index. Adding a member only
+// moves the shards whose highest hash becomes the one of the new member,
+// about 1/N of them, and removing one only moves the shards it held.
+func ownerOf(members []string, index int) string {
+	var owner string
+	var highest uint64
+	for _, member := range members {
+		h := fnv.New64a()
+		fmt.Fprintf(h, "%s/%d", member, index)
+		if score := mix(h.Sum64()); owner == "" || score > highest {
+			owner, highest = member, score
+		}
+	}
+	return owner
+}
+
+// mix spreads the bits of an FNV hash, which barely differ between the
+// similar inputs of ownerOf
+func mix(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
+
+func (c *Coordinator) syncShard(ctx context.Context, s *shard, desired bool) error {
+	logger := log.FromContext(ctx).WithValues("shard", s.index)
+	now := c.clock.Now()
+
+	var lease coordinationv1.Lease
+	err := c.client.Get(ctx, client.ObjectKey{Namespace: c.opts.Namespace, Name: s.name}, &lease)
+	if apierrors.IsNotFound(err) {
+		if !desired {
+			return nil
+		}
+		lease = c.newLease(s.name, RoleShard)
+		lease.Labels[ShardLabel] = strconv.Itoa(s.index)
+		lease.Spec.HolderIdentity = ptr.To(c.opts.Identity)
+		lease.Spec.AcquireTime = &metav1.MicroTime{Time: now}
+		lease.Spec.RenewTime = &metav1.MicroTime{Time: now}
+		if err := c.client.Create(ctx, &lease); err != nil {
+			return fmt.Errorf("failed to create shard lease %s/%s: %w", lease.Namespace, lease.Name, err)
+		}
+		c.acquired(ctx, s, &lease, now)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get shard lease %s/%s: %w", c.opts.Namespace, s.name, err)
+	}
+
+	s.mu.Lock()
+	if lease.ResourceVersion != s.observed {
+		s.observed, s.observedTime = lease.ResourceVersion, now
+	}
+	expired := now.Sub(s.observedTime) >= c.opts.LeaseDuration
+	held, releasing := s.held, s.releasing
+	s.mu.Unlock()
+
+	holder := ptr.Deref(lease.Spec.HolderIdentity, "")
+	switch {
+	case held:
+		if holder != c.opts.Identity {
+			// Only happens when the Lease was not renewed for LeaseDuration,
+			// after the shard stopped being valid locally
+			logger.Info("Lost shard lease", "holder", holder)
+			c.lost(s)
+			return nil
+		}
+		if !desired && !releasing {
+			logger.Info("Releasing shard once in-flight reconciles are done")
+			s.mu.Lock()
+			s.releasing = true
+			s.mu.Unlock()
+			go c.drain(s)
+		}
+		lease.Spec.RenewTime = &metav1.MicroTime{Time: now}
+		if err := c.client.Update(ctx, &lease); err != nil {
+			if apierrors.IsConflict(err) {
+				return nil
+			}
+			return fmt.Errorf("failed to renew shard lease %s/%s: %w", lease.Namespace, lease.Name, err)
+		}
+		s.mu.Lock()
+		s.renewed = now
+		s.observed, s.observedTime = lease.ResourceVersion, now
+		s.mu.Unlock()
+		return nil
+
+	case holder == c.opts.Identity && !desired:
+		// Drained, or left over by a previous run of this replica
+		lease.Spec.HolderIdentity = ptr.To("")
+		if err := c.client.Update(ctx, &lease); err != nil && !apierrors.IsConflict(err) {
+			return fmt.Errorf("failed to release shard lease %s/%s: %w", lease.Namespace, lease.Name, err)
+		}
+		logger.Info("Released shard")
+		return nil
+
+	case desired && (holder == "" || holder == c.opts.Identity || expired):
+		if holder != c.opts.Identity {
+			lease.Spec.LeaseTransitions = ptr.To(ptr.Deref(lease.Spec.LeaseTransitions, 0) + 1)
+		}
+		lease.Spec.HolderIdentity = ptr.To(c.opts.Identity)
+		lease.Spec.LeaseDurationSeconds = ptr.To(int32(c.opts.LeaseDuration.Seconds()))
+		lease.Spec.AcquireTime = &metav1.MicroTime{Time: now}
+		lease.Spec.RenewTime = &metav1.MicroTime{Time: now}
+		if err := c.client.Update(ctx, &lease); err != nil {
+			if apierrors.IsConflict(err) {
+				return nil
+			}
+			return fmt.Errorf("failed to acquire shard lease %s/%s: %w", lease.Namespace, lease.Name, err)
+		}
+		if holder != "" && holder != c.opts.Identity {
+			logger.Info("Took over expired shard lease", "previousHolder", holder)
+		}
+		c.acquired(ctx, s, &lease, now)
+	}
+	return nil
+}
+
+// acquired marks the shard as held and enqueues its ConfigReloaders
+func (c *Coordinator) acquired(ctx context.Context, s *shard, lease *coordinationv1.Lease, now time.Time) {
+	log.FromContext(ctx).Info("Acquired shard", "shard", s.index)
+
+	s.mu.Lock()
+	s.held = true
+	s.renewed = now
+	s.synced = make(map[string]bool)
+	s.observed, s.observedTime = lease.ResourceVersion, now
+	s.mu.Unlock()
+
+	evt := event.GenericEvent{Object: lease.DeepCopy()}
+	go func() {
+		select {
+		case c.events <- evt:
+		case <-ctx.Done():
+		}
+	}()
+}
+
+// drain waits for the Claims on the shard to be released and stops
+// reconciling it. Its Lease is released by the next sync.
+func (c *Coordinator) drain(s *shard) {
+	s.inflight.Lock()
+	defer s.inflight.Unlock()
+	c.lost(s)
+}
+
+func (c *Coordinator) lost(s *shard) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.held = false
+	s.releasing = false
+	s.synced = nil
+}
+
+// stop releases every shard and deletes the member Lease so that the other
+// replicas take over without waiting for the Leases to expire
+func (c *Coordinator) stop(ctx context.Context) error {
+	var errs []error
+	for _, s := range c.shards {
+		s.mu.Lock()
+		held := s.held
+		s.mu.Unlock()
+		if held {
+			c.drain(s)
+		}
+		if err := c.syncShard(ctx, s, false); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	member := c.newLease(c.memberName(), RoleMember)
+	if err := c.client.Delete(ctx, &member); err != nil && !apierrors.IsNotFound(err) {
+		errs = append(errs, fmt.Errorf("failed to delete member lease %s/%s: %w", member.Namespace, member.Name, err))
+	}
+	return errors.Join(errs...)
+}
+
+// renewMember creates or renews the member Lease of this replica
+func (c *Coordinator) renewMember(ctx context.Context) error {
+	now := &metav1.MicroTime{Time: c.clock.Now()}
+
+	var lease coordinationv1.Lease
+	err := c.client.Get(ctx, client.ObjectKey{Namespace: c.opts.Namespace, Name: c.memberName()}, &lease)
+	if apierrors.IsNotFound(err) {
+		lease = c.newLease(c.memberName(), RoleMember)
+		lease.Spec.HolderIdentity = ptr.To(c.opts.Identity)
+		lease.Spec.AcquireTime = now
+		lease.Spec.RenewTime = now
+		if err := c.client.Create(ctx, &lease); err != nil {
+			return fmt.Errorf("failed to create member lease %s/%s: %w", lease.Namespace, lease.Name, err)
+		}
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get member lease %s/%s: %w", c.opts.Namespace, c.memberName(), err)
+	}
+
+	lease.Spec.RenewTime = now
+	if err := c.client.Update(ctx, &lease); err != nil {
+		return fmt.Errorf("failed to renew member lease %s/%s: %w", lease.Namespace, lease.Name, err)
+	}
+	return nil
+}
+
+// members returns the identities of the live members of the group in order.
+// A member is live while its Lease is renewed. Clock skew between replicas
+// only affects how shards are balanced, not which replica holds them.
+func (c *Coordinator) members(ctx context.Context) ([]string, error) {
+	var leases coordinationv1.LeaseList
+	if err := c.client.List(ctx, &leases, client.InNamespace(c.opts.Namespace),
+		client.MatchingLabels{GroupLabel: c.opts.Name, RoleLabel: RoleMember}); err != nil {
+		return nil, fmt.Errorf("failed to list member leases: %w", err)
+	}
+
+	now := c.clock.Now()
+	members := []string{c.opts.Identity}
+	for _, lease := range leases.Items {
+		identity := ptr.Deref(lease.Spec.HolderIdentity, "")
+		if identity == "" || identity == c.opts.Identity || lease.Spec.RenewTime == nil {
+			continue
+		}
+		duration := time.Duration(ptr.Deref(lease.Spec.LeaseDurationSeconds, 0)) * time.Second
+		if now.Before(lease.Spec.RenewTime.Add(duration)) {
+			members = append(members, identity)
+		}
+	}
+	sort.Strings(members)
+	return members, nil
+}
+
+func (c *Coordinator) memberName() string {
+	return fmt.Sprintf("%s-member-%s", c.opts.Name, c.opts.Identity)
+}
+
+func (c *Coordinator) newLease(name, role string) coordinationv1.Lease {
+	return coordinationv1.Lease{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: c.opts.Namespace,
+			Labels:    map[string]string{GroupLabel: c.opts.Name, RoleLabel: role},
+		},
+		Spec: coordinationv1.LeaseSpec{
+			LeaseDurationSeconds: ptr.To(int32(c.opts.LeaseDuration.Seconds())),
+		},
+	}
+}
+
+// valid reports whether the shard is held and its Lease was renewed within
+// the renew deadline
+func (s *shard) valid(now time.Time, renewDeadline time.Duration) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.held && now.Sub(s.renewed) < renewDeadline
+}
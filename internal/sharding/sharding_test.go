@@ -0,0 +1,275 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sharding
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	"k8s.io/apimachinery/pkg/types"
+	clocktesting "k8s.io/utils/clock/testing"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var _ = Describe("Coordinator", func() {
+	const shards = 8
+
+	var (
+		ctx       context.Context
+		cancel    context.CancelFunc
+		k8sClient client.Client
+		clock     *clocktesting.FakeClock
+	)
+
+	BeforeEach(func() {
+		ctx, cancel = context.WithCancel(context.Background())
+		k8sClient = fake.NewClientBuilder().Build()
+		clock = clocktesting.NewFakeClock(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	})
+
+	AfterEach(func() {
+		cancel()
+	})
+
+	newCoordinator := func(identity string) *Coordinator {
+		coordinator, err := New(k8sClient, Options{
+			Name:          "config-reloader",
+			Namespace:     "config-reloader-system",
+			Identity:      identity,
+			Shards:        shards,
+			LeaseDuration: 15 * time.Second,
+			RenewDeadline: 10 * time.Second,
+			RetryPeriod:   2 * time.Second,
+		})
+		Expect(err).NotTo(HaveOccurred())
+		coordinator.clock = clock
+		return coordinator
+	}
+
+	// settled syncs the coordinators and reports whether every shard is
+	// held by exactly one of them, and by the replica it is assigned to
+	settled := func(coordinators ...*Coordinator) bool {
+		holders := make(map[int][]string)
+		members := make([]string, 0, len(coordinators))
+		for _, coordinator := range coordinators {
+			Expect(coordinator.sync(ctx)).To(Succeed())
+			members = append(members, coordinator.opts.Identity)
+		}
+		for _, coordinator := range coordinators {
+			for _, index := range coordinator.Owned() {
+				holders[index] = append(holders[index], coordinator.opts.Identity)
+			}
+		}
+		for index := range shards {
+			if len(holders[index]) != 1 || holders[index][0] != ownerOf(members, index) {
+				return false
+			}
+		}
+		return true
+	}
+
+	// shardOf returns a shard assigned to the member
+	shardOf := func(members []string, member string) int {
+		for index := range shards {
+			if ownerOf(members, index) == member {
+				return index
+			}
+		}
+		Fail("no shard assigned to " + member)
+		return -1
+	}
+
+	// keyInShard returns a ConfigReloader key hashed into the shard
+	keyInShard := func(coordinator *Coordinator, index int) types.NamespacedName {
+		for i := 0; ; i++ {
+			key := types.NamespacedName{Namespace: "default", Name: fmt.Sprintf("reloader-%d", i)}
+			if coordinator.ShardOf(key) == index {
+				return key
+			}
+		}
+	}
+
+	It("validates the options", func() {
+		_, err := New(k8sClient, Options{Name: "config-reloader", Namespace: "default", Identity: "a", Shards: 0})
+		Expect(err).To(MatchError(ContainSubstring("invalid number of shards")))
+
+		_, err = New(k8sClient, Options{
+			Name: "config-reloader", Namespace: "default", Identity: "a", Shards: 1,
+			LeaseDuration: 10 * time.Second, RenewDeadline: 10 * time.Second, RetryPeriod: 2 * time.Second,
+		})
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("spreads keys over every shard", func() {
+		coordinator := newCoordinator("a")
+		seen := make(map[int]bool)
+		for i := range 1000 {
+			seen[coordinator.ShardOf(types.NamespacedName{Namespace: "default", Name: fmt.Sprintf("reloader-%d", i)})] = true
+		}
+		Expect(seen).To(HaveLen(shards))
+	})
+
+	It("acquires every shard when alone", func() {
+		a := newCoordinator("a")
+		Expect(a.sync(ctx)).To(Succeed())
+
+		Expect(a.Owned()).To(HaveLen(shards))
+		Expect(a.Owns(types.NamespacedName{Namespace: "default", Name: "reloader"})).To(BeTrue())
+
+		var leases coordinationv1.LeaseList
+		Expect(k8sClient.List(ctx, &leases, client.MatchingLabels{RoleLabel: RoleShard})).To(Succeed())
+		Expect(leases.Items).To(HaveLen(shards))
+		for _, lease := range leases.Items {
+			Expect(*lease.Spec.HolderIdentity).To(Equal("a"))
+			Expect(lease.Labels).To(HaveKey(ShardLabel))
+		}
+	})
+
+	It("enqueues the shards it acquires", func() {
+		a := newCoordinator("a")
+		Expect(a.sync(ctx)).To(Succeed())
+
+		acquired := make(map[string]bool)
+		for range shards {
+			evt := <-a.Acquired()
+			acquired[evt.Object.GetLabels()[ShardLabel]] = true
+		}
+		Expect(acquired).To(HaveLen(shards))
+	})
+
+	It("rebalances the shards when replicas join and leave", func() {
+		a, b := newCoordinator("a"), newCoordinator("b")
+		Expect(a.sync(ctx)).To(Succeed())
+
+		Eventually(func() bool { return settled(a, b) }).Should(BeTrue())
+
+		By("adding two replicas")
+		c, d := newCoordinator("c"), newCoordinator("d")
+		Eventually(func() bool { return settled(a, b, c, d) }).Should(BeTrue())
+
+		By("stopping replicas")
+		Expect(c.stop(ctx)).To(Succeed())
+		Expect(d.stop(ctx)).To(Succeed())
+		Expect(d.Owned()).To(BeEmpty())
+		Eventually(func() bool { return settled(a, b) }).Should(BeTrue())
+	})
+
+	It("moves only the shards a replica gains or loses", func() {
+		const shards = 32
+		before := []string{"a", "b", "c"}
+		after := []string{"a", "b", "c", "d"}
+
+		moved := 0
+		for index := range shards {
+			if owner := ownerOf(after, index); owner != ownerOf(before, index) {
+				Expect(owner).To(Equal("d"), "only the joining replica gains shards")
+				moved++
+			}
+		}
+		// About a quarter of the shards move, a round-robin assignment would move 24
+		Expect(moved).To(BeNumerically(">", 0))
+		Expect(moved).To(BeNumerically("<=", shards/3))
+
+		for index := range shards {
+			if owner := ownerOf(after, index); owner != "d" {
+				Expect(ownerOf(before, index)).To(Equal(owner), "the leaving replica only releases its shards")
+			}
+		}
+	})
+
+	It("hands a shard over only once the reconciles in flight are done", func() {
+		a, b := newCoordinator("a"), newCoordinator("b")
+		Expect(a.sync(ctx)).To(Succeed())
+
+		key := keyInShard(a, shardOf([]string{"a", "b"}, "b"))
+		claim, ok := a.Claim(key)
+		Expect(ok).To(BeTrue())
+
+		for range 5 {
+			Expect(b.sync(ctx)).To(Succeed())
+			Expect(a.sync(ctx)).To(Succeed())
+		}
+		Expect(b.Owns(key)).To(BeFalse())
+		Expect(a.Owns(key)).To(BeTrue())
+
+		claim.Release()
+		Eventually(func() bool { return settled(a, b) }).Should(BeTrue())
+		Expect(a.Owns(key)).To(BeFalse())
+		Expect(b.Owns(key)).To(BeTrue())
+
+		_, ok = a.Claim(key)
+		Expect(ok).To(BeFalse())
+	})
+
+	It("takes over the shards of a replica that stopped renewing its leases", func() {
+		a, b := newCoordinator("a"), newCoordinator("b")
+		Expect(a.sync(ctx)).To(Succeed())
+		Expect(b.sync(ctx)).To(Succeed())
+		Expect(b.Owned()).To(BeEmpty())
+
+		clock.Step(11 * time.Second)
+		Expect(a.Owned()).To(BeEmpty(), "shards are not reconciled past the renew deadline")
+		Expect(b.sync(ctx)).To(Succeed())
+		Expect(b.Owned()).To(BeEmpty(), "the leases of a are not expired yet")
+
+		clock.Step(5 * time.Second)
+		Expect(b.sync(ctx)).To(Succeed())
+		Expect(b.Owned()).To(HaveLen(shards))
+
+		var lease coordinationv1.Lease
+		Expect(k8sClient.Get(ctx, client.ObjectKey{Namespace: "config-reloader-system", Name: "config-reloader-shard-0"},
+			&lease)).To(Succeed())
+		Expect(lease.Spec.LeaseTransitions).To(Equal(ptr.To[int32](1)))
+	})
+
+	It("releases its shards and member lease when stopped", func() {
+		a, b := newCoordinator("a"), newCoordinator("b")
+		Expect(a.sync(ctx)).To(Succeed())
+		Expect(a.stop(ctx)).To(Succeed())
+		Expect(a.Owned()).To(BeEmpty())
+
+		Expect(b.sync(ctx)).To(Succeed())
+		Expect(b.Owned()).To(HaveLen(shards))
+
+		var leases coordinationv1.LeaseList
+		Expect(k8sClient.List(ctx, &leases, client.MatchingLabels{RoleLabel: RoleMember})).To(Succeed())
+		Expect(leases.Items).To(HaveLen(1))
+		Expect(*leases.Items[0].Spec.HolderIdentity).To(Equal("b"))
+	})
+
+	It("flags the first claim on a key after the shard is acquired", func() {
+		a := newCoordinator("a")
+		Expect(a.sync(ctx)).To(Succeed())
+		key := types.NamespacedName{Namespace: "default", Name: "reloader"}
+
+		claim, ok := a.Claim(key)
+		Expect(ok).To(BeTrue())
+		Expect(claim.First).To(BeTrue())
+		claim.Release()
+
+		claim, ok = a.Claim(key)
+		Expect(ok).To(BeTrue())
+		Expect(claim.First).To(BeFalse())
+		claim.Release()
+	})
+})
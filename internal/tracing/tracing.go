@@ -0,0 +1,93 @@
+// Package tracing sets up the OpenTelemetry tracer provider of the operator.
+//
+// Spans are exported with OTLP over gRPC when an endpoint is configured. The
+// global no-op provider is left in place otherwise, so the operator and its
+// tests never reach out to a collector unless asked to.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+// shutdownTimeout bounds the export of the spans left when the operator stops
+const shutdownTimeout = 5 * time.Second
+
+// Options configures the export of spans
+type Options struct {
+	// Endpoint is the host:port of the OTLP gRPC collector. Tracing is
+	// disabled when empty.
+	Endpoint string
+	// Insecure disables TLS towards the collector
+	Insecure bool
+	// SampleRatio is the fraction of traces recorded, between 0 and 1
+	SampleRatio float64
+	// ServiceName identifies the operator in the traces
+	ServiceName string
+}
+
+// Provider exports the spans of the operator. It is added to the manager as
+// a Runnable that flushes the spans left when the manager stops, and runs on
+// every replica.
+type Provider struct {
+	provider *sdktrace.TracerProvider
+}
+
+// Setup installs the global tracer provider exporting to the endpoint of the
+// options. It returns a nil Provider when tracing is disabled.
+func Setup(ctx context.Context, opts Options) (*Provider, error) {
+	if opts.Endpoint == "" {
+		return nil, nil
+	}
+	if opts.SampleRatio < 0 || opts.SampleRatio > 1 {
+		return nil, fmt.Errorf("sample ratio must be between 0 and 1, got %v", opts.SampleRatio)
+	}
+
+	exporterOpts := []otlptracegrpc.Option{otlptracegrpc.WithEndpoint(opts.Endpoint)}
+	if opts.Insecure {
+		exporterOpts = append(exporterOpts, otlptracegrpc.WithInsecure())
+	}
+	exporter, err := otlptracegrpc.New(ctx, exporterOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OTLP exporter: %w", err)
+	}
+
+	res, err := resource.Merge(resource.Default(),
+		resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(opts.ServiceName)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create tracing resource: %w", err)
+	}
+
+	p := &Provider{provider: sdktrace.NewTracerProvider(sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(opts.SampleRatio))),
+	)}
+	otel.SetTracerProvider(p.provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{}, propagation.Baggage{}))
+	return p, nil
+}
+
+// Start waits for the manager to stop and flushes the spans left
+func (p *Provider) Start(ctx context.Context) error {
+	<-ctx.Done()
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := p.provider.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("failed to flush spans: %w", err)
+	}
+	return nil
+}
+
+// NeedLeaderElection tells the manager to run the Provider on every replica
+func (p *Provider) NeedLeaderElection() bool {
+	return false
+}
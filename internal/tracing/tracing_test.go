@@ -0,0 +1,65 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tracing
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+var _ = Describe("Setup", func() {
+	BeforeEach(func() {
+		provider := otel.GetTracerProvider()
+		DeferCleanup(func() {
+			otel.SetTracerProvider(provider)
+		})
+	})
+
+	It("should leave the no-op provider in place without an endpoint", func() {
+		provider := otel.GetTracerProvider()
+		p, err := Setup(context.Background(), Options{SampleRatio: 1})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(p).To(BeNil())
+		Expect(otel.GetTracerProvider()).To(BeIdenticalTo(provider))
+	})
+
+	It("should reject sample ratios outside of 0 and 1", func() {
+		_, err := Setup(context.Background(), Options{Endpoint: "localhost:4317", SampleRatio: 2})
+		Expect(err).To(MatchError(ContainSubstring("sample ratio")))
+	})
+
+	It("should install an exporting provider and flush it when stopped", func() {
+		p, err := Setup(context.Background(), Options{
+			Endpoint:    "localhost:4317",
+			Insecure:    true,
+			SampleRatio: 1,
+			ServiceName: "config-reloader-operator",
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(p).NotTo(BeNil())
+		Expect(p.NeedLeaderElection()).To(BeFalse())
+		Expect(otel.GetTracerProvider()).To(BeAssignableToTypeOf(&sdktrace.TracerProvider{}))
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		Expect(p.Start(ctx)).To(Succeed())
+	})
+})
@@ -0,0 +1,551 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	configv1 "github.com/shehbazk/config-reloader-operator/api/v1"
+	"github.com/shehbazk/config-reloader-operator/internal/podrefs"
+)
+
+// approveVerb is the verb on ConfigReloaders that allows approving their
+// held reloads
+const approveVerb = "approve"
+
+// log is for logging in this package.
+var configreloaderlog = logf.Log.WithName("configreloader-resource")
+
+// SetupConfigReloaderWebhookWithManager registers the webhook for ConfigReloader in the manager.
+func SetupConfigReloaderWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).For(&configv1.ConfigReloader{}).
+		WithValidator(&ConfigReloaderCustomValidator{Client: mgr.GetClient()}).
+		WithDefaulter(&ConfigReloaderCustomDefaulter{}).
+		Complete()
+}
+
+// +kubebuilder:webhook:path=/mutate-config-dev-v1-configreloader,mutating=true,failurePolicy=fail,sideEffects=None,groups=config.dev,resources=configreloaders,verbs=create;update,versions=v1,name=mconfigreloader-v1.kb.io,admissionReviewVersions=v1
+
+// ConfigReloaderCustomDefaulter struct is responsible for setting default values on the custom resource of the
+// Kind ConfigReloader when those are created or updated.
+type ConfigReloaderCustomDefaulter struct{}
+
+var _ webhook.CustomDefaulter = &ConfigReloaderCustomDefaulter{}
+
+// Default implements webhook.CustomDefaulter so a webhook will be registered for the Kind ConfigReloader.
+func (d *ConfigReloaderCustomDefaulter) Default(ctx context.Context, obj runtime.Object) error {
+	configreloader, ok := obj.(*configv1.ConfigReloader)
+	if !ok {
+		return fmt.Errorf("expected an ConfigReloader object but got %T", obj)
+	}
+	configreloaderlog.Info("Defaulting for ConfigReloader", "name", configreloader.GetName())
+
+	if configreloader.Spec.RestartPolicy == "" {
+		configreloader.Spec.RestartPolicy = configv1.RestartPolicyAnnotation
+	}
+	if configreloader.Spec.DeletionPolicy == "" {
+		configreloader.Spec.DeletionPolicy = configv1.DeletionPolicyRetain
+	}
+
+	return recordApprover(ctx, configreloader)
+}
+
+// recordApprover sets the approved-by annotation to the user who set the
+// approve annotation. Any other change to the approved-by annotation is
+// reverted.
+func recordApprover(ctx context.Context, cr *configv1.ConfigReloader) error {
+	req, err := admission.RequestFromContext(ctx)
+	if err != nil {
+		// Not called through the admission webhook, nobody to record
+		return nil
+	}
+	var old configv1.ConfigReloader
+	if len(req.OldObject.Raw) > 0 {
+		if err := json.Unmarshal(req.OldObject.Raw, &old); err != nil {
+			return fmt.Errorf("failed to decode the old ConfigReloader: %w", err)
+		}
+	}
+
+	approve := cr.Annotations[configv1.ApproveAnnotation]
+	approver, approved := old.Annotations[configv1.ApprovedByAnnotation]
+	switch {
+	case approve == "":
+		delete(cr.Annotations, configv1.ApprovedByAnnotation)
+	case approve != old.Annotations[configv1.ApproveAnnotation]:
+		cr.Annotations[configv1.ApprovedByAnnotation] = req.UserInfo.Username
+	case approved:
+		cr.Annotations[configv1.ApprovedByAnnotation] = approver
+	default:
+		delete(cr.Annotations, configv1.ApprovedByAnnotation)
+	}
+	return nil
+}
+
+// +kubebuilder:webhook:path=/validate-config-dev-v1-configreloader,mutating=false,failurePolicy=fail,sideEffects=None,groups=config.dev,resources=configreloaders,verbs=create;update,versions=v1,name=vconfigreloader-v1.kb.io,admissionReviewVersions=v1
+// +kubebuilder:rbac:groups=authorization.k8s.io,resources=subjectaccessreviews,verbs=create
+
+// ConfigReloaderCustomValidator struct is responsible for validating the ConfigReloader resource
+// when it is created, updated, or deleted.
+type ConfigReloaderCustomValidator struct {
+	Client client.Client
+}
+
+var _ webhook.CustomValidator = &ConfigReloaderCustomValidator{}
+
+// ValidateCreate implements webhook.CustomValidator so a webhook will be registered for the type ConfigReloader.
+func (v *ConfigReloaderCustomValidator) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	configreloader, ok := obj.(*configv1.ConfigReloader)
+	if !ok {
+		return nil, fmt.Errorf("expected a ConfigReloader object but got %T", obj)
+	}
+	configreloaderlog.Info("Validation for ConfigReloader upon creation", "name", configreloader.GetName())
+
+	if errs := v.validateApproval(ctx, &configv1.ConfigReloader{}, configreloader); len(errs) > 0 {
+		return nil, apierrors.NewInvalid(configv1.GroupVersion.WithKind("ConfigReloader").GroupKind(), configreloader.Name, errs)
+	}
+	return v.validateConfigReloader(ctx, configreloader)
+}
+
+// ValidateUpdate implements webhook.CustomValidator so a webhook will be registered for the type ConfigReloader.
+func (v *ConfigReloaderCustomValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	oldConfigReloader, ok := oldObj.(*configv1.ConfigReloader)
+	if !ok {
+		return nil, fmt.Errorf("expected a ConfigReloader object for the oldObj but got %T", oldObj)
+	}
+	configreloader, ok := newObj.(*configv1.ConfigReloader)
+	if !ok {
+		return nil, fmt.Errorf("expected a ConfigReloader object for the newObj but got %T", newObj)
+	}
+	configreloaderlog.Info("Validation for ConfigReloader upon update", "name", configreloader.GetName())
+
+	errs := v.validateApproval(ctx, oldConfigReloader, configreloader)
+	errs = append(errs, v.validateHeldChanges(ctx, oldConfigReloader, configreloader)...)
+	if len(errs) > 0 {
+		return nil, apierrors.NewInvalid(configv1.GroupVersion.WithKind("ConfigReloader").GroupKind(), configreloader.Name, errs)
+	}
+
+	// Metadata-only updates such as finalizers and reload requests must not be
+	// blocked by changes in the cluster since the spec was admitted
+	if configreloader.DeletionTimestamp != nil || equality.Semantic.DeepEqual(oldConfigReloader.Spec, configreloader.Spec) {
+		return nil, nil
+	}
+
+	return v.validateConfigReloader(ctx, configreloader)
+}
+
+// ValidateDelete implements webhook.CustomValidator so a webhook will be registered for the type ConfigReloader.
+func (v *ConfigReloaderCustomValidator) ValidateDelete(_ context.Context, _ runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+func (v *ConfigReloaderCustomValidator) validateConfigReloader(
+	ctx context.Context,
+	cr *configv1.ConfigReloader,
+) (admission.Warnings, error) {
+	var allErrs field.ErrorList
+	var warnings admission.Warnings
+	specPath := field.NewPath("spec")
+
+	selector := labels.Everything()
+	if cr.Spec.Selector != nil {
+		var err error
+		if selector, err = metav1.LabelSelectorAsSelector(cr.Spec.Selector); err != nil {
+			allErrs = append(allErrs, field.Invalid(specPath.Child("selector"), cr.Spec.Selector, err.Error()))
+		}
+	} else {
+		warnings = append(warnings,
+			"spec.selector is not set, every pod in the namespace that uses the watched resources will be restarted")
+	}
+
+	allErrs = append(allErrs, validateRefs(cr, specPath.Child("configMaps"), cr.Spec.ConfigMaps)...)
+	allErrs = append(allErrs, validateRefs(cr, specPath.Child("secrets"), cr.Spec.Secrets)...)
+	if len(cr.Spec.ConfigMaps) == 0 && len(cr.Spec.Secrets) == 0 {
+		warnings = append(warnings, "no configMaps or secrets are watched, no pods will be restarted")
+	}
+
+	for i, manager := range cr.Spec.IgnoredFieldManagers {
+		if manager == "" {
+			allErrs = append(allErrs, field.Required(specPath.Child("ignoredFieldManagers").Index(i),
+				"field manager must be set"))
+		}
+	}
+
+	if cr.Spec.IgnoreOwnerReferences && cr.Spec.RestartPolicy == configv1.RestartPolicyAnnotation {
+		allErrs = append(allErrs, field.Invalid(specPath.Child("ignoreOwnerReferences"), true,
+			"the annotation restart policy cannot restart pods that are not owned by a controller, use the delete policy"))
+	}
+	if cr.Spec.RestartPolicy == configv1.RestartPolicyDelete && cr.Spec.Canary == nil {
+		warnings = append(warnings,
+			"the delete restart policy deletes all affected pods at once, use the annotation policy for rolling restarts")
+	}
+	if cr.Spec.Canary != nil && cr.Spec.Canary.Analysis != nil {
+		address := cr.Spec.Canary.Analysis.Address
+		if u, err := url.Parse(address); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			allErrs = append(allErrs, field.Invalid(specPath.Child("canary", "analysis", "address"), address,
+				"must be an http or https URL"))
+		}
+	}
+	if cr.Spec.DryRun && cr.Spec.Suspend {
+		warnings = append(warnings, "spec.dryRun has no effect while spec.suspend is set")
+	}
+	if cr.Spec.Approval != nil && (cr.Spec.RevisionHistoryLimit == nil || *cr.Spec.RevisionHistoryLimit == 0) {
+		warnings = append(warnings,
+			"spec.revisionHistoryLimit is not set, approvers will not see which keys of the held changes changed")
+	}
+
+	if len(allErrs) > 0 {
+		return warnings, apierrors.NewInvalid(configv1.GroupVersion.WithKind("ConfigReloader").GroupKind(), cr.Name, allErrs)
+	}
+
+	// The remaining checks depend on the requester and on the cluster state
+	allErrs = append(allErrs, v.validateCrossNamespaceAccess(ctx, cr, specPath)...)
+
+	targetWarnings, targetErrs, err := v.validateTargets(ctx, cr, selector, specPath)
+	if err != nil {
+		return warnings, apierrors.NewInternalError(err)
+	}
+	warnings = append(warnings, targetWarnings...)
+	allErrs = append(allErrs, targetErrs...)
+
+	if len(allErrs) > 0 {
+		return warnings, apierrors.NewInvalid(configv1.GroupVersion.WithKind("ConfigReloader").GroupKind(), cr.Name, allErrs)
+	}
+	return warnings, nil
+}
+
+// validateRefs rejects refs without a name, refs that resolve to the same
+// resource and selected keys that cannot name a data key
+func validateRefs(cr *configv1.ConfigReloader, path *field.Path, refs []configv1.ResourceRef) field.ErrorList {
+	var allErrs field.ErrorList
+	seen := make(map[string]bool, len(refs))
+
+	for i, ref := range refs {
+		for j, key := range ref.Keys {
+			for _, msg := range validation.IsConfigMapKey(key) {
+				allErrs = append(allErrs, field.Invalid(path.Index(i).Child("keys").Index(j), key, msg))
+			}
+		}
+
+		if ref.Name == "" {
+			allErrs = append(allErrs, field.Required(path.Index(i).Child("name"), "name must be set"))
+			continue
+		}
+
+		key := podrefs.Key(refNamespace(cr, ref), ref.Name)
+		if seen[key] {
+			allErrs = append(allErrs, field.Duplicate(path.Index(i), key))
+		}
+		seen[key] = true
+	}
+
+	return allErrs
+}
+
+// validateCrossNamespaceAccess rejects refs to other namespaces that the
+// requesting user is not allowed to read
+func (v *ConfigReloaderCustomValidator) validateCrossNamespaceAccess(
+	ctx context.Context,
+	cr *configv1.ConfigReloader,
+	specPath *field.Path,
+) field.ErrorList {
+	req, err := admission.RequestFromContext(ctx)
+	if err != nil {
+		// Not called through the admission webhook, nobody to check access for
+		return nil
+	}
+
+	var allErrs field.ErrorList
+	check := func(path *field.Path, refs []configv1.ResourceRef, resource string) {
+		for i, ref := range refs {
+			namespace := refNamespace(cr, ref)
+			if namespace == cr.Namespace {
+				continue
+			}
+
+			review := &authorizationv1.SubjectAccessReview{
+				Spec: authorizationv1.SubjectAccessReviewSpec{
+					User:   req.UserInfo.Username,
+					Groups: req.UserInfo.Groups,
+					UID:    req.UserInfo.UID,
+					Extra:  subjectAccessReviewExtra(req.UserInfo.Extra),
+					ResourceAttributes: &authorizationv1.ResourceAttributes{
+						Namespace: namespace,
+						Verb:      "get",
+						Resource:  resource,
+						Name:      ref.Name,
+					},
+				},
+			}
+			if err := v.Client.Create(ctx, review); err != nil {
+				allErrs = append(allErrs, field.InternalError(path.Index(i), err))
+				continue
+			}
+			if !review.Status.Allowed {
+				allErrs = append(allErrs, field.Forbidden(path.Index(i).Child("namespace"),
+					fmt.Sprintf("user %q cannot get %s %s/%s", req.UserInfo.Username, resource, namespace, ref.Name)))
+			}
+		}
+	}
+
+	check(specPath.Child("configMaps"), cr.Spec.ConfigMaps, "configmaps")
+	check(specPath.Child("secrets"), cr.Spec.Secrets, "secrets")
+	return allErrs
+}
+
+// subjectAccessReviewExtra converts the extra user info of an admission
+// request for a SubjectAccessReview
+func subjectAccessReviewExtra(extra map[string]authenticationv1.ExtraValue) map[string]authorizationv1.ExtraValue {
+	out := make(map[string]authorizationv1.ExtraValue, len(extra))
+	for k, v := range extra {
+		out[k] = authorizationv1.ExtraValue(v)
+	}
+	return out
+}
+
+// validateApproval rejects approvals from users that are not allowed to
+// approve the ConfigReloader, that is to use the approve verb on it, and
+// approved-by annotations that do not name the approving user
+func (v *ConfigReloaderCustomValidator) validateApproval(
+	ctx context.Context,
+	oldCR, cr *configv1.ConfigReloader,
+) field.ErrorList {
+	approve := cr.Annotations[configv1.ApproveAnnotation]
+	approver := cr.Annotations[configv1.ApprovedByAnnotation]
+	if approve == oldCR.Annotations[configv1.ApproveAnnotation] &&
+		approver == oldCR.Annotations[configv1.ApprovedByAnnotation] {
+		return nil
+	}
+	req, err := admission.RequestFromContext(ctx)
+	if err != nil {
+		// Not called through the admission webhook, nobody to check access for
+		return nil
+	}
+
+	path := field.NewPath("metadata", "annotations")
+	switch {
+	case approve == "" && approver == "":
+		// Withdrawing an approval needs no permission
+		return nil
+	case approve == "" || approver != req.UserInfo.Username:
+		return field.ErrorList{field.Forbidden(path.Key(configv1.ApprovedByAnnotation),
+			"the approver is recorded by the admission webhook and cannot be set")}
+	}
+
+	allowed, err := v.canApprove(ctx, req, cr)
+	if err != nil {
+		return field.ErrorList{field.InternalError(path.Key(configv1.ApproveAnnotation), err)}
+	}
+	if !allowed {
+		return field.ErrorList{field.Forbidden(path.Key(configv1.ApproveAnnotation),
+			fmt.Sprintf("user %q cannot approve ConfigReloader %s/%s", req.UserInfo.Username, cr.Namespace, cr.Name))}
+	}
+	return nil
+}
+
+// validateHeldChanges rejects removing spec.approval while changes are held
+// for approval, as it applies them, from users that are not allowed to
+// approve the ConfigReloader
+func (v *ConfigReloaderCustomValidator) validateHeldChanges(
+	ctx context.Context,
+	oldCR, cr *configv1.ConfigReloader,
+) field.ErrorList {
+	if oldCR.Status.PendingApproval == nil || oldCR.Spec.Approval == nil || cr.Spec.Approval != nil {
+		return nil
+	}
+	req, err := admission.RequestFromContext(ctx)
+	if err != nil {
+		// Not called through the admission webhook, nobody to check access for
+		return nil
+	}
+
+	path := field.NewPath("spec", "approval")
+	allowed, err := v.canApprove(ctx, req, cr)
+	if err != nil {
+		return field.ErrorList{field.InternalError(path, err)}
+	}
+	if !allowed {
+		return field.ErrorList{field.Forbidden(path,
+			fmt.Sprintf("user %q cannot approve ConfigReloader %s/%s, which holds changes for approval",
+				req.UserInfo.Username, cr.Namespace, cr.Name))}
+	}
+	return nil
+}
+
+// canApprove reports whether the requesting user may use the approve verb on
+// the ConfigReloader
+func (v *ConfigReloaderCustomValidator) canApprove(
+	ctx context.Context,
+	req admission.Request,
+	cr *configv1.ConfigReloader,
+) (bool, error) {
+	review := &authorizationv1.SubjectAccessReview{
+		Spec: authorizationv1.SubjectAccessReviewSpec{
+			User:   req.UserInfo.Username,
+			Groups: req.UserInfo.Groups,
+			UID:    req.UserInfo.UID,
+			Extra:  subjectAccessReviewExtra(req.UserInfo.Extra),
+			ResourceAttributes: &authorizationv1.ResourceAttributes{
+				Namespace: cr.Namespace,
+				Verb:      approveVerb,
+				Group:     configv1.GroupVersion.Group,
+				Resource:  "configreloaders",
+				Name:      cr.Name,
+			},
+		},
+	}
+	if err := v.Client.Create(ctx, review); err != nil {
+		return false, err
+	}
+	return review.Status.Allowed, nil
+}
+
+// validateTargets rejects an annotation policy whose targets are all
+// standalone pods and ConfigReloaders that restart the same pods as another
+// one with the same priority. Overlaps with a different priority are admitted
+// with a warning.
+func (v *ConfigReloaderCustomValidator) validateTargets(
+	ctx context.Context,
+	cr *configv1.ConfigReloader,
+	selector labels.Selector,
+	specPath *field.Path,
+) (admission.Warnings, field.ErrorList, error) {
+	var warnings admission.Warnings
+	var allErrs field.ErrorList
+
+	targets, err := v.targetPods(ctx, cr, selector)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(targets) == 0 {
+		return nil, nil, nil
+	}
+
+	if cr.Spec.RestartPolicy == configv1.RestartPolicyAnnotation {
+		standalone := true
+		for _, pod := range targets {
+			if len(pod.OwnerReferences) > 0 {
+				standalone = false
+				break
+			}
+		}
+		if standalone {
+			allErrs = append(allErrs, field.Invalid(specPath.Child("restartPolicy"), cr.Spec.RestartPolicy,
+				"all affected pods are standalone pods, which the annotation restart policy cannot restart, use the delete policy"))
+		}
+	}
+
+	var others configv1.ConfigReloaderList
+	if err := v.Client.List(ctx, &others, client.InNamespace(cr.Namespace)); err != nil {
+		return nil, nil, fmt.Errorf("failed to list ConfigReloaders: %w", err)
+	}
+
+	for i := range others.Items {
+		other := &others.Items[i]
+		if other.Name == cr.Name || other.DeletionTimestamp != nil {
+			continue
+		}
+
+		otherSelector := labels.Everything()
+		if other.Spec.Selector != nil {
+			if otherSelector, err = metav1.LabelSelectorAsSelector(other.Spec.Selector); err != nil {
+				continue
+			}
+		}
+
+		watchedCMs, watchedSecrets := watchedResources(other)
+		for name, pod := range targets {
+			if !otherSelector.Matches(labels.Set(pod.Labels)) ||
+				!podrefs.Uses(&pod.Spec, pod.Namespace, watchedCMs, watchedSecrets) {
+				continue
+			}
+			if other.Spec.Priority == cr.Spec.Priority {
+				allErrs = append(allErrs, field.Forbidden(specPath.Child("selector"),
+					fmt.Sprintf("pod %s is also restarted by ConfigReloader %s with the same priority, "+
+						"set spec.priority to decide which one restarts it", name, other.Name)))
+			} else {
+				warnings = append(warnings, fmt.Sprintf(
+					"pod %s is also restarted by ConfigReloader %s, the one with the higher priority restarts the pods both select",
+					name, other.Name))
+			}
+			break
+		}
+	}
+
+	return warnings, allErrs, nil
+}
+
+// targetPods returns the pods the ConfigReloader would restart, keyed by name
+func (v *ConfigReloaderCustomValidator) targetPods(
+	ctx context.Context,
+	cr *configv1.ConfigReloader,
+	selector labels.Selector,
+) (map[string]*corev1.Pod, error) {
+	var podList corev1.PodList
+	if err := v.Client.List(ctx, &podList, client.InNamespace(cr.Namespace),
+		client.MatchingLabelsSelector{Selector: selector}); err != nil {
+		return nil, fmt.Errorf("failed to list pods: %w", err)
+	}
+
+	watchedCMs, watchedSecrets := watchedResources(cr)
+	targets := make(map[string]*corev1.Pod)
+	for i := range podList.Items {
+		pod := &podList.Items[i]
+		if podrefs.Uses(&pod.Spec, pod.Namespace, watchedCMs, watchedSecrets) {
+			targets[pod.Name] = pod
+		}
+	}
+	return targets, nil
+}
+
+// watchedResources builds the lookup maps of the resources watched by cr
+func watchedResources(cr *configv1.ConfigReloader) (map[string]bool, map[string]bool) {
+	watchedCMs := make(map[string]bool, len(cr.Spec.ConfigMaps))
+	for _, ref := range cr.Spec.ConfigMaps {
+		watchedCMs[podrefs.Key(refNamespace(cr, ref), ref.Name)] = true
+	}
+	watchedSecrets := make(map[string]bool, len(cr.Spec.Secrets))
+	for _, ref := range cr.Spec.Secrets {
+		watchedSecrets[podrefs.Key(refNamespace(cr, ref), ref.Name)] = true
+	}
+	return watchedCMs, watchedSecrets
+}
+
+func refNamespace(cr *configv1.ConfigReloader, ref configv1.ResourceRef) string {
+	if ref.Namespace == "" {
+		return cr.Namespace
+	}
+	return ref.Namespace
+}
@@ -0,0 +1,388 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	configv1 "github.com/shehbazk/config-reloader-operator/api/v1"
+)
+
+var _ = Describe("ConfigReloader Webhook", func() {
+	var (
+		ctx       context.Context
+		scheme    *runtime.Scheme
+		obj       *configv1.ConfigReloader
+		validator ConfigReloaderCustomValidator
+		defaulter ConfigReloaderCustomDefaulter
+		allowed   map[string]bool
+	)
+
+	newClient := func(objs ...client.Object) client.Client {
+		return fake.NewClientBuilder().
+			WithScheme(scheme).
+			WithObjects(objs...).
+			WithInterceptorFuncs(interceptor.Funcs{
+				Create: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.CreateOption) error {
+					if review, ok := obj.(*authorizationv1.SubjectAccessReview); ok {
+						attrs := review.Spec.ResourceAttributes
+						review.Status.Allowed = allowed[attrs.Resource+"/"+attrs.Namespace+"/"+attrs.Name]
+						return nil
+					}
+					return c.Create(ctx, obj, opts...)
+				},
+			}).
+			Build()
+	}
+
+	newPod := func(name string, labels map[string]string, owned bool, configMap string) *corev1.Pod {
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", Labels: labels},
+			Spec: corev1.PodSpec{
+				Containers: []corev1.Container{{
+					Name:  "app",
+					Image: "nginx",
+					EnvFrom: []corev1.EnvFromSource{{
+						ConfigMapRef: &corev1.ConfigMapEnvSource{
+							LocalObjectReference: corev1.LocalObjectReference{Name: configMap},
+						},
+					}},
+				}},
+			},
+		}
+		if owned {
+			controller := true
+			pod.OwnerReferences = []metav1.OwnerReference{{
+				APIVersion: "apps/v1",
+				Kind:       "ReplicaSet",
+				Name:       name + "-rs",
+				UID:        "uid-" + types.UID(name),
+				Controller: &controller,
+			}}
+		}
+		return pod
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		scheme = runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+		Expect(configv1.AddToScheme(scheme)).To(Succeed())
+		allowed = map[string]bool{}
+
+		obj = &configv1.ConfigReloader{
+			ObjectMeta: metav1.ObjectMeta{Name: "app-reloader", Namespace: "default"},
+			Spec: configv1.ConfigReloaderSpec{
+				ConfigMaps:    []configv1.ResourceRef{{Name: "app-config"}},
+				Selector:      &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+				RestartPolicy: configv1.RestartPolicyAnnotation,
+			},
+		}
+		validator = ConfigReloaderCustomValidator{Client: newClient()}
+		defaulter = ConfigReloaderCustomDefaulter{}
+	})
+
+	Context("When creating ConfigReloader under Defaulting Webhook", func() {
+		It("Should default the restart policy to annotation", func() {
+			obj.Spec.RestartPolicy = ""
+			Expect(defaulter.Default(ctx, obj)).To(Succeed())
+			Expect(obj.Spec.RestartPolicy).To(Equal(configv1.RestartPolicyAnnotation))
+		})
+
+		It("Should not default the namespace of refs", func() {
+			Expect(defaulter.Default(ctx, obj)).To(Succeed())
+			Expect(obj.Spec.ConfigMaps[0].Namespace).To(BeEmpty())
+		})
+	})
+
+	Context("When creating or updating ConfigReloader under Validating Webhook", func() {
+		It("Should admit a valid ConfigReloader", func() {
+			warnings, err := validator.ValidateCreate(ctx, obj)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(warnings).To(BeEmpty())
+		})
+
+		It("Should deny an invalid selector", func() {
+			obj.Spec.Selector = &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{{
+				Key: "app", Operator: "Bogus",
+			}}}
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(apierrors.IsInvalid(err)).To(BeTrue())
+			Expect(err.Error()).To(ContainSubstring("spec.selector"))
+		})
+
+		It("Should deny refs without a name and duplicate refs", func() {
+			obj.Spec.ConfigMaps = []configv1.ResourceRef{
+				{Name: "app-config"},
+				{Name: "app-config", Namespace: "default"},
+				{Name: ""},
+			}
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(apierrors.IsInvalid(err)).To(BeTrue())
+			Expect(err.Error()).To(ContainSubstring("spec.configMaps[1]: Duplicate value"))
+			Expect(err.Error()).To(ContainSubstring("spec.configMaps[2].name: Required value"))
+		})
+
+		It("Should deny selected keys that cannot name a data key", func() {
+			obj.Spec.ConfigMaps[0].Keys = []string{"level", "", "a/b"}
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(apierrors.IsInvalid(err)).To(BeTrue())
+			Expect(err.Error()).To(ContainSubstring("spec.configMaps[0].keys[1]: Invalid value"))
+			Expect(err.Error()).To(ContainSubstring("spec.configMaps[0].keys[2]: Invalid value"))
+			Expect(err.Error()).NotTo(ContainSubstring("keys[0]"))
+		})
+
+		It("Should deny empty ignored field managers", func() {
+			obj.Spec.IgnoredFieldManagers = []string{"helm", ""}
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(apierrors.IsInvalid(err)).To(BeTrue())
+			Expect(err.Error()).To(ContainSubstring("spec.ignoredFieldManagers[1]: Required value"))
+		})
+
+		It("Should deny ignoreOwnerReferences with the annotation policy", func() {
+			obj.Spec.IgnoreOwnerReferences = true
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(apierrors.IsInvalid(err)).To(BeTrue())
+			Expect(err.Error()).To(ContainSubstring("spec.ignoreOwnerReferences"))
+
+			obj.Spec.RestartPolicy = configv1.RestartPolicyDelete
+			_, err = validator.ValidateCreate(ctx, obj)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("Should deny canary analyses without an http address", func() {
+			obj.Spec.Canary = &configv1.CanaryStrategy{
+				Analysis: &configv1.CanaryAnalysis{Address: "prometheus:9090", Query: "up == 0"},
+			}
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(apierrors.IsInvalid(err)).To(BeTrue())
+			Expect(err.Error()).To(ContainSubstring("spec.canary.analysis.address"))
+
+			obj.Spec.Canary.Analysis.Address = "http://prometheus.monitoring:9090"
+			_, err = validator.ValidateCreate(ctx, obj)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("Should warn when approvals are required without revision history", func() {
+			obj.Spec.Approval = &configv1.ApprovalPolicy{}
+			warnings, err := validator.ValidateCreate(ctx, obj)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(warnings).To(ConsistOf(ContainSubstring("spec.revisionHistoryLimit is not set")))
+
+			obj.Spec.RevisionHistoryLimit = ptr.To(int32(5))
+			warnings, err = validator.ValidateCreate(ctx, obj)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(warnings).To(BeEmpty())
+		})
+
+		It("Should warn when nothing is watched or no selector is set", func() {
+			obj.Spec.ConfigMaps = nil
+			obj.Spec.Selector = nil
+			warnings, err := validator.ValidateCreate(ctx, obj)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(warnings).To(HaveLen(2))
+		})
+
+		It("Should deny the annotation policy when all affected pods are standalone", func() {
+			validator.Client = newClient(newPod("standalone", map[string]string{"app": "web"}, false, "app-config"))
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(apierrors.IsInvalid(err)).To(BeTrue())
+			Expect(err.Error()).To(ContainSubstring("spec.restartPolicy"))
+
+			validator.Client = newClient(
+				newPod("standalone", map[string]string{"app": "web"}, false, "app-config"),
+				newPod("owned", map[string]string{"app": "web"}, true, "app-config"),
+			)
+			_, err = validator.ValidateCreate(ctx, obj)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("Should deny a ConfigReloader that restarts the same pods as another one", func() {
+			other := &configv1.ConfigReloader{
+				ObjectMeta: metav1.ObjectMeta{Name: "other-reloader", Namespace: "default"},
+				Spec: configv1.ConfigReloaderSpec{
+					ConfigMaps:    []configv1.ResourceRef{{Name: "app-config"}},
+					RestartPolicy: configv1.RestartPolicyAnnotation,
+				},
+			}
+			pod := newPod("web", map[string]string{"app": "web"}, true, "app-config")
+			validator.Client = newClient(other, pod)
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(apierrors.IsInvalid(err)).To(BeTrue())
+			Expect(err.Error()).To(ContainSubstring("also restarted by ConfigReloader other-reloader"))
+
+			other.Spec.ConfigMaps = []configv1.ResourceRef{{Name: "unrelated"}}
+			validator.Client = newClient(other, pod)
+			_, err = validator.ValidateCreate(ctx, obj)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("Should warn about a ConfigReloader that restarts the same pods with another priority", func() {
+			other := &configv1.ConfigReloader{
+				ObjectMeta: metav1.ObjectMeta{Name: "other-reloader", Namespace: "default"},
+				Spec: configv1.ConfigReloaderSpec{
+					ConfigMaps:    []configv1.ResourceRef{{Name: "app-config"}},
+					RestartPolicy: configv1.RestartPolicyAnnotation,
+				},
+			}
+			obj.Spec.Priority = 10
+			validator.Client = newClient(other, newPod("web", map[string]string{"app": "web"}, true, "app-config"))
+			warnings, err := validator.ValidateCreate(ctx, obj)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(warnings).To(ContainElement(ContainSubstring("also restarted by ConfigReloader other-reloader")))
+		})
+
+		It("Should check access to refs in other namespaces", func() {
+			obj.Spec.Secrets = []configv1.ResourceRef{{Name: "shared-tls", Namespace: "shared"}}
+			ctx = admission.NewContextWithRequest(ctx, admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{
+				UserInfo: authenticationv1.UserInfo{Username: "alice"},
+			}})
+
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(apierrors.IsInvalid(err)).To(BeTrue())
+			Expect(err.Error()).To(ContainSubstring(`user "alice" cannot get secrets shared/shared-tls`))
+
+			allowed["secrets/shared/shared-tls"] = true
+			_, err = validator.ValidateCreate(ctx, obj)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("Should admit updates that do not change the spec", func() {
+			oldObj := obj.DeepCopy()
+			obj.Spec.IgnoreOwnerReferences = true
+			oldObj.Spec.IgnoreOwnerReferences = true
+			obj.Finalizers = []string{"config.dev/finalizer"}
+			_, err := validator.ValidateUpdate(ctx, oldObj, obj)
+			Expect(err).NotTo(HaveOccurred())
+
+			obj.Spec.Suspend = true
+			_, err = validator.ValidateUpdate(ctx, oldObj, obj)
+			Expect(apierrors.IsInvalid(err)).To(BeTrue())
+		})
+	})
+
+	Context("When approving a held reload", func() {
+		var oldObj *configv1.ConfigReloader
+
+		// requestBy returns a context holding an update request from the user
+		requestBy := func(user string) context.Context {
+			raw, err := json.Marshal(oldObj)
+			Expect(err).NotTo(HaveOccurred())
+			return admission.NewContextWithRequest(ctx, admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{
+				Operation: admissionv1.Update,
+				UserInfo:  authenticationv1.UserInfo{Username: user},
+				OldObject: runtime.RawExtension{Raw: raw},
+			}})
+		}
+
+		BeforeEach(func() {
+			obj.Spec.Approval = &configv1.ApprovalPolicy{}
+			oldObj = obj.DeepCopy()
+		})
+
+		It("Should record the approver", func() {
+			obj.Annotations = map[string]string{
+				configv1.ApproveAnnotation:    "abcd1234",
+				configv1.ApprovedByAnnotation: "mallory",
+			}
+			Expect(defaulter.Default(requestBy("alice"), obj)).To(Succeed())
+			Expect(obj.Annotations).To(HaveKeyWithValue(configv1.ApprovedByAnnotation, "alice"))
+
+			// The approver is kept until the approval changes
+			oldObj = obj.DeepCopy()
+			obj.Annotations[configv1.ApprovedByAnnotation] = "mallory"
+			Expect(defaulter.Default(requestBy("mallory"), obj)).To(Succeed())
+			Expect(obj.Annotations).To(HaveKeyWithValue(configv1.ApprovedByAnnotation, "alice"))
+
+			delete(obj.Annotations, configv1.ApproveAnnotation)
+			Expect(defaulter.Default(requestBy("bob"), obj)).To(Succeed())
+			Expect(obj.Annotations).NotTo(HaveKey(configv1.ApprovedByAnnotation))
+		})
+
+		It("Should only admit approvals from users allowed to approve", func() {
+			obj.Annotations = map[string]string{
+				configv1.ApproveAnnotation:    "abcd1234",
+				configv1.ApprovedByAnnotation: "alice",
+			}
+			_, err := validator.ValidateUpdate(requestBy("alice"), oldObj, obj)
+			Expect(apierrors.IsInvalid(err)).To(BeTrue())
+			Expect(err.Error()).To(ContainSubstring(`user "alice" cannot approve ConfigReloader default/app-reloader`))
+
+			allowed["configreloaders/default/app-reloader"] = true
+			_, err = validator.ValidateUpdate(requestBy("alice"), oldObj, obj)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("Should reject approvers that are not the requesting user", func() {
+			allowed["configreloaders/default/app-reloader"] = true
+			obj.Annotations = map[string]string{
+				configv1.ApproveAnnotation:    "abcd1234",
+				configv1.ApprovedByAnnotation: "alice",
+			}
+			_, err := validator.ValidateUpdate(requestBy("mallory"), oldObj, obj)
+			Expect(apierrors.IsInvalid(err)).To(BeTrue())
+			Expect(err.Error()).To(ContainSubstring("the approver is recorded by the admission webhook"))
+		})
+
+		It("Should only let approvers remove the approval while changes are held", func() {
+			obj.Spec.Approval = nil
+			_, err := validator.ValidateUpdate(requestBy("alice"), oldObj, obj)
+			Expect(err).NotTo(HaveOccurred())
+
+			oldObj.Status.PendingApproval = &configv1.PendingApproval{ID: "abcd1234"}
+			_, err = validator.ValidateUpdate(requestBy("alice"), oldObj, obj)
+			Expect(apierrors.IsInvalid(err)).To(BeTrue())
+			Expect(err.Error()).To(ContainSubstring(
+				`spec.approval: Forbidden: user "alice" cannot approve ConfigReloader default/app-reloader`))
+
+			allowed["configreloaders/default/app-reloader"] = true
+			_, err = validator.ValidateUpdate(requestBy("alice"), oldObj, obj)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("Should admit updates that leave the approval unchanged", func() {
+			oldObj.Annotations = map[string]string{
+				configv1.ApproveAnnotation:    "abcd1234",
+				configv1.ApprovedByAnnotation: "alice",
+			}
+			obj = oldObj.DeepCopy()
+			obj.Finalizers = []string{"config.dev/finalizer"}
+			_, err := validator.ValidateUpdate(requestBy("system:serviceaccount:config-reloader-system:controller-manager"),
+				oldObj, obj)
+			Expect(err).NotTo(HaveOccurred())
+		})
+	})
+})
@@ -0,0 +1,163 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	configv1 "github.com/shehbazk/config-reloader-operator/api/v1"
+)
+
+// log is for logging in this package.
+var reloadnotifierlog = logf.Log.WithName("reloadnotifier-resource")
+
+// SetupReloadNotifierWebhookWithManager registers the webhook for ReloadNotifier in the manager.
+func SetupReloadNotifierWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).For(&configv1.ReloadNotifier{}).
+		WithValidator(&ReloadNotifierCustomValidator{Client: mgr.GetClient()}).
+		Complete()
+}
+
+// +kubebuilder:webhook:path=/validate-config-dev-v1-reloadnotifier,mutating=false,failurePolicy=fail,sideEffects=None,groups=config.dev,resources=reloadnotifiers,verbs=create;update,versions=v1,name=vreloadnotifier-v1.kb.io,admissionReviewVersions=v1
+
+// ReloadNotifierCustomValidator struct is responsible for validating the ReloadNotifier resource
+// when it is created, updated, or deleted.
+type ReloadNotifierCustomValidator struct {
+	Client client.Client
+}
+
+var _ webhook.CustomValidator = &ReloadNotifierCustomValidator{}
+
+// ValidateCreate implements webhook.CustomValidator so a webhook will be registered for the type ReloadNotifier.
+func (v *ReloadNotifierCustomValidator) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	notifier, ok := obj.(*configv1.ReloadNotifier)
+	if !ok {
+		return nil, fmt.Errorf("expected a ReloadNotifier object but got %T", obj)
+	}
+	reloadnotifierlog.Info("Validation for ReloadNotifier upon creation", "name", notifier.GetName())
+
+	return nil, v.validateReloadNotifier(ctx, notifier)
+}
+
+// ValidateUpdate implements webhook.CustomValidator so a webhook will be registered for the type ReloadNotifier.
+func (v *ReloadNotifierCustomValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	oldNotifier, ok := oldObj.(*configv1.ReloadNotifier)
+	if !ok {
+		return nil, fmt.Errorf("expected a ReloadNotifier object for the oldObj but got %T", oldObj)
+	}
+	notifier, ok := newObj.(*configv1.ReloadNotifier)
+	if !ok {
+		return nil, fmt.Errorf("expected a ReloadNotifier object for the newObj but got %T", newObj)
+	}
+	reloadnotifierlog.Info("Validation for ReloadNotifier upon update", "name", notifier.GetName())
+
+	// Any change of the spec can redirect the Secrets to another receiver, so
+	// only metadata-only updates skip the access checks
+	if notifier.DeletionTimestamp != nil || equality.Semantic.DeepEqual(oldNotifier.Spec, notifier.Spec) {
+		return nil, nil
+	}
+	return nil, v.validateReloadNotifier(ctx, notifier)
+}
+
+// ValidateDelete implements webhook.CustomValidator so a webhook will be registered for the type ReloadNotifier.
+func (v *ReloadNotifierCustomValidator) ValidateDelete(_ context.Context, _ runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+func (v *ReloadNotifierCustomValidator) validateReloadNotifier(
+	ctx context.Context,
+	notifier *configv1.ReloadNotifier,
+) error {
+	var allErrs field.ErrorList
+	specPath := field.NewPath("spec")
+
+	if notifier.Spec.URL != "" {
+		u, err := url.Parse(notifier.Spec.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			allErrs = append(allErrs, field.Invalid(specPath.Child("url"), notifier.Spec.URL,
+				"must be an http or https URL"))
+		}
+	}
+	allErrs = append(allErrs, v.validateSecretAccess(ctx, notifier, specPath)...)
+
+	if len(allErrs) > 0 {
+		return apierrors.NewInvalid(configv1.GroupVersion.WithKind("ReloadNotifier").GroupKind(), notifier.Name, allErrs)
+	}
+	return nil
+}
+
+// validateSecretAccess rejects Secrets holding the URL or the token that the
+// requesting user is not allowed to read, as the operator reads them with its
+// own permissions and sends them to the receiver
+func (v *ReloadNotifierCustomValidator) validateSecretAccess(
+	ctx context.Context,
+	notifier *configv1.ReloadNotifier,
+	specPath *field.Path,
+) field.ErrorList {
+	req, err := admission.RequestFromContext(ctx)
+	if err != nil {
+		// Not called through the admission webhook, nobody to check access for
+		return nil
+	}
+
+	var allErrs field.ErrorList
+	check := func(path *field.Path, name string) {
+		review := &authorizationv1.SubjectAccessReview{
+			Spec: authorizationv1.SubjectAccessReviewSpec{
+				User:   req.UserInfo.Username,
+				Groups: req.UserInfo.Groups,
+				UID:    req.UserInfo.UID,
+				Extra:  subjectAccessReviewExtra(req.UserInfo.Extra),
+				ResourceAttributes: &authorizationv1.ResourceAttributes{
+					Namespace: notifier.Namespace,
+					Verb:      "get",
+					Resource:  "secrets",
+					Name:      name,
+				},
+			},
+		}
+		if err := v.Client.Create(ctx, review); err != nil {
+			allErrs = append(allErrs, field.InternalError(path, err))
+			return
+		}
+		if !review.Status.Allowed {
+			allErrs = append(allErrs, field.Forbidden(path,
+				fmt.Sprintf("user %q cannot get secrets %s/%s", req.UserInfo.Username, notifier.Namespace, name)))
+		}
+	}
+
+	if notifier.Spec.URLFrom != nil {
+		check(specPath.Child("urlFrom", "name"), notifier.Spec.URLFrom.Name)
+	}
+	if notifier.Spec.Auth != nil {
+		check(specPath.Child("auth", "tokenFrom", "name"), notifier.Spec.Auth.TokenFrom.Name)
+	}
+	return allErrs
+}
@@ -0,0 +1,132 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	configv1 "github.com/shehbazk/config-reloader-operator/api/v1"
+)
+
+var _ = Describe("ReloadNotifier Webhook", func() {
+	var (
+		ctx       context.Context
+		obj       *configv1.ReloadNotifier
+		validator ReloadNotifierCustomValidator
+		allowed   map[string]bool
+	)
+
+	BeforeEach(func() {
+		scheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+		Expect(configv1.AddToScheme(scheme)).To(Succeed())
+		allowed = map[string]bool{}
+
+		validator = ReloadNotifierCustomValidator{Client: fake.NewClientBuilder().
+			WithScheme(scheme).
+			WithInterceptorFuncs(interceptor.Funcs{
+				Create: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.CreateOption) error {
+					if review, ok := obj.(*authorizationv1.SubjectAccessReview); ok {
+						attrs := review.Spec.ResourceAttributes
+						review.Status.Allowed = allowed[attrs.Verb+"/"+attrs.Resource+"/"+attrs.Namespace+"/"+attrs.Name]
+						return nil
+					}
+					return c.Create(ctx, obj, opts...)
+				},
+			}).
+			Build()}
+
+		ctx = admission.NewContextWithRequest(context.Background(), admission.Request{
+			AdmissionRequest: admissionv1.AdmissionRequest{UserInfo: authenticationv1.UserInfo{Username: "alice"}},
+		})
+		obj = &configv1.ReloadNotifier{
+			ObjectMeta: metav1.ObjectMeta{Name: "slack", Namespace: "default"},
+			Spec: configv1.ReloadNotifierSpec{
+				Type: configv1.NotifierSlack,
+				URLFrom: &corev1.SecretKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: "slack-webhook"}, Key: "url",
+				},
+			},
+		}
+	})
+
+	It("Should only admit users allowed to read the Secret holding the URL", func() {
+		_, err := validator.ValidateCreate(ctx, obj)
+		Expect(apierrors.IsInvalid(err)).To(BeTrue())
+		Expect(err.Error()).To(ContainSubstring(`user "alice" cannot get secrets default/slack-webhook`))
+
+		allowed["get/secrets/default/slack-webhook"] = true
+		_, err = validator.ValidateCreate(ctx, obj)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("Should only admit users allowed to read the Secret holding the token", func() {
+		obj.Spec.URLFrom = nil
+		obj.Spec.URL = "https://hooks.example.com/reloads"
+		obj.Spec.Auth = &configv1.NotifierAuth{TokenFrom: corev1.SecretKeySelector{
+			LocalObjectReference: corev1.LocalObjectReference{Name: "hooks-token"}, Key: "token",
+		}}
+
+		_, err := validator.ValidateCreate(ctx, obj)
+		Expect(apierrors.IsInvalid(err)).To(BeTrue())
+		Expect(err.Error()).To(ContainSubstring(`user "alice" cannot get secrets default/hooks-token`))
+
+		allowed["get/secrets/default/hooks-token"] = true
+		_, err = validator.ValidateCreate(ctx, obj)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("Should check access again when the spec changes", func() {
+		oldObj := obj.DeepCopy()
+		obj.Labels = map[string]string{"team": "web"}
+		_, err := validator.ValidateUpdate(ctx, oldObj, obj)
+		Expect(err).NotTo(HaveOccurred())
+
+		obj.Spec.Template = "{{ .Summary }}"
+		_, err = validator.ValidateUpdate(ctx, oldObj, obj)
+		Expect(apierrors.IsInvalid(err)).To(BeTrue())
+	})
+
+	It("Should reject URLs that are not http or https", func() {
+		obj.Spec.URLFrom = nil
+		obj.Spec.URL = "file:///var/run/secrets/kubernetes.io/serviceaccount/token"
+		_, err := validator.ValidateCreate(ctx, obj)
+		Expect(apierrors.IsInvalid(err)).To(BeTrue())
+		Expect(err.Error()).To(ContainSubstring("must be an http or https URL"))
+	})
+
+	It("Should not check access outside of admission requests", func() {
+		_, err := validator.ValidateCreate(context.Background(), obj)
+		Expect(err).NotTo(HaveOccurred())
+	})
+})
@@ -0,0 +1,127 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v2
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	configv1 "github.com/shehbazk/config-reloader-operator/api/v1"
+	configv2 "github.com/shehbazk/config-reloader-operator/api/v2"
+	webhookv1 "github.com/shehbazk/config-reloader-operator/internal/webhook/v1"
+)
+
+// log is for logging in this package.
+var configreloaderlog = logf.Log.WithName("configreloader-resource")
+
+// SetupConfigReloaderWebhookWithManager registers the webhook for ConfigReloader in the manager.
+func SetupConfigReloaderWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).For(&configv2.ConfigReloader{}).
+		WithValidator(&ConfigReloaderCustomValidator{
+			Validator: webhookv1.ConfigReloaderCustomValidator{Client: mgr.GetClient()},
+		}).
+		WithDefaulter(&ConfigReloaderCustomDefaulter{}).
+		Complete()
+}
+
+// +kubebuilder:webhook:path=/mutate-config-dev-v2-configreloader,mutating=true,failurePolicy=fail,sideEffects=None,groups=config.dev,resources=configreloaders,verbs=create;update,versions=v2,name=mconfigreloader-v2.kb.io,admissionReviewVersions=v1
+
+// ConfigReloaderCustomDefaulter struct is responsible for setting default values on the custom resource of the
+// Kind ConfigReloader when those are created or updated.
+type ConfigReloaderCustomDefaulter struct{}
+
+var _ webhook.CustomDefaulter = &ConfigReloaderCustomDefaulter{}
+
+// Default implements webhook.CustomDefaulter so a webhook will be registered for the Kind ConfigReloader.
+func (d *ConfigReloaderCustomDefaulter) Default(_ context.Context, obj runtime.Object) error {
+	configreloader, ok := obj.(*configv2.ConfigReloader)
+	if !ok {
+		return fmt.Errorf("expected an ConfigReloader object but got %T", obj)
+	}
+	configreloaderlog.Info("Defaulting for ConfigReloader", "name", configreloader.GetName())
+
+	if configreloader.Spec.Strategy.Type == "" {
+		configreloader.Spec.Strategy.Type = configv2.StrategyRolloutRestart
+	}
+	if configreloader.Spec.Targets.OwnerReferences == "" {
+		configreloader.Spec.Targets.OwnerReferences = configv2.OwnerReferencePolicyInclude
+	}
+	if configreloader.Spec.DeletionPolicy == "" {
+		configreloader.Spec.DeletionPolicy = configv2.DeletionPolicyRetain
+	}
+
+	return nil
+}
+
+// +kubebuilder:webhook:path=/validate-config-dev-v2-configreloader,mutating=false,failurePolicy=fail,sideEffects=None,groups=config.dev,resources=configreloaders,verbs=create;update,versions=v2,name=vconfigreloader-v2.kb.io,admissionReviewVersions=v1
+
+// ConfigReloaderCustomValidator struct is responsible for validating the ConfigReloader resource
+// when it is created, updated, or deleted. It converts v2 objects to v1 and
+// applies the v1 validation so both versions admit the same objects. Field
+// paths in the returned errors refer to the v1 schema.
+type ConfigReloaderCustomValidator struct {
+	Validator webhookv1.ConfigReloaderCustomValidator
+}
+
+var _ webhook.CustomValidator = &ConfigReloaderCustomValidator{}
+
+// ValidateCreate implements webhook.CustomValidator so a webhook will be registered for the type ConfigReloader.
+func (v *ConfigReloaderCustomValidator) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	configreloader, err := toV1(obj)
+	if err != nil {
+		return nil, err
+	}
+	return v.Validator.ValidateCreate(ctx, configreloader)
+}
+
+// ValidateUpdate implements webhook.CustomValidator so a webhook will be registered for the type ConfigReloader.
+func (v *ConfigReloaderCustomValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	oldConfigReloader, err := toV1(oldObj)
+	if err != nil {
+		return nil, err
+	}
+	configreloader, err := toV1(newObj)
+	if err != nil {
+		return nil, err
+	}
+	return v.Validator.ValidateUpdate(ctx, oldConfigReloader, configreloader)
+}
+
+// ValidateDelete implements webhook.CustomValidator so a webhook will be registered for the type ConfigReloader.
+func (v *ConfigReloaderCustomValidator) ValidateDelete(_ context.Context, _ runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+// toV1 converts a v2 ConfigReloader to v1 for validation
+func toV1(obj runtime.Object) (*configv1.ConfigReloader, error) {
+	configreloader, ok := obj.(*configv2.ConfigReloader)
+	if !ok {
+		return nil, fmt.Errorf("expected a ConfigReloader object but got %T", obj)
+	}
+
+	converted := &configv1.ConfigReloader{}
+	if err := converted.ConvertFrom(configreloader.DeepCopy()); err != nil {
+		return nil, fmt.Errorf("failed to convert ConfigReloader %s to v1: %w", configreloader.Name, err)
+	}
+	return converted, nil
+}
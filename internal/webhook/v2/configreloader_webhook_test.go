@@ -0,0 +1,104 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v2
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	configv1 "github.com/shehbazk/config-reloader-operator/api/v1"
+	configv2 "github.com/shehbazk/config-reloader-operator/api/v2"
+	webhookv1 "github.com/shehbazk/config-reloader-operator/internal/webhook/v1"
+)
+
+var _ = Describe("ConfigReloader Webhook", func() {
+	var (
+		ctx       context.Context
+		obj       *configv2.ConfigReloader
+		validator ConfigReloaderCustomValidator
+		defaulter ConfigReloaderCustomDefaulter
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		scheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+		Expect(configv1.AddToScheme(scheme)).To(Succeed())
+		Expect(configv2.AddToScheme(scheme)).To(Succeed())
+
+		obj = &configv2.ConfigReloader{
+			ObjectMeta: metav1.ObjectMeta{Name: "app-reloader", Namespace: "default"},
+			Spec: configv2.ConfigReloaderSpec{
+				Trigger: configv2.ReloadTrigger{ConfigMaps: []configv2.ObjectReference{{Name: "app-config"}}},
+				Targets: configv2.ReloadTargets{
+					Selector:        &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+					OwnerReferences: configv2.OwnerReferencePolicyInclude,
+				},
+				Strategy: configv2.ReloadStrategy{Type: configv2.StrategyRolloutRestart},
+			},
+		}
+		validator = ConfigReloaderCustomValidator{
+			Validator: webhookv1.ConfigReloaderCustomValidator{
+				Client: fake.NewClientBuilder().WithScheme(scheme).Build(),
+			},
+		}
+		defaulter = ConfigReloaderCustomDefaulter{}
+	})
+
+	Context("When creating ConfigReloader under Defaulting Webhook", func() {
+		It("Should default the strategy and owner reference policy", func() {
+			obj.Spec.Strategy = configv2.ReloadStrategy{}
+			obj.Spec.Targets.OwnerReferences = ""
+			Expect(defaulter.Default(ctx, obj)).To(Succeed())
+			Expect(obj.Spec.Strategy.Type).To(Equal(configv2.StrategyRolloutRestart))
+			Expect(obj.Spec.Targets.OwnerReferences).To(Equal(configv2.OwnerReferencePolicyInclude))
+		})
+	})
+
+	Context("When creating or updating ConfigReloader under Validating Webhook", func() {
+		It("Should admit a valid ConfigReloader", func() {
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("Should apply the v1 validation", func() {
+			obj.Spec.Targets.OwnerReferences = configv2.OwnerReferencePolicyIgnore
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(apierrors.IsInvalid(err)).To(BeTrue())
+
+			obj.Spec.Strategy.Type = configv2.StrategyDeletePods
+			_, err = validator.ValidateCreate(ctx, obj)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("Should admit updates that do not change the spec", func() {
+			obj.Spec.Trigger.ConfigMaps = append(obj.Spec.Trigger.ConfigMaps, obj.Spec.Trigger.ConfigMaps[0])
+			oldObj := obj.DeepCopy()
+			obj.Finalizers = []string{"config.dev/finalizer"}
+			_, err := validator.ValidateUpdate(ctx, oldObj, obj)
+			Expect(err).NotTo(HaveOccurred())
+		})
+	})
+})